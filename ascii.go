@@ -0,0 +1,42 @@
+package wireread
+
+import "math"
+
+// parseASCIIInt parses an optionally signed base-10 integer without allocating.
+// It reports false for empty input, stray characters and int64 overflow.
+func parseASCIIInt(b []byte) (int64, bool) {
+	if len(b) == 0 {
+		return 0, false
+	}
+	neg := false
+	switch b[0] {
+	case '-':
+		neg = true
+		b = b[1:]
+	case '+':
+		b = b[1:]
+	}
+	if len(b) == 0 {
+		return 0, false
+	}
+	var val uint64
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		if val > (math.MaxUint64-9)/10 {
+			return 0, false
+		}
+		val = val*10 + uint64(c-'0')
+	}
+	if neg {
+		if val > uint64(math.MaxInt64)+1 {
+			return 0, false
+		}
+		return -int64(val), true
+	}
+	if val > math.MaxInt64 {
+		return 0, false
+	}
+	return int64(val), true
+}
@@ -0,0 +1,50 @@
+// Package avro decodes Apache Avro binary data and object container files.
+//
+// ParseSchema turns a JSON schema into a Schema and Decode reads one datum
+// written with it. File walks an object container file: it reads the
+// header, its metadata and the writer's schema, then yields the blocks of
+// records, inflating deflate-compressed ones. The reader's schema is always
+// the writer's: schema resolution is not supported.
+//
+// Example usage:
+//
+//	f, err := avro.NewFile(data)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	for {
+//	    rec, err := f.Next()
+//	    if err == io.EOF {
+//	        break
+//	    }
+//	    if err != nil {
+//	        log.Fatal(err)
+//	    }
+//	    fmt.Println(rec)
+//	}
+package avro
+
+import "errors"
+
+var (
+	// ErrMalformed is returned for data that does not match its schema:
+	// out of range indexes, negative lengths, oversized counts and varints,
+	// and container files with bad magic or sync markers.
+	ErrMalformed = errors.New("avro: malformed data")
+
+	// ErrInvalidSchema is returned by ParseSchema for schemas that are not
+	// valid JSON or break the specification's rules.
+	ErrInvalidSchema = errors.New("avro: invalid schema")
+
+	// ErrUnsupportedCodec is returned by NewFile for container files
+	// compressed with codecs other than null and deflate.
+	ErrUnsupportedCodec = errors.New("avro: unsupported codec")
+
+	// ErrDepthExceeded is returned by Decode when records, arrays, maps and
+	// unions nest deeper than MaxDepth.
+	ErrDepthExceeded = errors.New("avro: nesting too deep")
+)
+
+// MaxDepth limits the nesting of values decoded by Decode. Recursive
+// schemas can otherwise describe data of any depth.
+const MaxDepth = 256
@@ -0,0 +1,245 @@
+package avro
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nemohan/wireread"
+)
+
+// enc builds Avro binary test data.
+type enc []byte
+
+func (e *enc) long(v int64)     { *e = binary.AppendUvarint(*e, uint64(v<<1^v>>63)) }
+func (e *enc) str(s string)     { e.long(int64(len(s))); *e = append(*e, s...) }
+func (e *enc) raw(b ...byte)    { *e = append(*e, b...) }
+func (e *enc) double(f float64) { *e = binary.LittleEndian.AppendUint64(*e, math.Float64bits(f)) }
+
+const userSchema = `{
+	"type": "record", "name": "User", "namespace": "com.example",
+	"fields": [
+		{"name": "id", "type": "long"},
+		{"name": "name", "type": "string"},
+		{"name": "email", "type": ["null", "string"]},
+		{"name": "active", "type": "boolean"},
+		{"name": "score", "type": "float"},
+		{"name": "ratio", "type": "double"},
+		{"name": "role", "type": {"type": "enum", "name": "Role", "symbols": ["ADMIN", "USER"]}},
+		{"name": "tags", "type": {"type": "array", "items": "string"}},
+		{"name": "attrs", "type": {"type": "map", "values": "int"}},
+		{"name": "hash", "type": {"type": "fixed", "name": "MD5", "size": 4}},
+		{"name": "raw", "type": "bytes"},
+		{"name": "manager", "type": ["null", "User"]}
+	]
+}`
+
+func encodeUser(e *enc, id int64, manager bool) {
+	e.long(id)
+	e.str("ann")
+	e.long(1)
+	e.str("ann@example.com")
+	e.raw(1)
+	*e = binary.LittleEndian.AppendUint32(*e, math.Float32bits(2.5))
+	e.double(0.25)
+	e.long(1)  // USER
+	e.long(-2) // block of two tags with a byte size
+	e.long(6)
+	e.str("a")
+	e.str("bc")
+	e.long(0)
+	e.long(1)
+	e.str("k")
+	e.long(-7)
+	e.long(0)
+	e.raw(1, 2, 3, 4)
+	e.str("\x00\xff")
+	if manager {
+		e.long(1)
+		encodeUser(e, id+1, false)
+	} else {
+		e.long(0)
+	}
+}
+
+func TestDecode_Record(t *testing.T) {
+	s, err := ParseSchema([]byte(userSchema))
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	if s.Name != "com.example.User" || s.Fields[11].Type.Branches[1] != s {
+		t.Errorf("recursive reference not resolved to the same schema")
+	}
+
+	var e enc
+	encodeUser(&e, 7, true)
+	r := wireread.NewSafeReader(e)
+	got, err := Decode(r, s)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if len(r.Bytes()) != 0 {
+		t.Errorf("%d bytes left after Decode", len(r.Bytes()))
+	}
+
+	want := func(id int64, manager any) map[string]any {
+		return map[string]any{
+			"id": id, "name": "ann", "email": "ann@example.com", "active": true,
+			"score": float32(2.5), "ratio": 0.25, "role": "USER",
+			"tags": []any{"a", "bc"}, "attrs": map[string]any{"k": int32(-7)},
+			"hash": []byte{1, 2, 3, 4}, "raw": []byte{0, 0xff}, "manager": manager,
+		}
+	}
+	w := want(7, want(8, nil))
+	if !reflect.DeepEqual(got, w) {
+		t.Errorf("Decode() = %v, want %v", got, w)
+	}
+
+	// Appending to a fixed value must not overwrite the fields after it in
+	// the input, whichever reader it was decoded with; each decode checks
+	// the append of the one before.
+	for _, r := range []wireread.Reader{wireread.NewFastReader(e), wireread.NewSafeReader(e)} {
+		_ = append(got.(map[string]any)["hash"].([]byte), 0xEE, 0xEE)
+		if got, err = Decode(r, s); err != nil || !reflect.DeepEqual(got, w) {
+			t.Fatalf("Decode(%T) after append = %v, %v, want %v", r, got, err, w)
+		}
+	}
+}
+
+func TestDecode_Logical(t *testing.T) {
+	tests := []struct {
+		schema string
+		data   []byte
+		want   any
+	}{
+		{`{"type": "int", "logicalType": "date"}`, []byte{0x96, 0xb4, 0x02}, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{`{"type": "int", "logicalType": "time-millis"}`, []byte{0xd0, 0x0f}, time.Second},
+		{`{"type": "long", "logicalType": "timestamp-millis"}`, binary.AppendUvarint(nil, 2*1363896240000), time.Unix(1363896240, 0).UTC()},
+		{`{"type": "long", "logicalType": "timestamp-micros"}`, []byte{0x03}, time.UnixMicro(-2).UTC()},
+		{`{"type": "bytes", "logicalType": "decimal", "precision": 5, "scale": 2}`, []byte{0x04, 0xfe, 0x0c}, big.NewRat(-500, 100)},
+		{`{"type": "fixed", "name": "d", "size": 2, "logicalType": "decimal", "precision": 4, "scale": 1}`, []byte{0x01, 0x00}, big.NewRat(256, 10)},
+		{`{"type": "fixed", "name": "dur", "size": 12, "logicalType": "duration"}`, []byte{1, 0, 0, 0, 2, 0, 0, 0, 3, 0, 0, 0}, Duration{1, 2, 3}},
+		{`{"type": "fixed", "name": "id", "size": 16, "logicalType": "uuid"}`, []byte{0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0, 0, 1, 2, 3, 4, 5, 6, 7}, "12345678-9abc-def0-0001-020304050607"},
+		{`{"type": "string", "logicalType": "date"}`, []byte{0x02, 'x'}, "x"}, // ignored annotation
+		{`{"type": "bytes", "logicalType": "decimal", "precision": 2, "scale": 3}`, []byte{0x00}, []byte{}},
+		{`{"type": "bytes", "logicalType": "decimal", "precision": 1000000000, "scale": 1000000000}`, []byte{0x02, 0x01}, []byte{0x01}},
+		{`{"type": "fixed", "name": "d", "size": 2, "logicalType": "decimal", "precision": 5}`, []byte{0x01, 0x00}, []byte{0x01, 0x00}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.schema, func(t *testing.T) {
+			s, err := ParseSchema([]byte(tt.schema))
+			if err != nil {
+				t.Fatalf("ParseSchema() error = %v", err)
+			}
+			got, err := Decode(wireread.NewSafeReader(tt.data), s)
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if r, ok := tt.want.(*big.Rat); ok {
+				if g, ok := got.(*big.Rat); !ok || g.Cmp(r) != 0 {
+					t.Errorf("Decode() = %v, want %v", got, r)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Decode() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecode_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		data   []byte
+		want   error
+	}{
+		{"int overflow", `"int"`, binary.AppendUvarint(nil, 1<<32), ErrMalformed},
+		{"varint overflow", `"long"`, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}, ErrMalformed},
+		{"truncated varint", `"long"`, []byte{0x80}, io.ErrUnexpectedEOF},
+		{"boolean", `"boolean"`, []byte{2}, ErrMalformed},
+		{"negative length", `"string"`, []byte{0x01}, ErrMalformed},
+		{"long string", `"bytes"`, []byte{0x08, 'a'}, io.ErrUnexpectedEOF},
+		{"enum index", `{"type": "enum", "name": "E", "symbols": ["A"]}`, []byte{0x02}, ErrMalformed},
+		{"union index", `["null", "int"]`, []byte{0x04}, ErrMalformed},
+		{"array count", `{"type": "array", "items": "int"}`, []byte{0x40, 0x00}, ErrMalformed},
+		{"null array count", `{"type": "array", "items": "null"}`, binary.AppendUvarint(nil, 2<<20), ErrMalformed},
+		{"null array blocks", `{"type": "array", "items": "null"}`, append(bytes.Repeat(binary.AppendUvarint(nil, 2<<16), 20), 0), ErrMalformed},
+		{"deep", `{"type": "record", "name": "L", "fields": [{"name": "next", "type": ["null", "L"]}]}`, deepList(MaxDepth), ErrDepthExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := ParseSchema([]byte(tt.schema))
+			if err != nil {
+				t.Fatalf("ParseSchema() error = %v", err)
+			}
+			if _, err := Decode(wireread.NewSafeReader(tt.data), s); !errors.Is(err, tt.want) {
+				t.Errorf("Decode() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// deepList encodes a linked list of n nodes.
+func deepList(n int) []byte {
+	b := make([]byte, n, n+1)
+	for i := range b {
+		b[i] = 0x02
+	}
+	return append(b, 0x00)
+}
+
+func TestParseSchema_Errors(t *testing.T) {
+	tests := []string{
+		`{`,
+		`"User"`,
+		`{"type": "record", "fields": []}`,
+		`{"type": "record", "name": "1x", "fields": []}`,
+		`{"type": "record", "name": "R", "fields": [{"name": "a", "type": "int"}, {"name": "a", "type": "int"}]}`,
+		`{"type": "enum", "name": "E", "symbols": []}`,
+		`{"type": "enum", "name": "E", "symbols": ["A", "A"]}`,
+		`{"type": "fixed", "name": "F"}`,
+		`["int", "int"]`,
+		`["null", ["int"]]`,
+		`[]`,
+		`[{"type": "fixed", "name": "F", "size": 1}, {"type": "fixed", "name": "F", "size": 2}]`,
+		`{"type": "record", "name": "int", "fields": []}`,
+	}
+
+	for _, schema := range tests {
+		if _, err := ParseSchema([]byte(schema)); !errors.Is(err, ErrInvalidSchema) {
+			t.Errorf("ParseSchema(%s) error = %v, want ErrInvalidSchema", schema, err)
+		}
+	}
+}
+
+func TestParseSchema_Namespaces(t *testing.T) {
+	s, err := ParseSchema([]byte(`{
+		"type": "record", "name": "a.Outer",
+		"fields": [
+			{"name": "x", "type": {"type": "fixed", "name": "Hash", "size": 1}},
+			{"name": "y", "type": {"type": "enum", "name": "b.Kind", "symbols": ["K"]}},
+			{"name": "z", "type": "Hash"},
+			{"name": "w", "type": "b.Kind"}
+		]
+	}`))
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	names := []string{s.Fields[0].Type.Name, s.Fields[1].Type.Name}
+	if !reflect.DeepEqual(names, []string{"a.Hash", "b.Kind"}) {
+		t.Errorf("names = %v", names)
+	}
+	if s.Fields[2].Type != s.Fields[0].Type || s.Fields[3].Type != s.Fields[1].Type {
+		t.Errorf("references not resolved to their definitions")
+	}
+}
@@ -0,0 +1,172 @@
+package avro
+
+import (
+	"bytes"
+	"compress/flate"
+	"fmt"
+	"io"
+
+	"github.com/nemohan/wireread"
+)
+
+// SyncLen is the size of the sync marker that ends every block.
+const SyncLen = 16
+
+const (
+	magic = "Obj\x01"
+
+	// maxDecompressedSize bounds the inflated size of a block.
+	maxDecompressedSize = 64 << 20
+)
+
+// Codec names.
+const (
+	CodecNull    = "null"
+	CodecDeflate = "deflate"
+)
+
+// Metadata keys.
+const (
+	MetaSchema = "avro.schema"
+	MetaCodec  = "avro.codec"
+)
+
+// File reads an object container file held in memory.
+type File struct {
+	Schema   *Schema
+	Codec    string
+	Metadata map[string][]byte
+	Sync     [SyncLen]byte
+
+	r     *wireread.SafeReader
+	size  int
+	block *wireread.SafeReader
+	left  int64
+}
+
+// Block is a block of records. Data holds Count records, inflated if the
+// file is compressed.
+type Block struct {
+	Count int64
+	Data  []byte
+}
+
+// NewFile reads the header of a container file: the magic, the metadata
+// map and the sync marker. It parses the writer's schema and checks that
+// the codec is supported.
+func NewFile(data []byte) (*File, error) {
+	r := wireread.NewSafeReader(data)
+	m, err := r.ReadBytes(len(magic))
+	if err != nil {
+		return nil, err
+	}
+	if string(m) != magic {
+		return nil, fmt.Errorf("%w: bad magic %q", ErrMalformed, m)
+	}
+
+	f := &File{r: r, size: len(data), Metadata: make(map[string][]byte)}
+	d := decoder{r: r, start: len(data)}
+	err = d.blocks(-1, func() error {
+		k, err := d.readBytes()
+		if err != nil {
+			return err
+		}
+		v, err := d.readBytes()
+		f.Metadata[string(k)] = v
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	sync, err := r.ReadBytes(SyncLen)
+	if err != nil {
+		return nil, err
+	}
+	f.Sync = [SyncLen]byte(sync)
+
+	schema, ok := f.Metadata[MetaSchema]
+	if !ok {
+		return nil, fmt.Errorf("%w: no %s in metadata", ErrMalformed, MetaSchema)
+	}
+	if f.Schema, err = ParseSchema(schema); err != nil {
+		return nil, err
+	}
+	f.Codec = CodecNull
+	if c, ok := f.Metadata[MetaCodec]; ok && len(c) > 0 {
+		f.Codec = string(c)
+	}
+	if f.Codec != CodecNull && f.Codec != CodecDeflate {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedCodec, f.Codec)
+	}
+	return f, nil
+}
+
+// NextBlock reads the next block, checking the sync marker that follows it.
+// It returns io.EOF after the last block.
+func (f *File) NextBlock() (Block, error) {
+	if len(f.r.Bytes()) == 0 {
+		return Block{}, io.EOF
+	}
+	d := decoder{r: f.r, start: f.size}
+	off := d.offset()
+	count, err := d.readLong()
+	if err != nil {
+		return Block{}, err
+	}
+	size, err := d.readLong()
+	if err != nil {
+		return Block{}, err
+	}
+	if count < 0 || size < 0 {
+		return Block{}, d.errorf(off, "block count %d, size %d", count, size)
+	}
+	if size > int64(len(f.r.Bytes())) {
+		return Block{}, io.ErrUnexpectedEOF
+	}
+	data, err := f.r.ReadSlice(int(size))
+	if err != nil {
+		return Block{}, err
+	}
+	sync, err := f.r.ReadBytes(SyncLen)
+	if err != nil {
+		return Block{}, err
+	}
+	if !bytes.Equal(sync, f.Sync[:]) {
+		return Block{}, d.errorf(f.size-len(f.r.Bytes())-SyncLen, "sync marker mismatch")
+	}
+
+	if f.Codec == CodecDeflate {
+		zr := flate.NewReader(bytes.NewReader(data))
+		raw, err := io.ReadAll(io.LimitReader(zr, maxDecompressedSize+1))
+		if err != nil {
+			return Block{}, fmt.Errorf("%w: block at offset %d: %v", ErrMalformed, off, err)
+		}
+		if len(raw) > maxDecompressedSize {
+			return Block{}, fmt.Errorf("%w: decompressed block at offset %d too large", ErrMalformed, off)
+		}
+		data = raw
+	}
+	if count > int64(len(data)) && !zeroSize(f.Schema, nil) {
+		return Block{}, d.errorf(off, "block count %d exceeds its %d bytes", count, len(data))
+	}
+	return Block{Count: count, Data: data}, nil
+}
+
+// Next decodes the next record, reading blocks as needed. It returns io.EOF
+// after the last record. Bytes and fixed values alias the file data or an
+// inflated block.
+func (f *File) Next() (any, error) {
+	for f.left == 0 {
+		if f.block != nil && len(f.block.Bytes()) > 0 {
+			return nil, fmt.Errorf("%w: %d bytes left over after the records of a block", ErrMalformed, len(f.block.Bytes()))
+		}
+		b, err := f.NextBlock()
+		if err != nil {
+			return nil, err
+		}
+		f.block = wireread.NewSafeReader(b.Data)
+		f.left = b.Count
+	}
+	f.left--
+	return Decode(f.block, f.Schema)
+}
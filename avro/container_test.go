@@ -0,0 +1,143 @@
+package avro
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+var testSync = [SyncLen]byte{0xde, 0xad, 0xbe, 0xef, 15: 1}
+
+const pointSchema = `{"type": "record", "name": "P", "fields": [{"name": "x", "type": "int"}, {"name": "y", "type": "int"}]}`
+
+// container builds a container file holding blocks of points.
+func container(t *testing.T, codec string, blocks ...[][2]int64) []byte {
+	t.Helper()
+	e := enc(magic)
+	e.long(2)
+	e.str(MetaSchema)
+	e.str(pointSchema)
+	e.str(MetaCodec)
+	e.str(codec)
+	e.long(0)
+	e = append(e, testSync[:]...)
+
+	for _, points := range blocks {
+		var body enc
+		for _, p := range points {
+			body.long(p[0])
+			body.long(p[1])
+		}
+		if codec == CodecDeflate {
+			var buf bytes.Buffer
+			zw, _ := flate.NewWriter(&buf, flate.BestCompression)
+			zw.Write(body)
+			zw.Close()
+			body = buf.Bytes()
+		}
+		e.long(int64(len(points)))
+		e.long(int64(len(body)))
+		e = append(e, body...)
+		e = append(e, testSync[:]...)
+	}
+	return e
+}
+
+func TestFile(t *testing.T) {
+	for _, codec := range []string{CodecNull, CodecDeflate} {
+		t.Run(codec, func(t *testing.T) {
+			data := container(t, codec, [][2]int64{{1, 2}, {3, -4}}, [][2]int64{{5, 6}})
+			f, err := NewFile(data)
+			if err != nil {
+				t.Fatalf("NewFile() error = %v", err)
+			}
+			if f.Codec != codec || f.Sync != testSync || string(f.Metadata[MetaSchema]) != pointSchema {
+				t.Errorf("header = %q, % x, %q", f.Codec, f.Sync, f.Metadata[MetaSchema])
+			}
+
+			var got []any
+			for {
+				rec, err := f.Next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("Next() error = %v", err)
+				}
+				got = append(got, rec)
+			}
+			want := []any{
+				map[string]any{"x": int32(1), "y": int32(2)},
+				map[string]any{"x": int32(3), "y": int32(-4)},
+				map[string]any{"x": int32(5), "y": int32(6)},
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("records = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestFile_NextBlockAppend(t *testing.T) {
+	data := container(t, CodecNull, [][2]int64{{1, 2}}, [][2]int64{{3, 4}})
+	f, err := NewFile(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := f.NextBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Appending to the block must not overwrite the sync marker or the
+	// block after it.
+	_ = append(b.Data, make([]byte, SyncLen+2)...)
+	if b, err = f.NextBlock(); err != nil || b.Count != 1 {
+		t.Errorf("second NextBlock() = %+v, %v", b, err)
+	}
+}
+
+func TestFile_Errors(t *testing.T) {
+	good := container(t, CodecNull, [][2]int64{{1, 2}})
+
+	if _, err := NewFile([]byte("Obj\x02")); !errors.Is(err, ErrMalformed) {
+		t.Errorf("NewFile(bad magic) error = %v, want ErrMalformed", err)
+	}
+	if _, err := NewFile(container(t, "snappy")); !errors.Is(err, ErrUnsupportedCodec) {
+		t.Errorf("NewFile(snappy) error = %v, want ErrUnsupportedCodec", err)
+	}
+	if _, err := NewFile(good[:20]); err != io.ErrUnexpectedEOF {
+		t.Errorf("NewFile(truncated) error = %v, want io.ErrUnexpectedEOF", err)
+	}
+
+	bad := append([]byte{}, good...)
+	bad[len(bad)-1] ^= 1
+	f, _ := NewFile(bad)
+	if _, err := f.Next(); !errors.Is(err, ErrMalformed) {
+		t.Errorf("Next(bad sync) error = %v, want ErrMalformed", err)
+	}
+
+	// Blocks whose count disagrees with their data.
+	tests := []struct {
+		count byte
+		want  error
+	}{
+		{2 * 3, ErrMalformed},        // three records cannot fit in two bytes
+		{2 * 2, io.ErrUnexpectedEOF}, // the second record is missing
+		{0, ErrMalformed},            // the record is left over
+	}
+	for _, tt := range tests {
+		bad = append(bad[:0:0], good...)
+		bad[len(bad)-SyncLen-4] = tt.count
+		f, _ = NewFile(bad)
+		var err error
+		for err == nil {
+			_, err = f.Next()
+		}
+		if !errors.Is(err, tt.want) {
+			t.Errorf("Next(count %d) error = %v, want %v", tt.count/2, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,320 @@
+package avro
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/nemohan/wireread"
+)
+
+// maxZeroSizeItems bounds the element count of an array whose items encode
+// to zero bytes, such as nulls, which the remaining input cannot.
+const maxZeroSizeItems = 1 << 16
+
+// Duration is the value of the duration logical type.
+type Duration struct {
+	Months       uint32
+	Days         uint32
+	Milliseconds uint32
+}
+
+// Decode reads one datum written with schema s.
+//
+// Values decode to nil, bool, int32, int64, float32, float64, []byte
+// (bytes and fixed, aliasing the input), string, map[string]any (records
+// and maps), string (enum symbols) and []any (arrays). A union decodes to
+// the value of its selected branch. Logical types decode to time.Time
+// (dates and timestamps, in UTC), time.Duration (times of day), *big.Rat
+// (decimals), string (uuid) and Duration.
+func Decode(r wireread.Reader, s *Schema) (any, error) {
+	d := decoder{r: r, start: len(r.Bytes())}
+	return d.decode(s, MaxDepth)
+}
+
+type decoder struct {
+	r     wireread.Reader
+	start int
+}
+
+func (d *decoder) offset() int {
+	return d.start - len(d.r.Bytes())
+}
+
+func (d *decoder) errorf(off int, format string, args ...any) error {
+	return fmt.Errorf("%w: %s at offset %d", ErrMalformed, fmt.Sprintf(format, args...), off)
+}
+
+func (d *decoder) decode(s *Schema, depth int) (any, error) {
+	if depth == 0 {
+		return nil, ErrDepthExceeded
+	}
+	v, err := d.decodeBase(s, depth)
+	if err != nil || s.Logical == "" {
+		return v, err
+	}
+	return d.logical(s, v)
+}
+
+func (d *decoder) decodeBase(s *Schema, depth int) (any, error) {
+	switch s.Kind {
+	case Null:
+		return nil, nil
+	case Boolean:
+		off := d.offset()
+		b, err := d.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if b > 1 {
+			return nil, d.errorf(off, "boolean value %d", b)
+		}
+		return b == 1, nil
+	case Int:
+		return d.readInt()
+	case Long:
+		return d.readLong()
+	case Float:
+		v, err := d.r.ReadUint32LE()
+		return math.Float32frombits(v), err
+	case Double:
+		v, err := d.r.ReadUint64LE()
+		return math.Float64frombits(v), err
+	case Bytes:
+		return d.readBytes()
+	case String:
+		b, err := d.readBytes()
+		return string(b), err
+	case Fixed:
+		return d.next(s.Size)
+	case Enum:
+		off := d.offset()
+		i, err := d.readInt()
+		if err != nil {
+			return nil, err
+		}
+		if i < 0 || int(i) >= len(s.Symbols) {
+			return nil, d.errorf(off, "enum index %d out of range for %s", i, s.Name)
+		}
+		return s.Symbols[i], nil
+	case Union:
+		off := d.offset()
+		i, err := d.readLong()
+		if err != nil {
+			return nil, err
+		}
+		if i < 0 || i >= int64(len(s.Branches)) {
+			return nil, d.errorf(off, "union index %d out of range", i)
+		}
+		return d.decode(s.Branches[i], depth-1)
+	case Record:
+		rec := make(map[string]any, len(s.Fields))
+		for _, f := range s.Fields {
+			v, err := d.decode(f.Type, depth-1)
+			if err != nil {
+				return nil, err
+			}
+			rec[f.Name] = v
+		}
+		return rec, nil
+	case Array:
+		limit := int64(-1)
+		if zeroSize(s.Items, nil) {
+			limit = maxZeroSizeItems
+		}
+		arr := []any{}
+		err := d.blocks(limit, func() error {
+			v, err := d.decode(s.Items, depth-1)
+			arr = append(arr, v)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		return arr, nil
+	case Map:
+		m := make(map[string]any)
+		err := d.blocks(-1, func() error {
+			k, err := d.readBytes()
+			if err != nil {
+				return err
+			}
+			v, err := d.decode(s.Values, depth-1)
+			m[string(k)] = v
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		return m, nil
+	}
+	return nil, fmt.Errorf("%w: unknown kind %v", ErrInvalidSchema, s.Kind)
+}
+
+// blocks reads the blocks of an array or map, calling item for each
+// element. A block is a count followed by that many items; a negative count
+// is followed by the block's size in bytes. A zero count ends the list.
+// A non-negative limit bounds the total count over all blocks; otherwise
+// each count is bounded by the remaining input, as every item takes at least
+// one byte.
+func (d *decoder) blocks(limit int64, item func() error) error {
+	var total int64
+	for {
+		off := d.offset()
+		n, err := d.readLong()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return nil
+		}
+		if n < 0 {
+			if n == math.MinInt64 {
+				return d.errorf(off, "block count %d", n)
+			}
+			n = -n
+			if _, err := d.readLong(); err != nil {
+				return err
+			}
+		}
+		if limit < 0 && n > int64(len(d.r.Bytes())) {
+			return d.errorf(off, "block count %d exceeds the remaining data", n)
+		}
+		if limit >= 0 && n > limit-total {
+			return d.errorf(off, "%d items exceed the limit of %d", total+n, limit)
+		}
+		total += n
+		for ; n > 0; n-- {
+			if err := item(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// zeroSize reports whether values of s can encode to zero bytes.
+func zeroSize(s *Schema, seen map[*Schema]bool) bool {
+	switch s.Kind {
+	case Null:
+		return true
+	case Fixed:
+		return s.Size == 0
+	case Record:
+		if seen[s] {
+			return false
+		}
+		if seen == nil {
+			seen = make(map[*Schema]bool)
+		}
+		seen[s] = true
+		for _, f := range s.Fields {
+			if !zeroSize(f.Type, seen) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// readLong reads a zigzag varint of at most ten bytes.
+func (d *decoder) readLong() (int64, error) {
+	off := d.offset()
+	u, err := d.r.ReadUvarint()
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return 0, d.errorf(off, "varint overflows 64 bits")
+	}
+	return int64(u>>1) ^ -int64(u&1), err
+}
+
+// readInt reads a zigzag varint that must fit in 32 bits.
+func (d *decoder) readInt() (int32, error) {
+	off := d.offset()
+	v, err := d.readLong()
+	if err != nil {
+		return 0, err
+	}
+	if int64(int32(v)) != v {
+		return 0, d.errorf(off, "int value %d overflows 32 bits", v)
+	}
+	return int32(v), nil
+}
+
+// readBytes reads a long length followed by that many bytes.
+func (d *decoder) readBytes() ([]byte, error) {
+	off := d.offset()
+	n, err := d.readLong()
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 {
+		return nil, d.errorf(off, "negative length %d", n)
+	}
+	if n > int64(len(d.r.Bytes())) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return d.next(int(n))
+}
+
+// next consumes n bytes and returns them without copying, with the capacity
+// capped so that appending to the result cannot overwrite what follows.
+// Readers other than SafeReader go through Bytes and Skip.
+func (d *decoder) next(n int) ([]byte, error) {
+	if sr, ok := d.r.(*wireread.SafeReader); ok {
+		return sr.ReadSlice(n)
+	}
+	b := d.r.Bytes()
+	if n > len(b) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if err := d.r.Skip(n); err != nil {
+		return nil, err
+	}
+	return b[:n:n], nil
+}
+
+// logical converts the decoded value v of the underlying type of s.
+func (d *decoder) logical(s *Schema, v any) (any, error) {
+	switch s.Logical {
+	case LogicalDate:
+		return time.Unix(int64(v.(int32))*86400, 0).UTC(), nil
+	case LogicalTimeMillis:
+		return time.Duration(v.(int32)) * time.Millisecond, nil
+	case LogicalTimeMicros:
+		return time.Duration(v.(int64)) * time.Microsecond, nil
+	case LogicalTimestampMillis, LogicalLocalTimestampMillis:
+		return time.UnixMilli(v.(int64)).UTC(), nil
+	case LogicalTimestampMicros, LogicalLocalTimestampMicros:
+		return time.UnixMicro(v.(int64)).UTC(), nil
+	case LogicalTimestampNanos, LogicalLocalTimestampNanos:
+		return time.Unix(0, v.(int64)).UTC(), nil
+	case LogicalUUID:
+		if b, ok := v.([]byte); ok {
+			return fmt.Sprintf("%x-%x-%x-%x-%x", b[:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+		}
+		return v, nil
+	case LogicalDuration:
+		b := v.([]byte)
+		return Duration{
+			Months:       binary.LittleEndian.Uint32(b),
+			Days:         binary.LittleEndian.Uint32(b[4:]),
+			Milliseconds: binary.LittleEndian.Uint32(b[8:]),
+		}, nil
+	case LogicalDecimal:
+		// The unscaled value is a big-endian two's-complement integer.
+		b := v.([]byte)
+		n := new(big.Int).SetBytes(b)
+		if len(b) > 0 && b[0]&0x80 != 0 {
+			n.Sub(n, new(big.Int).Lsh(big.NewInt(1), uint(8*len(b))))
+		}
+		scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(s.Scale)), nil)
+		return new(big.Rat).SetFrac(n, scale), nil
+	}
+	return v, nil
+}
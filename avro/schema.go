@@ -0,0 +1,398 @@
+package avro
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Kind is the type of a schema.
+type Kind uint8
+
+// Schema kinds.
+const (
+	Null Kind = iota
+	Boolean
+	Int
+	Long
+	Float
+	Double
+	Bytes
+	String
+	Record
+	Enum
+	Array
+	Map
+	Union
+	Fixed
+)
+
+var kindNames = [...]string{
+	Null:    "null",
+	Boolean: "boolean",
+	Int:     "int",
+	Long:    "long",
+	Float:   "float",
+	Double:  "double",
+	Bytes:   "bytes",
+	String:  "string",
+	Record:  "record",
+	Enum:    "enum",
+	Array:   "array",
+	Map:     "map",
+	Union:   "union",
+	Fixed:   "fixed",
+}
+
+func (k Kind) String() string {
+	if int(k) < len(kindNames) {
+		return kindNames[k]
+	}
+	return fmt.Sprintf("Kind(%d)", uint8(k))
+}
+
+// primitives maps primitive type names to their kinds.
+var primitives = map[string]Kind{
+	"null":    Null,
+	"boolean": Boolean,
+	"int":     Int,
+	"long":    Long,
+	"float":   Float,
+	"double":  Double,
+	"bytes":   Bytes,
+	"string":  String,
+}
+
+// Logical type names.
+const (
+	LogicalDecimal              = "decimal"
+	LogicalUUID                 = "uuid"
+	LogicalDate                 = "date"
+	LogicalTimeMillis           = "time-millis"
+	LogicalTimeMicros           = "time-micros"
+	LogicalTimestampMillis      = "timestamp-millis"
+	LogicalTimestampMicros      = "timestamp-micros"
+	LogicalTimestampNanos       = "timestamp-nanos"
+	LogicalLocalTimestampMillis = "local-timestamp-millis"
+	LogicalLocalTimestampMicros = "local-timestamp-micros"
+	LogicalLocalTimestampNanos  = "local-timestamp-nanos"
+	LogicalDuration             = "duration"
+)
+
+// Schema is a parsed Avro schema. Named types referenced more than once,
+// including recursively, are the same *Schema.
+type Schema struct {
+	Kind Kind
+
+	// Name is the full name of a record, enum or fixed.
+	Name string
+
+	// Logical is the logical type, or empty. Annotations that do not apply
+	// to the underlying type are dropped, as the specification requires.
+	Logical string
+
+	// Precision and Scale describe a decimal.
+	Precision int
+	Scale     int
+
+	Fields   []Field   // record fields, in order
+	Symbols  []string  // enum symbols
+	Items    *Schema   // array items
+	Values   *Schema   // map values
+	Branches []*Schema // union branches
+	Size     int       // fixed size
+}
+
+// Field is a record field.
+type Field struct {
+	Name string
+	Type *Schema
+}
+
+// ParseSchema parses a schema in its JSON form.
+func ParseSchema(text []byte) (*Schema, error) {
+	var v any
+	if err := json.Unmarshal(text, &v); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+	}
+	p := schemaParser{named: make(map[string]*Schema)}
+	return p.parse(v, "")
+}
+
+// schemaParser tracks named types so later references can find them.
+type schemaParser struct {
+	named map[string]*Schema
+}
+
+func (p *schemaParser) parse(v any, ns string) (*Schema, error) {
+	switch v := v.(type) {
+	case string:
+		if k, ok := primitives[v]; ok {
+			return &Schema{Kind: k}, nil
+		}
+		return p.lookup(v, ns)
+	case []any:
+		return p.parseUnion(v, ns)
+	case map[string]any:
+		return p.parseObject(v, ns)
+	}
+	return nil, fmt.Errorf("%w: unexpected %T", ErrInvalidSchema, v)
+}
+
+// lookup resolves a reference to a named type, trying the enclosing
+// namespace first.
+func (p *schemaParser) lookup(name, ns string) (*Schema, error) {
+	if !strings.Contains(name, ".") && ns != "" {
+		if s, ok := p.named[ns+"."+name]; ok {
+			return s, nil
+		}
+	}
+	if s, ok := p.named[name]; ok {
+		return s, nil
+	}
+	return nil, fmt.Errorf("%w: unknown type %q", ErrInvalidSchema, name)
+}
+
+func (p *schemaParser) parseUnion(v []any, ns string) (*Schema, error) {
+	s := &Schema{Kind: Union}
+	seen := make(map[string]bool)
+	for _, b := range v {
+		branch, err := p.parse(b, ns)
+		if err != nil {
+			return nil, err
+		}
+		if branch.Kind == Union {
+			return nil, fmt.Errorf("%w: union nested in union", ErrInvalidSchema)
+		}
+		key := branch.Name
+		if key == "" {
+			key = branch.Kind.String()
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("%w: duplicate %s in union", ErrInvalidSchema, key)
+		}
+		seen[key] = true
+		s.Branches = append(s.Branches, branch)
+	}
+	if len(s.Branches) == 0 {
+		return nil, fmt.Errorf("%w: empty union", ErrInvalidSchema)
+	}
+	return s, nil
+}
+
+func (p *schemaParser) parseObject(v map[string]any, ns string) (*Schema, error) {
+	t, ok := v["type"].(string)
+	if !ok {
+		// {"type": {...}} and {"type": [...]} wrap another schema.
+		if v["type"] == nil {
+			return nil, fmt.Errorf("%w: missing type", ErrInvalidSchema)
+		}
+		return p.parse(v["type"], ns)
+	}
+
+	var s *Schema
+	var err error
+	switch t {
+	case "record", "error":
+		s, err = p.parseRecord(v, ns)
+	case "enum":
+		s, err = p.parseEnum(v, ns)
+	case "fixed":
+		s, err = p.parseFixed(v, ns)
+	case "array":
+		s = &Schema{Kind: Array}
+		s.Items, err = p.parse(v["items"], ns)
+	case "map":
+		s = &Schema{Kind: Map}
+		s.Values, err = p.parse(v["values"], ns)
+	default:
+		if k, ok := primitives[t]; ok {
+			s = &Schema{Kind: k}
+		} else {
+			return p.lookup(t, ns)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if lt, ok := v["logicalType"].(string); ok {
+		p.applyLogical(s, lt, v)
+	}
+	return s, nil
+}
+
+// define registers a named type, returning the namespace for its children.
+func (p *schemaParser) define(s *Schema, v map[string]any, ns string) (string, error) {
+	name, _ := v["name"].(string)
+	if name == "" {
+		return "", fmt.Errorf("%w: %v without a name", ErrInvalidSchema, s.Kind)
+	}
+	full := name
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		ns, name = name[:i], name[i+1:]
+	} else {
+		if n, ok := v["namespace"].(string); ok {
+			ns = n
+		}
+		if ns != "" {
+			full = ns + "." + name
+		}
+	}
+	if !validName(name) {
+		return "", fmt.Errorf("%w: invalid name %q", ErrInvalidSchema, name)
+	}
+	if _, ok := primitives[full]; ok {
+		return "", fmt.Errorf("%w: name %q redefines a primitive type", ErrInvalidSchema, full)
+	}
+	if _, ok := p.named[full]; ok {
+		return "", fmt.Errorf("%w: %q defined twice", ErrInvalidSchema, full)
+	}
+	s.Name = full
+	p.named[full] = s
+	return ns, nil
+}
+
+func (p *schemaParser) parseRecord(v map[string]any, ns string) (*Schema, error) {
+	s := &Schema{Kind: Record}
+	// Defining the name before the fields lets a record refer to itself.
+	ns, err := p.define(s, v, ns)
+	if err != nil {
+		return nil, err
+	}
+	fields, ok := v["fields"].([]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: record %s without fields", ErrInvalidSchema, s.Name)
+	}
+	seen := make(map[string]bool)
+	for _, f := range fields {
+		fm, ok := f.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%w: field of %s is not an object", ErrInvalidSchema, s.Name)
+		}
+		name, _ := fm["name"].(string)
+		if !validName(name) || seen[name] {
+			return nil, fmt.Errorf("%w: invalid or duplicate field %q in %s", ErrInvalidSchema, name, s.Name)
+		}
+		seen[name] = true
+		t, err := p.parse(fm["type"], ns)
+		if err != nil {
+			return nil, err
+		}
+		s.Fields = append(s.Fields, Field{Name: name, Type: t})
+	}
+	return s, nil
+}
+
+func (p *schemaParser) parseEnum(v map[string]any, ns string) (*Schema, error) {
+	s := &Schema{Kind: Enum}
+	if _, err := p.define(s, v, ns); err != nil {
+		return nil, err
+	}
+	symbols, _ := v["symbols"].([]any)
+	seen := make(map[string]bool)
+	for _, sym := range symbols {
+		name, _ := sym.(string)
+		if !validName(name) || seen[name] {
+			return nil, fmt.Errorf("%w: invalid or duplicate symbol %q in %s", ErrInvalidSchema, name, s.Name)
+		}
+		seen[name] = true
+		s.Symbols = append(s.Symbols, name)
+	}
+	if len(s.Symbols) == 0 {
+		return nil, fmt.Errorf("%w: enum %s without symbols", ErrInvalidSchema, s.Name)
+	}
+	return s, nil
+}
+
+func (p *schemaParser) parseFixed(v map[string]any, ns string) (*Schema, error) {
+	s := &Schema{Kind: Fixed}
+	if _, err := p.define(s, v, ns); err != nil {
+		return nil, err
+	}
+	size, ok := intProp(v, "size")
+	if !ok || size < 0 {
+		return nil, fmt.Errorf("%w: fixed %s without a valid size", ErrInvalidSchema, s.Name)
+	}
+	s.Size = size
+	return s, nil
+}
+
+// applyLogical sets the logical type of s if it is valid for the
+// underlying type.
+func (p *schemaParser) applyLogical(s *Schema, lt string, v map[string]any) {
+	switch lt {
+	case LogicalDecimal:
+		if s.Kind != Bytes && s.Kind != Fixed {
+			return
+		}
+		precision, ok := intProp(v, "precision")
+		scale, _ := intProp(v, "scale")
+		if !ok || precision <= 0 || scale < 0 || scale > precision || precision > maxPrecision(s) {
+			return
+		}
+		s.Precision, s.Scale = precision, scale
+	case LogicalUUID:
+		if s.Kind != String && !(s.Kind == Fixed && s.Size == 16) {
+			return
+		}
+	case LogicalDate, LogicalTimeMillis:
+		if s.Kind != Int {
+			return
+		}
+	case LogicalTimeMicros, LogicalTimestampMillis, LogicalTimestampMicros, LogicalTimestampNanos,
+		LogicalLocalTimestampMillis, LogicalLocalTimestampMicros, LogicalLocalTimestampNanos:
+		if s.Kind != Long {
+			return
+		}
+	case LogicalDuration:
+		if s.Kind != Fixed || s.Size != 12 {
+			return
+		}
+	default:
+		return
+	}
+	s.Logical = lt
+}
+
+// maxDecimalPrecision bounds the precision of a bytes decimal, whose scale
+// would otherwise let the schema make every value arbitrarily costly to
+// decode.
+const maxDecimalPrecision = 1000
+
+// maxPrecision returns the largest decimal precision s can hold: for fixed,
+// the number of digits in the largest signed integer of its size.
+func maxPrecision(s *Schema) int {
+	if s.Kind != Fixed {
+		return maxDecimalPrecision
+	}
+	if s.Size == 0 {
+		return 0
+	}
+	return min(maxDecimalPrecision, int(math.Log10(2)*float64(8*s.Size-1)))
+}
+
+// intProp returns a non-fractional number property.
+func intProp(v map[string]any, key string) (int, bool) {
+	f, ok := v[key].(float64)
+	if !ok || f != float64(int(f)) {
+		return 0, false
+	}
+	return int(f), true
+}
+
+// validName reports whether s matches [A-Za-z_][A-Za-z0-9_]*.
+func validName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		switch {
+		case c == '_', 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z':
+		case i > 0 && '0' <= c && c <= '9':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -1,6 +1,7 @@
 package wireread
 
 import (
+	"encoding/binary"
 	"testing"
 )
 
@@ -49,6 +50,33 @@ func BenchmarkSafeReader_ReadUint32LE(b *testing.B) {
 	}
 }
 
+func BenchmarkOrderedReader_ReadUint32(b *testing.B) {
+	data := make([]byte, b.N*4)
+	r := NewOrderedReader(data, binary.LittleEndian)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		r.ReadUint32()
+	}
+}
+
+func BenchmarkSegmentedReader_ReadUint32LE(b *testing.B) {
+	// 1500-byte segments, like Ethernet frames, so that one read in 375
+	// spans a boundary.
+	data := make([]byte, b.N*4)
+	var segs [][]byte
+	for len(data) > 1500 {
+		segs = append(segs, data[:1500])
+		data = data[1500:]
+	}
+	r := NewSegmentedReader(append(segs, data))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		r.ReadUint32LE()
+	}
+}
+
 func BenchmarkSafeReader_ReadBytes(b *testing.B) {
 	data := make([]byte, b.N*100)
 	r := NewSafeReader(data)
@@ -184,3 +212,78 @@ func BenchmarkComparison_MixedOperations(b *testing.B) {
 		}
 	})
 }
+
+// frame is a typical small message: a 32-bit id, a 16-bit type and a payload.
+var frame = []byte{0x00, 0x00, 0x00, 0x2A, 0x00, 0x01, 'p', 'a', 'y', 'l', 'o', 'a', 'd', '!', '!', '!'}
+
+// readFrame takes a Reader so that, as in a real decoder, the reader escapes.
+func readFrame(r Reader) {
+	r.ReadUint32BE()
+	r.ReadUint16BE()
+	r.Skip(len(r.Bytes()))
+}
+
+func BenchmarkSafeReader_NewPerFrame(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		readFrame(NewSafeReader(frame))
+	}
+}
+
+func BenchmarkSafeReader_ResetPerFrame(b *testing.B) {
+	r := NewSafeReader(nil)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		r.Reset(frame)
+		readFrame(r)
+	}
+}
+
+func BenchmarkSafeReader_PoolPerFrame(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		r := GetSafeReader(frame)
+		readFrame(r)
+		PutSafeReader(r)
+	}
+}
+
+func BenchmarkFastReader_NewPerFrame(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		readFrame(NewFastReader(frame))
+	}
+}
+
+func BenchmarkFastReader_ResetPerFrame(b *testing.B) {
+	r := NewFastReader(nil)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		r.Reset(frame)
+		readFrame(r)
+	}
+}
+
+func BenchmarkFastReader_PoolPerFrame(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		r := GetFastReader(frame)
+		readFrame(r)
+		PutFastReader(r)
+	}
+}
+
+func BenchmarkFastReader_PoolPerFrameParallel(b *testing.B) {
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			r := GetFastReader(frame)
+			readFrame(r)
+			PutFastReader(r)
+		}
+	})
+}
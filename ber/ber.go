@@ -0,0 +1,348 @@
+// Package ber reads ASN.1 data encoded with the Basic and Distinguished
+// Encoding Rules (ITU-T X.690).
+//
+// Reader walks one level of TLVs (tag, length, value) at a time without
+// building a tree: Next returns each element with its content as a slice of
+// the input, and Children opens a Reader over the elements nested in a
+// constructed one. Indefinite-length elements are delimited by their
+// end-of-contents marker, which is not included in the content. The typed
+// accessors on TLV decode the common universal types. They do not check the
+// tag, so they work on implicitly tagged values too.
+//
+// In DER mode, enabled with SetDER, every encoding that DER forbids is
+// rejected with ErrNotDER: indefinite and non-minimal lengths, constructed
+// strings, booleans other than 0x00 and 0xff, non-zero bit string padding
+// and times that are not in UTC with seconds.
+//
+// Example usage:
+//
+//	r := ber.NewReader(data)
+//	r.SetDER(true)
+//	cert, err := r.Next()
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	fields, err := cert.Children()
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	for fields.More() {
+//	    f, err := fields.Next()
+//	    if err != nil {
+//	        log.Fatal(err)
+//	    }
+//	    fmt.Println(f.Class, f.Tag, len(f.Content))
+//	}
+package ber
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/nemohan/wireread"
+)
+
+var (
+	// ErrMalformed is returned for encodings that no BER encoder may
+	// produce, such as reserved length octets, non-minimal tag numbers and
+	// integers, and content of the wrong size for its type.
+	ErrMalformed = errors.New("ber: malformed data")
+
+	// ErrNotDER is returned in DER mode for valid BER that DER forbids.
+	ErrNotDER = errors.New("ber: not valid DER")
+
+	// ErrDepthExceeded is returned when indefinite-length elements nest
+	// deeper than MaxDepth.
+	ErrDepthExceeded = errors.New("ber: nesting too deep")
+)
+
+// MaxDepth limits the nesting of indefinite-length elements, which have to
+// be walked to find where they end.
+const MaxDepth = 64
+
+// Class is the class of a tag.
+type Class uint8
+
+// Tag classes.
+const (
+	ClassUniversal       Class = 0
+	ClassApplication     Class = 1
+	ClassContextSpecific Class = 2
+	ClassPrivate         Class = 3
+)
+
+func (c Class) String() string {
+	switch c {
+	case ClassUniversal:
+		return "universal"
+	case ClassApplication:
+		return "application"
+	case ClassContextSpecific:
+		return "context-specific"
+	case ClassPrivate:
+		return "private"
+	}
+	return fmt.Sprintf("Class(%d)", uint8(c))
+}
+
+// Universal tag numbers.
+const (
+	TagEndOfContents    = 0
+	TagBoolean          = 1
+	TagInteger          = 2
+	TagBitString        = 3
+	TagOctetString      = 4
+	TagNull             = 5
+	TagOID              = 6
+	TagEnumerated       = 10
+	TagUTF8String       = 12
+	TagSequence         = 16
+	TagSet              = 17
+	TagNumericString    = 18
+	TagPrintableString  = 19
+	TagT61String        = 20
+	TagIA5String        = 22
+	TagUTCTime          = 23
+	TagGeneralizedTime  = 24
+	TagVisibleString    = 26
+	TagUniversalString  = 28
+	TagBMPString        = 30
+	highTagNumberMarker = 0x1f
+)
+
+// Header is the identifier and length octets of an element.
+type Header struct {
+	Class       Class
+	Constructed bool
+	Tag         uint32
+
+	// Indefinite is set for constructed elements whose content is ended by
+	// an end-of-contents marker instead of a length.
+	Indefinite bool
+
+	// HeaderLen is the size of the identifier and length octets.
+	HeaderLen int
+}
+
+// Is reports whether h has the given class and tag number.
+func (h Header) Is(class Class, tag uint32) bool {
+	return h.Class == class && h.Tag == tag
+}
+
+// TLV is an element and its content.
+type TLV struct {
+	Header
+
+	// Content is the value octets, without the end-of-contents marker of
+	// an indefinite-length element. It aliases the input.
+	Content []byte
+
+	// Offset is the position of the element's first octet in the data
+	// given to the outermost Reader.
+	Offset int
+
+	der bool
+}
+
+// Children returns a Reader over the elements of a constructed TLV.
+func (t TLV) Children() (*Reader, error) {
+	if !t.Constructed {
+		return nil, fmt.Errorf("%w: children of primitive element at offset %d", ErrMalformed, t.Offset)
+	}
+	r := NewReader(t.Content)
+	r.base = t.Offset + t.HeaderLen
+	r.der = t.der
+	return r, nil
+}
+
+// Reader reads a sequence of TLVs from a buffer.
+type Reader struct {
+	data []byte
+	r    *wireread.SafeReader
+	base int
+	der  bool
+}
+
+// NewReader creates a Reader over data in BER mode.
+func NewReader(data []byte) *Reader {
+	return &Reader{data: data, r: wireread.NewSafeReader(data)}
+}
+
+// SetDER enables or disables DER mode. Readers opened with Children
+// inherit the mode.
+func (r *Reader) SetDER(der bool) {
+	r.der = der
+}
+
+// More reports whether any data is left.
+func (r *Reader) More() bool {
+	return len(r.r.Bytes()) > 0
+}
+
+// Offset returns the position of the next element, counted like
+// TLV.Offset.
+func (r *Reader) Offset() int {
+	return r.base + len(r.data) - len(r.r.Bytes())
+}
+
+// Next reads the next element. It returns io.EOF when no data is left and
+// io.ErrUnexpectedEOF when an element runs past the end. An end-of-contents
+// marker is malformed here, since indefinite-length content never includes
+// its own.
+func (r *Reader) Next() (TLV, error) {
+	if !r.More() {
+		return TLV{}, io.EOF
+	}
+	off := r.Offset()
+	h, length, err := readHeader(r.r, r.der)
+	if err != nil {
+		return TLV{}, locate(err, off)
+	}
+	if h.Class == ClassUniversal && h.Tag == TagEndOfContents {
+		return TLV{}, fmt.Errorf("%w: unexpected end-of-contents at offset %d", ErrMalformed, off)
+	}
+	t := TLV{Header: h, Offset: off, der: r.der}
+	if h.Indefinite {
+		if length, err = indefiniteLen(r.r.Bytes(), 1); err != nil {
+			return TLV{}, locate(err, off)
+		}
+	}
+	if t.Content, err = r.r.ReadSlice(length); err != nil {
+		return TLV{}, err
+	}
+	if h.Indefinite {
+		// Skip the end-of-contents marker.
+		if err := r.r.Skip(2); err != nil {
+			return TLV{}, err
+		}
+	}
+	return t, nil
+}
+
+// locate adds the element offset to an error other than io.ErrUnexpectedEOF.
+func locate(err error, off int) error {
+	if err == io.ErrUnexpectedEOF {
+		return err
+	}
+	return fmt.Errorf("%w at offset %d", err, off)
+}
+
+// readHeader reads identifier and length octets. The returned length is
+// meaningless for indefinite-length elements.
+func readHeader(r *wireread.SafeReader, der bool) (Header, int, error) {
+	start := len(r.Bytes())
+	b, err := r.ReadByte()
+	if err != nil {
+		return Header{}, 0, io.ErrUnexpectedEOF
+	}
+	h := Header{Class: Class(b >> 6), Constructed: b&0x20 != 0, Tag: uint32(b & 0x1f)}
+	if h.Tag == highTagNumberMarker {
+		if h.Tag, err = readHighTag(r); err != nil {
+			return Header{}, 0, err
+		}
+	}
+
+	b, err = r.ReadByte()
+	if err != nil {
+		return Header{}, 0, io.ErrUnexpectedEOF
+	}
+	var length int
+	switch {
+	case b < 0x80:
+		length = int(b)
+	case b == 0x80:
+		if !h.Constructed {
+			return Header{}, 0, fmt.Errorf("%w: indefinite length on primitive element", ErrMalformed)
+		}
+		if der {
+			return Header{}, 0, fmt.Errorf("%w: indefinite length", ErrNotDER)
+		}
+		h.Indefinite = true
+	case b == 0xff:
+		return Header{}, 0, fmt.Errorf("%w: reserved length octet", ErrMalformed)
+	default:
+		n := int(b & 0x7f)
+		lb, err := r.ReadBytes(n)
+		if err != nil {
+			return Header{}, 0, err
+		}
+		if der && lb[0] == 0 {
+			return Header{}, 0, fmt.Errorf("%w: length with leading zero", ErrNotDER)
+		}
+		var v uint64
+		for _, c := range lb {
+			if v > math.MaxInt32>>8 {
+				return Header{}, 0, fmt.Errorf("%w: length too large", ErrMalformed)
+			}
+			v = v<<8 | uint64(c)
+		}
+		if der && v < 0x80 {
+			return Header{}, 0, fmt.Errorf("%w: long form for length %d", ErrNotDER, v)
+		}
+		length = int(v)
+	}
+	h.HeaderLen = start - len(r.Bytes())
+	if length > len(r.Bytes()) {
+		return Header{}, 0, io.ErrUnexpectedEOF
+	}
+	return h, length, nil
+}
+
+// readHighTag reads a tag number of 31 or more in base-128 octets.
+func readHighTag(r *wireread.SafeReader) (uint32, error) {
+	var tag uint32
+	for i := 0; ; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, io.ErrUnexpectedEOF
+		}
+		if i == 0 && b == 0x80 {
+			return 0, fmt.Errorf("%w: tag number with leading zero", ErrMalformed)
+		}
+		if tag > math.MaxUint32>>7 {
+			return 0, fmt.Errorf("%w: tag number too large", ErrMalformed)
+		}
+		tag = tag<<7 | uint32(b&0x7f)
+		if b&0x80 == 0 {
+			break
+		}
+	}
+	if tag < highTagNumberMarker {
+		return 0, fmt.Errorf("%w: tag number %d in high-tag form", ErrMalformed, tag)
+	}
+	return tag, nil
+}
+
+// indefiniteLen returns the size of indefinite-length content at the start
+// of b, up to but excluding its end-of-contents marker.
+func indefiniteLen(b []byte, depth int) (int, error) {
+	if depth > MaxDepth {
+		return 0, ErrDepthExceeded
+	}
+	r := wireread.NewSafeReader(b)
+	for {
+		pos := len(b) - len(r.Bytes())
+		h, length, err := readHeader(r, false)
+		if err != nil {
+			return 0, err
+		}
+		if h.Class == ClassUniversal && h.Tag == TagEndOfContents {
+			if h.Constructed || length != 0 {
+				return 0, fmt.Errorf("%w: invalid end-of-contents", ErrMalformed)
+			}
+			return pos, nil
+		}
+		if h.Indefinite {
+			length, err = indefiniteLen(r.Bytes(), depth+1)
+			if err != nil {
+				return 0, err
+			}
+			length += 2
+		}
+		if err := r.Skip(length); err != nil {
+			return 0, err
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package ber
+
+import (
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(strings.ReplaceAll(s, " ", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestReader_Definite(t *testing.T) {
+	// SEQUENCE { INTEGER 5, [0] { BOOLEAN TRUE }, [APPLICATION 33] OCTET "hi" }
+	data := mustHex(t, "30 0d 02 01 05 a0 03 01 01 ff 5f 21 02 68 69 05 00")
+	r := NewReader(data)
+	seq, err := r.Next()
+	if err != nil {
+		t.Fatalf("Next() error = %v", err)
+	}
+	if !seq.Is(ClassUniversal, TagSequence) || !seq.Constructed || seq.HeaderLen != 2 || len(seq.Content) != 13 {
+		t.Fatalf("Next() = %+v", seq)
+	}
+	// Appending to the content must not overwrite the NULL after it.
+	_ = append(seq.Content, 0xEE, 0xEE)
+
+	kids, err := seq.Children()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		class  Class
+		tag    uint32
+		offset int
+	}{
+		{ClassUniversal, TagInteger, 2},
+		{ClassContextSpecific, 0, 5},
+		{ClassApplication, 33, 10},
+	}
+	for i, w := range want {
+		e, err := kids.Next()
+		if err != nil {
+			t.Fatalf("child %d error = %v", i, err)
+		}
+		if !e.Is(w.class, w.tag) || e.Offset != w.offset {
+			t.Errorf("child %d = %v %d at %d, want %v %d at %d", i, e.Class, e.Tag, e.Offset, w.class, w.tag, w.offset)
+		}
+	}
+	if _, err := kids.Next(); err != io.EOF {
+		t.Errorf("Next() past children error = %v, want io.EOF", err)
+	}
+
+	null, err := r.Next()
+	if err != nil || null.Null() != nil || null.Offset != 15 {
+		t.Errorf("second element = %+v, %v", null, err)
+	}
+	if r.More() {
+		t.Errorf("More() = true at end")
+	}
+}
+
+func TestReader_Indefinite(t *testing.T) {
+	// SEQUENCE (indefinite) { [1] (indefinite) { INTEGER 1 }, INTEGER 2 }, NULL
+	data := mustHex(t, "30 80 a1 80 02 01 01 00 00 02 01 02 00 00 05 00")
+	r := NewReader(data)
+	seq, err := r.Next()
+	if err != nil {
+		t.Fatalf("Next() error = %v", err)
+	}
+	if !seq.Indefinite || len(seq.Content) != 10 {
+		t.Fatalf("Next() = %+v, content % x", seq.Header, seq.Content)
+	}
+	_ = append(seq.Content, 0xEE, 0xEE, 0xEE, 0xEE)
+	kids, _ := seq.Children()
+	inner, err := kids.Next()
+	if err != nil || !inner.Indefinite || inner.Offset != 2 || len(inner.Content) != 3 {
+		t.Fatalf("inner = %+v, %v", inner, err)
+	}
+	two, err := kids.Next()
+	if v, _ := two.Int64(); err != nil || v != 2 || two.Offset != 9 {
+		t.Errorf("second child = %+v, %v", two, err)
+	}
+	if kids.More() {
+		t.Errorf("end-of-contents leaked into the children")
+	}
+	if e, err := r.Next(); err != nil || !e.Is(ClassUniversal, TagNull) {
+		t.Errorf("element after indefinite = %+v, %v", e, err)
+	}
+
+	r = NewReader(data)
+	r.SetDER(true)
+	if _, err := r.Next(); !errors.Is(err, ErrNotDER) {
+		t.Errorf("DER Next() error = %v, want ErrNotDER", err)
+	}
+}
+
+func TestReader_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		hex  string
+		der  bool
+		want error
+	}{
+		{"truncated header", "30", false, io.ErrUnexpectedEOF},
+		{"truncated content", "04 05 01", false, io.ErrUnexpectedEOF},
+		{"truncated long length", "04 82 01", false, io.ErrUnexpectedEOF},
+		{"reserved length", "04 ff", false, ErrMalformed},
+		{"indefinite primitive", "04 80 00 00", false, ErrMalformed},
+		{"stray end-of-contents", "00 00", false, ErrMalformed},
+		{"missing end-of-contents", "30 80 05 00", false, io.ErrUnexpectedEOF},
+		{"bad end-of-contents", "30 80 00 01 00", false, ErrMalformed},
+		{"high tag leading zero", "5f 80 21 00", false, ErrMalformed},
+		{"high tag for low number", "5f 1e 00", false, ErrMalformed},
+		{"tag overflow", "5f ff ff ff ff 7f 00", false, ErrMalformed},
+		{"length overflow", "04 88 01 00 00 00 00 00 00 00", false, ErrMalformed},
+		{"long form short length", "04 81 01 aa", false, nil},
+		{"DER long form short length", "04 81 01 aa", true, ErrNotDER},
+		{"DER length leading zero", "04 82 00 81" + strings.Repeat("00", 0x81), true, ErrNotDER},
+		{"DER indefinite", "30 80 00 00", true, ErrNotDER},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewReader(mustHex(t, tt.hex))
+			r.SetDER(tt.der)
+			if _, err := r.Next(); !errors.Is(err, tt.want) {
+				t.Errorf("Next() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	deep := strings.Repeat("30 80 ", MaxDepth+1) + strings.Repeat("00 00 ", MaxDepth+1)
+	if _, err := NewReader(mustHex(t, deep)).Next(); !errors.Is(err, ErrDepthExceeded) {
+		t.Errorf("Next(deep) error = %v, want ErrDepthExceeded", err)
+	}
+}
@@ -0,0 +1,412 @@
+package ber
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OID is an object identifier.
+type OID []uint64
+
+// String returns the dotted decimal form of o.
+func (o OID) String() string {
+	var b strings.Builder
+	for i, v := range o {
+		if i > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(strconv.FormatUint(v, 10))
+	}
+	return b.String()
+}
+
+// Equal reports whether o and other have the same components.
+func (o OID) Equal(other OID) bool {
+	if len(o) != len(other) {
+		return false
+	}
+	for i := range o {
+		if o[i] != other[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// BitString is the value of a BIT STRING. The bits are numbered from the
+// most significant bit of the first byte.
+type BitString struct {
+	Bytes     []byte
+	BitLength int
+}
+
+// At returns bit i, or zero if i is out of range.
+func (b BitString) At(i int) int {
+	if i < 0 || i >= b.BitLength {
+		return 0
+	}
+	return int(b.Bytes[i/8]>>(7-uint(i%8))) & 1
+}
+
+func (t TLV) errorf(format string, args ...any) error {
+	return fmt.Errorf("%w: %s at offset %d", ErrMalformed, fmt.Sprintf(format, args...), t.Offset)
+}
+
+func (t TLV) notDER(format string, args ...any) error {
+	return fmt.Errorf("%w: %s at offset %d", ErrNotDER, fmt.Sprintf(format, args...), t.Offset)
+}
+
+func (t TLV) primitive(what string) error {
+	if t.Constructed {
+		return t.errorf("constructed %s", what)
+	}
+	return nil
+}
+
+// Bool decodes a BOOLEAN. BER treats any non-zero octet as true; DER only
+// accepts 0xff.
+func (t TLV) Bool() (bool, error) {
+	if err := t.primitive("BOOLEAN"); err != nil {
+		return false, err
+	}
+	if len(t.Content) != 1 {
+		return false, t.errorf("BOOLEAN of %d octets", len(t.Content))
+	}
+	v := t.Content[0]
+	if t.der && v != 0 && v != 0xff {
+		return false, t.notDER("BOOLEAN value 0x%02x", v)
+	}
+	return v != 0, nil
+}
+
+// Null checks that t is a valid NULL.
+func (t TLV) Null() error {
+	if err := t.primitive("NULL"); err != nil {
+		return err
+	}
+	if len(t.Content) != 0 {
+		return t.errorf("NULL with content")
+	}
+	return nil
+}
+
+// checkInteger checks the content of an INTEGER or ENUMERATED, which X.690
+// requires to be minimal in BER as well as DER.
+func (t TLV) checkInteger() error {
+	if err := t.primitive("INTEGER"); err != nil {
+		return err
+	}
+	c := t.Content
+	if len(c) == 0 {
+		return t.errorf("empty INTEGER")
+	}
+	if len(c) > 1 && (c[0] == 0 && c[1]&0x80 == 0 || c[0] == 0xff && c[1]&0x80 != 0) {
+		return t.errorf("non-minimal INTEGER")
+	}
+	return nil
+}
+
+// Int64 decodes an INTEGER or ENUMERATED that fits in an int64.
+func (t TLV) Int64() (int64, error) {
+	if err := t.checkInteger(); err != nil {
+		return 0, err
+	}
+	if len(t.Content) > 8 {
+		return 0, t.errorf("INTEGER too large for int64")
+	}
+	// Sign-extend from the first octet.
+	v := int64(int8(t.Content[0]))
+	for _, b := range t.Content[1:] {
+		v = v<<8 | int64(b)
+	}
+	return v, nil
+}
+
+// BigInt decodes an INTEGER of any size.
+func (t TLV) BigInt() (*big.Int, error) {
+	if err := t.checkInteger(); err != nil {
+		return nil, err
+	}
+	n := new(big.Int).SetBytes(t.Content)
+	if t.Content[0]&0x80 != 0 {
+		n.Sub(n, new(big.Int).Lsh(big.NewInt(1), uint(8*len(t.Content))))
+	}
+	return n, nil
+}
+
+// OID decodes an OBJECT IDENTIFIER.
+func (t TLV) OID() (OID, error) {
+	if err := t.primitive("OBJECT IDENTIFIER"); err != nil {
+		return nil, err
+	}
+	if len(t.Content) == 0 {
+		return nil, t.errorf("empty OBJECT IDENTIFIER")
+	}
+	var oid OID
+	for c := t.Content; len(c) > 0; {
+		if c[0] == 0x80 {
+			return nil, t.errorf("OBJECT IDENTIFIER component with leading zero")
+		}
+		var v uint64
+		i := 0
+		for ; ; i++ {
+			if i == len(c) {
+				return nil, t.errorf("truncated OBJECT IDENTIFIER")
+			}
+			if v>>57 != 0 {
+				return nil, t.errorf("OBJECT IDENTIFIER component too large")
+			}
+			v = v<<7 | uint64(c[i]&0x7f)
+			if c[i]&0x80 == 0 {
+				break
+			}
+		}
+		c = c[i+1:]
+		if oid == nil {
+			// The first subidentifier packs the first two components.
+			first := min(v/40, 2)
+			oid = append(oid, first, v-first*40)
+			continue
+		}
+		oid = append(oid, v)
+	}
+	return oid, nil
+}
+
+// OctetString decodes an OCTET STRING. A constructed one, allowed in BER
+// only, is the concatenation of its segments and is returned in a new
+// slice; otherwise the content is returned as is.
+func (t TLV) OctetString() ([]byte, error) {
+	if !t.Constructed {
+		return t.Content, nil
+	}
+	if t.der {
+		return nil, t.notDER("constructed OCTET STRING")
+	}
+	return t.appendSegments(nil, 1)
+}
+
+func (t TLV) appendSegments(dst []byte, depth int) ([]byte, error) {
+	if depth > MaxDepth {
+		return nil, ErrDepthExceeded
+	}
+	r, _ := t.Children()
+	for r.More() {
+		seg, err := r.Next()
+		if err != nil {
+			return nil, err
+		}
+		if !seg.Is(ClassUniversal, TagOctetString) {
+			return nil, seg.errorf("OCTET STRING segment with tag %d", seg.Tag)
+		}
+		if !seg.Constructed {
+			dst = append(dst, seg.Content...)
+			continue
+		}
+		if dst, err = seg.appendSegments(dst, depth+1); err != nil {
+			return nil, err
+		}
+	}
+	return dst, nil
+}
+
+// BitString decodes a primitive BIT STRING. DER additionally requires the
+// unused bits to be zero.
+func (t TLV) BitString() (BitString, error) {
+	if t.Constructed {
+		if t.der {
+			return BitString{}, t.notDER("constructed BIT STRING")
+		}
+		return BitString{}, t.errorf("constructed BIT STRING is not supported")
+	}
+	c := t.Content
+	if len(c) == 0 {
+		return BitString{}, t.errorf("empty BIT STRING")
+	}
+	unused := int(c[0])
+	if unused > 7 || len(c) == 1 && unused != 0 {
+		return BitString{}, t.errorf("BIT STRING with %d unused bits", unused)
+	}
+	if t.der && unused > 0 && c[len(c)-1]&(1<<unused-1) != 0 {
+		return BitString{}, t.notDER("BIT STRING with non-zero padding")
+	}
+	return BitString{Bytes: c[1:], BitLength: 8*(len(c)-1) - unused}, nil
+}
+
+// Time decodes a UTCTime or GeneralizedTime, chosen by the tag, which must
+// therefore be the universal one. The result is in UTC. Times without a
+// zone, allowed in BER GeneralizedTime, are taken as UTC.
+func (t TLV) Time() (time.Time, error) {
+	if err := t.primitive("time"); err != nil {
+		return time.Time{}, err
+	}
+	s := string(t.Content)
+	var tm time.Time
+	var ok bool
+	switch {
+	case t.Is(ClassUniversal, TagUTCTime):
+		tm, ok = t.parseUTCTime(s)
+	case t.Is(ClassUniversal, TagGeneralizedTime):
+		tm, ok = t.parseGeneralizedTime(s)
+	default:
+		return time.Time{}, t.errorf("tag %d is not a time type", t.Tag)
+	}
+	if !ok {
+		if t.der && t.validBERTime(s) {
+			return time.Time{}, t.notDER("time %q", s)
+		}
+		return time.Time{}, t.errorf("time %q", s)
+	}
+	return tm, nil
+}
+
+// validBERTime reports whether s would have parsed outside DER mode.
+func (t TLV) validBERTime(s string) bool {
+	ber := t
+	ber.der = false
+	_, err := ber.Time()
+	return err == nil
+}
+
+// parseUTCTime parses YYMMDDhhmm[ss](Z|±hhmm). DER requires the seconds
+// and Z.
+func (t TLV) parseUTCTime(s string) (time.Time, bool) {
+	p := timeParser{s: s}
+	year := p.digits(2)
+	if year < 50 {
+		year += 2000
+	} else {
+		year += 1900
+	}
+	month, day, hour, minute := p.digits(2), p.digits(2), p.digits(2), p.digits(2)
+	sec := 0
+	if p.peekDigit() {
+		sec = p.digits(2)
+	} else if t.der {
+		return time.Time{}, false
+	}
+	loc, ok := p.zone(t.der, false)
+	if !ok || !p.ok() {
+		return time.Time{}, false
+	}
+	return validDate(year, month, day, hour, minute, sec, 0, loc)
+}
+
+// parseGeneralizedTime parses YYYYMMDDhh[mm[ss[.f+]]][Z|±hhmm]. DER requires
+// minutes, seconds and Z, a '.' separator and no trailing zeros in the
+// fraction.
+func (t TLV) parseGeneralizedTime(s string) (time.Time, bool) {
+	p := timeParser{s: s}
+	year, month, day, hour := p.digits(4), p.digits(2), p.digits(2), p.digits(2)
+	minute, sec, nsec := 0, 0, 0
+	if p.peekDigit() {
+		minute = p.digits(2)
+		if p.peekDigit() {
+			sec = p.digits(2)
+		} else if t.der {
+			return time.Time{}, false
+		}
+	} else if t.der {
+		return time.Time{}, false
+	}
+	if p.peek('.') || p.peek(',') {
+		if t.der && p.peek(',') {
+			return time.Time{}, false
+		}
+		p.s = p.s[1:]
+		n := 0
+		for p.peekDigit() && n < 9 {
+			nsec = nsec*10 + int(p.s[0]-'0')
+			p.s = p.s[1:]
+			n++
+		}
+		if n == 0 || t.der && nsec%10 == 0 {
+			return time.Time{}, false
+		}
+		for ; n < 9; n++ {
+			nsec *= 10
+		}
+	}
+	loc, ok := p.zone(t.der, true)
+	if !ok || !p.ok() {
+		return time.Time{}, false
+	}
+	return validDate(year, month, day, hour, minute, sec, nsec, loc)
+}
+
+// validDate builds a time, rejecting out of range fields that time.Date
+// would normalize.
+func validDate(year, month, day, hour, minute, sec, nsec int, loc *time.Location) (time.Time, bool) {
+	tm := time.Date(year, time.Month(month), day, hour, minute, sec, nsec, loc)
+	if tm.Year() != year || int(tm.Month()) != month || tm.Day() != day ||
+		tm.Hour() != hour || tm.Minute() != minute || tm.Second() != sec {
+		return time.Time{}, false
+	}
+	return tm.UTC(), true
+}
+
+// timeParser consumes fixed-width fields from a time string, remembering
+// whether any was invalid.
+type timeParser struct {
+	s   string
+	bad bool
+}
+
+func (p *timeParser) ok() bool {
+	return !p.bad && p.s == ""
+}
+
+func (p *timeParser) peek(c byte) bool {
+	return len(p.s) > 0 && p.s[0] == c
+}
+
+func (p *timeParser) peekDigit() bool {
+	return len(p.s) > 0 && '0' <= p.s[0] && p.s[0] <= '9'
+}
+
+func (p *timeParser) digits(n int) int {
+	if len(p.s) < n {
+		p.bad = true
+		return 0
+	}
+	v := 0
+	for i := 0; i < n; i++ {
+		c := p.s[i]
+		if c < '0' || c > '9' {
+			p.bad = true
+			return 0
+		}
+		v = v*10 + int(c-'0')
+	}
+	p.s = p.s[n:]
+	return v
+}
+
+// zone parses Z or a ±hhmm offset. A missing zone is only allowed, as
+// local time, when optional is set.
+func (p *timeParser) zone(der, optional bool) (*time.Location, bool) {
+	switch {
+	case p.peek('Z'):
+		p.s = p.s[1:]
+		return time.UTC, true
+	case der:
+		return nil, false
+	case p.peek('+') || p.peek('-'):
+		sign := 1
+		if p.s[0] == '-' {
+			sign = -1
+		}
+		p.s = p.s[1:]
+		h, m := p.digits(2), p.digits(2)
+		if h > 23 || m > 59 {
+			return nil, false
+		}
+		return time.FixedZone("", sign*(h*3600+m*60)), true
+	case optional && p.s == "":
+		return time.UTC, true
+	}
+	return nil, false
+}
@@ -0,0 +1,186 @@
+package ber
+
+import (
+	"encoding/asn1"
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// tlv reads the single element in data, in DER mode if der is set.
+func tlv(t *testing.T, data []byte, der bool) TLV {
+	t.Helper()
+	r := NewReader(data)
+	r.SetDER(der)
+	e, err := r.Next()
+	if err != nil {
+		t.Fatalf("Next(% x) error = %v", data, err)
+	}
+	return e
+}
+
+func mustMarshal(t *testing.T, v any, params string) []byte {
+	t.Helper()
+	b, err := asn1.MarshalWithParams(v, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestTLV_Integer(t *testing.T) {
+	for _, v := range []int64{0, 1, 127, 128, 256, -1, -128, -129, math.MaxInt64, math.MinInt64} {
+		e := tlv(t, mustMarshal(t, v, ""), true)
+		if got, err := e.Int64(); got != v || err != nil {
+			t.Errorf("Int64() = %d, %v, want %d", got, err, v)
+		}
+		if got, err := e.BigInt(); err != nil || got.Int64() != v {
+			t.Errorf("BigInt() = %v, %v, want %d", got, err, v)
+		}
+	}
+
+	huge, _ := new(big.Int).SetString("-123456789012345678901234567890", 10)
+	e := tlv(t, mustMarshal(t, huge, ""), true)
+	if got, err := e.BigInt(); err != nil || got.Cmp(huge) != 0 {
+		t.Errorf("BigInt() = %v, %v, want %v", got, err, huge)
+	}
+	if _, err := e.Int64(); !errors.Is(err, ErrMalformed) {
+		t.Errorf("Int64(huge) error = %v, want ErrMalformed", err)
+	}
+
+	for _, bad := range [][]byte{{0x02, 0x00}, {0x02, 0x02, 0x00, 0x7f}, {0x02, 0x02, 0xff, 0x80}, {0x22, 0x00}} {
+		if _, err := tlv(t, bad, false).Int64(); !errors.Is(err, ErrMalformed) {
+			t.Errorf("Int64(% x) error = %v, want ErrMalformed", bad, err)
+		}
+	}
+}
+
+func TestTLV_OID(t *testing.T) {
+	tests := []asn1.ObjectIdentifier{
+		{1, 2, 840, 113549, 1, 1, 11},
+		{2, 5, 4, 3},
+		{2, 999, 3},
+		{0, 39},
+	}
+	for _, want := range tests {
+		got, err := tlv(t, mustMarshal(t, want, ""), true).OID()
+		if err != nil || got.String() != want.String() {
+			t.Errorf("OID() = %v, %v, want %v", got, err, want)
+		}
+	}
+	if !(OID{1, 2, 3}).Equal(OID{1, 2, 3}) || (OID{1, 2}).Equal(OID{1, 2, 3}) {
+		t.Errorf("Equal() is wrong")
+	}
+
+	for _, bad := range [][]byte{{0x06, 0x00}, {0x06, 0x02, 0x2a, 0x86}, {0x06, 0x02, 0x80, 0x01}} {
+		if _, err := tlv(t, bad, false).OID(); !errors.Is(err, ErrMalformed) {
+			t.Errorf("OID(% x) error = %v, want ErrMalformed", bad, err)
+		}
+	}
+}
+
+func TestTLV_Bool(t *testing.T) {
+	tests := []struct {
+		data []byte
+		der  bool
+		want bool
+		err  error
+	}{
+		{[]byte{0x01, 0x01, 0xff}, true, true, nil},
+		{[]byte{0x01, 0x01, 0x00}, true, false, nil},
+		{[]byte{0x01, 0x01, 0x01}, false, true, nil},
+		{[]byte{0x01, 0x01, 0x01}, true, false, ErrNotDER},
+		{[]byte{0x01, 0x02, 0x00, 0x00}, false, false, ErrMalformed},
+	}
+	for _, tt := range tests {
+		got, err := tlv(t, tt.data, tt.der).Bool()
+		if got != tt.want || !errors.Is(err, tt.err) {
+			t.Errorf("Bool(% x, der=%v) = %v, %v, want %v, %v", tt.data, tt.der, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestTLV_OctetString(t *testing.T) {
+	// Constructed OCTET STRING of "ab" and a nested constructed "c", "d".
+	data := []byte{0x24, 0x80, 0x04, 0x02, 'a', 'b', 0x24, 0x06, 0x04, 0x01, 'c', 0x04, 0x01, 'd', 0x00, 0x00}
+	got, err := tlv(t, data, false).OctetString()
+	if err != nil || string(got) != "abcd" {
+		t.Errorf("OctetString() = %q, %v, want abcd", got, err)
+	}
+
+	data = []byte{0x24, 0x04, 0x04, 0x02, 'a', 'b'}
+	if _, err := tlv(t, data, true).OctetString(); !errors.Is(err, ErrNotDER) {
+		t.Errorf("DER OctetString(constructed) error = %v, want ErrNotDER", err)
+	}
+	data = []byte{0x24, 0x03, 0x02, 0x01, 0x00}
+	if _, err := tlv(t, data, false).OctetString(); !errors.Is(err, ErrMalformed) {
+		t.Errorf("OctetString(INTEGER segment) error = %v, want ErrMalformed", err)
+	}
+}
+
+func TestTLV_BitString(t *testing.T) {
+	bs := asn1.BitString{Bytes: []byte{0xb0}, BitLength: 4}
+	got, err := tlv(t, mustMarshal(t, bs, ""), true).BitString()
+	if err != nil || got.BitLength != 4 || got.At(0) != 1 || got.At(1) != 0 || got.At(2) != 1 || got.At(3) != 1 || got.At(4) != 0 {
+		t.Errorf("BitString() = %+v, %v", got, err)
+	}
+
+	tests := []struct {
+		data []byte
+		der  bool
+		err  error
+	}{
+		{[]byte{0x03, 0x02, 0x04, 0xb1}, false, nil},
+		{[]byte{0x03, 0x02, 0x04, 0xb1}, true, ErrNotDER},
+		{[]byte{0x03, 0x01, 0x00}, true, nil},
+		{[]byte{0x03, 0x01, 0x01}, false, ErrMalformed},
+		{[]byte{0x03, 0x02, 0x08, 0x00}, false, ErrMalformed},
+		{[]byte{0x03, 0x00}, false, ErrMalformed},
+	}
+	for _, tt := range tests {
+		if _, err := tlv(t, tt.data, tt.der).BitString(); !errors.Is(err, tt.err) {
+			t.Errorf("BitString(% x, der=%v) error = %v, want %v", tt.data, tt.der, err, tt.err)
+		}
+	}
+}
+
+func TestTLV_Time(t *testing.T) {
+	tests := []struct {
+		tag  byte
+		s    string
+		der  bool
+		want time.Time
+		err  error
+	}{
+		{TagUTCTime, "230102150405Z", true, time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC), nil},
+		{TagUTCTime, "990102150405Z", true, time.Date(1999, 1, 2, 15, 4, 5, 0, time.UTC), nil},
+		{TagUTCTime, "2301021504Z", false, time.Date(2023, 1, 2, 15, 4, 0, 0, time.UTC), nil},
+		{TagUTCTime, "2301021504Z", true, time.Time{}, ErrNotDER},
+		{TagUTCTime, "230102150405+0130", false, time.Date(2023, 1, 2, 13, 34, 5, 0, time.UTC), nil},
+		{TagUTCTime, "230102150405+0130", true, time.Time{}, ErrNotDER},
+		{TagUTCTime, "231302150405Z", false, time.Time{}, ErrMalformed},
+		{TagUTCTime, "230102150405", false, time.Time{}, ErrMalformed},
+		{TagGeneralizedTime, "20230102150405Z", true, time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC), nil},
+		{TagGeneralizedTime, "20230102150405.25Z", true, time.Date(2023, 1, 2, 15, 4, 5, 250e6, time.UTC), nil},
+		{TagGeneralizedTime, "20230102150405.250Z", true, time.Time{}, ErrNotDER},
+		{TagGeneralizedTime, "20230102150405,25Z", false, time.Date(2023, 1, 2, 15, 4, 5, 250e6, time.UTC), nil},
+		{TagGeneralizedTime, "2023010215", false, time.Date(2023, 1, 2, 15, 0, 0, 0, time.UTC), nil},
+		{TagGeneralizedTime, "2023010215Z", true, time.Time{}, ErrNotDER},
+		{TagGeneralizedTime, "20230230150405Z", false, time.Time{}, ErrMalformed},
+		{TagGeneralizedTime, "20230102150405.Z", false, time.Time{}, ErrMalformed},
+	}
+
+	for _, tt := range tests {
+		data := append([]byte{tt.tag, byte(len(tt.s))}, tt.s...)
+		got, err := tlv(t, data, tt.der).Time()
+		if !got.Equal(tt.want) || !errors.Is(err, tt.err) {
+			t.Errorf("Time(%q, der=%v) = %v, %v, want %v, %v", tt.s, tt.der, got, err, tt.want, tt.err)
+		}
+	}
+
+	if _, err := tlv(t, []byte{0x04, 0x00}, false).Time(); !errors.Is(err, ErrMalformed) {
+		t.Errorf("Time(OCTET STRING) error = %v, want ErrMalformed", err)
+	}
+}
@@ -0,0 +1,522 @@
+// Package bson walks BSON documents and reads MongoDB wire protocol messages.
+//
+// Documents are never materialized. A Document is the raw encoding, and
+// iterating it yields elements whose values are sub-slices of the input,
+// decoded on demand by the RawValue accessors. This keeps a proxy that only
+// looks at a few fields of each command from paying for the rest.
+//
+// Example usage:
+//
+//	msg, err := bson.ParseMsg(data)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	it := msg.Body.Elements()
+//	for {
+//	    e, err := it.Next()
+//	    if err == io.EOF {
+//	        break
+//	    }
+//	    if err != nil {
+//	        log.Fatal(err)
+//	    }
+//	    fmt.Println(e.Key, e.Value.Type)
+//	}
+package bson
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/nemohan/wireread"
+)
+
+var (
+	// ErrMalformed is returned for documents and values whose lengths or
+	// terminators are inconsistent.
+	ErrMalformed = errors.New("bson: malformed document")
+
+	// ErrDepthExceeded is returned by Validate when documents nest deeper
+	// than MaxDepth.
+	ErrDepthExceeded = errors.New("bson: nesting too deep")
+
+	// ErrNotFound is returned by Lookup when the path does not exist.
+	ErrNotFound = errors.New("bson: element not found")
+)
+
+// MaxDepth limits the nesting of documents and arrays checked by Validate.
+const MaxDepth = 200
+
+// minDocumentLen is the size of an empty document: the length and the
+// trailing NUL.
+const minDocumentLen = 5
+
+// Type is the type byte of an element.
+type Type byte
+
+// Element types.
+const (
+	Double           Type = 0x01
+	String           Type = 0x02
+	EmbeddedDocument Type = 0x03
+	Array            Type = 0x04
+	Binary           Type = 0x05
+	Undefined        Type = 0x06 // deprecated
+	ObjectIDType     Type = 0x07
+	Boolean          Type = 0x08
+	DateTime         Type = 0x09
+	Null             Type = 0x0a
+	Regex            Type = 0x0b
+	DBPointer        Type = 0x0c // deprecated
+	JavaScript       Type = 0x0d
+	Symbol           Type = 0x0e // deprecated
+	CodeWithScope    Type = 0x0f // deprecated
+	Int32            Type = 0x10
+	Timestamp        Type = 0x11
+	Int64            Type = 0x12
+	Decimal128       Type = 0x13
+	MaxKey           Type = 0x7f
+	MinKey           Type = 0xff
+)
+
+var typeNames = map[Type]string{
+	Double:           "double",
+	String:           "string",
+	EmbeddedDocument: "document",
+	Array:            "array",
+	Binary:           "binary",
+	Undefined:        "undefined",
+	ObjectIDType:     "objectId",
+	Boolean:          "bool",
+	DateTime:         "date",
+	Null:             "null",
+	Regex:            "regex",
+	DBPointer:        "dbPointer",
+	JavaScript:       "javascript",
+	Symbol:           "symbol",
+	CodeWithScope:    "javascriptWithScope",
+	Int32:            "int",
+	Timestamp:        "timestamp",
+	Int64:            "long",
+	Decimal128:       "decimal",
+	MaxKey:           "maxKey",
+	MinKey:           "minKey",
+}
+
+// String returns the type alias used by the $type query operator.
+func (t Type) String() string {
+	if s, ok := typeNames[t]; ok {
+		return s
+	}
+	return fmt.Sprintf("Type(0x%02x)", byte(t))
+}
+
+// ObjectID is a 12-byte object identifier.
+type ObjectID [12]byte
+
+// Time returns the creation time stored in the first four bytes of id.
+func (id ObjectID) Time() time.Time {
+	return time.Unix(int64(binary.BigEndian.Uint32(id[:4])), 0).UTC()
+}
+
+// String returns id as 24 hexadecimal digits.
+func (id ObjectID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// Document is the raw encoding of a BSON document, including its length
+// prefix and trailing NUL.
+type Document []byte
+
+// ReadDocument reads a document from r. It checks the length prefix and the
+// trailing NUL but not the elements; use Validate for that. The returned
+// Document aliases the reader's buffer.
+func ReadDocument(r wireread.Reader) (Document, error) {
+	b := r.Bytes()
+	if len(b) < 4 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	n := int32(binary.LittleEndian.Uint32(b))
+	if n < minDocumentLen {
+		return nil, fmt.Errorf("%w: document length %d", ErrMalformed, n)
+	}
+	if int(n) > len(b) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	doc := b[:n:n]
+	if doc[n-1] != 0 {
+		return nil, fmt.Errorf("%w: document not NUL-terminated", ErrMalformed)
+	}
+	if err := r.Skip(int(n)); err != nil {
+		return nil, err
+	}
+	return Document(doc), nil
+}
+
+// Elements returns an iterator over the top-level elements of d.
+func (d Document) Elements() *Iterator {
+	if len(d) < minDocumentLen || int(binary.LittleEndian.Uint32(d)) != len(d) || d[len(d)-1] != 0 {
+		return &Iterator{err: fmt.Errorf("%w: document length does not match its %d bytes", ErrMalformed, len(d))}
+	}
+	return &Iterator{r: wireread.NewSafeReader(d[4 : len(d)-1]), size: len(d) - 1}
+}
+
+// Lookup finds the value at path, descending into embedded documents and
+// arrays for each key after the first. Array elements are addressed by
+// their decimal index.
+func (d Document) Lookup(path ...string) (RawValue, error) {
+	if len(path) == 0 {
+		return RawValue{}, ErrNotFound
+	}
+	it := d.Elements()
+	for {
+		e, err := it.Next()
+		if err == io.EOF {
+			return RawValue{}, ErrNotFound
+		}
+		if err != nil {
+			return RawValue{}, err
+		}
+		if e.Key != path[0] {
+			continue
+		}
+		if len(path) == 1 {
+			return e.Value, nil
+		}
+		sub, ok := e.Value.Document()
+		if !ok {
+			return RawValue{}, ErrNotFound
+		}
+		return sub.Lookup(path[1:]...)
+	}
+}
+
+// Validate walks d and every document nested in it, checking that all
+// lengths and terminators are consistent.
+func (d Document) Validate() error {
+	return d.validate(1)
+}
+
+func (d Document) validate(depth int) error {
+	if depth > MaxDepth {
+		return ErrDepthExceeded
+	}
+	it := d.Elements()
+	for {
+		e, err := it.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var sub Document
+		switch e.Value.Type {
+		case EmbeddedDocument, Array:
+			sub, _ = e.Value.Document()
+		case CodeWithScope:
+			_, sub, _ = e.Value.CodeWithScope()
+		default:
+			continue
+		}
+		if err := sub.validate(depth + 1); err != nil {
+			return err
+		}
+	}
+}
+
+// Element is a key and its undecoded value.
+type Element struct {
+	Key   string
+	Value RawValue
+}
+
+// Iterator walks the elements of a document.
+type Iterator struct {
+	r    *wireread.SafeReader
+	size int
+	err  error
+}
+
+// Next returns the next element. It returns io.EOF after the last one.
+// Malformed elements are reported with their offset in the document.
+func (it *Iterator) Next() (Element, error) {
+	if it.err != nil {
+		return Element{}, it.err
+	}
+	e, err := it.next()
+	if err != nil {
+		it.err = err
+	}
+	return e, err
+}
+
+func (it *Iterator) next() (Element, error) {
+	rest := it.r.Bytes()
+	if len(rest) == 0 {
+		return Element{}, io.EOF
+	}
+	off := it.size - len(rest)
+	t, _ := it.r.ReadByte()
+	key, err := it.r.ReadNullTerminatedString()
+	if err != nil {
+		return Element{}, fmt.Errorf("%w: unterminated element name at offset %d", ErrMalformed, off)
+	}
+	n, err := valueLen(Type(t), it.r.Bytes())
+	if err != nil {
+		return Element{}, fmt.Errorf("%w in element %q at offset %d", err, key, off)
+	}
+	data, err := it.r.ReadSlice(n)
+	if err != nil {
+		return Element{}, err
+	}
+	return Element{Key: key, Value: RawValue{Type: Type(t), Data: data}}, nil
+}
+
+// valueLen returns the encoded size of a value of type t at the start of b.
+func valueLen(t Type, b []byte) (int, error) {
+	var n int
+	switch t {
+	case Undefined, Null, MinKey, MaxKey:
+		return 0, nil
+	case Boolean:
+		if len(b) > 0 && b[0] > 1 {
+			return 0, fmt.Errorf("%w: boolean value %d", ErrMalformed, b[0])
+		}
+		n = 1
+	case Int32:
+		n = 4
+	case Double, DateTime, Timestamp, Int64:
+		n = 8
+	case ObjectIDType:
+		n = 12
+	case Decimal128:
+		n = 16
+	case String, JavaScript, Symbol:
+		return stringLen(b)
+	case EmbeddedDocument, Array:
+		return documentLen(b)
+	case Binary:
+		if len(b) < 5 {
+			return 0, fmt.Errorf("%w: truncated binary", ErrMalformed)
+		}
+		size := int32(binary.LittleEndian.Uint32(b))
+		if size < 0 {
+			return 0, fmt.Errorf("%w: binary length %d", ErrMalformed, size)
+		}
+		n = 5 + int(size)
+	case Regex:
+		for i := 0; i < 2; i++ {
+			end := indexNUL(b[n:])
+			if end < 0 {
+				return 0, fmt.Errorf("%w: unterminated regex", ErrMalformed)
+			}
+			n += end + 1
+		}
+		return n, nil
+	case DBPointer:
+		s, err := stringLen(b)
+		if err != nil {
+			return 0, err
+		}
+		n = s + 12
+	case CodeWithScope:
+		if len(b) < 4 {
+			return 0, fmt.Errorf("%w: truncated code with scope", ErrMalformed)
+		}
+		total := int32(binary.LittleEndian.Uint32(b))
+		if total < 4+5+minDocumentLen || int(total) > len(b) {
+			return 0, fmt.Errorf("%w: code with scope length %d", ErrMalformed, total)
+		}
+		s, err := stringLen(b[4:total])
+		if err != nil {
+			return 0, err
+		}
+		d, err := documentLen(b[4+s : total])
+		if err != nil {
+			return 0, err
+		}
+		if 4+s+d != int(total) {
+			return 0, fmt.Errorf("%w: code with scope length %d", ErrMalformed, total)
+		}
+		return int(total), nil
+	default:
+		return 0, fmt.Errorf("%w: unknown type 0x%02x", ErrMalformed, byte(t))
+	}
+	if n > len(b) {
+		return 0, fmt.Errorf("%w: truncated %v", ErrMalformed, t)
+	}
+	return n, nil
+}
+
+// stringLen returns the size of a length-prefixed, NUL-terminated string.
+func stringLen(b []byte) (int, error) {
+	if len(b) < 4 {
+		return 0, fmt.Errorf("%w: truncated string", ErrMalformed)
+	}
+	n := int32(binary.LittleEndian.Uint32(b))
+	if n < 1 || int(n) > len(b)-4 {
+		return 0, fmt.Errorf("%w: string length %d", ErrMalformed, n)
+	}
+	if b[4+n-1] != 0 {
+		return 0, fmt.Errorf("%w: string not NUL-terminated", ErrMalformed)
+	}
+	return 4 + int(n), nil
+}
+
+// documentLen returns the size of an embedded document or array.
+func documentLen(b []byte) (int, error) {
+	if len(b) < 4 {
+		return 0, fmt.Errorf("%w: truncated document", ErrMalformed)
+	}
+	n := int32(binary.LittleEndian.Uint32(b))
+	if n < minDocumentLen || int(n) > len(b) {
+		return 0, fmt.Errorf("%w: document length %d", ErrMalformed, n)
+	}
+	if b[n-1] != 0 {
+		return 0, fmt.Errorf("%w: document not NUL-terminated", ErrMalformed)
+	}
+	return int(n), nil
+}
+
+func indexNUL(b []byte) int {
+	for i, c := range b {
+		if c == 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// RawValue is an undecoded element value. Data aliases the document.
+//
+// The accessors decode Data according to Type and report false when the
+// value has a different type. Values returned by Iterator.Next have had
+// their lengths checked, so the accessors never read out of bounds.
+type RawValue struct {
+	Type Type
+	Data []byte
+}
+
+// Double returns the value of a double.
+func (v RawValue) Double() (float64, bool) {
+	if v.Type != Double {
+		return 0, false
+	}
+	return math.Float64frombits(binary.LittleEndian.Uint64(v.Data)), true
+}
+
+// StringValue returns the value of a string, JavaScript code or symbol.
+func (v RawValue) StringValue() (string, bool) {
+	if v.Type != String && v.Type != JavaScript && v.Type != Symbol {
+		return "", false
+	}
+	return string(v.Data[4 : len(v.Data)-1]), true
+}
+
+// Document returns an embedded document or array. Arrays are documents
+// whose keys are the decimal indexes "0", "1", and so on.
+func (v RawValue) Document() (Document, bool) {
+	if v.Type != EmbeddedDocument && v.Type != Array {
+		return nil, false
+	}
+	return Document(v.Data), true
+}
+
+// Binary returns the subtype and payload of binary data.
+func (v RawValue) Binary() (subtype byte, data []byte, ok bool) {
+	if v.Type != Binary {
+		return 0, nil, false
+	}
+	return v.Data[4], v.Data[5:], true
+}
+
+// ObjectID returns the value of an ObjectId.
+func (v RawValue) ObjectID() (ObjectID, bool) {
+	if v.Type != ObjectIDType {
+		return ObjectID{}, false
+	}
+	return ObjectID(v.Data), true
+}
+
+// Boolean returns the value of a boolean.
+func (v RawValue) Boolean() (bool, bool) {
+	if v.Type != Boolean {
+		return false, false
+	}
+	return v.Data[0] == 1, true
+}
+
+// DateTime returns a UTC datetime, stored as milliseconds since the Unix
+// epoch.
+func (v RawValue) DateTime() (time.Time, bool) {
+	if v.Type != DateTime {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(int64(binary.LittleEndian.Uint64(v.Data))).UTC(), true
+}
+
+// Int32 returns the value of a 32-bit integer.
+func (v RawValue) Int32() (int32, bool) {
+	if v.Type != Int32 {
+		return 0, false
+	}
+	return int32(binary.LittleEndian.Uint32(v.Data)), true
+}
+
+// Int64 returns the value of a 64-bit integer.
+func (v RawValue) Int64() (int64, bool) {
+	if v.Type != Int64 {
+		return 0, false
+	}
+	return int64(binary.LittleEndian.Uint64(v.Data)), true
+}
+
+// Timestamp returns the seconds and ordinal of an internal timestamp.
+func (v RawValue) Timestamp() (t, i uint32, ok bool) {
+	if v.Type != Timestamp {
+		return 0, 0, false
+	}
+	return binary.LittleEndian.Uint32(v.Data[4:]), binary.LittleEndian.Uint32(v.Data), true
+}
+
+// Decimal128 returns the high and low halves of an IEEE 754-2008 decimal.
+func (v RawValue) Decimal128() (hi, lo uint64, ok bool) {
+	if v.Type != Decimal128 {
+		return 0, 0, false
+	}
+	return binary.LittleEndian.Uint64(v.Data[8:]), binary.LittleEndian.Uint64(v.Data), true
+}
+
+// Regex returns the pattern and options of a regular expression.
+func (v RawValue) Regex() (pattern, options string, ok bool) {
+	if v.Type != Regex {
+		return "", "", false
+	}
+	end := indexNUL(v.Data)
+	return string(v.Data[:end]), string(v.Data[end+1 : len(v.Data)-1]), true
+}
+
+// DBPointer returns the namespace and id of a DBPointer.
+func (v RawValue) DBPointer() (ns string, id ObjectID, ok bool) {
+	if v.Type != DBPointer {
+		return "", ObjectID{}, false
+	}
+	n := len(v.Data) - 12
+	return string(v.Data[4 : n-1]), ObjectID(v.Data[n:]), true
+}
+
+// CodeWithScope returns the code and scope document of JavaScript code
+// with scope.
+func (v RawValue) CodeWithScope() (code string, scope Document, ok bool) {
+	if v.Type != CodeWithScope {
+		return "", nil, false
+	}
+	n := 4 + int(binary.LittleEndian.Uint32(v.Data[4:]))
+	return string(v.Data[8 : 4+n-1]), Document(v.Data[4+n:]), true
+}
@@ -0,0 +1,220 @@
+package bson
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"testing"
+	"time"
+)
+
+// doc builds a document from pre-encoded elements.
+func doc(elems ...[]byte) []byte {
+	b := []byte{0, 0, 0, 0}
+	for _, e := range elems {
+		b = append(b, e...)
+	}
+	b = append(b, 0)
+	binary.LittleEndian.PutUint32(b, uint32(len(b)))
+	return b
+}
+
+// elem encodes an element with a raw value.
+func elem(t Type, key string, value ...byte) []byte {
+	b := append([]byte{byte(t)}, key...)
+	b = append(b, 0)
+	return append(b, value...)
+}
+
+func str(s string) []byte {
+	b := binary.LittleEndian.AppendUint32(nil, uint32(len(s)+1))
+	b = append(b, s...)
+	return append(b, 0)
+}
+
+func le32(v uint32) []byte { return binary.LittleEndian.AppendUint32(nil, v) }
+func le64(v uint64) []byte { return binary.LittleEndian.AppendUint64(nil, v) }
+
+func TestDocument_Elements(t *testing.T) {
+	oid := ObjectID{0x65, 0x00, 0x00, 0x00, 1, 2, 3, 4, 5, 6, 7, 8}
+	scope := doc(elem(Int32, "x", le32(1)...))
+	cws := le32(uint32(4 + len(str("f()")) + len(scope)))
+	cws = append(append(cws, str("f()")...), scope...)
+
+	d := Document(doc(
+		elem(Double, "d", le64(math.Float64bits(1.5))...),
+		elem(String, "s", str("héllo")...),
+		elem(EmbeddedDocument, "doc", doc(elem(Null, "n"))...),
+		elem(Array, "arr", doc(elem(Int32, "0", le32(7)...), elem(Int32, "1", le32(8)...))...),
+		elem(Binary, "bin", append(append(le32(3), 0x04), 'a', 'b', 'c')...),
+		elem(ObjectIDType, "_id", oid[:]...),
+		elem(Boolean, "ok", 1),
+		elem(DateTime, "at", le64(1363896240000)...),
+		elem(Regex, "re", 'a', '+', 0, 'i', 0),
+		elem(Int32, "i32", le32(0xffffffff)...),
+		elem(Timestamp, "ts", append(le32(3), le32(1700000000)...)...),
+		elem(Int64, "i64", le64(1<<40)...),
+		elem(Decimal128, "dec", append(le64(1), le64(0x3040000000000000)...)...),
+		elem(DBPointer, "ptr", append(str("db.c"), oid[:]...)...),
+		elem(CodeWithScope, "code", cws...),
+		elem(MinKey, "min"),
+		elem(MaxKey, "max"),
+	))
+	if err := d.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	var keys []string
+	it := d.Elements()
+	for {
+		e, err := it.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		keys = append(keys, e.Key)
+		v := e.Value
+		// 0xEE is not a type, so Next fails if this reaches the next element.
+		_ = append(v.Data, 0xEE)
+		var ok bool
+		switch e.Key {
+		case "d":
+			var f float64
+			f, ok = v.Double()
+			ok = ok && f == 1.5
+		case "s":
+			var s string
+			s, ok = v.StringValue()
+			ok = ok && s == "héllo"
+		case "doc":
+			var sub Document
+			sub, ok = v.Document()
+			ok = ok && len(sub) == 8
+		case "bin":
+			sub, data, k := v.Binary()
+			ok = k && sub == 4 && string(data) == "abc"
+		case "_id":
+			var id ObjectID
+			id, ok = v.ObjectID()
+			ok = ok && id == oid && id.Time().Unix() == 0x65000000 && id.String() == "650000000102030405060708"
+		case "ok":
+			var b bool
+			b, ok = v.Boolean()
+			ok = ok && b
+		case "at":
+			var at time.Time
+			at, ok = v.DateTime()
+			ok = ok && at.Equal(time.Unix(1363896240, 0))
+		case "re":
+			p, o, k := v.Regex()
+			ok = k && p == "a+" && o == "i"
+		case "i32":
+			var n int32
+			n, ok = v.Int32()
+			ok = ok && n == -1
+		case "ts":
+			ts, i, k := v.Timestamp()
+			ok = k && ts == 1700000000 && i == 3
+		case "i64":
+			var n int64
+			n, ok = v.Int64()
+			ok = ok && n == 1<<40
+		case "dec":
+			hi, lo, k := v.Decimal128()
+			ok = k && hi == 0x3040000000000000 && lo == 1
+		case "ptr":
+			ns, id, k := v.DBPointer()
+			ok = k && ns == "db.c" && id == oid
+		case "code":
+			code, sc, k := v.CodeWithScope()
+			ok = k && code == "f()" && string(sc) == string(scope)
+		default:
+			ok = true
+		}
+		if !ok {
+			t.Errorf("element %q (%v) decoded incorrectly from % x", e.Key, v.Type, v.Data)
+		}
+	}
+	if len(keys) != 17 {
+		t.Errorf("got %d elements, want 17: %v", len(keys), keys)
+	}
+
+	if _, ok := (RawValue{Type: Int32, Data: le32(1)}).Int64(); ok {
+		t.Errorf("Int64() on an int32 reported ok")
+	}
+}
+
+func TestDocument_Lookup(t *testing.T) {
+	d := Document(doc(
+		elem(String, "find", str("users")...),
+		elem(EmbeddedDocument, "filter", doc(
+			elem(Array, "$in", doc(elem(Int32, "0", le32(1)...), elem(Int32, "1", le32(2)...))...),
+		)...),
+	))
+
+	v, err := d.Lookup("filter", "$in", "1")
+	if n, ok := v.Int32(); err != nil || !ok || n != 2 {
+		t.Errorf("Lookup(filter.$in.1) = %v, %v, want 2", v, err)
+	}
+	if _, err := d.Lookup("find", "x"); err != ErrNotFound {
+		t.Errorf("Lookup(find.x) error = %v, want ErrNotFound", err)
+	}
+	if _, err := d.Lookup("limit"); err != ErrNotFound {
+		t.Errorf("Lookup(limit) error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestIterator_Malformed(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  []byte
+	}{
+		{"short", []byte{4, 0, 0, 0}},
+		{"length mismatch", []byte{6, 0, 0, 0, 0}},
+		{"no terminator", []byte{5, 0, 0, 0, 1}},
+		{"unterminated key", doc([]byte{byte(Int32), 'a'})},
+		{"truncated int32", doc(elem(Int32, "a", 1, 2))},
+		{"unknown type", doc(elem(0x20, "a"))},
+		{"bad boolean", doc(elem(Boolean, "a", 2))},
+		{"string overrun", doc(elem(String, "a", 9, 0, 0, 0, 'x', 0))},
+		{"empty string length", doc(elem(String, "a", 0, 0, 0, 0))},
+		{"string not terminated", doc(elem(String, "a", 2, 0, 0, 0, 'x', 'y'))},
+		{"negative binary", doc(elem(Binary, "a", 0xff, 0xff, 0xff, 0xff, 0))},
+		{"subdoc too short", doc(elem(EmbeddedDocument, "a", 4, 0, 0, 0))},
+		{"unterminated regex", doc(elem(Regex, "a", 'x', 0, 'i'))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			it := Document(tt.doc).Elements()
+			var err error
+			for err == nil {
+				_, err = it.Next()
+			}
+			if !errors.Is(err, ErrMalformed) {
+				t.Errorf("Next() error = %v, want ErrMalformed", err)
+			}
+			if _, again := it.Next(); again != err {
+				t.Errorf("Next() after error = %v, want %v", again, err)
+			}
+		})
+	}
+}
+
+func TestDocument_Validate(t *testing.T) {
+	bad := doc(elem(EmbeddedDocument, "a", doc(elem(Int32, "b", 1))...))
+	if err := Document(bad).Validate(); !errors.Is(err, ErrMalformed) {
+		t.Errorf("Validate(bad nested) error = %v, want ErrMalformed", err)
+	}
+
+	d := doc()
+	for i := 0; i < MaxDepth; i++ {
+		d = doc(elem(EmbeddedDocument, "a", d...))
+	}
+	if err := Document(d).Validate(); err != ErrDepthExceeded {
+		t.Errorf("Validate(deep) error = %v, want ErrDepthExceeded", err)
+	}
+}
@@ -0,0 +1,303 @@
+package bson
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"slices"
+
+	"github.com/nemohan/wireread"
+)
+
+var (
+	// ErrChecksum is returned by ParseMsg when the CRC-32C checksum does not
+	// match the message.
+	ErrChecksum = errors.New("bson: message checksum mismatch")
+
+	// ErrMessageTooLarge is returned by MessageReader for messages longer
+	// than the configured limit.
+	ErrMessageTooLarge = errors.New("bson: message too large")
+)
+
+// HeaderLen is the size of the standard message header.
+const HeaderLen = 16
+
+// DefaultMaxMessageSize is the server's default maxMessageSizeBytes.
+const DefaultMaxMessageSize = 48000000
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// OpCode identifies the type of a wire message.
+type OpCode int32
+
+// Message opcodes. All but OpMsg and OpCompressed are legacy opcodes.
+const (
+	OpReply       OpCode = 1
+	OpUpdate      OpCode = 2001
+	OpInsert      OpCode = 2002
+	OpQuery       OpCode = 2004
+	OpGetMore     OpCode = 2005
+	OpDelete      OpCode = 2006
+	OpKillCursors OpCode = 2007
+	OpCompressed  OpCode = 2012
+	OpMsg         OpCode = 2013
+)
+
+var opCodeNames = map[OpCode]string{
+	OpReply:       "OP_REPLY",
+	OpUpdate:      "OP_UPDATE",
+	OpInsert:      "OP_INSERT",
+	OpQuery:       "OP_QUERY",
+	OpGetMore:     "OP_GET_MORE",
+	OpDelete:      "OP_DELETE",
+	OpKillCursors: "OP_KILL_CURSORS",
+	OpCompressed:  "OP_COMPRESSED",
+	OpMsg:         "OP_MSG",
+}
+
+func (o OpCode) String() string {
+	if s, ok := opCodeNames[o]; ok {
+		return s
+	}
+	return fmt.Sprintf("OpCode(%d)", int32(o))
+}
+
+// Header is the standard message header shared by all opcodes.
+type Header struct {
+	Length     int32 // total message size, including the header
+	RequestID  int32
+	ResponseTo int32
+	OpCode     OpCode
+}
+
+// ReadHeader reads a message header from r.
+func ReadHeader(r wireread.Reader) (Header, error) {
+	var h Header
+	var v [4]uint32
+	for i := range v {
+		if err := r.ReadUint32LEInto(&v[i]); err != nil {
+			return Header{}, err
+		}
+	}
+	h.Length = int32(v[0])
+	h.RequestID = int32(v[1])
+	h.ResponseTo = int32(v[2])
+	h.OpCode = OpCode(v[3])
+	if h.Length < HeaderLen {
+		return Header{}, fmt.Errorf("%w: message length %d", ErrMalformed, h.Length)
+	}
+	return h, nil
+}
+
+// MsgFlags holds the flag bits of an OP_MSG.
+type MsgFlags uint32
+
+// OP_MSG flag bits. The low 16 bits are required: a receiver must reject
+// messages with unknown bits set there. The high 16 bits are optional.
+const (
+	FlagChecksumPresent MsgFlags = 1 << 0
+	FlagMoreToCome      MsgFlags = 1 << 1
+	FlagExhaustAllowed  MsgFlags = 1 << 16
+
+	requiredFlags = 0xffff
+	knownFlags    = FlagChecksumPresent | FlagMoreToCome | FlagExhaustAllowed
+)
+
+// Section kinds.
+const (
+	kindBody     = 0
+	kindSequence = 1
+)
+
+// Msg is an OP_MSG message. Its documents alias the message buffer.
+type Msg struct {
+	Header
+	Flags MsgFlags
+
+	// Body is the single kind-0 section, holding the command or reply.
+	Body Document
+
+	// Sequences are the kind-1 sections, in message order.
+	Sequences []Sequence
+
+	// Checksum is the CRC-32C trailer. It is only set when Flags has
+	// FlagChecksumPresent, in which case ParseMsg has verified it.
+	Checksum uint32
+}
+
+// Sequence is a kind-1 section: a run of documents that belong to the
+// command argument named by Identifier, such as "documents" for insert.
+type Sequence struct {
+	Identifier string
+	Documents  []Document
+}
+
+// ParseMsg parses an OP_MSG. data must hold exactly one message, header
+// included, as returned by MessageReader.Next. The checksum is verified
+// when present. The documents are checked for their length and trailing
+// NUL only.
+func ParseMsg(data []byte) (*Msg, error) {
+	r := wireread.NewSafeReader(data)
+	h, err := ReadHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	if h.OpCode != OpMsg {
+		return nil, fmt.Errorf("%w: opcode %v is not OP_MSG", ErrMalformed, h.OpCode)
+	}
+	if int(h.Length) != len(data) {
+		return nil, fmt.Errorf("%w: message length %d, have %d bytes", ErrMalformed, h.Length, len(data))
+	}
+	var flags uint32
+	if err := r.ReadUint32LEInto(&flags); err != nil {
+		return nil, err
+	}
+	m := &Msg{Header: h, Flags: MsgFlags(flags)}
+	if unknown := m.Flags &^ knownFlags & requiredFlags; unknown != 0 {
+		return nil, fmt.Errorf("%w: unknown required flag bits 0x%04x", ErrMalformed, uint32(unknown))
+	}
+
+	end := len(data)
+	if m.Flags&FlagChecksumPresent != 0 {
+		end -= 4
+		if end < HeaderLen+4 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		m.Checksum = binary.LittleEndian.Uint32(data[end:])
+		if crc32.Checksum(data[:end], castagnoli) != m.Checksum {
+			return nil, ErrChecksum
+		}
+	}
+
+	sr := wireread.NewSafeReader(data[HeaderLen+4 : end])
+	for len(sr.Bytes()) > 0 {
+		off := end - len(sr.Bytes())
+		kind, _ := sr.ReadByte()
+		switch kind {
+		case kindBody:
+			if m.Body != nil {
+				return nil, fmt.Errorf("%w: second body section at offset %d", ErrMalformed, off)
+			}
+			if m.Body, err = ReadDocument(sr); err != nil {
+				return nil, sectionError(err, off)
+			}
+		case kindSequence:
+			seq, err := readSequence(sr)
+			if err != nil {
+				return nil, sectionError(err, off)
+			}
+			m.Sequences = append(m.Sequences, seq)
+		default:
+			return nil, fmt.Errorf("%w: unknown section kind %d at offset %d", ErrMalformed, kind, off)
+		}
+	}
+	if m.Body == nil {
+		return nil, fmt.Errorf("%w: missing body section", ErrMalformed)
+	}
+	return m, nil
+}
+
+// readSequence reads a kind-1 section after its kind byte.
+func readSequence(r *wireread.SafeReader) (Sequence, error) {
+	var size uint32
+	if err := r.ReadUint32LEInto(&size); err != nil {
+		return Sequence{}, err
+	}
+	if size < 4 || int32(size) < 0 {
+		return Sequence{}, fmt.Errorf("%w: section size %d", ErrMalformed, int32(size))
+	}
+	body, err := r.ReadSlice(int(size) - 4)
+	if err != nil {
+		return Sequence{}, err
+	}
+	sr := wireread.NewSafeReader(body)
+	var seq Sequence
+	if seq.Identifier, err = sr.ReadNullTerminatedString(); err != nil {
+		return Sequence{}, fmt.Errorf("%w: unterminated sequence identifier", ErrMalformed)
+	}
+	for len(sr.Bytes()) > 0 {
+		doc, err := ReadDocument(sr)
+		if err != nil {
+			return Sequence{}, err
+		}
+		seq.Documents = append(seq.Documents, doc)
+	}
+	return seq, nil
+}
+
+// sectionError reports a truncated section as malformed, since the message
+// length has already been checked, and adds the section offset.
+func sectionError(err error, off int) error {
+	if err == io.ErrUnexpectedEOF {
+		err = fmt.Errorf("%w: truncated section", ErrMalformed)
+	}
+	return fmt.Errorf("%w at offset %d", err, off)
+}
+
+// messageChunk bounds how far Next allocates ahead of the bytes that have
+// arrived, so that a header announcing a large message on a stream that then
+// stalls or ends does not cost the whole announced length.
+const messageChunk = 64 << 10
+
+// MessageReader splits a stream into wire messages of any opcode.
+type MessageReader struct {
+	r       io.Reader
+	maxSize int
+	off     int64
+	buf     []byte
+}
+
+// NewMessageReader creates a MessageReader reading from r, with
+// DefaultMaxMessageSize as the size limit.
+func NewMessageReader(r io.Reader) *MessageReader {
+	return &MessageReader{r: r, maxSize: DefaultMaxMessageSize}
+}
+
+// SetMaxMessageSize limits the total size of a message. Larger messages are
+// rejected with ErrMessageTooLarge before their body is read.
+func (mr *MessageReader) SetMaxMessageSize(n int) {
+	mr.maxSize = n
+}
+
+// Offset returns the number of bytes consumed from the stream.
+func (mr *MessageReader) Offset() int64 {
+	return mr.off
+}
+
+// Next reads the next message, header included. It returns io.EOF when the
+// stream ends cleanly between messages and io.ErrUnexpectedEOF when it ends
+// inside one. The returned slice is reused by the following call, so a
+// caller that keeps a message, or a Msg parsed from it, must copy it.
+func (mr *MessageReader) Next() ([]byte, error) {
+	var hdr [HeaderLen]byte
+	n, err := io.ReadFull(mr.r, hdr[:])
+	mr.off += int64(n)
+	if err != nil {
+		return nil, err
+	}
+	h, err := ReadHeader(wireread.NewSafeReader(hdr[:]))
+	if err != nil {
+		return nil, fmt.Errorf("%w at offset %d", err, mr.off-HeaderLen)
+	}
+	if int64(h.Length) > int64(mr.maxSize) {
+		return nil, fmt.Errorf("%w: %d bytes at offset %d", ErrMessageTooLarge, h.Length, mr.off-HeaderLen)
+	}
+	msg := append(mr.buf[:0], hdr[:]...)
+	for len(msg) < int(h.Length) {
+		m := min(int(h.Length)-len(msg), max(messageChunk, len(msg)))
+		end := len(msg)
+		msg = slices.Grow(msg, m)[:end+m]
+		n, err = io.ReadFull(mr.r, msg[end:])
+		mr.off += int64(n)
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	mr.buf = msg
+	return msg, nil
+}
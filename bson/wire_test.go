@@ -0,0 +1,181 @@
+package bson
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// opMsg builds an OP_MSG from encoded sections, appending a checksum when
+// the flag is set.
+func opMsg(flags MsgFlags, sections ...[]byte) []byte {
+	b := make([]byte, HeaderLen, 64)
+	binary.LittleEndian.PutUint32(b[4:], 7)
+	binary.LittleEndian.PutUint32(b[12:], uint32(OpMsg))
+	b = binary.LittleEndian.AppendUint32(b, uint32(flags))
+	for _, s := range sections {
+		b = append(b, s...)
+	}
+	if flags&FlagChecksumPresent != 0 {
+		binary.LittleEndian.PutUint32(b, uint32(len(b)+4))
+		b = binary.LittleEndian.AppendUint32(b, crc32.Checksum(b, castagnoli))
+	}
+	binary.LittleEndian.PutUint32(b, uint32(len(b)))
+	return b
+}
+
+func body(d []byte) []byte {
+	return append([]byte{kindBody}, d...)
+}
+
+func sequence(id string, docs ...[]byte) []byte {
+	b := append([]byte(id), 0)
+	for _, d := range docs {
+		b = append(b, d...)
+	}
+	return append(append([]byte{kindSequence}, le32(uint32(len(b)+4))...), b...)
+}
+
+func TestParseMsg(t *testing.T) {
+	cmd := doc(elem(String, "insert", str("users")...), elem(String, "$db", str("app")...))
+	d1 := doc(elem(Int32, "_id", le32(1)...))
+	d2 := doc(elem(Int32, "_id", le32(2)...))
+	data := opMsg(FlagChecksumPresent|FlagExhaustAllowed, body(cmd), sequence("documents", d1, d2))
+
+	m, err := ParseMsg(data)
+	if err != nil {
+		t.Fatalf("ParseMsg() error = %v", err)
+	}
+	if m.RequestID != 7 || m.OpCode != OpMsg || int(m.Length) != len(data) {
+		t.Errorf("Header = %+v", m.Header)
+	}
+	if m.Flags != FlagChecksumPresent|FlagExhaustAllowed {
+		t.Errorf("Flags = %#x", m.Flags)
+	}
+	if !bytes.Equal(m.Body, cmd) {
+		t.Errorf("Body = % x, want % x", []byte(m.Body), cmd)
+	}
+	if len(m.Sequences) != 1 || m.Sequences[0].Identifier != "documents" || len(m.Sequences[0].Documents) != 2 {
+		t.Fatalf("Sequences = %+v", m.Sequences)
+	}
+	// Appending to a document must not overwrite the section or document
+	// that follows it.
+	_ = append(m.Body, 0xEE)
+	_ = append(m.Sequences[0].Documents[0], 0xEE)
+	if v, _ := m.Sequences[0].Documents[1].Lookup("_id"); !bytes.Equal(v.Data, le32(2)) {
+		t.Errorf("second document _id = % x", v.Data)
+	}
+	if m.Checksum != binary.LittleEndian.Uint32(data[len(data)-4:]) {
+		t.Errorf("Checksum = %#x", m.Checksum)
+	}
+	if _, err := ParseMsg(data); err != nil {
+		t.Errorf("ParseMsg() after appending to the result error = %v", err)
+	}
+
+	data[len(data)-1] ^= 0xff
+	if _, err := ParseMsg(data); err != ErrChecksum {
+		t.Errorf("ParseMsg(corrupt) error = %v, want ErrChecksum", err)
+	}
+}
+
+func TestParseMsg_Errors(t *testing.T) {
+	cmd := doc(elem(Int32, "ping", le32(1)...))
+	legacy := opMsg(0, body(cmd))
+	binary.LittleEndian.PutUint32(legacy[12:], uint32(OpQuery))
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"short header", opMsg(0)[:10], io.ErrUnexpectedEOF},
+		{"legacy opcode", legacy, ErrMalformed},
+		{"length mismatch", append(opMsg(0, body(cmd)), 0), ErrMalformed},
+		{"unknown required flag", opMsg(1<<2, body(cmd)), ErrMalformed},
+		{"unknown optional flag", opMsg(1<<20, body(cmd)), nil},
+		{"missing body", opMsg(0, sequence("documents")), ErrMalformed},
+		{"two bodies", opMsg(0, body(cmd), body(cmd)), ErrMalformed},
+		{"unknown kind", opMsg(0, body(cmd), []byte{2}), ErrMalformed},
+		{"truncated body", opMsg(0, body(cmd)[:6]), ErrMalformed},
+		{"sequence overrun", opMsg(0, body(cmd), []byte{kindSequence, 0xff, 0, 0, 0}), ErrMalformed},
+		{"sequence document overrun", opMsg(0, body(cmd), sequence("d", cmd[:7])), ErrMalformed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseMsg(tt.data); !errors.Is(err, tt.want) {
+				t.Errorf("ParseMsg() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestMessageReader(t *testing.T) {
+	m1 := opMsg(0, body(doc(elem(Int32, "ping", le32(1)...))))
+	m2 := opMsg(FlagChecksumPresent, body(doc()))
+	stream := append(append([]byte{}, m1...), m2...)
+
+	mr := NewMessageReader(bytes.NewReader(stream))
+	for i, want := range [][]byte{m1, m2} {
+		got, err := mr.Next()
+		if err != nil {
+			t.Fatalf("Next() #%d error = %v", i, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("Next() #%d = % x, want % x", i, got, want)
+		}
+	}
+	if _, err := mr.Next(); err != io.EOF {
+		t.Errorf("Next() at end error = %v, want io.EOF", err)
+	}
+	if mr.Offset() != int64(len(stream)) {
+		t.Errorf("Offset() = %d, want %d", mr.Offset(), len(stream))
+	}
+
+	mr = NewMessageReader(bytes.NewReader(m1[:len(m1)-1]))
+	if _, err := mr.Next(); err != io.ErrUnexpectedEOF {
+		t.Errorf("Next(truncated) error = %v, want io.ErrUnexpectedEOF", err)
+	}
+
+	mr = NewMessageReader(bytes.NewReader(m1))
+	mr.SetMaxMessageSize(len(m1) - 1)
+	if _, err := mr.Next(); !errors.Is(err, ErrMessageTooLarge) {
+		t.Errorf("Next(large) error = %v, want ErrMessageTooLarge", err)
+	}
+
+	bad := append([]byte{}, m1...)
+	binary.LittleEndian.PutUint32(bad, 3)
+	mr = NewMessageReader(bytes.NewReader(bad))
+	if _, err := mr.Next(); !errors.Is(err, ErrMalformed) {
+		t.Errorf("Next(bad length) error = %v, want ErrMalformed", err)
+	}
+}
+
+func TestMessageReader_Large(t *testing.T) {
+	// A header announcing the largest allowed message must not allocate it
+	// before the body arrives.
+	hdr := make([]byte, HeaderLen, HeaderLen+10)
+	binary.LittleEndian.PutUint32(hdr, DefaultMaxMessageSize)
+	binary.LittleEndian.PutUint32(hdr[12:], uint32(OpMsg))
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	_, err := NewMessageReader(bytes.NewReader(append(hdr, make([]byte, 10)...))).Next()
+	runtime.ReadMemStats(&after)
+	if err != io.ErrUnexpectedEOF {
+		t.Errorf("Next(truncated) error = %v, want io.ErrUnexpectedEOF", err)
+	}
+	if n := after.TotalAlloc - before.TotalAlloc; n > 1<<20 {
+		t.Errorf("Next(truncated) allocated %d bytes", n)
+	}
+
+	// A message spanning several chunks is still read whole.
+	big := opMsg(0, body(doc(elem(String, "s", str(strings.Repeat("x", 300<<10))...))))
+	if got, err := NewMessageReader(bytes.NewReader(big)).Next(); err != nil || !bytes.Equal(got, big) {
+		t.Errorf("Next() = %d bytes, %v; want %d bytes", len(got), err, len(big))
+	}
+}
@@ -0,0 +1,296 @@
+// Package cbor decodes CBOR data items (RFC 8949).
+//
+// Decoder offers a token API, where arrays, maps, tags and
+// indefinite-length strings appear as headers followed by their contents,
+// and Decode, which builds complete values from plain Go types. Both accept
+// all major types, indefinite-length items and half, single and double
+// precision floats; Decode also interprets the standard date/time and
+// bignum tags.
+//
+// In strict mode the decoder also enforces the core deterministic encoding
+// requirements of RFC 8949 Section 4.2.1: shortest arguments and floats, no
+// indefinite lengths, and map keys in bytewise lexicographic order without
+// duplicates. This is what signature formats such as COSE and WebAuthn
+// rely on.
+//
+// Example usage:
+//
+//	d := cbor.NewDecoder(data)
+//	d.SetStrict(true)
+//	v, err := d.Decode()
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	fmt.Println(v)
+package cbor
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"unicode/utf8"
+
+	"github.com/nemohan/wireread"
+)
+
+var (
+	// ErrMalformed is returned for reserved additional information values,
+	// misplaced break codes, invalid UTF-8 in text strings and tag contents
+	// of the wrong type.
+	ErrMalformed = errors.New("cbor: malformed data")
+
+	// ErrNotCanonical is returned in strict mode for encodings that are
+	// well-formed but not deterministic.
+	ErrNotCanonical = errors.New("cbor: non-canonical encoding")
+
+	// ErrDuplicateKey is returned in strict mode for maps with repeated
+	// keys.
+	ErrDuplicateKey = errors.New("cbor: duplicate map key")
+
+	// ErrDepthExceeded is returned by Decode and Skip when items nest
+	// deeper than MaxDepth.
+	ErrDepthExceeded = errors.New("cbor: nesting too deep")
+)
+
+// MaxDepth limits the nesting of arrays, maps and tags read by Decode and Skip.
+const MaxDepth = 512
+
+// Kind is the kind of a token.
+type Kind uint8
+
+// Token kinds. Major types 0-6 map to the first seven kinds; major type 7
+// is split into its simple values, floats and the break code.
+const (
+	Uint Kind = iota
+	NegInt
+	Bytes
+	Text
+	Array
+	Map
+	Tag
+	Bool
+	Null
+	Undefined
+	Simple
+	Float
+	Break
+)
+
+var kindNames = [...]string{
+	Uint:      "uint",
+	NegInt:    "negint",
+	Bytes:     "bytes",
+	Text:      "text",
+	Array:     "array",
+	Map:       "map",
+	Tag:       "tag",
+	Bool:      "bool",
+	Null:      "null",
+	Undefined: "undefined",
+	Simple:    "simple",
+	Float:     "float",
+	Break:     "break",
+}
+
+func (k Kind) String() string {
+	if int(k) < len(kindNames) {
+		return kindNames[k]
+	}
+	return fmt.Sprintf("Kind(%d)", uint8(k))
+}
+
+// Token is a single CBOR item header or scalar.
+type Token struct {
+	Kind Kind
+	// Arg is the argument of the item: the value of Uint, the encoded n of
+	// NegInt (whose value is -1-n), the length of definite Bytes, Text,
+	// Array and Map items, the tag number, or the simple value.
+	Arg uint64
+	// Indefinite is set on headers of indefinite-length items, which are
+	// followed by their chunks or elements and a Break token.
+	Indefinite bool
+	// Bytes is the payload of definite-length Bytes and Text items. It
+	// aliases the decoder's input.
+	Bytes []byte
+	Bool  bool
+	Float float64
+}
+
+// Decoder decodes CBOR items from a buffer.
+type Decoder struct {
+	data   []byte
+	r      *wireread.SafeReader
+	strict bool
+}
+
+// NewDecoder creates a Decoder reading data.
+func NewDecoder(data []byte) *Decoder {
+	return &Decoder{data: data, r: wireread.NewSafeReader(data)}
+}
+
+// SetStrict enables or disables deterministic encoding checks.
+func (d *Decoder) SetStrict(strict bool) {
+	d.strict = strict
+}
+
+// Offset returns the number of bytes consumed.
+func (d *Decoder) Offset() int {
+	return len(d.data) - len(d.r.Bytes())
+}
+
+// More reports whether unread data remains.
+func (d *Decoder) More() bool {
+	return len(d.r.Bytes()) > 0
+}
+
+func (d *Decoder) errorf(err error, off int, format string, args ...any) error {
+	return fmt.Errorf("%w: %s at offset %d", err, fmt.Sprintf(format, args...), off)
+}
+
+// Next reads the next token. It returns io.EOF when the input is exhausted
+// and io.ErrUnexpectedEOF when it ends inside an item. Next checks each
+// token on its own; the structure of indefinite-length strings and the
+// order of map keys are only checked by Decode.
+func (d *Decoder) Next() (Token, error) {
+	off := d.Offset()
+	ib, err := d.r.ReadByte()
+	if err != nil {
+		return Token{}, io.EOF
+	}
+	major, info := ib>>5, ib&0x1f
+	if major == 7 {
+		return d.simple(info, off)
+	}
+
+	if info == 31 {
+		switch {
+		case major == 0 || major == 1 || major == 6:
+			return Token{}, d.errorf(ErrMalformed, off, "indefinite length for major type %d", major)
+		case d.strict:
+			return Token{}, d.errorf(ErrNotCanonical, off, "indefinite length")
+		}
+		return Token{Kind: Kind(major), Indefinite: true}, nil
+	}
+	arg, err := d.argument(info, off)
+	if err != nil {
+		return Token{}, err
+	}
+	t := Token{Kind: Kind(major), Arg: arg}
+
+	switch t.Kind {
+	case Bytes, Text:
+		if arg > uint64(len(d.r.Bytes())) {
+			return Token{}, io.ErrUnexpectedEOF
+		}
+		if t.Bytes, err = d.r.ReadSlice(int(arg)); err != nil {
+			return Token{}, err
+		}
+		if t.Kind == Text && !utf8.Valid(t.Bytes) {
+			return Token{}, d.errorf(ErrMalformed, off, "invalid UTF-8 in text string")
+		}
+	case Array, Map:
+		// Every element takes at least one byte.
+		min := arg
+		if t.Kind == Map {
+			min *= 2
+		}
+		if arg > math.MaxInt32 || min > uint64(len(d.r.Bytes())) {
+			return Token{}, io.ErrUnexpectedEOF
+		}
+	}
+	return t, nil
+}
+
+// argument reads the argument encoded by the additional information info.
+func (d *Decoder) argument(info byte, off int) (uint64, error) {
+	var v uint64
+	var err error
+	switch {
+	case info < 24:
+		return uint64(info), nil
+	case info == 24:
+		var b byte
+		b, err = d.r.ReadByte()
+		v = uint64(b)
+	case info == 25:
+		var n uint16
+		n, err = d.r.ReadUint16BE()
+		v = uint64(n)
+	case info == 26:
+		var n uint32
+		n, err = d.r.ReadUint32BE()
+		v = uint64(n)
+	case info == 27:
+		v, err = d.r.ReadUint64BE()
+	default:
+		return 0, d.errorf(ErrMalformed, off, "reserved additional information %d", info)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if d.strict && v < minArgument[info-24] {
+		return 0, d.errorf(ErrNotCanonical, off, "argument %d not in shortest form", v)
+	}
+	return v, nil
+}
+
+// minArgument is the smallest argument that needs each of the 1, 2, 4 and
+// 8-byte forms.
+var minArgument = [4]uint64{24, 1 << 8, 1 << 16, 1 << 32}
+
+// simple decodes a major type 7 item.
+func (d *Decoder) simple(info byte, off int) (Token, error) {
+	switch info {
+	case 20, 21:
+		return Token{Kind: Bool, Arg: uint64(info), Bool: info == 21}, nil
+	case 22:
+		return Token{Kind: Null, Arg: 22}, nil
+	case 23:
+		return Token{Kind: Undefined, Arg: 23}, nil
+	case 24:
+		v, err := d.r.ReadByte()
+		if err != nil {
+			return Token{}, err
+		}
+		if v < 32 {
+			return Token{}, d.errorf(ErrMalformed, off, "two-byte encoding of simple value %d", v)
+		}
+		return Token{Kind: Simple, Arg: uint64(v)}, nil
+	case 25:
+		h, err := d.r.ReadUint16BE()
+		if err != nil {
+			return Token{}, err
+		}
+		f := float16(h)
+		if d.strict && math.IsNaN(f) && h != 0x7e00 {
+			return Token{}, d.errorf(ErrNotCanonical, off, "NaN payload")
+		}
+		return Token{Kind: Float, Float: f}, nil
+	case 26:
+		v, err := d.r.ReadUint32BE()
+		if err != nil {
+			return Token{}, err
+		}
+		f := math.Float32frombits(v)
+		if d.strict && (f != f || fitsFloat16(f)) {
+			return Token{}, d.errorf(ErrNotCanonical, off, "float32 %v fits in a shorter float", f)
+		}
+		return Token{Kind: Float, Float: float64(f)}, nil
+	case 27:
+		v, err := d.r.ReadUint64BE()
+		if err != nil {
+			return Token{}, err
+		}
+		f := math.Float64frombits(v)
+		if d.strict && (f != f || fitsFloat32(f)) {
+			return Token{}, d.errorf(ErrNotCanonical, off, "float64 %v fits in a shorter float", f)
+		}
+		return Token{Kind: Float, Float: f}, nil
+	case 28, 29, 30:
+		return Token{}, d.errorf(ErrMalformed, off, "reserved additional information %d", info)
+	case 31:
+		return Token{Kind: Break}, nil
+	}
+	return Token{Kind: Simple, Arg: uint64(info)}, nil
+}
@@ -0,0 +1,266 @@
+package cbor
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"math"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func bigInt(s string) *big.Int {
+	n, _ := new(big.Int).SetString(s, 10)
+	return n
+}
+
+// Examples from RFC 8949 Appendix A.
+func TestDecode_Appendix(t *testing.T) {
+	tests := []struct {
+		hex  string
+		want any
+	}{
+		{"00", int64(0)},
+		{"17", int64(23)},
+		{"1818", int64(24)},
+		{"1903e8", int64(1000)},
+		{"1a000f4240", int64(1000000)},
+		{"1b000000e8d4a51000", int64(1000000000000)},
+		{"1bffffffffffffffff", uint64(math.MaxUint64)},
+		{"c249010000000000000000", bigInt("18446744073709551616")},
+		{"3bffffffffffffffff", bigInt("-18446744073709551616")},
+		{"c349010000000000000000", bigInt("-18446744073709551617")},
+		{"20", int64(-1)},
+		{"3903e7", int64(-1000)},
+		{"f90000", 0.0},
+		{"f93c00", 1.0},
+		{"fb3ff199999999999a", 1.1},
+		{"f97bff", 65504.0},
+		{"fa47c35000", 100000.0},
+		{"fa7f7fffff", 3.4028234663852886e+38},
+		{"f90001", 5.960464477539063e-8},
+		{"f90400", 0.00006103515625},
+		{"f9c400", -4.0},
+		{"f97c00", math.Inf(1)},
+		{"f9fc00", math.Inf(-1)},
+		{"f4", false},
+		{"f5", true},
+		{"f6", nil},
+		{"f7", SimpleValue(23)},
+		{"f0", SimpleValue(16)},
+		{"f8ff", SimpleValue(255)},
+		{"c074323031332d30332d32315432303a30343a30305a", time.Date(2013, 3, 21, 20, 4, 0, 0, time.UTC)},
+		{"c11a514b67b0", time.Unix(1363896240, 0).UTC()},
+		{"c1fb41d452d9ec200000", time.Unix(1363896240, 5e8).UTC()},
+		{"d74401020304", TagValue{Number: 23, Content: []byte{1, 2, 3, 4}}},
+		{"40", []byte{}},
+		{"4401020304", []byte{1, 2, 3, 4}},
+		{"60", ""},
+		{"6449455446", "IETF"},
+		{"62c3bc", "ü"},
+		{"64f0908591", "𐅑"},
+		{"80", []any{}},
+		{"8301820203820405", []any{int64(1), []any{int64(2), int64(3)}, []any{int64(4), int64(5)}}},
+		{"a0", map[string]any{}},
+		{"a201020304", map[any]any{int64(1): int64(2), int64(3): int64(4)}},
+		{"a26161016162820203", map[string]any{"a": int64(1), "b": []any{int64(2), int64(3)}}},
+		{"5f42010243030405ff", []byte{1, 2, 3, 4, 5}},
+		{"7f657374726561646d696e67ff", "streaming"},
+		{"9fff", []any{}},
+		{"9f018202039f0405ffff", []any{int64(1), []any{int64(2), int64(3)}, []any{int64(4), int64(5)}}},
+		{"bf61610161629f0203ffff", map[string]any{"a": int64(1), "b": []any{int64(2), int64(3)}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.hex, func(t *testing.T) {
+			data := mustHex(t, tt.hex)
+			got, n, err := Decode(data)
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if n != len(data) {
+				t.Errorf("Decode() consumed %d bytes, want %d", n, len(data))
+			}
+			switch want := tt.want.(type) {
+			case *big.Int:
+				if g, ok := got.(*big.Int); !ok || g.Cmp(want) != 0 {
+					t.Errorf("Decode() = %v, want %v", got, want)
+				}
+			case time.Time:
+				if g, ok := got.(time.Time); !ok || !g.Equal(want) {
+					t.Errorf("Decode() = %v, want %v", got, want)
+				}
+			default:
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("Decode() = %#v, want %#v", got, tt.want)
+				}
+			}
+		})
+	}
+
+	if v, _, _ := Decode(mustHex(t, "f97e00")); !math.IsNaN(v.(float64)) {
+		t.Errorf("Decode(NaN) = %v", v)
+	}
+}
+
+func TestDecode_Errors(t *testing.T) {
+	tests := []struct {
+		hex  string
+		want error
+	}{
+		{"", io.ErrUnexpectedEOF},
+		{"1c", ErrMalformed},           // reserved additional information
+		{"fc", ErrMalformed},           // reserved simple encoding
+		{"f801", ErrMalformed},         // two-byte simple value below 32
+		{"ff", ErrMalformed},           // stray break
+		{"1f", ErrMalformed},           // indefinite unsigned integer
+		{"62c328", ErrMalformed},       // invalid UTF-8
+		{"5f6161ff", ErrMalformed},     // text chunk in byte string
+		{"5f5f4101ffff", ErrMalformed}, // nested indefinite chunk
+		{"c06161", ErrMalformed},       // tag 0 with bad date
+		{"c201", ErrMalformed},         // bignum content not bytes
+		{"a1810101", ErrMalformed},     // array as map key
+		{"83010203ff", nil},            // trailing data is left unread
+		{"4501", io.ErrUnexpectedEOF},  // truncated bytes
+		{"9f01", io.ErrUnexpectedEOF},  // unterminated array
+		{"9bffffffffffffffff", io.ErrUnexpectedEOF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.hex, func(t *testing.T) {
+			if _, _, err := Decode(mustHex(t, tt.hex)); !errors.Is(err, tt.want) {
+				t.Errorf("Decode() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	deep := make([]byte, MaxDepth+1)
+	for i := range deep {
+		deep[i] = 0x81
+	}
+	if _, _, err := Decode(append(deep, 0x00)); err != ErrDepthExceeded {
+		t.Errorf("Decode(deep) error = %v, want ErrDepthExceeded", err)
+	}
+}
+
+func TestDecodeStrict(t *testing.T) {
+	tests := []struct {
+		hex  string
+		want error
+	}{
+		{"a201020304", nil},
+		{"a2016161616201", nil},
+		{"f93e00", nil},
+		{"fb3ff199999999999a", nil},
+		{"c249010000000000000000", nil},
+		{"1817", ErrNotCanonical},
+		{"190017", ErrNotCanonical},
+		{"1a0000ffff", ErrNotCanonical},
+		{"1b00000000ffffffff", ErrNotCanonical},
+		{"fa3fc00000", ErrNotCanonical},
+		{"fb3ff8000000000000", ErrNotCanonical},
+		{"fa7fc00000", ErrNotCanonical},
+		{"f97e01", ErrNotCanonical},
+		{"5f4101ff", ErrNotCanonical},
+		{"9fff", ErrNotCanonical},
+		{"c24101", ErrNotCanonical},
+		{"c2490001000000000000000000", ErrNotCanonical},
+		{"a2616201616102", ErrNotCanonical},
+		{"a2616101616102", ErrDuplicateKey},
+		{"a2616101016102", ErrNotCanonical},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.hex, func(t *testing.T) {
+			if _, _, err := DecodeStrict(mustHex(t, tt.hex)); !errors.Is(err, tt.want) {
+				t.Errorf("DecodeStrict() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestFitsFloat16(t *testing.T) {
+	tests := []struct {
+		f    float32
+		want bool
+	}{
+		{0, true},
+		{1.5, true},
+		{65504, true},
+		{65520, false},
+		{5.960464477539063e-8, true},    // smallest subnormal
+		{2.98023223876953125e-8, false}, // half of it
+		{0.00006103515625, true},
+		{1.1, false},
+		{float32(math.Inf(-1)), true},
+	}
+
+	for _, tt := range tests {
+		if got := fitsFloat16(tt.f); got != tt.want {
+			t.Errorf("fitsFloat16(%v) = %v, want %v", tt.f, got, tt.want)
+		}
+	}
+}
+
+func TestDecoder_Tokens(t *testing.T) {
+	data := mustHex(t, "bf6161d82001ff")
+	d := NewDecoder(data)
+	var kinds []Kind
+	for {
+		tok, err := d.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		kinds = append(kinds, tok.Kind)
+		if tok.Kind == Text {
+			// 0x1c uses a reserved additional-information value, so
+			// decoding fails if this reaches the input.
+			_ = append(tok.Bytes, 0x1c)
+		}
+	}
+	want := []Kind{Map, Text, Tag, Uint, Break}
+	if !reflect.DeepEqual(kinds, want) {
+		t.Errorf("kinds = %v, want %v", kinds, want)
+	}
+}
+
+func TestDecoder_Skip(t *testing.T) {
+	d := NewDecoder(mustHex(t, "bf61610161629f0203ffffc1011818"))
+	for i := 0; i < 2; i++ {
+		if err := d.Skip(); err != nil {
+			t.Fatalf("Skip() #%d error = %v", i, err)
+		}
+	}
+	if v, err := d.Decode(); v != int64(24) || err != nil {
+		t.Errorf("Decode() after Skip = %v, %v, want 24", v, err)
+	}
+	if _, err := d.Decode(); err != io.EOF {
+		t.Errorf("Decode() at end error = %v, want io.EOF", err)
+	}
+
+	// Arrays, indefinite-length arrays and tags nested one level too deep.
+	for _, head := range [][]byte{{0x81}, {0x9f}, {0xd8, 0x20}} {
+		deep := bytes.Repeat(head, MaxDepth+1)
+		if err := NewDecoder(append(deep, 0x00)).Skip(); err != ErrDepthExceeded {
+			t.Errorf("Skip(deep %x) error = %v, want ErrDepthExceeded", head, err)
+		}
+	}
+	if err := NewDecoder(mustHex(t, "5f41619f00ffff")).Skip(); !errors.Is(err, ErrMalformed) {
+		t.Errorf("Skip(array chunk) error = %v, want ErrMalformed", err)
+	}
+}
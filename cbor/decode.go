@@ -0,0 +1,344 @@
+package cbor
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"math/big"
+	"time"
+)
+
+// Standard tags interpreted by Decode.
+const (
+	TagDateTime     uint64 = 0
+	TagEpochTime    uint64 = 1
+	TagPosBignum    uint64 = 2
+	TagNegBignum    uint64 = 3
+	TagSelfDescribe uint64 = 55799
+)
+
+// TagValue is a tagged item whose tag Decode does not interpret.
+type TagValue struct {
+	Number  uint64
+	Content any
+}
+
+// SimpleValue is an unassigned simple value, or undefined (23).
+type SimpleValue uint8
+
+// Skip skips the next complete item, including the contents of arrays,
+// maps, tags and indefinite-length strings. Like Decode, it returns
+// ErrDepthExceeded when items nest deeper than MaxDepth.
+func (d *Decoder) Skip() error {
+	return d.skip(MaxDepth)
+}
+
+func (d *Decoder) skip(depth int) error {
+	off := d.Offset()
+	t, err := d.Next()
+	if err != nil {
+		return unexpectedEOF(err)
+	}
+
+	var n uint64
+	switch t.Kind {
+	case Break:
+		return d.errorf(ErrMalformed, off, "unexpected break")
+	case Bytes, Text:
+		if t.Indefinite {
+			return d.skipChunks(t.Kind)
+		}
+		return nil
+	case Array:
+		n = t.Arg
+	case Map:
+		n = 2 * t.Arg
+	case Tag:
+		n = 1
+	default:
+		return nil
+	}
+
+	if depth == 0 {
+		return ErrDepthExceeded
+	}
+	if t.Indefinite {
+		for !d.atBreak() {
+			if err := d.skip(depth - 1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for ; n > 0; n-- {
+		if err := d.skip(depth - 1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// skipChunks skips the chunks of an indefinite-length string up to and
+// including the break code. Like chunks, it requires definite-length
+// strings of the same kind.
+func (d *Decoder) skipChunks(kind Kind) error {
+	for {
+		off := d.Offset()
+		t, err := d.Next()
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+		if t.Kind == Break {
+			return nil
+		}
+		if t.Kind != kind || t.Indefinite {
+			return d.errorf(ErrMalformed, off, "%s chunk in indefinite-length %s", t.Kind, kind)
+		}
+	}
+}
+
+// Decode reads the next complete item as a Go value:
+//
+//	unsigned and negative integers   int64, or uint64 and *big.Int beyond int64
+//	byte string                      []byte
+//	text string                      string
+//	array                            []any
+//	map with only text keys          map[string]any
+//	other maps                       map[any]any
+//	tag 0 and 1                      time.Time
+//	tag 2 and 3                      *big.Int
+//	other tags                       TagValue
+//	false, true                      bool
+//	null                             nil
+//	undefined and simple values      SimpleValue
+//	floats                           float64
+//
+// Definite byte strings alias the input. Map keys that are byte strings,
+// arrays, maps or big integers cannot be used in a Go map and fail with
+// ErrMalformed. Outside strict mode, duplicate keys keep the last value.
+// Decode returns io.EOF when the input is exhausted.
+func (d *Decoder) Decode() (any, error) {
+	return d.decode(MaxDepth)
+}
+
+func (d *Decoder) decode(depth int) (any, error) {
+	off := d.Offset()
+	t, err := d.Next()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t.Kind {
+	case Uint:
+		if t.Arg > math.MaxInt64 {
+			return t.Arg, nil
+		}
+		return int64(t.Arg), nil
+	case NegInt:
+		if t.Arg > math.MaxInt64 {
+			n := new(big.Int).SetUint64(t.Arg)
+			return n.Not(n), nil // -1-n
+		}
+		return -1 - int64(t.Arg), nil
+	case Bytes, Text:
+		b := t.Bytes
+		if t.Indefinite {
+			if b, err = d.chunks(t.Kind); err != nil {
+				return nil, err
+			}
+		}
+		if t.Kind == Text {
+			return string(b), nil
+		}
+		return b, nil
+	case Bool:
+		return t.Bool, nil
+	case Null:
+		return nil, nil
+	case Undefined, Simple:
+		return SimpleValue(t.Arg), nil
+	case Float:
+		return t.Float, nil
+	case Break:
+		return nil, d.errorf(ErrMalformed, off, "unexpected break")
+	}
+
+	if depth == 0 {
+		return nil, ErrDepthExceeded
+	}
+	switch t.Kind {
+	case Array:
+		return d.decodeArray(t, depth)
+	case Map:
+		return d.decodeMap(t, depth)
+	}
+	return d.decodeTag(t.Arg, off, depth)
+}
+
+// chunks concatenates the chunks of an indefinite-length string, which
+// must be definite-length strings of the same kind.
+func (d *Decoder) chunks(kind Kind) ([]byte, error) {
+	var b []byte
+	for {
+		off := d.Offset()
+		t, err := d.Next()
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		if t.Kind == Break {
+			if b == nil {
+				b = []byte{}
+			}
+			return b, nil
+		}
+		if t.Kind != kind || t.Indefinite {
+			return nil, d.errorf(ErrMalformed, off, "%s chunk in indefinite-length %s", t.Kind, kind)
+		}
+		b = append(b, t.Bytes...)
+	}
+}
+
+// atBreak consumes a break code if one is next.
+func (d *Decoder) atBreak() bool {
+	if b := d.r.Bytes(); len(b) > 0 && b[0] == 0xff {
+		d.r.Skip(1)
+		return true
+	}
+	return false
+}
+
+func (d *Decoder) decodeArray(t Token, depth int) (any, error) {
+	arr := make([]any, 0, t.Arg)
+	for i := 0; t.Indefinite || i < int(t.Arg); i++ {
+		if t.Indefinite && d.atBreak() {
+			break
+		}
+		v, err := d.decode(depth - 1)
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		arr = append(arr, v)
+	}
+	return arr, nil
+}
+
+func (d *Decoder) decodeMap(t Token, depth int) (any, error) {
+	m := make(map[string]any, t.Arg)
+	var other map[any]any
+	var prevKey []byte
+	for i := 0; t.Indefinite || i < int(t.Arg); i++ {
+		if t.Indefinite && d.atBreak() {
+			break
+		}
+		keyOff := d.Offset()
+		k, err := d.decode(depth - 1)
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		if d.strict {
+			key := d.data[keyOff:d.Offset()]
+			switch c := bytes.Compare(prevKey, key); {
+			case c == 0:
+				return nil, d.errorf(ErrDuplicateKey, keyOff, "key %v", k)
+			case c > 0 && prevKey != nil:
+				return nil, d.errorf(ErrNotCanonical, keyOff, "map keys out of order")
+			}
+			prevKey = key
+		}
+		v, err := d.decode(depth - 1)
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+
+		if s, ok := k.(string); ok && other == nil {
+			m[s] = v
+			continue
+		}
+		switch k.(type) {
+		case []byte, []any, map[string]any, map[any]any, *big.Int, TagValue:
+			return nil, d.errorf(ErrMalformed, keyOff, "unsupported map key type %T", k)
+		}
+		if other == nil {
+			other = make(map[any]any, t.Arg)
+			for ks, kv := range m {
+				other[ks] = kv
+			}
+		}
+		other[k] = v
+	}
+	if other != nil {
+		return other, nil
+	}
+	return m, nil
+}
+
+func (d *Decoder) decodeTag(num uint64, off, depth int) (any, error) {
+	content, err := d.decode(depth - 1)
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+
+	switch num {
+	case TagDateTime:
+		s, ok := content.(string)
+		if !ok {
+			return nil, d.errorf(ErrMalformed, off, "tag 0 content %T", content)
+		}
+		ts, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return nil, d.errorf(ErrMalformed, off, "tag 0: %v", err)
+		}
+		return ts, nil
+
+	case TagEpochTime:
+		switch v := content.(type) {
+		case int64:
+			return time.Unix(v, 0).UTC(), nil
+		case float64:
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				return nil, d.errorf(ErrMalformed, off, "tag 1 content %v", v)
+			}
+			sec, frac := math.Modf(v)
+			return time.Unix(int64(sec), int64(frac*1e9)).UTC(), nil
+		}
+		return nil, d.errorf(ErrMalformed, off, "tag 1 content %T", content)
+
+	case TagPosBignum, TagNegBignum:
+		b, ok := content.([]byte)
+		if !ok {
+			return nil, d.errorf(ErrMalformed, off, "tag %d content %T", num, content)
+		}
+		if d.strict && (len(b) <= 8 || b[0] == 0) {
+			return nil, d.errorf(ErrNotCanonical, off, "bignum that fits in an integer or has leading zeros")
+		}
+		n := new(big.Int).SetBytes(b)
+		if num == TagNegBignum {
+			n.Not(n) // -1-n
+		}
+		return n, nil
+	}
+	return TagValue{Number: num, Content: content}, nil
+}
+
+// Decode decodes the first item of data and returns it with the number of
+// bytes consumed.
+func Decode(data []byte) (any, int, error) {
+	d := NewDecoder(data)
+	v, err := d.Decode()
+	return v, d.Offset(), unexpectedEOF(err)
+}
+
+// DecodeStrict is like Decode with deterministic encoding checks enabled.
+func DecodeStrict(data []byte) (any, int, error) {
+	d := NewDecoder(data)
+	d.SetStrict(true)
+	v, err := d.Decode()
+	return v, d.Offset(), unexpectedEOF(err)
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,47 @@
+package cbor
+
+import "math"
+
+// float16 converts an IEEE 754 half-precision value to float64.
+func float16(h uint16) float64 {
+	sign := 1.0
+	if h&0x8000 != 0 {
+		sign = -1
+	}
+	exp := int(h>>10) & 0x1f
+	mant := float64(h & 0x3ff)
+	switch exp {
+	case 0:
+		return sign * math.Ldexp(mant, -24)
+	case 0x1f:
+		if mant == 0 {
+			return math.Inf(int(sign))
+		}
+		return math.NaN()
+	}
+	return sign * math.Ldexp(1024+mant, exp-25)
+}
+
+// fitsFloat16 reports whether f can be represented exactly in half
+// precision. NaNs are not considered.
+func fitsFloat16(f float32) bool {
+	bits := math.Float32bits(f)
+	exp := int(bits>>23&0xff) - 127
+	sig := bits&0x7fffff | 1<<23
+	switch {
+	case bits&0x7fffffff == 0, math.IsInf(float64(f), 0):
+		return true
+	case exp >= -14 && exp <= 15:
+		return bits&0x1fff == 0
+	case exp >= -24 && exp < -14:
+		// Half subnormals are multiples of 2^-24.
+		return sig&(1<<uint(-exp-1)-1) == 0
+	}
+	return false
+}
+
+// fitsFloat32 reports whether f can be represented exactly in single
+// precision. NaNs are not considered.
+func fitsFloat32(f float64) bool {
+	return float64(float32(f)) == f || math.IsInf(f, 0)
+}
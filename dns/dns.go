@@ -0,0 +1,298 @@
+// Package dns parses DNS messages (RFC 1035) including EDNS0 (RFC 6891).
+//
+// Sections are read sequentially with a SafeReader while domain names are
+// decoded with absolute-offset reads into the message, so that compression
+// pointers can refer back to any earlier position. Pointer chains are
+// bounded and names are limited to 255 octets on the wire.
+//
+// Example usage:
+//
+//	msg, err := dns.Parse(packet)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	for _, rr := range msg.Answers {
+//	    if a, ok := rr.Data.(*dns.A); ok {
+//	        fmt.Println(rr.Name, a.Addr)
+//	    }
+//	}
+package dns
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/nemohan/wireread"
+)
+
+var (
+	// ErrPointerLoop is returned when compression pointers point forward or
+	// form a chain longer than MaxPointers.
+	ErrPointerLoop = errors.New("dns: compression pointer loop")
+
+	// ErrNameTooLong is returned when a name exceeds MaxNameLen octets.
+	ErrNameTooLong = errors.New("dns: name too long")
+
+	// ErrLabelType is returned for the reserved 0b01 and 0b10 label types.
+	ErrLabelType = errors.New("dns: unsupported label type")
+
+	// ErrRDataLength is returned when record data does not match its
+	// RDLENGTH.
+	ErrRDataLength = errors.New("dns: rdata length mismatch")
+)
+
+// MaxNameLen is the maximum length of a name on the wire, including length
+// octets and the terminating root label.
+const MaxNameLen = 255
+
+// MaxPointers is the maximum number of compression pointers followed while
+// decoding a single name.
+const MaxPointers = 64
+
+// Type is a resource record type.
+type Type uint16
+
+// Resource record types with dedicated decoders.
+const (
+	TypeA     Type = 1
+	TypeNS    Type = 2
+	TypeCNAME Type = 5
+	TypeSOA   Type = 6
+	TypePTR   Type = 12
+	TypeMX    Type = 15
+	TypeTXT   Type = 16
+	TypeAAAA  Type = 28
+	TypeSRV   Type = 33
+	TypeOPT   Type = 41
+)
+
+var typeNames = map[Type]string{
+	TypeA:     "A",
+	TypeNS:    "NS",
+	TypeCNAME: "CNAME",
+	TypeSOA:   "SOA",
+	TypePTR:   "PTR",
+	TypeMX:    "MX",
+	TypeTXT:   "TXT",
+	TypeAAAA:  "AAAA",
+	TypeSRV:   "SRV",
+	TypeOPT:   "OPT",
+}
+
+func (t Type) String() string {
+	if s, ok := typeNames[t]; ok {
+		return s
+	}
+	return fmt.Sprintf("TYPE%d", uint16(t))
+}
+
+// Class is a resource record class.
+type Class uint16
+
+// ClassINET is the Internet class.
+const ClassINET Class = 1
+
+// Header is the fixed 12-byte message header.
+type Header struct {
+	ID                 uint16
+	Response           bool
+	Opcode             uint8
+	Authoritative      bool
+	Truncated          bool
+	RecursionDesired   bool
+	RecursionAvailable bool
+	Zero               bool
+	AuthenticData      bool
+	CheckingDisabled   bool
+	// Rcode is the 4-bit response code from the header. The EDNS0 OPT
+	// record may extend it; see Message.Rcode.
+	Rcode uint8
+
+	QDCount uint16
+	ANCount uint16
+	NSCount uint16
+	ARCount uint16
+}
+
+// Question is an entry of the question section.
+type Question struct {
+	Name  string
+	Type  Type
+	Class Class
+}
+
+// Resource is a resource record. For OPT records Class carries the UDP
+// payload size and TTL the extended flags; use the decoded *OPT instead.
+type Resource struct {
+	Name  string
+	Type  Type
+	Class Class
+	TTL   uint32
+	Data  RData
+}
+
+// Message is a parsed DNS message.
+type Message struct {
+	Header     Header
+	Questions  []Question
+	Answers    []Resource
+	Authority  []Resource
+	Additional []Resource
+}
+
+// OPT returns the EDNS0 pseudo-record from the additional section, or nil.
+func (m *Message) OPT() *OPT {
+	for _, rr := range m.Additional {
+		if opt, ok := rr.Data.(*OPT); ok {
+			return opt
+		}
+	}
+	return nil
+}
+
+// Rcode returns the full 12-bit response code, combining the header with the
+// extended bits of the OPT record when present.
+func (m *Message) Rcode() uint16 {
+	rc := uint16(m.Header.Rcode)
+	if opt := m.OPT(); opt != nil {
+		rc |= uint16(opt.ExtendedRcode) << 4
+	}
+	return rc
+}
+
+// message couples the raw message with the sequential section reader, whose
+// random-access methods follow compression pointers.
+type message struct {
+	data []byte
+	r    *wireread.SafeReader
+}
+
+// offset returns the absolute position of the section reader.
+func (m *message) offset() int {
+	return len(m.data) - len(m.r.Bytes())
+}
+
+// name reads a possibly compressed name at the current position.
+func (m *message) name() (string, error) {
+	name, next, err := readName(m.r, m.offset())
+	if err != nil {
+		return "", err
+	}
+	return name, m.r.Skip(next - m.offset())
+}
+
+// Parse parses a complete DNS message.
+func Parse(data []byte) (*Message, error) {
+	m := &message{data: data, r: wireread.NewSafeReader(data)}
+	msg := new(Message)
+	if err := m.header(&msg.Header); err != nil {
+		return nil, err
+	}
+
+	h := &msg.Header
+	// Every question takes at least 5 bytes and every record at least 11,
+	// which bounds the allocations driven by the header counts.
+	if int(h.QDCount)*5+(int(h.ANCount)+int(h.NSCount)+int(h.ARCount))*11 > len(m.r.Bytes()) {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	msg.Questions = make([]Question, h.QDCount)
+	for i := range msg.Questions {
+		q := &msg.Questions[i]
+		var err error
+		if q.Name, err = m.name(); err != nil {
+			return nil, fmt.Errorf("question %d: %w", i, err)
+		}
+		var t, c uint16
+		if err := m.r.ReadUint16BEInto(&t); err != nil {
+			return nil, err
+		}
+		if err := m.r.ReadUint16BEInto(&c); err != nil {
+			return nil, err
+		}
+		q.Type, q.Class = Type(t), Class(c)
+	}
+
+	var err error
+	if msg.Answers, err = m.section("answer", h.ANCount); err != nil {
+		return nil, err
+	}
+	if msg.Authority, err = m.section("authority", h.NSCount); err != nil {
+		return nil, err
+	}
+	if msg.Additional, err = m.section("additional", h.ARCount); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func (m *message) header(h *Header) error {
+	var flags uint16
+	if err := m.r.ReadUint16BEInto(&h.ID); err != nil {
+		return err
+	}
+	if err := m.r.ReadUint16BEInto(&flags); err != nil {
+		return err
+	}
+	h.Response = flags&0x8000 != 0
+	h.Opcode = uint8(flags>>11) & 0x0F
+	h.Authoritative = flags&0x0400 != 0
+	h.Truncated = flags&0x0200 != 0
+	h.RecursionDesired = flags&0x0100 != 0
+	h.RecursionAvailable = flags&0x0080 != 0
+	h.Zero = flags&0x0040 != 0
+	h.AuthenticData = flags&0x0020 != 0
+	h.CheckingDisabled = flags&0x0010 != 0
+	h.Rcode = uint8(flags & 0x0F)
+
+	for _, c := range []*uint16{&h.QDCount, &h.ANCount, &h.NSCount, &h.ARCount} {
+		if err := m.r.ReadUint16BEInto(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *message) section(name string, count uint16) ([]Resource, error) {
+	if count == 0 {
+		return nil, nil
+	}
+	rrs := make([]Resource, count)
+	for i := range rrs {
+		if err := m.resource(&rrs[i]); err != nil {
+			return nil, fmt.Errorf("%s record %d: %w", name, i, err)
+		}
+	}
+	return rrs, nil
+}
+
+func (m *message) resource(rr *Resource) error {
+	var err error
+	if rr.Name, err = m.name(); err != nil {
+		return err
+	}
+	var t, c, rdlen uint16
+	if err := m.r.ReadUint16BEInto(&t); err != nil {
+		return err
+	}
+	if err := m.r.ReadUint16BEInto(&c); err != nil {
+		return err
+	}
+	if err := m.r.ReadUint32BEInto(&rr.TTL); err != nil {
+		return err
+	}
+	if err := m.r.ReadUint16BEInto(&rdlen); err != nil {
+		return err
+	}
+	rr.Type, rr.Class = Type(t), Class(c)
+
+	start := m.offset()
+	if int(rdlen) > len(m.r.Bytes()) {
+		return io.ErrUnexpectedEOF
+	}
+	if rr.Data, err = m.rdata(rr, start, start+int(rdlen)); err != nil {
+		return err
+	}
+	return m.r.Skip(int(rdlen))
+}
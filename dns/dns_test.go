@@ -0,0 +1,239 @@
+package dns
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net/netip"
+	"testing"
+
+	"github.com/nemohan/wireread"
+)
+
+// builder assembles test messages; names are written uncompressed unless a
+// pointer is appended explicitly.
+type builder []byte
+
+func (b *builder) u16(v uint16) { *b = binary.BigEndian.AppendUint16(*b, v) }
+func (b *builder) u32(v uint32) { *b = binary.BigEndian.AppendUint32(*b, v) }
+
+func (b *builder) labels(labels ...string) {
+	for _, l := range labels {
+		*b = append(*b, byte(len(l)))
+		*b = append(*b, l...)
+	}
+}
+
+func (b *builder) ptr(off int) { b.u16(0xC000 | uint16(off)) }
+
+// rr writes a record header for an already written owner name and returns a
+// function that patches RDLENGTH once the data has been appended.
+func (b *builder) rr(t Type, class Class, ttl uint32) func() {
+	b.u16(uint16(t))
+	b.u16(uint16(class))
+	b.u32(ttl)
+	at := len(*b)
+	b.u16(0)
+	return func() {
+		binary.BigEndian.PutUint16((*b)[at:], uint16(len(*b)-at-2))
+	}
+}
+
+func sampleResponse() []byte {
+	var b builder
+	b.u16(0xBEEF)
+	b.u16(0x8180) // response, RD, RA
+	b.u16(1)      // QDCOUNT
+	b.u16(6)      // ANCOUNT
+	b.u16(1)      // NSCOUNT
+	b.u16(1)      // ARCOUNT
+
+	// Question: www.example.com. A IN at offset 12.
+	b.labels("www", "example", "com")
+	b = append(b, 0)
+	b.u16(uint16(TypeA))
+	b.u16(uint16(ClassINET))
+
+	b.ptr(12)
+	done := b.rr(TypeCNAME, ClassINET, 300)
+	b.labels("web")
+	b.ptr(16) // example.com.
+	done()
+
+	b.ptr(16)
+	done = b.rr(TypeA, ClassINET, 60)
+	b = append(b, 93, 184, 216, 34)
+	done()
+
+	b.ptr(16)
+	done = b.rr(TypeAAAA, ClassINET, 60)
+	b = append(b, 0x26, 0x06, 0x28, 0x00, 0x02, 0x20, 0, 1, 0x2, 0x48, 0x18, 0x93, 0x25, 0xC8, 0x19, 0x46)
+	done()
+
+	b.ptr(16)
+	done = b.rr(TypeMX, ClassINET, 3600)
+	b.u16(10)
+	b.labels("mail")
+	b.ptr(16)
+	done()
+
+	b.ptr(16)
+	done = b.rr(TypeTXT, ClassINET, 3600)
+	b = append(b, 5)
+	b = append(b, "v=spf"...)
+	b = append(b, 0)
+	done()
+
+	b.labels("_sip", "_tcp")
+	b.ptr(16)
+	done = b.rr(TypeSRV, ClassINET, 3600)
+	b.u16(1)
+	b.u16(5)
+	b.u16(5060)
+	b.labels("sip")
+	b.ptr(16)
+	done()
+
+	b.ptr(16)
+	done = b.rr(TypeSOA, ClassINET, 3600)
+	b.labels("ns1")
+	b.ptr(16)
+	b.labels("hostmaster")
+	b.ptr(16)
+	for _, v := range []uint32{2024010101, 7200, 3600, 1209600, 300} {
+		b.u32(v)
+	}
+	done()
+
+	b = append(b, 0) // root
+	done = b.rr(TypeOPT, 1232, 0x01008000)
+	b.u16(10) // cookie
+	b.u16(8)
+	b = append(b, 1, 2, 3, 4, 5, 6, 7, 8)
+	done()
+	return b
+}
+
+func TestParse(t *testing.T) {
+	msg, err := Parse(sampleResponse())
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	h := msg.Header
+	if h.ID != 0xBEEF || !h.Response || !h.RecursionDesired || !h.RecursionAvailable || h.Rcode != 0 {
+		t.Errorf("Header = %+v", h)
+	}
+	if len(msg.Questions) != 1 || msg.Questions[0].Name != "www.example.com." || msg.Questions[0].Type != TypeA {
+		t.Errorf("Questions = %+v", msg.Questions)
+	}
+	if len(msg.Answers) != 6 || len(msg.Authority) != 1 || len(msg.Additional) != 1 {
+		t.Fatalf("section sizes = %d, %d, %d", len(msg.Answers), len(msg.Authority), len(msg.Additional))
+	}
+
+	an := msg.Answers
+	if c, ok := an[0].Data.(*CNAME); !ok || an[0].Name != "www.example.com." || c.Target != "web.example.com." || an[0].TTL != 300 {
+		t.Errorf("CNAME = %+v, %+v", an[0], an[0].Data)
+	}
+	if a, ok := an[1].Data.(*A); !ok || a.Addr != netip.MustParseAddr("93.184.216.34") {
+		t.Errorf("A = %+v", an[1].Data)
+	}
+	if a, ok := an[2].Data.(*AAAA); !ok || a.Addr != netip.MustParseAddr("2606:2800:220:1:248:1893:25c8:1946") {
+		t.Errorf("AAAA = %+v", an[2].Data)
+	}
+	if mx, ok := an[3].Data.(*MX); !ok || mx.Preference != 10 || mx.Exchange != "mail.example.com." {
+		t.Errorf("MX = %+v", an[3].Data)
+	}
+	if txt, ok := an[4].Data.(*TXT); !ok || len(txt.Strings) != 2 || txt.Strings[0] != "v=spf" || txt.Strings[1] != "" {
+		t.Errorf("TXT = %+v", an[4].Data)
+	}
+	if srv, ok := an[5].Data.(*SRV); !ok || an[5].Name != "_sip._tcp.example.com." || srv.Port != 5060 || srv.Target != "sip.example.com." {
+		t.Errorf("SRV = %+v, %+v", an[5], an[5].Data)
+	}
+	soa, ok := msg.Authority[0].Data.(*SOA)
+	if !ok || soa.MName != "ns1.example.com." || soa.RName != "hostmaster.example.com." || soa.Serial != 2024010101 || soa.Minimum != 300 {
+		t.Errorf("SOA = %+v", msg.Authority[0].Data)
+	}
+
+	opt := msg.OPT()
+	if opt == nil || opt.UDPSize != 1232 || !opt.DNSSECOK || opt.ExtendedRcode != 1 || len(opt.Options) != 1 || opt.Options[0].Code != 10 {
+		t.Errorf("OPT = %+v", opt)
+	}
+	if msg.Rcode() != 16 {
+		t.Errorf("Rcode() = %d, want 16 (BADVERS)", msg.Rcode())
+	}
+	if msg.Additional[0].Name != "." {
+		t.Errorf("OPT owner = %q, want root", msg.Additional[0].Name)
+	}
+}
+
+func TestParse_Truncated(t *testing.T) {
+	data := sampleResponse()
+	for n := 0; n < len(data); n++ {
+		if _, err := Parse(data[:n]); err == nil {
+			t.Fatalf("Parse(data[:%d]) succeeded", n)
+		}
+	}
+}
+
+func TestReadName(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  []byte
+		off  int
+		want string
+		next int
+		err  error
+	}{
+		{"root", []byte{0}, 0, ".", 1, nil},
+		{"plain", []byte{1, 'a', 2, 'b', 'c', 0}, 0, "a.bc.", 6, nil},
+		{"pointer", []byte{1, 'a', 0, 1, 'b', 0xC0, 0x00}, 3, "b.a.", 7, nil},
+		{"escaped", []byte{3, 'a', '.', 0x07, 0}, 0, "a\\.\\007.", 5, nil},
+		{"self pointer", []byte{0xC0, 0x00}, 0, "", 0, ErrPointerLoop},
+		{"forward pointer", []byte{0xC0, 0x02, 0}, 0, "", 0, ErrPointerLoop},
+		{"backward loop", []byte{1, 'a', 0xC0, 0x00}, 0, "", 0, ErrPointerLoop},
+		{"reserved label", []byte{0x40}, 0, "", 0, ErrLabelType},
+		{"truncated", []byte{3, 'a'}, 0, "", 0, io.ErrUnexpectedEOF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, next, err := readName(wireread.NewSafeReader(tt.msg), tt.off)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("readName() error = %v, want %v", err, tt.err)
+			}
+			if got != tt.want || next != tt.next {
+				t.Errorf("readName() = %q, %d, want %q, %d", got, next, tt.want, tt.next)
+			}
+		})
+	}
+}
+
+func TestReadName_TooLong(t *testing.T) {
+	var b builder
+	for i := 0; i < 5; i++ {
+		b.labels(string(make([]byte, 63)))
+	}
+	b = append(b, 0)
+	if _, _, err := readName(wireread.NewSafeReader(b), 0); err != ErrNameTooLong {
+		t.Errorf("readName() error = %v, want ErrNameTooLong", err)
+	}
+}
+
+func TestParse_RDataLengthMismatch(t *testing.T) {
+	var b builder
+	b.u16(1)
+	b.u16(0x8000)
+	b.u16(0)
+	b.u16(1)
+	b.u16(0)
+	b.u16(0)
+	b = append(b, 0)
+	done := b.rr(TypeA, ClassINET, 0)
+	b = append(b, 1, 2, 3)
+	done()
+
+	if _, err := Parse(b); !errors.Is(err, ErrRDataLength) {
+		t.Errorf("Parse() error = %v, want ErrRDataLength", err)
+	}
+}
@@ -0,0 +1,91 @@
+package dns
+
+import (
+	"strconv"
+
+	"github.com/nemohan/wireread"
+)
+
+// readName decodes the name starting at absolute offset off of the message
+// held by r and returns it in presentation format together with the offset
+// just past the name's wire representation (after the first pointer, if
+// any). Only the random-access methods of r are used, so its read position
+// is left unchanged.
+func readName(r *wireread.SafeReader, off int) (string, int, error) {
+	name := make([]byte, 0, 64)
+	next := -1
+	wireLen := 0
+	pointers := 0
+	for {
+		b, err := r.ReadByteAt(off)
+		if err != nil {
+			return "", 0, err
+		}
+		c := int(b)
+		switch c & 0xC0 {
+		case 0x00:
+			wireLen += c + 1
+			if wireLen > MaxNameLen {
+				return "", 0, ErrNameTooLong
+			}
+			if c == 0 {
+				if next < 0 {
+					next = off + 1
+				}
+				if len(name) == 0 {
+					name = append(name, '.')
+				}
+				return string(name), next, nil
+			}
+			label, err := r.ReadBytesAt(off+1, c)
+			if err != nil {
+				return "", 0, err
+			}
+			name = appendLabel(name, label)
+			off += 1 + c
+
+		case 0xC0:
+			lo, err := r.ReadByteAt(off + 1)
+			if err != nil {
+				return "", 0, err
+			}
+			ptr := (c&0x3F)<<8 | int(lo)
+			if next < 0 {
+				next = off + 2
+			}
+			// Pointers must refer to earlier data; together with the hop
+			// limit this rules out cycles.
+			pointers++
+			if ptr >= off || pointers > MaxPointers {
+				return "", 0, ErrPointerLoop
+			}
+			off = ptr
+
+		default:
+			return "", 0, ErrLabelType
+		}
+	}
+}
+
+// appendLabel appends a label and its trailing dot to name, escaping dots,
+// backslashes and non-printable octets as in zone files.
+func appendLabel(name, label []byte) []byte {
+	for _, b := range label {
+		switch {
+		case b == '.' || b == '\\' || b == '"' || b == '(' || b == ')' || b == ';':
+			name = append(name, '\\', b)
+		case b < 0x21 || b > 0x7E:
+			name = append(name, '\\')
+			if b < 100 {
+				name = append(name, '0')
+			}
+			if b < 10 {
+				name = append(name, '0')
+			}
+			name = strconv.AppendUint(name, uint64(b), 10)
+		default:
+			name = append(name, b)
+		}
+	}
+	return append(name, '.')
+}
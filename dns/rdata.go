@@ -0,0 +1,261 @@
+package dns
+
+import (
+	"fmt"
+	"net/netip"
+
+	"github.com/nemohan/wireread"
+)
+
+// RData is the decoded data of a resource record.
+type RData interface {
+	Type() Type
+}
+
+// A is an IPv4 address record.
+type A struct {
+	Addr netip.Addr
+}
+
+// AAAA is an IPv6 address record.
+type AAAA struct {
+	Addr netip.Addr
+}
+
+// NS is a name server record.
+type NS struct {
+	Host string
+}
+
+// CNAME is a canonical name record.
+type CNAME struct {
+	Target string
+}
+
+// PTR is a domain name pointer record.
+type PTR struct {
+	Target string
+}
+
+// MX is a mail exchange record.
+type MX struct {
+	Preference uint16
+	Exchange   string
+}
+
+// TXT is a text record holding one or more character strings.
+type TXT struct {
+	Strings []string
+}
+
+// SRV is a service location record (RFC 2782).
+type SRV struct {
+	Priority uint16
+	Weight   uint16
+	Port     uint16
+	Target   string
+}
+
+// SOA is a start of authority record.
+type SOA struct {
+	MName   string
+	RName   string
+	Serial  uint32
+	Refresh uint32
+	Retry   uint32
+	Expire  uint32
+	Minimum uint32
+}
+
+// EDNSOption is a single option of an OPT record.
+type EDNSOption struct {
+	Code uint16
+	Data []byte
+}
+
+// OPT is the EDNS0 pseudo-record (RFC 6891).
+type OPT struct {
+	UDPSize       uint16
+	ExtendedRcode uint8
+	Version       uint8
+	DNSSECOK      bool
+	Options       []EDNSOption
+}
+
+// Unknown holds the raw data of records without a dedicated decoder.
+type Unknown struct {
+	RRType Type
+	Data   []byte
+}
+
+func (*A) Type() Type         { return TypeA }
+func (*AAAA) Type() Type      { return TypeAAAA }
+func (*NS) Type() Type        { return TypeNS }
+func (*CNAME) Type() Type     { return TypeCNAME }
+func (*PTR) Type() Type       { return TypePTR }
+func (*MX) Type() Type        { return TypeMX }
+func (*TXT) Type() Type       { return TypeTXT }
+func (*SRV) Type() Type       { return TypeSRV }
+func (*SOA) Type() Type       { return TypeSOA }
+func (*OPT) Type() Type       { return TypeOPT }
+func (u *Unknown) Type() Type { return u.RRType }
+
+// rdataReader reads record data bounded to [start, end) of the message.
+type rdataReader struct {
+	m   *message
+	r   *wireread.SafeReader
+	end int
+}
+
+func (rd *rdataReader) offset() int {
+	return rd.end - len(rd.r.Bytes())
+}
+
+func (rd *rdataReader) name() (string, error) {
+	name, next, err := readName(rd.m.r, rd.offset())
+	if err != nil {
+		return "", err
+	}
+	if next > rd.end {
+		return "", ErrRDataLength
+	}
+	return name, rd.r.Skip(next - rd.offset())
+}
+
+func (rd *rdataReader) uint16() (uint16, error) {
+	v, err := rd.r.ReadUint16BE()
+	if err != nil {
+		return 0, ErrRDataLength
+	}
+	return v, nil
+}
+
+func (rd *rdataReader) uint32() (uint32, error) {
+	v, err := rd.r.ReadUint32BE()
+	if err != nil {
+		return 0, ErrRDataLength
+	}
+	return v, nil
+}
+
+// rdata decodes the record data of rr located at [start, end).
+func (m *message) rdata(rr *Resource, start, end int) (RData, error) {
+	rd := &rdataReader{m: m, r: wireread.NewSafeReader(m.data[start:end]), end: end}
+	data, err := rd.decode(rr)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", rr.Type, err)
+	}
+	if rd.offset() != end {
+		return nil, fmt.Errorf("%s: %w", rr.Type, ErrRDataLength)
+	}
+	return data, nil
+}
+
+func (rd *rdataReader) decode(rr *Resource) (RData, error) {
+	var err error
+	switch rr.Type {
+	case TypeA:
+		b, err := rd.r.ReadBytes(4)
+		if err != nil {
+			return nil, ErrRDataLength
+		}
+		return &A{Addr: netip.AddrFrom4([4]byte(b))}, nil
+
+	case TypeAAAA:
+		b, err := rd.r.ReadBytes(16)
+		if err != nil {
+			return nil, ErrRDataLength
+		}
+		return &AAAA{Addr: netip.AddrFrom16([16]byte(b))}, nil
+
+	case TypeNS:
+		ns := &NS{}
+		ns.Host, err = rd.name()
+		return ns, err
+
+	case TypeCNAME:
+		c := &CNAME{}
+		c.Target, err = rd.name()
+		return c, err
+
+	case TypePTR:
+		p := &PTR{}
+		p.Target, err = rd.name()
+		return p, err
+
+	case TypeMX:
+		mx := &MX{}
+		if mx.Preference, err = rd.uint16(); err != nil {
+			return nil, err
+		}
+		mx.Exchange, err = rd.name()
+		return mx, err
+
+	case TypeTXT:
+		txt := &TXT{}
+		for len(rd.r.Bytes()) > 0 {
+			n, _ := rd.r.ReadByte()
+			s, err := rd.r.ReadString(int(n))
+			if err != nil {
+				return nil, ErrRDataLength
+			}
+			txt.Strings = append(txt.Strings, s)
+		}
+		return txt, nil
+
+	case TypeSRV:
+		srv := &SRV{}
+		if srv.Priority, err = rd.uint16(); err != nil {
+			return nil, err
+		}
+		if srv.Weight, err = rd.uint16(); err != nil {
+			return nil, err
+		}
+		if srv.Port, err = rd.uint16(); err != nil {
+			return nil, err
+		}
+		srv.Target, err = rd.name()
+		return srv, err
+
+	case TypeSOA:
+		soa := &SOA{}
+		if soa.MName, err = rd.name(); err != nil {
+			return nil, err
+		}
+		if soa.RName, err = rd.name(); err != nil {
+			return nil, err
+		}
+		for _, v := range []*uint32{&soa.Serial, &soa.Refresh, &soa.Retry, &soa.Expire, &soa.Minimum} {
+			if *v, err = rd.uint32(); err != nil {
+				return nil, err
+			}
+		}
+		return soa, nil
+
+	case TypeOPT:
+		opt := &OPT{
+			UDPSize:       uint16(rr.Class),
+			ExtendedRcode: uint8(rr.TTL >> 24),
+			Version:       uint8(rr.TTL >> 16),
+			DNSSECOK:      rr.TTL&0x8000 != 0,
+		}
+		for len(rd.r.Bytes()) > 0 {
+			var o EDNSOption
+			if o.Code, err = rd.uint16(); err != nil {
+				return nil, err
+			}
+			n, err := rd.uint16()
+			if err != nil {
+				return nil, err
+			}
+			if o.Data, err = rd.r.ReadBytes(int(n)); err != nil {
+				return nil, ErrRDataLength
+			}
+			opt.Options = append(opt.Options, o)
+		}
+		return opt, nil
+	}
+
+	raw, _ := rd.r.ReadBytes(len(rd.r.Bytes()))
+	return &Unknown{RRType: rr.Type, Data: raw}, nil
+}
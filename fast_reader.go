@@ -3,6 +3,8 @@ package wireread
 import (
 	"bytes"
 	"encoding/binary"
+	"io"
+	"unicode/utf8"
 )
 
 // FastReader is a high-performance reader for complete, trusted data frames.
@@ -28,6 +30,13 @@ func NewFastReader(data []byte) *FastReader {
 	}
 }
 
+// Reset makes fr read from data, so that one FastReader can be reused for
+// many frames instead of allocating a new one for each.
+func (fr *FastReader) Reset(data []byte) {
+	fr.data = data
+	fr.rpos = 0
+}
+
 // Bytes returns the remaining unparsed bytes from the current read position
 func (fr *FastReader) Bytes() []byte {
 	return fr.data[fr.rpos:]
@@ -141,6 +150,26 @@ func (fr *FastReader) ReadLine() (string, error) {
 	return string(fr.data[begin:end]), nil
 }
 
+// ReadASCIIInt reads a signed base-10 integer terminated by \n (handles \r\n).
+// It returns the same results and errors as SafeReader.ReadASCIIInt, and leaves
+// the cursor untouched when the line is incomplete or malformed.
+func (fr *FastReader) ReadASCIIInt() (int64, error) {
+	idx := bytes.IndexByte(fr.data[fr.rpos:], '\n')
+	if idx < 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	line := fr.data[fr.rpos : fr.rpos+idx]
+	if idx > 0 && line[idx-1] == '\r' {
+		line = line[:idx-1]
+	}
+	val, ok := parseASCIIInt(line)
+	if !ok {
+		return 0, ErrInvalidASCIIInt
+	}
+	fr.rpos += idx + 1
+	return val, nil
+}
+
 // ReadUint16BE reads a 16-bit unsigned integer in big-endian byte order
 func (fr *FastReader) ReadUint16BE() (uint16, error) {
 	val := binary.BigEndian.Uint16(fr.data[fr.rpos:])
@@ -238,3 +267,121 @@ func (fr *FastReader) ReadUint64LEInto(out *uint64) error {
 	fr.rpos += 8
 	return nil
 }
+
+// ReadByteAt reads the byte at offset off without boundary checks or moving the read position
+func (fr *FastReader) ReadByteAt(off int) (byte, error) {
+	return fr.data[off], nil
+}
+
+// ReadBytesAt returns the n bytes at offset off without boundary checks or moving
+// the read position. Unlike ReadBytes, the result aliases the underlying data
+func (fr *FastReader) ReadBytesAt(off, n int) ([]byte, error) {
+	return fr.data[off : off+n : off+n], nil
+}
+
+// ReadUint16BEAt reads a 16-bit unsigned integer in big-endian byte order at offset off
+func (fr *FastReader) ReadUint16BEAt(off int) (uint16, error) {
+	return binary.BigEndian.Uint16(fr.data[off:]), nil
+}
+
+// ReadUint16LEAt reads a 16-bit unsigned integer in little-endian byte order at offset off
+func (fr *FastReader) ReadUint16LEAt(off int) (uint16, error) {
+	return binary.LittleEndian.Uint16(fr.data[off:]), nil
+}
+
+// ReadUint32BEAt reads a 32-bit unsigned integer in big-endian byte order at offset off
+func (fr *FastReader) ReadUint32BEAt(off int) (uint32, error) {
+	return binary.BigEndian.Uint32(fr.data[off:]), nil
+}
+
+// ReadUint32LEAt reads a 32-bit unsigned integer in little-endian byte order at offset off
+func (fr *FastReader) ReadUint32LEAt(off int) (uint32, error) {
+	return binary.LittleEndian.Uint32(fr.data[off:]), nil
+}
+
+// ReadUint64BEAt reads a 64-bit unsigned integer in big-endian byte order at offset off
+func (fr *FastReader) ReadUint64BEAt(off int) (uint64, error) {
+	return binary.BigEndian.Uint64(fr.data[off:]), nil
+}
+
+// ReadUint64LEAt reads a 64-bit unsigned integer in little-endian byte order at offset off
+func (fr *FastReader) ReadUint64LEAt(off int) (uint64, error) {
+	return binary.LittleEndian.Uint64(fr.data[off:]), nil
+}
+
+// Read implements io.Reader, copying from the current read position. It
+// returns io.EOF once the data is exhausted
+func (fr *FastReader) Read(p []byte) (int, error) {
+	if fr.rpos >= len(fr.data) {
+		if len(p) == 0 {
+			return 0, nil
+		}
+		return 0, io.EOF
+	}
+	n := copy(p, fr.data[fr.rpos:])
+	fr.rpos += n
+	return n, nil
+}
+
+// UnreadByte implements io.ByteScanner, moving the read position back one byte
+func (fr *FastReader) UnreadByte() error {
+	if fr.rpos <= 0 {
+		return ErrUnreadByte
+	}
+	fr.rpos--
+	return nil
+}
+
+// ReadRune implements io.RuneReader, decoding one UTF-8 encoded rune. Invalid
+// encodings are returned as utf8.RuneError of size 1
+func (fr *FastReader) ReadRune() (rune, int, error) {
+	if fr.rpos >= len(fr.data) {
+		return 0, 0, io.EOF
+	}
+	if c := fr.data[fr.rpos]; c < utf8.RuneSelf {
+		fr.rpos++
+		return rune(c), 1, nil
+	}
+	r, size := utf8.DecodeRune(fr.data[fr.rpos:])
+	fr.rpos += size
+	return r, size, nil
+}
+
+// Seek implements io.Seeker. Like Skip, it does not check the end of the data,
+// but seeking before the start returns ErrInvalidOffset
+func (fr *FastReader) Seek(offset int64, whence int) (int64, error) {
+	var abs int64
+	switch whence {
+	case io.SeekStart:
+		abs = offset
+	case io.SeekCurrent:
+		abs = int64(fr.rpos) + offset
+	case io.SeekEnd:
+		abs = int64(len(fr.data)) + offset
+	default:
+		return 0, ErrInvalidWhence
+	}
+	if abs < 0 {
+		return 0, ErrInvalidOffset
+	}
+	fr.rpos = int(abs)
+	return abs, nil
+}
+
+// WriteTo implements io.WriterTo, writing the remaining bytes to w and
+// advancing the read position past those written
+func (fr *FastReader) WriteTo(w io.Writer) (int64, error) {
+	b := fr.data[fr.rpos:]
+	if len(b) == 0 {
+		return 0, nil
+	}
+	n, err := w.Write(b)
+	if n < 0 || n > len(b) {
+		return 0, ErrInvalidWrite
+	}
+	fr.rpos += n
+	if err == nil && n != len(b) {
+		err = io.ErrShortWrite
+	}
+	return int64(n), err
+}
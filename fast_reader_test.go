@@ -1,6 +1,8 @@
 package wireread
 
 import (
+	"bytes"
+	"io"
 	"testing"
 )
 
@@ -183,6 +185,46 @@ func TestFastReader_ReadLine(t *testing.T) {
 	}
 }
 
+func TestFastReader_ReadASCIIInt(t *testing.T) {
+	r := NewFastReader([]byte("1234\r\n-5\nx"))
+
+	got, _ := r.ReadASCIIInt()
+	if got != 1234 {
+		t.Errorf("ReadASCIIInt() = %d, want 1234", got)
+	}
+
+	got, _ = r.ReadASCIIInt()
+	if got != -5 {
+		t.Errorf("ReadASCIIInt() = %d, want -5", got)
+	}
+
+	if b, _ := r.ReadByte(); b != 'x' {
+		t.Errorf("ReadByte() after ReadASCIIInt() = %q, want 'x'", b)
+	}
+}
+
+func TestFastReader_ReadASCIIIntMatchesSafeReader(t *testing.T) {
+	for _, in := range []string{
+		"42\r\n",
+		"-9223372036854775808\n",
+		"9223372036854775808\n", // overflow
+		"12x\n",                 // junk before the terminator
+		"+\n",
+		"\r\n",
+		"77", // no terminator
+	} {
+		sr, fr := NewSafeReader([]byte(in)), NewFastReader([]byte(in))
+		want, wantErr := sr.ReadASCIIInt()
+		got, err := fr.ReadASCIIInt()
+		if got != want || err != wantErr {
+			t.Errorf("ReadASCIIInt(%q) = %d, %v; SafeReader returns %d, %v", in, got, err, want, wantErr)
+		}
+		if len(fr.Bytes()) != len(sr.Bytes()) {
+			t.Errorf("ReadASCIIInt(%q) left %d bytes, SafeReader leaves %d", in, len(fr.Bytes()), len(sr.Bytes()))
+		}
+	}
+}
+
 func TestFastReader_ReadLengthEncodedInteger(t *testing.T) {
 	tests := []struct {
 		name string
@@ -291,12 +333,130 @@ func TestFastReader_StringInto(t *testing.T) {
 	}
 }
 
+func TestFastReader_ReadAtMethods(t *testing.T) {
+	data := []byte{0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}
+	r := NewFastReader(data)
+	_ = r.Skip(3)
+
+	if got, _ := r.ReadUint16BEAt(1); got != 0x0102 {
+		t.Errorf("ReadUint16BEAt(1) = 0x%04x, want 0x0102", got)
+	}
+	if got, _ := r.ReadUint16LEAt(1); got != 0x0201 {
+		t.Errorf("ReadUint16LEAt(1) = 0x%04x, want 0x0201", got)
+	}
+	if got, _ := r.ReadUint32BEAt(5); got != 0x05060708 {
+		t.Errorf("ReadUint32BEAt(5) = 0x%08x, want 0x05060708", got)
+	}
+	if got, _ := r.ReadUint32LEAt(0); got != 0x03020100 {
+		t.Errorf("ReadUint32LEAt(0) = 0x%08x, want 0x03020100", got)
+	}
+	if got, _ := r.ReadUint64BEAt(1); got != 0x0102030405060708 {
+		t.Errorf("ReadUint64BEAt(1) = 0x%016x, want 0x0102030405060708", got)
+	}
+	if got, _ := r.ReadUint64LEAt(0); got != 0x0706050403020100 {
+		t.Errorf("ReadUint64LEAt(0) = 0x%016x, want 0x0706050403020100", got)
+	}
+	if got, _ := r.ReadByteAt(1); got != 0x01 {
+		t.Errorf("ReadByteAt(1) = 0x%02x, want 0x01", got)
+	}
+	if got, _ := r.ReadBytesAt(7, 2); !bytesEqual(got, []byte{7, 8}) {
+		t.Errorf("ReadBytesAt(7, 2) = %v, want [7 8]", got)
+	}
+
+	if got, _ := r.ReadByte(); got != 0x03 {
+		t.Errorf("ReadByte() after random access = %v, want 3", got)
+	}
+}
+
+func TestFastReader_IOInterfaces(t *testing.T) {
+	data := []byte("\x00\x05héllo, world")
+	r := NewFastReader(data)
+
+	if n, _ := r.ReadUint16BE(); n != 5 {
+		t.Fatalf("ReadUint16BE() = %d, want 5", n)
+	}
+	if c, size, err := r.ReadRune(); err != nil || c != 'h' || size != 1 {
+		t.Errorf("ReadRune() = %q, %d, %v; want 'h', 1, nil", c, size, err)
+	}
+	if c, size, err := r.ReadRune(); err != nil || c != 'é' || size != 2 {
+		t.Errorf("ReadRune() = %q, %d, %v; want 'é', 2, nil", c, size, err)
+	}
+	p := make([]byte, 3)
+	if n, err := io.ReadFull(r, p); err != nil || string(p[:n]) != "llo" {
+		t.Errorf("ReadFull() = %q, %v; want \"llo\", nil", p[:n], err)
+	}
+	if err := r.UnreadByte(); err != nil {
+		t.Errorf("UnreadByte() error = %v", err)
+	}
+	if b, _ := r.ReadByte(); b != 'o' {
+		t.Errorf("ReadByte() after UnreadByte() = %q, want 'o'", b)
+	}
+
+	if pos, err := r.Seek(-5, io.SeekEnd); err != nil || pos != int64(len(data)-5) {
+		t.Errorf("Seek(-5, io.SeekEnd) = %d, %v; want %d, nil", pos, err, len(data)-5)
+	}
+	var buf bytes.Buffer
+	if n, err := r.WriteTo(&buf); err != nil || n != 5 || buf.String() != "world" {
+		t.Errorf("WriteTo() = %d, %v, wrote %q; want 5, nil, \"world\"", n, err, buf.String())
+	}
+	if n, err := r.Read(p); n != 0 || err != io.EOF {
+		t.Errorf("Read() at end = %d, %v; want 0, io.EOF", n, err)
+	}
+	if _, _, err := r.ReadRune(); err != io.EOF {
+		t.Errorf("ReadRune() at end error = %v, want io.EOF", err)
+	}
+
+	if pos, err := r.Seek(2, io.SeekStart); err != nil || pos != 2 {
+		t.Errorf("Seek(2, io.SeekStart) = %d, %v; want 2, nil", pos, err)
+	}
+	if pos, err := r.Seek(-2, io.SeekCurrent); err != nil || pos != 0 {
+		t.Errorf("Seek(-2, io.SeekCurrent) = %d, %v; want 0, nil", pos, err)
+	}
+	if err := r.UnreadByte(); err != ErrUnreadByte {
+		t.Errorf("UnreadByte() at start error = %v, want ErrUnreadByte", err)
+	}
+	if _, err := r.Seek(-1, io.SeekStart); err != ErrInvalidOffset {
+		t.Errorf("Seek(-1, io.SeekStart) error = %v, want ErrInvalidOffset", err)
+	}
+	if _, err := r.Seek(0, 3); err != ErrInvalidWhence {
+		t.Errorf("Seek(0, 3) error = %v, want ErrInvalidWhence", err)
+	}
+}
+
+func TestFastReader_Reset(t *testing.T) {
+	r := NewFastReader([]byte{0x01, 0x02, 0x03})
+	r.ReadUint16BE()
+
+	r.Reset([]byte{0x0A, 0x0B})
+	if got, _ := r.ReadUint16BE(); got != 0x0A0B {
+		t.Errorf("ReadUint16BE() after Reset() = 0x%04x, want 0x0a0b", got)
+	}
+	if len(r.Bytes()) != 0 {
+		t.Errorf("Bytes() after Reset() = %v, want empty", r.Bytes())
+	}
+
+	data := []byte{0x00, 0x01}
+	allocs := testing.AllocsPerRun(100, func() {
+		r.Reset(data)
+		r.ReadUint16BE()
+	})
+	if allocs != 0 {
+		t.Errorf("Reset() and read allocated %v times, want 0", allocs)
+	}
+}
+
 // Test that FastReader satisfies Reader interface
 func TestFastReader_ImplementsReader(t *testing.T) {
 	var _ Reader = (*FastReader)(nil)
+	var _ ASCIIIntReader = (*FastReader)(nil)
+	var _ RandomAccessReader = (*FastReader)(nil)
+	var _ StreamReader = (*FastReader)(nil)
 }
 
 // Test that SafeReader satisfies Reader interface
 func TestSafeReader_ImplementsReader(t *testing.T) {
 	var _ Reader = (*SafeReader)(nil)
+	var _ ASCIIIntReader = (*SafeReader)(nil)
+	var _ RandomAccessReader = (*SafeReader)(nil)
+	var _ StreamReader = (*SafeReader)(nil)
 }
@@ -0,0 +1,184 @@
+package http2
+
+import (
+	"errors"
+	"io"
+)
+
+// ErrNeedMore is returned by Decoder.Next when the buffered data does not
+// hold a complete frame.
+var ErrNeedMore = errors.New("http2: need more data")
+
+// DefaultMaxHeaderBlockSize is the default limit on a header block joined
+// from HEADERS or PUSH_PROMISE and CONTINUATION frames.
+const DefaultMaxHeaderBlockSize = 1 << 20
+
+// Decoder decodes the frames of one direction of a connection, which must
+// be fed without the client preface. HEADERS and PUSH_PROMISE frames are
+// held until their header block is complete and are then returned once,
+// with BlockFragment holding the whole block and Fields the decoded
+// headers; the CONTINUATION frames in between are consumed internally.
+type Decoder struct {
+	buf   []byte
+	off   int
+	hpack *HPACKDecoder
+
+	maxFrameSize uint32
+	maxBlockSize int
+
+	// pending is the HEADERS or PUSH_PROMISE frame awaiting CONTINUATION.
+	pending Frame
+	block   []byte
+}
+
+// NewDecoder creates a Decoder with the protocol's initial settings.
+func NewDecoder() *Decoder {
+	return &Decoder{
+		hpack:        NewHPACKDecoder(DefaultHeaderTableSize),
+		maxFrameSize: DefaultMaxFrameSize,
+		maxBlockSize: DefaultMaxHeaderBlockSize,
+	}
+}
+
+// HPACK returns the decoder's header decompression state, for example to
+// apply an acknowledged SETTINGS_HEADER_TABLE_SIZE.
+func (d *Decoder) HPACK() *HPACKDecoder {
+	return d.hpack
+}
+
+// SetMaxFrameSize sets the largest accepted frame payload, normally the
+// SETTINGS_MAX_FRAME_SIZE advertised to the peer.
+func (d *Decoder) SetMaxFrameSize(n uint32) {
+	d.maxFrameSize = n
+}
+
+// SetMaxHeaderBlockSize limits the size of a header block, guarding against
+// unbounded CONTINUATION sequences.
+func (d *Decoder) SetMaxHeaderBlockSize(n int) {
+	d.maxBlockSize = n
+}
+
+// Feed appends data to the decoder's buffer. The data is copied.
+func (d *Decoder) Feed(data []byte) {
+	if d.off > 0 && d.off == len(d.buf) {
+		d.buf = d.buf[:0]
+		d.off = 0
+	} else if d.off > cap(d.buf)/2 {
+		n := copy(d.buf, d.buf[d.off:])
+		d.buf = d.buf[:n]
+		d.off = 0
+	}
+	d.buf = append(d.buf, data...)
+}
+
+// Buffered returns the number of bytes fed but not yet consumed.
+func (d *Decoder) Buffered() int {
+	return len(d.buf) - d.off
+}
+
+// Next returns the next frame. It returns ErrNeedMore when more data must be
+// fed first. Byte slices in the returned frame alias the decoder's buffer and
+// are only valid until the next call to Feed. Any other error is a
+// connection error and leaves the decoder unusable.
+func (d *Decoder) Next() (Frame, error) {
+	for {
+		f, err := d.frame()
+		if err != nil {
+			return nil, err
+		}
+		if f, err = d.headers(f); f != nil || err != nil {
+			return f, err
+		}
+	}
+}
+
+// frame parses the next buffered frame, enforcing the frame size limit as
+// soon as the header is available.
+func (d *Decoder) frame() (Frame, error) {
+	data := d.buf[d.off:]
+	if len(data) >= FrameHeaderLen {
+		length := uint32(data[0])<<16 | uint32(data[1])<<8 | uint32(data[2])
+		if length > d.maxFrameSize {
+			return nil, frameError(ErrFrameSize, "length %d exceeds maximum %d", length, d.maxFrameSize)
+		}
+	}
+	f, n, err := ParseFrame(data)
+	if err == io.ErrUnexpectedEOF {
+		return nil, ErrNeedMore
+	}
+	if err != nil {
+		return nil, err
+	}
+	d.off += n
+	return f, nil
+}
+
+// headers joins header block fragments. It returns nil while a block is
+// still incomplete.
+func (d *Decoder) headers(f Frame) (Frame, error) {
+	h := f.Header()
+	if d.pending != nil {
+		c, ok := f.(*ContinuationFrame)
+		if !ok || h.StreamID != d.pending.Header().StreamID {
+			return nil, frameError(ErrProtocol, "%s frame on stream %d interrupts header block", h.Type, h.StreamID)
+		}
+		if err := d.appendBlock(c.BlockFragment); err != nil {
+			return nil, err
+		}
+		if !h.Flags.Has(FlagEndHeaders) {
+			return nil, nil
+		}
+		// Hand the joined block over to the frame.
+		f, block := d.pending, d.block
+		d.pending, d.block = nil, nil
+		return f, d.decodeBlock(f, block)
+	}
+
+	var fragment []byte
+	switch f := f.(type) {
+	case *HeadersFrame:
+		fragment = f.BlockFragment
+	case *PushPromiseFrame:
+		fragment = f.BlockFragment
+	case *ContinuationFrame:
+		return nil, frameError(ErrProtocol, "CONTINUATION frame on stream %d without header block", h.StreamID)
+	default:
+		return f, nil
+	}
+	if h.Flags.Has(FlagEndHeaders) {
+		return f, d.decodeBlock(f, fragment)
+	}
+
+	// The fragment aliases the buffer, which Feed may overwrite before the
+	// block is complete.
+	if err := d.appendBlock(fragment); err != nil {
+		return nil, err
+	}
+	d.pending = f
+	return nil, nil
+}
+
+func (d *Decoder) appendBlock(fragment []byte) error {
+	if len(d.block)+len(fragment) > d.maxBlockSize {
+		return frameError(ErrProtocol, "header block exceeds %d bytes", d.maxBlockSize)
+	}
+	d.block = append(d.block, fragment...)
+	return nil
+}
+
+// decodeBlock decodes a complete header block into f. The fields are copied
+// so that they survive the next block.
+func (d *Decoder) decodeBlock(f Frame, block []byte) error {
+	fields, err := d.hpack.Decode(block)
+	if err != nil {
+		return err
+	}
+	fields = append([]HeaderField(nil), fields...)
+	switch f := f.(type) {
+	case *HeadersFrame:
+		f.BlockFragment, f.Fields = block, fields
+	case *PushPromiseFrame:
+		f.BlockFragment, f.Fields = block, fields
+	}
+	return nil
+}
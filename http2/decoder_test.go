@@ -0,0 +1,125 @@
+package http2
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDecoder_Continuation(t *testing.T) {
+	// The C.4.1 request block split across HEADERS and two CONTINUATIONs,
+	// with a SETTINGS frame before and a DATA frame after.
+	block := mustHex(t, "828684418cf1e3c2e5f23a6ba0ab90f4ff")
+	var stream []byte
+	stream = append(stream, frame(FrameSettings, 0, 0)...)
+	stream = append(stream, frame(FrameHeaders, 0, 1, block[:3]...)...)
+	stream = append(stream, frame(FrameContinuation, 0, 1, block[3:10]...)...)
+	stream = append(stream, frame(FrameContinuation, FlagEndHeaders, 1, block[10:]...)...)
+	stream = append(stream, frame(FrameData, FlagEndStream, 1, 'o', 'k')...)
+
+	// Feeding one byte at a time also checks that held fragments survive
+	// buffer compaction.
+	d := NewDecoder()
+	var frames []Frame
+	for _, b := range stream {
+		d.Feed([]byte{b})
+		for {
+			f, err := d.Next()
+			if err == ErrNeedMore {
+				break
+			}
+			if err != nil {
+				t.Fatalf("Next() error = %v", err)
+			}
+			frames = append(frames, f)
+		}
+	}
+
+	if len(frames) != 3 {
+		t.Fatalf("got %d frames, want 3", len(frames))
+	}
+	h, ok := frames[1].(*HeadersFrame)
+	if !ok {
+		t.Fatalf("frame 1 = %T, want *HeadersFrame", frames[1])
+	}
+	if string(h.BlockFragment) != string(block) {
+		t.Errorf("BlockFragment = %x, want %x", h.BlockFragment, block)
+	}
+	checkFields(t, h.Fields, [][2]string{{":method", "GET"}, {":scheme", "http"}, {":path", "/"}, {":authority", "www.example.com"}})
+	if d, ok := frames[2].(*DataFrame); !ok || string(d.Data) != "ok" {
+		t.Errorf("frame 2 = %+v", frames[2])
+	}
+	if d.Buffered() != 0 {
+		t.Errorf("Buffered() = %d, want 0", d.Buffered())
+	}
+}
+
+func TestDecoder_StateAcrossBlocks(t *testing.T) {
+	d := NewDecoder()
+	d.Feed(frame(FrameHeaders, FlagEndHeaders, 1, mustHex(t, "828684410f7777772e6578616d706c652e636f6d")...))
+	d.Feed(frame(FrameHeaders, FlagEndHeaders, 3, mustHex(t, "828684be58086e6f2d6361636865")...))
+
+	if _, err := d.Next(); err != nil {
+		t.Fatal(err)
+	}
+	f, err := d.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := f.(*HeadersFrame)
+	if len(h.Fields) != 5 || h.Fields[3].Value != "www.example.com" {
+		t.Errorf("Fields = %v", h.Fields)
+	}
+	if d.HPACK().DynamicTableLen() != 2 {
+		t.Errorf("DynamicTableLen() = %d, want 2", d.HPACK().DynamicTableLen())
+	}
+}
+
+func TestDecoder_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(d *Decoder)
+		data  [][]byte
+		want  error
+	}{
+		{"interleaved frame", nil, [][]byte{
+			frame(FrameHeaders, 0, 1, 0x82),
+			frame(FrameData, 0, 1),
+		}, ErrProtocol},
+		{"continuation on other stream", nil, [][]byte{
+			frame(FrameHeaders, 0, 1, 0x82),
+			frame(FrameContinuation, FlagEndHeaders, 3, 0x84),
+		}, ErrProtocol},
+		{"orphan continuation", nil, [][]byte{
+			frame(FrameContinuation, FlagEndHeaders, 1, 0x84),
+		}, ErrProtocol},
+		{"frame too large", func(d *Decoder) { d.SetMaxFrameSize(1) }, [][]byte{
+			frame(FrameData, 0, 1, 1, 2),
+		}, ErrFrameSize},
+		{"header block too large", func(d *Decoder) { d.SetMaxHeaderBlockSize(2) }, [][]byte{
+			frame(FrameHeaders, 0, 1, 0x82, 0x84),
+			frame(FrameContinuation, 0, 1, 0x86),
+		}, ErrProtocol},
+		{"bad header block", nil, [][]byte{
+			frame(FrameHeaders, FlagEndHeaders, 1, 0x80),
+		}, ErrCompression},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDecoder()
+			if tt.setup != nil {
+				tt.setup(d)
+			}
+			for _, b := range tt.data {
+				d.Feed(b)
+			}
+			var err error
+			for err == nil {
+				_, err = d.Next()
+			}
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Next() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
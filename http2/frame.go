@@ -0,0 +1,545 @@
+// Package http2 parses HTTP/2 frames (RFC 9113) and decodes HPACK header
+// blocks (RFC 7541).
+//
+// ParseFrame decodes a single frame from a buffer without any connection
+// state. Decoder adds the state needed to follow a connection direction: it
+// joins HEADERS or PUSH_PROMISE frames with their CONTINUATION frames and
+// decodes the complete block with an HPACKDecoder whose dynamic table is
+// kept across blocks.
+//
+// Example usage:
+//
+//	d := http2.NewDecoder()
+//	d.Feed(data[len(http2.ClientPreface):])
+//	for {
+//	    f, err := d.Next()
+//	    if err == http2.ErrNeedMore {
+//	        break
+//	    }
+//	    if err != nil {
+//	        log.Fatal(err)
+//	    }
+//	    if h, ok := f.(*http2.HeadersFrame); ok {
+//	        fmt.Println(h.StreamID, h.Fields)
+//	    }
+//	}
+package http2
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/nemohan/wireread"
+)
+
+// ClientPreface is the connection preface sent by clients before the first
+// frame.
+const ClientPreface = "PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n"
+
+// FrameHeaderLen is the size of the fixed frame header.
+const FrameHeaderLen = 9
+
+const (
+	// DefaultMaxFrameSize is the initial SETTINGS_MAX_FRAME_SIZE.
+	DefaultMaxFrameSize = 1 << 14
+	// MaxFrameSize is the largest frame size a peer may advertise.
+	MaxFrameSize = 1<<24 - 1
+)
+
+var (
+	// ErrFrameSize is returned for frames whose length is invalid for their
+	// type or exceeds the negotiated maximum.
+	ErrFrameSize = errors.New("http2: frame size error")
+
+	// ErrProtocol is returned for frames that violate the protocol, such as
+	// a stream identifier not allowed for the frame type or padding longer
+	// than the payload.
+	ErrProtocol = errors.New("http2: protocol error")
+)
+
+// FrameType is the type of a frame.
+type FrameType uint8
+
+// Frame types defined by RFC 9113.
+const (
+	FrameData         FrameType = 0x0
+	FrameHeaders      FrameType = 0x1
+	FramePriority     FrameType = 0x2
+	FrameRSTStream    FrameType = 0x3
+	FrameSettings     FrameType = 0x4
+	FramePushPromise  FrameType = 0x5
+	FramePing         FrameType = 0x6
+	FrameGoAway       FrameType = 0x7
+	FrameWindowUpdate FrameType = 0x8
+	FrameContinuation FrameType = 0x9
+)
+
+var frameTypeNames = [...]string{
+	FrameData:         "DATA",
+	FrameHeaders:      "HEADERS",
+	FramePriority:     "PRIORITY",
+	FrameRSTStream:    "RST_STREAM",
+	FrameSettings:     "SETTINGS",
+	FramePushPromise:  "PUSH_PROMISE",
+	FramePing:         "PING",
+	FrameGoAway:       "GOAWAY",
+	FrameWindowUpdate: "WINDOW_UPDATE",
+	FrameContinuation: "CONTINUATION",
+}
+
+func (t FrameType) String() string {
+	if int(t) < len(frameTypeNames) {
+		return frameTypeNames[t]
+	}
+	return fmt.Sprintf("UNKNOWN_FRAME_TYPE_%d", uint8(t))
+}
+
+// Flags holds the frame flags. Their meaning depends on the frame type.
+type Flags uint8
+
+// Frame flags.
+const (
+	FlagEndStream  Flags = 0x1  // DATA, HEADERS
+	FlagAck        Flags = 0x1  // SETTINGS, PING
+	FlagEndHeaders Flags = 0x4  // HEADERS, PUSH_PROMISE, CONTINUATION
+	FlagPadded     Flags = 0x8  // DATA, HEADERS, PUSH_PROMISE
+	FlagPriority   Flags = 0x20 // HEADERS
+)
+
+// Has reports whether all bits of v are set.
+func (f Flags) Has(v Flags) bool {
+	return f&v == v
+}
+
+// ErrCode is an error code carried by RST_STREAM and GOAWAY frames.
+type ErrCode uint32
+
+// Error codes defined by RFC 9113.
+const (
+	ErrCodeNo                 ErrCode = 0x0
+	ErrCodeProtocol           ErrCode = 0x1
+	ErrCodeInternal           ErrCode = 0x2
+	ErrCodeFlowControl        ErrCode = 0x3
+	ErrCodeSettingsTimeout    ErrCode = 0x4
+	ErrCodeStreamClosed       ErrCode = 0x5
+	ErrCodeFrameSize          ErrCode = 0x6
+	ErrCodeRefusedStream      ErrCode = 0x7
+	ErrCodeCancel             ErrCode = 0x8
+	ErrCodeCompression        ErrCode = 0x9
+	ErrCodeConnect            ErrCode = 0xa
+	ErrCodeEnhanceYourCalm    ErrCode = 0xb
+	ErrCodeInadequateSecurity ErrCode = 0xc
+	ErrCodeHTTP11Required     ErrCode = 0xd
+)
+
+var errCodeNames = [...]string{
+	ErrCodeNo:                 "NO_ERROR",
+	ErrCodeProtocol:           "PROTOCOL_ERROR",
+	ErrCodeInternal:           "INTERNAL_ERROR",
+	ErrCodeFlowControl:        "FLOW_CONTROL_ERROR",
+	ErrCodeSettingsTimeout:    "SETTINGS_TIMEOUT",
+	ErrCodeStreamClosed:       "STREAM_CLOSED",
+	ErrCodeFrameSize:          "FRAME_SIZE_ERROR",
+	ErrCodeRefusedStream:      "REFUSED_STREAM",
+	ErrCodeCancel:             "CANCEL",
+	ErrCodeCompression:        "COMPRESSION_ERROR",
+	ErrCodeConnect:            "CONNECT_ERROR",
+	ErrCodeEnhanceYourCalm:    "ENHANCE_YOUR_CALM",
+	ErrCodeInadequateSecurity: "INADEQUATE_SECURITY",
+	ErrCodeHTTP11Required:     "HTTP_1_1_REQUIRED",
+}
+
+func (c ErrCode) String() string {
+	if int(c) < len(errCodeNames) {
+		return errCodeNames[c]
+	}
+	return fmt.Sprintf("UNKNOWN_ERROR_0x%x", uint32(c))
+}
+
+// SettingID identifies a parameter of a SETTINGS frame.
+type SettingID uint16
+
+// Settings defined by RFC 9113 and RFC 8441.
+const (
+	SettingHeaderTableSize       SettingID = 0x1
+	SettingEnablePush            SettingID = 0x2
+	SettingMaxConcurrentStreams  SettingID = 0x3
+	SettingInitialWindowSize     SettingID = 0x4
+	SettingMaxFrameSize          SettingID = 0x5
+	SettingMaxHeaderListSize     SettingID = 0x6
+	SettingEnableConnectProtocol SettingID = 0x8
+)
+
+var settingNames = map[SettingID]string{
+	SettingHeaderTableSize:       "HEADER_TABLE_SIZE",
+	SettingEnablePush:            "ENABLE_PUSH",
+	SettingMaxConcurrentStreams:  "MAX_CONCURRENT_STREAMS",
+	SettingInitialWindowSize:     "INITIAL_WINDOW_SIZE",
+	SettingMaxFrameSize:          "MAX_FRAME_SIZE",
+	SettingMaxHeaderListSize:     "MAX_HEADER_LIST_SIZE",
+	SettingEnableConnectProtocol: "ENABLE_CONNECT_PROTOCOL",
+}
+
+func (s SettingID) String() string {
+	if name, ok := settingNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("UNKNOWN_SETTING_%d", uint16(s))
+}
+
+// FrameHeader is the fixed 9-byte header preceding every frame.
+type FrameHeader struct {
+	Length   uint32 // payload length, 24 bits
+	Type     FrameType
+	Flags    Flags
+	StreamID uint32 // 31 bits; the reserved bit is cleared
+}
+
+// Header returns h; it lets every frame type satisfy Frame by embedding.
+func (h FrameHeader) Header() FrameHeader {
+	return h
+}
+
+// ReadFrameHeader reads a frame header from r.
+func ReadFrameHeader(r wireread.Reader) (FrameHeader, error) {
+	b, err := r.ReadBytes(FrameHeaderLen)
+	if err != nil {
+		return FrameHeader{}, err
+	}
+	return FrameHeader{
+		Length:   uint32(b[0])<<16 | uint32(b[1])<<8 | uint32(b[2]),
+		Type:     FrameType(b[3]),
+		Flags:    Flags(b[4]),
+		StreamID: (uint32(b[5])<<24 | uint32(b[6])<<16 | uint32(b[7])<<8 | uint32(b[8])) & 0x7FFFFFFF,
+	}, nil
+}
+
+// Frame is a parsed frame. The concrete types are *DataFrame,
+// *HeadersFrame, *PriorityFrame, *RSTStreamFrame, *SettingsFrame,
+// *PushPromiseFrame, *PingFrame, *GoAwayFrame, *WindowUpdateFrame,
+// *ContinuationFrame and *UnknownFrame.
+type Frame interface {
+	Header() FrameHeader
+}
+
+// PriorityParam is the deprecated stream priority signal of HEADERS and
+// PRIORITY frames.
+type PriorityParam struct {
+	StreamDep uint32
+	Exclusive bool
+	// Weight is the wire value; the effective weight is Weight+1.
+	Weight uint8
+}
+
+// DataFrame carries stream data with any padding removed.
+type DataFrame struct {
+	FrameHeader
+	Data []byte
+}
+
+// HeadersFrame opens a stream and carries a header block fragment. Fields
+// is only set by Decoder, which also replaces BlockFragment with the
+// complete block including any CONTINUATION fragments.
+type HeadersFrame struct {
+	FrameHeader
+	Priority      *PriorityParam
+	BlockFragment []byte
+	Fields        []HeaderField
+}
+
+// PriorityFrame carries a priority signal for a stream.
+type PriorityFrame struct {
+	FrameHeader
+	PriorityParam
+}
+
+// RSTStreamFrame terminates a stream.
+type RSTStreamFrame struct {
+	FrameHeader
+	ErrCode ErrCode
+}
+
+// Setting is a single SETTINGS parameter.
+type Setting struct {
+	ID    SettingID
+	Value uint32
+}
+
+// SettingsFrame conveys configuration parameters or acknowledges them.
+type SettingsFrame struct {
+	FrameHeader
+	Settings []Setting
+}
+
+// Value returns the last value of the setting id in the frame.
+func (f *SettingsFrame) Value(id SettingID) (uint32, bool) {
+	for i := len(f.Settings) - 1; i >= 0; i-- {
+		if f.Settings[i].ID == id {
+			return f.Settings[i].Value, true
+		}
+	}
+	return 0, false
+}
+
+// PushPromiseFrame announces a server-initiated stream. Fields is only set
+// by Decoder.
+type PushPromiseFrame struct {
+	FrameHeader
+	PromisedStreamID uint32
+	BlockFragment    []byte
+	Fields           []HeaderField
+}
+
+// PingFrame is a PING or its acknowledgement.
+type PingFrame struct {
+	FrameHeader
+	Data [8]byte
+}
+
+// GoAwayFrame initiates connection shutdown.
+type GoAwayFrame struct {
+	FrameHeader
+	LastStreamID uint32
+	ErrCode      ErrCode
+	DebugData    []byte
+}
+
+// WindowUpdateFrame increments a flow-control window.
+type WindowUpdateFrame struct {
+	FrameHeader
+	Increment uint32
+}
+
+// ContinuationFrame carries the remainder of a header block.
+type ContinuationFrame struct {
+	FrameHeader
+	BlockFragment []byte
+}
+
+// UnknownFrame is a frame of an extension type, which receivers ignore.
+type UnknownFrame struct {
+	FrameHeader
+	Payload []byte
+}
+
+// ParseFrame parses the frame at the start of data and returns it with the
+// number of bytes consumed. It returns io.ErrUnexpectedEOF when data holds
+// less than a complete frame. Byte slices in the result alias data.
+func ParseFrame(data []byte) (Frame, int, error) {
+	r := wireread.NewSafeReader(data)
+	h, err := ReadFrameHeader(r)
+	if err != nil {
+		return nil, 0, err
+	}
+	if int(h.Length) > len(r.Bytes()) {
+		return nil, 0, io.ErrUnexpectedEOF
+	}
+	f, err := parsePayload(h, r.Bytes()[:h.Length:h.Length])
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s frame on stream %d: %w", h.Type, h.StreamID, err)
+	}
+	return f, FrameHeaderLen + int(h.Length), nil
+}
+
+func frameError(err error, format string, args ...any) error {
+	return fmt.Errorf("%w: %s", err, fmt.Sprintf(format, args...))
+}
+
+// streamRule is whether a frame type requires a stream (1), requires stream
+// zero (-1), or allows either (0).
+var streamRule = [...]int8{
+	FrameData:         1,
+	FrameHeaders:      1,
+	FramePriority:     1,
+	FrameRSTStream:    1,
+	FrameSettings:     -1,
+	FramePushPromise:  1,
+	FramePing:         -1,
+	FrameGoAway:       -1,
+	FrameWindowUpdate: 0,
+	FrameContinuation: 1,
+}
+
+func parsePayload(h FrameHeader, payload []byte) (Frame, error) {
+	if int(h.Type) >= len(streamRule) {
+		return &UnknownFrame{FrameHeader: h, Payload: payload}, nil
+	}
+	switch rule := streamRule[h.Type]; {
+	case rule > 0 && h.StreamID == 0:
+		return nil, frameError(ErrProtocol, "stream identifier is zero")
+	case rule < 0 && h.StreamID != 0:
+		return nil, frameError(ErrProtocol, "stream identifier is not zero")
+	}
+
+	r := wireread.NewSafeReader(payload)
+	switch h.Type {
+	case FrameData:
+		data, err := unpad(h, r)
+		if err != nil {
+			return nil, err
+		}
+		return &DataFrame{FrameHeader: h, Data: data}, nil
+
+	case FrameHeaders:
+		f := &HeadersFrame{FrameHeader: h}
+		padLen, err := padLength(h, r)
+		if err != nil {
+			return nil, err
+		}
+		if h.Flags.Has(FlagPriority) {
+			p, err := readPriority(r)
+			if err != nil {
+				return nil, frameError(ErrFrameSize, "priority fields truncated")
+			}
+			if p.StreamDep == h.StreamID {
+				return nil, frameError(ErrProtocol, "stream depends on itself")
+			}
+			f.Priority = &p
+		}
+		if f.BlockFragment, err = stripPadding(r, padLen); err != nil {
+			return nil, err
+		}
+		return f, nil
+
+	case FramePriority:
+		if h.Length != 5 {
+			return nil, frameError(ErrFrameSize, "length %d, want 5", h.Length)
+		}
+		p, _ := readPriority(r)
+		return &PriorityFrame{FrameHeader: h, PriorityParam: p}, nil
+
+	case FrameRSTStream:
+		if h.Length != 4 {
+			return nil, frameError(ErrFrameSize, "length %d, want 4", h.Length)
+		}
+		code, _ := r.ReadUint32BE()
+		return &RSTStreamFrame{FrameHeader: h, ErrCode: ErrCode(code)}, nil
+
+	case FrameSettings:
+		return parseSettings(h, r)
+
+	case FramePushPromise:
+		padLen, err := padLength(h, r)
+		if err != nil {
+			return nil, err
+		}
+		id, err := r.ReadUint32BE()
+		if err != nil {
+			return nil, frameError(ErrFrameSize, "promised stream identifier truncated")
+		}
+		f := &PushPromiseFrame{FrameHeader: h, PromisedStreamID: id & 0x7FFFFFFF}
+		if f.BlockFragment, err = stripPadding(r, padLen); err != nil {
+			return nil, err
+		}
+		return f, nil
+
+	case FramePing:
+		if h.Length != 8 {
+			return nil, frameError(ErrFrameSize, "length %d, want 8", h.Length)
+		}
+		f := &PingFrame{FrameHeader: h}
+		copy(f.Data[:], payload)
+		return f, nil
+
+	case FrameGoAway:
+		if h.Length < 8 {
+			return nil, frameError(ErrFrameSize, "length %d, want at least 8", h.Length)
+		}
+		last, _ := r.ReadUint32BE()
+		code, _ := r.ReadUint32BE()
+		return &GoAwayFrame{
+			FrameHeader:  h,
+			LastStreamID: last & 0x7FFFFFFF,
+			ErrCode:      ErrCode(code),
+			DebugData:    r.Bytes(),
+		}, nil
+
+	case FrameWindowUpdate:
+		if h.Length != 4 {
+			return nil, frameError(ErrFrameSize, "length %d, want 4", h.Length)
+		}
+		inc, _ := r.ReadUint32BE()
+		inc &= 0x7FFFFFFF
+		if inc == 0 {
+			return nil, frameError(ErrProtocol, "zero window increment")
+		}
+		return &WindowUpdateFrame{FrameHeader: h, Increment: inc}, nil
+
+	default: // FrameContinuation
+		return &ContinuationFrame{FrameHeader: h, BlockFragment: payload}, nil
+	}
+}
+
+// padLength reads the Pad Length field when the PADDED flag is set.
+func padLength(h FrameHeader, r *wireread.SafeReader) (int, error) {
+	if !h.Flags.Has(FlagPadded) {
+		return 0, nil
+	}
+	n, err := r.ReadByte()
+	if err != nil {
+		return 0, frameError(ErrFrameSize, "pad length missing")
+	}
+	return int(n), nil
+}
+
+// stripPadding returns the rest of the payload without its padding.
+func stripPadding(r *wireread.SafeReader, padLen int) ([]byte, error) {
+	rest := r.Bytes()
+	if padLen > len(rest) {
+		return nil, frameError(ErrProtocol, "padding %d exceeds payload", padLen)
+	}
+	n := len(rest) - padLen
+	return rest[:n:n], nil
+}
+
+func unpad(h FrameHeader, r *wireread.SafeReader) ([]byte, error) {
+	padLen, err := padLength(h, r)
+	if err != nil {
+		return nil, err
+	}
+	return stripPadding(r, padLen)
+}
+
+func readPriority(r *wireread.SafeReader) (PriorityParam, error) {
+	dep, err := r.ReadUint32BE()
+	if err != nil {
+		return PriorityParam{}, err
+	}
+	w, err := r.ReadByte()
+	if err != nil {
+		return PriorityParam{}, err
+	}
+	return PriorityParam{StreamDep: dep & 0x7FFFFFFF, Exclusive: dep&0x80000000 != 0, Weight: w}, nil
+}
+
+func parseSettings(h FrameHeader, r *wireread.SafeReader) (Frame, error) {
+	if h.Flags.Has(FlagAck) && h.Length != 0 {
+		return nil, frameError(ErrFrameSize, "acknowledgement with payload")
+	}
+	if h.Length%6 != 0 {
+		return nil, frameError(ErrFrameSize, "length %d is not a multiple of 6", h.Length)
+	}
+	f := &SettingsFrame{FrameHeader: h}
+	if h.Length > 0 {
+		f.Settings = make([]Setting, 0, h.Length/6)
+	}
+	for len(r.Bytes()) > 0 {
+		id, _ := r.ReadUint16BE()
+		v, _ := r.ReadUint32BE()
+		s := Setting{ID: SettingID(id), Value: v}
+		switch {
+		case s.ID == SettingEnablePush && v > 1,
+			s.ID == SettingEnableConnectProtocol && v > 1:
+			return nil, frameError(ErrProtocol, "%s = %d", s.ID, v)
+		case s.ID == SettingInitialWindowSize && v > 1<<31-1:
+			// A FLOW_CONTROL_ERROR on the wire; reported as a protocol
+			// violation here.
+			return nil, frameError(ErrProtocol, "%s = %d", s.ID, v)
+		case s.ID == SettingMaxFrameSize && (v < DefaultMaxFrameSize || v > MaxFrameSize):
+			return nil, frameError(ErrProtocol, "%s = %d", s.ID, v)
+		}
+		f.Settings = append(f.Settings, s)
+	}
+	return f, nil
+}
@@ -0,0 +1,183 @@
+package http2
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/nemohan/wireread"
+)
+
+// frame builds a raw frame.
+func frame(t FrameType, flags Flags, stream uint32, payload ...byte) []byte {
+	n := len(payload)
+	b := []byte{byte(n >> 16), byte(n >> 8), byte(n), byte(t), byte(flags),
+		byte(stream >> 24), byte(stream >> 16), byte(stream >> 8), byte(stream)}
+	return append(b, payload...)
+}
+
+func TestReadFrameHeader(t *testing.T) {
+	r := wireread.NewSafeReader([]byte{0x01, 0x02, 0x03, 0x01, 0x25, 0x80, 0x00, 0x00, 0x05})
+	h, err := ReadFrameHeader(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := FrameHeader{Length: 0x010203, Type: FrameHeaders, Flags: FlagEndStream | FlagEndHeaders | FlagPriority, StreamID: 5}
+	if h != want {
+		t.Errorf("ReadFrameHeader() = %+v, want %+v", h, want)
+	}
+	if _, err := ReadFrameHeader(wireread.NewSafeReader(make([]byte, 8))); err != io.ErrUnexpectedEOF {
+		t.Errorf("ReadFrameHeader(short) error = %v, want io.ErrUnexpectedEOF", err)
+	}
+}
+
+func TestParseFrame(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  []byte
+		check func(t *testing.T, f Frame)
+	}{
+		{"data padded", frame(FrameData, FlagPadded|FlagEndStream, 1, 2, 'h', 'i', 0, 0), func(t *testing.T, f Frame) {
+			d := f.(*DataFrame)
+			if string(d.Data) != "hi" || !d.Flags.Has(FlagEndStream) {
+				t.Errorf("DataFrame = %+v", d)
+			}
+		}},
+		{"headers priority", frame(FrameHeaders, FlagPriority|FlagEndHeaders, 3, 0x80, 0, 0, 1, 15, 0x82), func(t *testing.T, f Frame) {
+			h := f.(*HeadersFrame)
+			if h.Priority == nil || !h.Priority.Exclusive || h.Priority.StreamDep != 1 || h.Priority.Weight != 15 || !bytes.Equal(h.BlockFragment, []byte{0x82}) {
+				t.Errorf("HeadersFrame = %+v, %+v", h, h.Priority)
+			}
+		}},
+		{"priority", frame(FramePriority, 0, 3, 0, 0, 0, 1, 7), func(t *testing.T, f Frame) {
+			if p := f.(*PriorityFrame); p.StreamDep != 1 || p.Weight != 7 {
+				t.Errorf("PriorityFrame = %+v", p)
+			}
+		}},
+		{"rst_stream", frame(FrameRSTStream, 0, 3, 0, 0, 0, 8), func(t *testing.T, f Frame) {
+			if c := f.(*RSTStreamFrame).ErrCode; c != ErrCodeCancel {
+				t.Errorf("ErrCode = %v, want CANCEL", c)
+			}
+		}},
+		{"settings", frame(FrameSettings, 0, 0, 0, 1, 0, 0, 0x10, 0, 0, 5, 0, 0, 0x40, 0), func(t *testing.T, f Frame) {
+			s := f.(*SettingsFrame)
+			if v, ok := s.Value(SettingMaxFrameSize); !ok || v != 1<<14 {
+				t.Errorf("MAX_FRAME_SIZE = %d, %v", v, ok)
+			}
+			if v, ok := s.Value(SettingHeaderTableSize); !ok || v != 4096 {
+				t.Errorf("HEADER_TABLE_SIZE = %d, %v", v, ok)
+			}
+		}},
+		{"settings ack", frame(FrameSettings, FlagAck, 0), func(t *testing.T, f Frame) {
+			if s := f.(*SettingsFrame); !s.Flags.Has(FlagAck) || len(s.Settings) != 0 {
+				t.Errorf("SettingsFrame = %+v", s)
+			}
+		}},
+		{"push_promise", frame(FramePushPromise, FlagEndHeaders|FlagPadded, 1, 1, 0x80, 0, 0, 2, 0x82, 0), func(t *testing.T, f Frame) {
+			p := f.(*PushPromiseFrame)
+			if p.PromisedStreamID != 2 || !bytes.Equal(p.BlockFragment, []byte{0x82}) {
+				t.Errorf("PushPromiseFrame = %+v", p)
+			}
+		}},
+		{"ping", frame(FramePing, FlagAck, 0, 1, 2, 3, 4, 5, 6, 7, 8), func(t *testing.T, f Frame) {
+			if p := f.(*PingFrame); p.Data != [8]byte{1, 2, 3, 4, 5, 6, 7, 8} {
+				t.Errorf("PingFrame = %+v", p)
+			}
+		}},
+		{"goaway", frame(FrameGoAway, 0, 0, 0, 0, 0, 9, 0, 0, 0, 0xb, 'x'), func(t *testing.T, f Frame) {
+			g := f.(*GoAwayFrame)
+			if g.LastStreamID != 9 || g.ErrCode != ErrCodeEnhanceYourCalm || string(g.DebugData) != "x" {
+				t.Errorf("GoAwayFrame = %+v", g)
+			}
+		}},
+		{"window_update", frame(FrameWindowUpdate, 0, 0, 0x80, 0, 0x10, 0), func(t *testing.T, f Frame) {
+			if w := f.(*WindowUpdateFrame); w.Increment != 0x1000 {
+				t.Errorf("Increment = %d, want 4096", w.Increment)
+			}
+		}},
+		{"continuation", frame(FrameContinuation, FlagEndHeaders, 1, 0x84), func(t *testing.T, f Frame) {
+			if c := f.(*ContinuationFrame); !bytes.Equal(c.BlockFragment, []byte{0x84}) {
+				t.Errorf("ContinuationFrame = %+v", c)
+			}
+		}},
+		{"unknown", frame(0xfa, 0, 0, 1, 2), func(t *testing.T, f Frame) {
+			if u := f.(*UnknownFrame); u.Type.String() != "UNKNOWN_FRAME_TYPE_250" || len(u.Payload) != 2 {
+				t.Errorf("UnknownFrame = %+v", u)
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := append(tt.data, 0xff) // trailing byte of the next frame
+			f, n, err := ParseFrame(data)
+			if err != nil {
+				t.Fatalf("ParseFrame() error = %v", err)
+			}
+			if n != len(tt.data) {
+				t.Errorf("ParseFrame() consumed %d bytes, want %d", n, len(tt.data))
+			}
+			tt.check(t, f)
+		})
+	}
+}
+
+func TestParseFrame_AppendDoesNotClobber(t *testing.T) {
+	data := frame(FrameData, 0, 1, 'a', 'b')
+	data = append(data, frame(FrameData, FlagPadded, 3, 1, 'c', 0)...)
+	data = append(data, frame(FramePing, 0, 0, 1, 2, 3, 4, 5, 6, 7, 8)...)
+
+	f, n, err := ParseFrame(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = append(f.(*DataFrame).Data, 0xEE, 0xEE, 0xEE)
+	f, m, err := ParseFrame(data[n:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := f.(*DataFrame); d.StreamID != 3 || string(d.Data) != "c" {
+		t.Errorf("second DataFrame = %+v", d)
+	}
+	_ = append(f.(*DataFrame).Data, 0xEE, 0xEE, 0xEE)
+	f, _, err = ParseFrame(data[n+m:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := f.(*PingFrame); p.Data != [8]byte{1, 2, 3, 4, 5, 6, 7, 8} {
+		t.Errorf("PingFrame = %+v", p)
+	}
+}
+
+func TestParseFrame_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"truncated payload", frame(FrameData, 0, 1, 1, 2)[:10], io.ErrUnexpectedEOF},
+		{"data on stream 0", frame(FrameData, 0, 0), ErrProtocol},
+		{"settings on stream 1", frame(FrameSettings, 0, 1), ErrProtocol},
+		{"padding exceeds payload", frame(FrameData, FlagPadded, 1, 3, 'a'), ErrProtocol},
+		{"missing pad length", frame(FrameData, FlagPadded, 1), ErrFrameSize},
+		{"self dependency", frame(FrameHeaders, FlagPriority, 3, 0, 0, 0, 3, 0), ErrProtocol},
+		{"priority length", frame(FramePriority, 0, 1, 0, 0, 0, 0), ErrFrameSize},
+		{"rst_stream length", frame(FrameRSTStream, 0, 1, 0, 0, 0), ErrFrameSize},
+		{"settings length", frame(FrameSettings, 0, 0, 0, 1, 0, 0, 0), ErrFrameSize},
+		{"settings ack payload", frame(FrameSettings, FlagAck, 0, 0, 1, 0, 0, 0, 0), ErrFrameSize},
+		{"enable_push value", frame(FrameSettings, 0, 0, 0, 2, 0, 0, 0, 2), ErrProtocol},
+		{"max_frame_size value", frame(FrameSettings, 0, 0, 0, 5, 0, 0, 0x10, 0), ErrProtocol},
+		{"ping length", frame(FramePing, 0, 0, 1), ErrFrameSize},
+		{"goaway length", frame(FrameGoAway, 0, 0, 0, 0, 0, 0), ErrFrameSize},
+		{"zero window increment", frame(FrameWindowUpdate, 0, 1, 0, 0, 0, 0), ErrProtocol},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := ParseFrame(tt.data); !errors.Is(err, tt.want) {
+				t.Errorf("ParseFrame() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
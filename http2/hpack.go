@@ -0,0 +1,335 @@
+package http2
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrCompression is returned for malformed HPACK header blocks. The HTTP/2
+// connection must be closed with COMPRESSION_ERROR since the decoder state
+// can no longer be trusted.
+var ErrCompression = errors.New("http2: compression error")
+
+// DefaultHeaderTableSize is the initial SETTINGS_HEADER_TABLE_SIZE.
+const DefaultHeaderTableSize = 4096
+
+// HeaderField is a decoded header name/value pair.
+type HeaderField struct {
+	Name  string
+	Value string
+	// Sensitive is set for fields encoded as "never indexed"; intermediaries
+	// must keep them out of their own compression contexts.
+	Sensitive bool
+}
+
+// Size returns the size of the field as accounted by the dynamic table.
+func (f HeaderField) Size() uint32 {
+	return uint32(len(f.Name) + len(f.Value) + 32)
+}
+
+func (f HeaderField) String() string {
+	return f.Name + ": " + f.Value
+}
+
+// staticTable is RFC 7541 Appendix A; index 1 is staticTable[0].
+var staticTable = [...]HeaderField{
+	{Name: ":authority"},
+	{Name: ":method", Value: "GET"},
+	{Name: ":method", Value: "POST"},
+	{Name: ":path", Value: "/"},
+	{Name: ":path", Value: "/index.html"},
+	{Name: ":scheme", Value: "http"},
+	{Name: ":scheme", Value: "https"},
+	{Name: ":status", Value: "200"},
+	{Name: ":status", Value: "204"},
+	{Name: ":status", Value: "206"},
+	{Name: ":status", Value: "304"},
+	{Name: ":status", Value: "400"},
+	{Name: ":status", Value: "404"},
+	{Name: ":status", Value: "500"},
+	{Name: "accept-charset"},
+	{Name: "accept-encoding", Value: "gzip, deflate"},
+	{Name: "accept-language"},
+	{Name: "accept-ranges"},
+	{Name: "accept"},
+	{Name: "access-control-allow-origin"},
+	{Name: "age"},
+	{Name: "allow"},
+	{Name: "authorization"},
+	{Name: "cache-control"},
+	{Name: "content-disposition"},
+	{Name: "content-encoding"},
+	{Name: "content-language"},
+	{Name: "content-length"},
+	{Name: "content-location"},
+	{Name: "content-range"},
+	{Name: "content-type"},
+	{Name: "cookie"},
+	{Name: "date"},
+	{Name: "etag"},
+	{Name: "expect"},
+	{Name: "expires"},
+	{Name: "from"},
+	{Name: "host"},
+	{Name: "if-match"},
+	{Name: "if-modified-since"},
+	{Name: "if-none-match"},
+	{Name: "if-range"},
+	{Name: "if-unmodified-since"},
+	{Name: "last-modified"},
+	{Name: "link"},
+	{Name: "location"},
+	{Name: "max-forwards"},
+	{Name: "proxy-authenticate"},
+	{Name: "proxy-authorization"},
+	{Name: "range"},
+	{Name: "referer"},
+	{Name: "refresh"},
+	{Name: "retry-after"},
+	{Name: "server"},
+	{Name: "set-cookie"},
+	{Name: "strict-transport-security"},
+	{Name: "transfer-encoding"},
+	{Name: "user-agent"},
+	{Name: "vary"},
+	{Name: "via"},
+	{Name: "www-authenticate"},
+}
+
+// dynamicTable holds the most recently inserted entry last.
+type dynamicTable struct {
+	ents    []HeaderField
+	size    uint32
+	maxSize uint32
+}
+
+func (t *dynamicTable) add(f HeaderField) {
+	f.Sensitive = false
+	t.size += f.Size()
+	t.ents = append(t.ents, f)
+	t.evict()
+}
+
+func (t *dynamicTable) setMaxSize(n uint32) {
+	t.maxSize = n
+	t.evict()
+}
+
+// evict drops the oldest entries until the table fits; an entry larger than
+// the whole table simply empties it.
+func (t *dynamicTable) evict() {
+	n := 0
+	for t.size > t.maxSize {
+		t.size -= t.ents[n].Size()
+		n++
+	}
+	if n > 0 {
+		m := copy(t.ents, t.ents[n:])
+		clear(t.ents[m:])
+		t.ents = t.ents[:m]
+	}
+}
+
+// HPACKDecoder decodes header blocks, keeping the dynamic table across
+// blocks. A single decoder must see every header block of a connection
+// direction, in order.
+type HPACKDecoder struct {
+	table dynamicTable
+	// limit is the largest table size the peer may select, i.e. our
+	// SETTINGS_HEADER_TABLE_SIZE.
+	limit  uint32
+	fields []HeaderField
+}
+
+// NewHPACKDecoder creates a decoder whose dynamic table may grow to maxSize
+// bytes, normally the advertised SETTINGS_HEADER_TABLE_SIZE.
+func NewHPACKDecoder(maxSize uint32) *HPACKDecoder {
+	return &HPACKDecoder{
+		table: dynamicTable{maxSize: maxSize},
+		limit: maxSize,
+	}
+}
+
+// SetMaxTableSize changes the limit on dynamic table size updates, after a
+// new SETTINGS_HEADER_TABLE_SIZE has been acknowledged. The current table is
+// shrunk if needed.
+func (d *HPACKDecoder) SetMaxTableSize(n uint32) {
+	d.limit = n
+	if d.table.maxSize > n {
+		d.table.setMaxSize(n)
+	}
+}
+
+// DynamicTableSize returns the current size and the maximum size of the
+// dynamic table.
+func (d *HPACKDecoder) DynamicTableSize() (size, max uint32) {
+	return d.table.size, d.table.maxSize
+}
+
+// DynamicTableLen returns the number of entries in the dynamic table.
+func (d *HPACKDecoder) DynamicTableLen() int {
+	return len(d.table.ents)
+}
+
+// Decode decodes a complete header block. The returned slice is reused by
+// the next call to Decode. After an error the dynamic table is in an
+// undefined state.
+func (d *HPACKDecoder) Decode(block []byte) ([]HeaderField, error) {
+	d.fields = d.fields[:0]
+	p := hpackParser{buf: block}
+	for len(p.buf) > 0 {
+		if err := d.field(&p); err != nil {
+			return nil, fmt.Errorf("%w at offset %d: %v", ErrCompression, len(block)-len(p.buf), err)
+		}
+	}
+	return d.fields, nil
+}
+
+func (d *HPACKDecoder) field(p *hpackParser) error {
+	b := p.buf[0]
+	switch {
+	case b&0x80 != 0: // indexed field
+		idx, err := p.integer(7)
+		if err != nil {
+			return err
+		}
+		f, err := d.at(idx)
+		if err != nil {
+			return err
+		}
+		d.fields = append(d.fields, f)
+		return nil
+
+	case b&0xC0 == 0x40: // literal with incremental indexing
+		f, err := d.literal(p, 6)
+		if err != nil {
+			return err
+		}
+		d.table.add(f)
+		d.fields = append(d.fields, f)
+		return nil
+
+	case b&0xE0 == 0x20: // dynamic table size update
+		if len(d.fields) > 0 {
+			return errors.New("table size update after header field")
+		}
+		n, err := p.integer(5)
+		if err != nil {
+			return err
+		}
+		if n > uint64(d.limit) {
+			return fmt.Errorf("table size %d exceeds limit %d", n, d.limit)
+		}
+		d.table.setMaxSize(uint32(n))
+		return nil
+
+	default: // literal without indexing (0000) or never indexed (0001)
+		f, err := d.literal(p, 4)
+		if err != nil {
+			return err
+		}
+		f.Sensitive = b&0x10 != 0
+		d.fields = append(d.fields, f)
+		return nil
+	}
+}
+
+// literal reads a literal field whose name is indexed with an n-bit prefix,
+// or given literally when the index is zero.
+func (d *HPACKDecoder) literal(p *hpackParser, n uint8) (HeaderField, error) {
+	idx, err := p.integer(n)
+	if err != nil {
+		return HeaderField{}, err
+	}
+	var f HeaderField
+	if idx == 0 {
+		if f.Name, err = p.string(); err != nil {
+			return HeaderField{}, err
+		}
+	} else {
+		nf, err := d.at(idx)
+		if err != nil {
+			return HeaderField{}, err
+		}
+		f.Name = nf.Name
+	}
+	if f.Value, err = p.string(); err != nil {
+		return HeaderField{}, err
+	}
+	return f, nil
+}
+
+// at resolves an index into the combined static and dynamic address space.
+func (d *HPACKDecoder) at(idx uint64) (HeaderField, error) {
+	switch {
+	case idx == 0:
+		return HeaderField{}, errors.New("index 0")
+	case idx <= uint64(len(staticTable)):
+		return staticTable[idx-1], nil
+	}
+	i := idx - uint64(len(staticTable))
+	if i > uint64(len(d.table.ents)) {
+		return HeaderField{}, fmt.Errorf("index %d out of range", idx)
+	}
+	return d.table.ents[uint64(len(d.table.ents))-i], nil
+}
+
+// hpackParser reads HPACK primitives from the remainder of a header block.
+type hpackParser struct {
+	buf []byte
+}
+
+var errHPACKTruncated = errors.New("truncated header block")
+
+// integer decodes an integer with an n-bit prefix (RFC 7541 Section 5.1).
+// Values are limited to 32 bits.
+func (p *hpackParser) integer(n uint8) (uint64, error) {
+	max := uint64(1)<<n - 1
+	v := uint64(p.buf[0]) & max
+	p.buf = p.buf[1:]
+	if v < max {
+		return v, nil
+	}
+	for shift := uint(0); ; shift += 7 {
+		if len(p.buf) == 0 {
+			return 0, errHPACKTruncated
+		}
+		if shift > 28 {
+			return 0, errors.New("integer overflow")
+		}
+		b := p.buf[0]
+		p.buf = p.buf[1:]
+		v += uint64(b&0x7F) << shift
+		if v > 1<<32-1 {
+			return 0, errors.New("integer overflow")
+		}
+		if b&0x80 == 0 {
+			return v, nil
+		}
+	}
+}
+
+// string decodes a string literal, Huffman-coded or not.
+func (p *hpackParser) string() (string, error) {
+	if len(p.buf) == 0 {
+		return "", errHPACKTruncated
+	}
+	huffman := p.buf[0]&0x80 != 0
+	n, err := p.integer(7)
+	if err != nil {
+		return "", err
+	}
+	if n > uint64(len(p.buf)) {
+		return "", errHPACKTruncated
+	}
+	raw := p.buf[:n]
+	p.buf = p.buf[n:]
+	if !huffman {
+		return string(raw), nil
+	}
+	s, err := appendHuffman(make([]byte, 0, len(raw)*8/5), raw)
+	if err != nil {
+		return "", err
+	}
+	return string(s), nil
+}
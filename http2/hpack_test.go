@@ -0,0 +1,171 @@
+package http2
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func checkFields(t *testing.T, got []HeaderField, want [][2]string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d fields %v, want %d", len(got), got, len(want))
+	}
+	for i, f := range got {
+		if f.Name != want[i][0] || f.Value != want[i][1] {
+			t.Errorf("field %d = %q: %q, want %q: %q", i, f.Name, f.Value, want[i][0], want[i][1])
+		}
+	}
+}
+
+// Request examples from RFC 7541 Appendix C.3 (plain) and C.4 (Huffman).
+func TestHPACKDecoder_Requests(t *testing.T) {
+	want := [][][2]string{
+		{{":method", "GET"}, {":scheme", "http"}, {":path", "/"}, {":authority", "www.example.com"}},
+		{{":method", "GET"}, {":scheme", "http"}, {":path", "/"}, {":authority", "www.example.com"}, {"cache-control", "no-cache"}},
+		{{":method", "GET"}, {":scheme", "https"}, {":path", "/index.html"}, {":authority", "www.example.com"}, {"custom-key", "custom-value"}},
+	}
+	tests := []struct {
+		name   string
+		blocks []string
+	}{
+		{"plain", []string{
+			"828684410f7777772e6578616d706c652e636f6d",
+			"828684be58086e6f2d6361636865",
+			"828785bf400a637573746f6d2d6b65790c637573746f6d2d76616c7565",
+		}},
+		{"huffman", []string{
+			"828684418cf1e3c2e5f23a6ba0ab90f4ff",
+			"828684be5886a8eb10649cbf",
+			"828785bf408825a849e95ba97d7f8925a849e95bb8e8b4bf",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewHPACKDecoder(DefaultHeaderTableSize)
+			for i, block := range tt.blocks {
+				fields, err := d.Decode(mustHex(t, block))
+				if err != nil {
+					t.Fatalf("Decode(block %d) error = %v", i, err)
+				}
+				checkFields(t, fields, want[i])
+			}
+			if size, _ := d.DynamicTableSize(); size != 164 || d.DynamicTableLen() != 3 {
+				t.Errorf("dynamic table = %d bytes, %d entries, want 164, 3", size, d.DynamicTableLen())
+			}
+		})
+	}
+}
+
+// Response examples from RFC 7541 Appendix C.6, which evict entries from a
+// 256-byte table.
+func TestHPACKDecoder_Eviction(t *testing.T) {
+	d := NewHPACKDecoder(256)
+	blocks := []string{
+		"488264025885aec3771a4b6196d07abe941054d444a8200595040b8166e082a62d1bff6e919d29ad171863c78f0b97c8e9ae82ae43d3",
+		"4883640effc1c0bf",
+		"88c16196d07abe941054d444a8200595040b8166e084a62d1bffc05a839bd9ab77ad94e7821dd7f2e6c7b335dfdfcd5b3960d5af27087f3672c1ab270fb5291f9587316065c003ed4ee5b1063d5007",
+	}
+	wantSize := []uint32{222, 222, 215}
+	for i, block := range blocks {
+		if _, err := d.Decode(mustHex(t, block)); err != nil {
+			t.Fatalf("Decode(block %d) error = %v", i, err)
+		}
+		if size, _ := d.DynamicTableSize(); size != wantSize[i] {
+			t.Errorf("after block %d table size = %d, want %d", i, size, wantSize[i])
+		}
+	}
+	fields, _ := d.Decode(mustHex(t, "be"))
+	checkFields(t, fields, [][2]string{{"set-cookie", "foo=ASDJKHQKBZXOQWEOPIUAXQWEOIU; max-age=3600; version=1"}})
+}
+
+func TestHPACKDecoder_TableSizeUpdate(t *testing.T) {
+	d := NewHPACKDecoder(DefaultHeaderTableSize)
+	if _, err := d.Decode(mustHex(t, "400161016240016301644001650166")); err != nil {
+		t.Fatal(err)
+	}
+	// Shrink to 40 bytes: only the newest entry (34 bytes) survives.
+	if _, err := d.Decode(mustHex(t, "3f09")); err != nil {
+		t.Fatalf("Decode(size update) error = %v", err)
+	}
+	if size, max := d.DynamicTableSize(); size != 34 || max != 40 || d.DynamicTableLen() != 1 {
+		t.Errorf("DynamicTableSize() = %d, %d with %d entries, want 34, 40, 1", size, max, d.DynamicTableLen())
+	}
+
+	d.SetMaxTableSize(100)
+	if _, err := d.Decode(mustHex(t, "3fe107")); !errors.Is(err, ErrCompression) {
+		t.Errorf("Decode(size above limit) error = %v, want ErrCompression", err)
+	}
+}
+
+func TestHPACKDecoder_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		block string
+	}{
+		{"index zero", "80"},
+		{"index past dynamic table", "be"},
+		{"size update after field", "823f09"},
+		{"truncated string", "400a6375"},
+		{"truncated integer", "ff"},
+		{"integer overflow", "ffffffffff0f"},
+		{"huffman EOS", "4083ffffff0161"},
+		{"huffman padding too long", "4082f1ff0161"},
+		{"huffman padding not ones", "4081f00161"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewHPACKDecoder(DefaultHeaderTableSize)
+			if _, err := d.Decode(mustHex(t, tt.block)); !errors.Is(err, ErrCompression) {
+				t.Errorf("Decode() error = %v, want ErrCompression", err)
+			}
+		})
+	}
+}
+
+func TestHPACKDecoder_NeverIndexed(t *testing.T) {
+	d := NewHPACKDecoder(DefaultHeaderTableSize)
+	fields, err := d.Decode(mustHex(t, "1f0803736563"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fields) != 1 || fields[0].Name != "authorization" || fields[0].Value != "sec" || !fields[0].Sensitive {
+		t.Errorf("Decode() = %+v", fields)
+	}
+	if d.DynamicTableLen() != 0 {
+		t.Errorf("never-indexed field was added to the dynamic table")
+	}
+}
+
+func TestAppendHuffman(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"", ""},
+		{"f1e3c2e5f23a6ba0ab90f4ff", "www.example.com"},
+		{"a8eb10649cbf", "no-cache"},
+		{"6402", "302"},
+		{"ffc7f9", "\x00?"},
+		{"fffffbbfff987193", "\xff\x80abc"},
+		{"fffe1ffdff9f", "\\~|"},
+	}
+
+	for _, tt := range tests {
+		got, err := appendHuffman(nil, mustHex(t, tt.in))
+		if err != nil || string(got) != tt.want {
+			t.Errorf("appendHuffman(%s) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
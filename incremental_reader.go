@@ -0,0 +1,410 @@
+package wireread
+
+import (
+	"fmt"
+	"io"
+)
+
+// NeedMoreError is returned by an IncrementalReader when a read needs more
+// bytes than have been appended. It matches ErrNeedMore and, for code
+// written against SafeReader, io.ErrUnexpectedEOF under errors.Is.
+type NeedMoreError struct {
+	// N is the minimum number of additional bytes the read needs. Reads
+	// that scan for a delimiter cannot know the full length in advance and
+	// report 1.
+	N int
+}
+
+func (e *NeedMoreError) Error() string {
+	return fmt.Sprintf("wireread: need at least %d more bytes", e.N)
+}
+
+// Is reports whether target is ErrNeedMore or io.ErrUnexpectedEOF.
+func (e *NeedMoreError) Is(target error) bool {
+	return target == ErrNeedMore || target == io.ErrUnexpectedEOF
+}
+
+// IncrementalReader reads data that arrives in pieces, checking every read as
+// SafeReader does. A parse starts with Begin and either ends with Commit once a
+// whole message has been read, or, when a read returns a NeedMoreError, with
+// Rollback to the start of the message so it can be parsed again after Append
+// adds more bytes:
+//
+//	for {
+//	    r.Begin()
+//	    msg, err := parseMessage(r)
+//	    if errors.Is(err, wireread.ErrNeedMore) {
+//	        r.Rollback()
+//	        break // wait for the next Append
+//	    }
+//	    ...
+//	    r.Commit()
+//	}
+//
+// Append drops the committed bytes and may reuse their space, so offsets
+// passed to the random-access methods, and slices returned by Bytes,
+// ReadSlice and ReadBytesAt, are only valid until the next Append. ReadBytes
+// returns a copy that can be kept.
+//
+// It implements Reader, ASCIIIntReader and RandomAccessReader, whose short
+// reads all report a NeedMoreError. The stream interfaces are left out: their
+// io.EOF at the end of the buffered bytes, and seeking behind Begin, do not
+// fit Rollback.
+type IncrementalReader struct {
+	sr    *SafeReader
+	start int  // read position at Begin or Commit
+	owned bool // data was allocated by Append and may be reused
+}
+
+// NewIncrementalReader creates an IncrementalReader holding data, which may be
+// nil. Later Appends do not modify data.
+func NewIncrementalReader(data []byte) *IncrementalReader {
+	return &IncrementalReader{sr: NewSafeReader(data)}
+}
+
+// Reset discards the buffered bytes and makes ir read from data, ending any
+// parse in progress.
+func (ir *IncrementalReader) Reset(data []byte) {
+	ir.sr.Reset(data)
+	ir.start = 0
+	ir.owned = false
+}
+
+// Begin marks the read position as the start of a message, where Rollback
+// returns to. The bytes before it are committed.
+func (ir *IncrementalReader) Begin() {
+	ir.start = ir.sr.rpos
+}
+
+// Commit accepts the bytes read since Begin. Append may then drop them.
+func (ir *IncrementalReader) Commit() {
+	ir.start = ir.sr.rpos
+}
+
+// Rollback moves the read position back to the last Begin or Commit.
+func (ir *IncrementalReader) Rollback() {
+	ir.sr.rpos = ir.start
+}
+
+// Append adds data to the end of the buffered bytes, dropping those before
+// the last Begin or Commit. The read position is kept.
+func (ir *IncrementalReader) Append(data []byte) {
+	keep := ir.sr.data[ir.start:]
+	var buf []byte
+	if ir.owned && cap(ir.sr.data) >= len(keep)+len(data) {
+		buf = ir.sr.data[:copy(ir.sr.data, keep)]
+	} else {
+		buf = make([]byte, len(keep), 2*(len(keep)+len(data)))
+		copy(buf, keep)
+	}
+	ir.sr.data = append(buf, data...)
+	ir.sr.size = len(ir.sr.data)
+	ir.sr.rpos -= ir.start
+	ir.start = 0
+	ir.owned = true
+}
+
+// need returns a *NeedMoreError if fewer than n bytes are left to read
+func (ir *IncrementalReader) need(n int) error {
+	if left := ir.sr.size - ir.sr.rpos; n > left {
+		return &NeedMoreError{N: n - left}
+	}
+	return nil
+}
+
+// needAt returns a *NeedMoreError if the n bytes at offset off have not been
+// appended yet
+func (ir *IncrementalReader) needAt(off, n int) error {
+	if off >= 0 && n >= 0 && off > ir.sr.size-n {
+		return &NeedMoreError{N: off - (ir.sr.size - n)}
+	}
+	return nil
+}
+
+// needMore converts the io.ErrUnexpectedEOF of a read that scans for a
+// delimiter
+func needMore(err error) error {
+	if err == io.ErrUnexpectedEOF {
+		return &NeedMoreError{N: 1}
+	}
+	return err
+}
+
+// Bytes returns the remaining unparsed bytes from the current read position
+func (ir *IncrementalReader) Bytes() []byte {
+	return ir.sr.Bytes()
+}
+
+// ReadBytes reads n bytes
+func (ir *IncrementalReader) ReadBytes(n int) ([]byte, error) {
+	if err := ir.need(n); err != nil {
+		return nil, err
+	}
+	return ir.sr.ReadBytes(n)
+}
+
+// ReadSlice reads n bytes without copying, as SafeReader.ReadSlice does
+func (ir *IncrementalReader) ReadSlice(n int) ([]byte, error) {
+	if err := ir.need(n); err != nil {
+		return nil, err
+	}
+	return ir.sr.ReadSlice(n)
+}
+
+// ReadByte reads a single byte
+func (ir *IncrementalReader) ReadByte() (byte, error) {
+	if err := ir.need(1); err != nil {
+		return 0, err
+	}
+	return ir.sr.ReadByte()
+}
+
+// Skip skips n bytes
+func (ir *IncrementalReader) Skip(n int) error {
+	if err := ir.need(n); err != nil {
+		return err
+	}
+	return ir.sr.Skip(n)
+}
+
+// ReadUvarint reads a variable-length unsigned integer. Unlike SafeReader, it
+// leaves the read position unchanged when the integer is incomplete
+func (ir *IncrementalReader) ReadUvarint() (uint64, error) {
+	rpos := ir.sr.rpos
+	v, err := ir.sr.ReadUvarint()
+	if err != nil {
+		ir.sr.rpos = rpos
+		return 0, needMore(err)
+	}
+	return v, nil
+}
+
+// ReadString reads n bytes and returns them as a string
+func (ir *IncrementalReader) ReadString(n int) (string, error) {
+	if err := ir.need(n); err != nil {
+		return "", err
+	}
+	return ir.sr.ReadString(n)
+}
+
+// ReadStringInto reads n bytes into the provided string pointer
+func (ir *IncrementalReader) ReadStringInto(out *string, n int) error {
+	if err := ir.need(n); err != nil {
+		return err
+	}
+	return ir.sr.ReadStringInto(out, n)
+}
+
+// ReadNullTerminatedString reads a null-terminated string (C-style string)
+func (ir *IncrementalReader) ReadNullTerminatedString() (string, error) {
+	s, err := ir.sr.ReadNullTerminatedString()
+	return s, needMore(err)
+}
+
+// ReadLengthEncodedInteger reads a MySQL length-encoded integer
+func (ir *IncrementalReader) ReadLengthEncodedInteger() (uint64, error) {
+	if err := ir.need(1); err != nil {
+		return 0, err
+	}
+	n := 1
+	switch ir.sr.data[ir.sr.rpos] {
+	case 0xFC:
+		n = 3
+	case 0xFD:
+		n = 4
+	case 0xFE:
+		n = 9
+	}
+	if err := ir.need(n); err != nil {
+		return 0, err
+	}
+	return ir.sr.ReadLengthEncodedInteger()
+}
+
+// ReadLine reads a line terminated by \n (handles \r\n)
+func (ir *IncrementalReader) ReadLine() (string, error) {
+	s, err := ir.sr.ReadLine()
+	return s, needMore(err)
+}
+
+// ReadASCIIInt reads a signed base-10 integer terminated by \n (handles \r\n)
+func (ir *IncrementalReader) ReadASCIIInt() (int64, error) {
+	v, err := ir.sr.ReadASCIIInt()
+	return v, needMore(err)
+}
+
+// ReadUint16BE reads a 16-bit unsigned integer in big-endian byte order
+func (ir *IncrementalReader) ReadUint16BE() (uint16, error) {
+	if err := ir.need(2); err != nil {
+		return 0, err
+	}
+	return ir.sr.ReadUint16BE()
+}
+
+// ReadUint16BEInto reads a 16-bit unsigned integer in big-endian byte order into the provided pointer
+func (ir *IncrementalReader) ReadUint16BEInto(out *uint16) error {
+	if err := ir.need(2); err != nil {
+		return err
+	}
+	return ir.sr.ReadUint16BEInto(out)
+}
+
+// ReadInt16BEInto reads a 16-bit signed integer in big-endian byte order into the provided pointer
+func (ir *IncrementalReader) ReadInt16BEInto(out *int16) error {
+	if err := ir.need(2); err != nil {
+		return err
+	}
+	return ir.sr.ReadInt16BEInto(out)
+}
+
+// ReadUint32BE reads a 32-bit unsigned integer in big-endian byte order
+func (ir *IncrementalReader) ReadUint32BE() (uint32, error) {
+	if err := ir.need(4); err != nil {
+		return 0, err
+	}
+	return ir.sr.ReadUint32BE()
+}
+
+// ReadUint32BEInto reads a 32-bit unsigned integer in big-endian byte order into the provided pointer
+func (ir *IncrementalReader) ReadUint32BEInto(out *uint32) error {
+	if err := ir.need(4); err != nil {
+		return err
+	}
+	return ir.sr.ReadUint32BEInto(out)
+}
+
+// ReadInt32BEInto reads a 32-bit signed integer in big-endian byte order into the provided pointer
+func (ir *IncrementalReader) ReadInt32BEInto(out *int32) error {
+	if err := ir.need(4); err != nil {
+		return err
+	}
+	return ir.sr.ReadInt32BEInto(out)
+}
+
+// ReadUint64BE reads a 64-bit unsigned integer in big-endian byte order
+func (ir *IncrementalReader) ReadUint64BE() (uint64, error) {
+	if err := ir.need(8); err != nil {
+		return 0, err
+	}
+	return ir.sr.ReadUint64BE()
+}
+
+// ReadUint64BEInto reads a 64-bit unsigned integer in big-endian byte order into the provided pointer
+func (ir *IncrementalReader) ReadUint64BEInto(out *uint64) error {
+	if err := ir.need(8); err != nil {
+		return err
+	}
+	return ir.sr.ReadUint64BEInto(out)
+}
+
+// ReadUint16LE reads a 16-bit unsigned integer in little-endian byte order
+func (ir *IncrementalReader) ReadUint16LE() (uint16, error) {
+	if err := ir.need(2); err != nil {
+		return 0, err
+	}
+	return ir.sr.ReadUint16LE()
+}
+
+// ReadUint16LEInto reads a 16-bit unsigned integer in little-endian byte order into the provided pointer
+func (ir *IncrementalReader) ReadUint16LEInto(out *uint16) error {
+	if err := ir.need(2); err != nil {
+		return err
+	}
+	return ir.sr.ReadUint16LEInto(out)
+}
+
+// ReadUint32LE reads a 32-bit unsigned integer in little-endian byte order
+func (ir *IncrementalReader) ReadUint32LE() (uint32, error) {
+	if err := ir.need(4); err != nil {
+		return 0, err
+	}
+	return ir.sr.ReadUint32LE()
+}
+
+// ReadUint32LEInto reads a 32-bit unsigned integer in little-endian byte order into the provided pointer
+func (ir *IncrementalReader) ReadUint32LEInto(out *uint32) error {
+	if err := ir.need(4); err != nil {
+		return err
+	}
+	return ir.sr.ReadUint32LEInto(out)
+}
+
+// ReadUint64LE reads a 64-bit unsigned integer in little-endian byte order
+func (ir *IncrementalReader) ReadUint64LE() (uint64, error) {
+	if err := ir.need(8); err != nil {
+		return 0, err
+	}
+	return ir.sr.ReadUint64LE()
+}
+
+// ReadUint64LEInto reads a 64-bit unsigned integer in little-endian byte order into the provided pointer
+func (ir *IncrementalReader) ReadUint64LEInto(out *uint64) error {
+	if err := ir.need(8); err != nil {
+		return err
+	}
+	return ir.sr.ReadUint64LEInto(out)
+}
+
+// ReadByteAt reads the byte at offset off without moving the read position
+func (ir *IncrementalReader) ReadByteAt(off int) (byte, error) {
+	if err := ir.needAt(off, 1); err != nil {
+		return 0, err
+	}
+	return ir.sr.ReadByteAt(off)
+}
+
+// ReadBytesAt returns the n bytes at offset off without moving the read position
+func (ir *IncrementalReader) ReadBytesAt(off, n int) ([]byte, error) {
+	if err := ir.needAt(off, n); err != nil {
+		return nil, err
+	}
+	return ir.sr.ReadBytesAt(off, n)
+}
+
+// ReadUint16BEAt reads a 16-bit unsigned integer in big-endian byte order at offset off
+func (ir *IncrementalReader) ReadUint16BEAt(off int) (uint16, error) {
+	if err := ir.needAt(off, 2); err != nil {
+		return 0, err
+	}
+	return ir.sr.ReadUint16BEAt(off)
+}
+
+// ReadUint16LEAt reads a 16-bit unsigned integer in little-endian byte order at offset off
+func (ir *IncrementalReader) ReadUint16LEAt(off int) (uint16, error) {
+	if err := ir.needAt(off, 2); err != nil {
+		return 0, err
+	}
+	return ir.sr.ReadUint16LEAt(off)
+}
+
+// ReadUint32BEAt reads a 32-bit unsigned integer in big-endian byte order at offset off
+func (ir *IncrementalReader) ReadUint32BEAt(off int) (uint32, error) {
+	if err := ir.needAt(off, 4); err != nil {
+		return 0, err
+	}
+	return ir.sr.ReadUint32BEAt(off)
+}
+
+// ReadUint32LEAt reads a 32-bit unsigned integer in little-endian byte order at offset off
+func (ir *IncrementalReader) ReadUint32LEAt(off int) (uint32, error) {
+	if err := ir.needAt(off, 4); err != nil {
+		return 0, err
+	}
+	return ir.sr.ReadUint32LEAt(off)
+}
+
+// ReadUint64BEAt reads a 64-bit unsigned integer in big-endian byte order at offset off
+func (ir *IncrementalReader) ReadUint64BEAt(off int) (uint64, error) {
+	if err := ir.needAt(off, 8); err != nil {
+		return 0, err
+	}
+	return ir.sr.ReadUint64BEAt(off)
+}
+
+// ReadUint64LEAt reads a 64-bit unsigned integer in little-endian byte order at offset off
+func (ir *IncrementalReader) ReadUint64LEAt(off int) (uint64, error) {
+	if err := ir.needAt(off, 8); err != nil {
+		return 0, err
+	}
+	return ir.sr.ReadUint64LEAt(off)
+}
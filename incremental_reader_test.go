@@ -0,0 +1,177 @@
+package wireread
+
+import (
+	"errors"
+	"io"
+	"testing"
+)
+
+// parseFrame reads a frame of a 16-bit length, a NUL-terminated name and a
+// payload of the given length.
+func parseFrame(r Reader) (name string, payload []byte, err error) {
+	n, err := r.ReadUint16BE()
+	if err != nil {
+		return "", nil, err
+	}
+	if name, err = r.ReadNullTerminatedString(); err != nil {
+		return "", nil, err
+	}
+	if payload, err = r.ReadBytes(int(n)); err != nil {
+		return "", nil, err
+	}
+	return name, payload, nil
+}
+
+func TestIncrementalReader_ByteByByte(t *testing.T) {
+	stream := []byte("\x00\x03a\x00xyz\x00\x01bc\x00!")
+	r := NewIncrementalReader(nil)
+	var names, payloads []string
+	for i := range stream {
+		r.Append(stream[i : i+1])
+		for {
+			r.Begin()
+			name, payload, err := parseFrame(r)
+			if errors.Is(err, ErrNeedMore) {
+				r.Rollback()
+				break
+			}
+			if err != nil {
+				t.Fatalf("parseFrame() after %d bytes error = %v", i+1, err)
+			}
+			r.Commit()
+			names = append(names, name)
+			payloads = append(payloads, string(payload))
+		}
+	}
+	if len(names) != 2 || names[0] != "a" || payloads[0] != "xyz" || names[1] != "bc" || payloads[1] != "!" {
+		t.Errorf("frames = %q %q, want [a bc] [xyz !]", names, payloads)
+	}
+	if r.Append(nil); len(r.sr.data) != 0 {
+		t.Errorf("Append() kept committed bytes %q", r.sr.data)
+	}
+}
+
+func TestIncrementalReader_NeedMore(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		read func(r *IncrementalReader) error
+		want int
+	}{
+		{"uint32", "\x01", func(r *IncrementalReader) error { _, err := r.ReadUint32LE(); return err }, 3},
+		{"uint64 into", "", func(r *IncrementalReader) error { var v uint64; return r.ReadUint64BEInto(&v) }, 8},
+		{"bytes", "abc", func(r *IncrementalReader) error { _, err := r.ReadBytes(10); return err }, 7},
+		{"skip", "abc", func(r *IncrementalReader) error { return r.Skip(5) }, 2},
+		{"slice", "ab", func(r *IncrementalReader) error { _, err := r.ReadSlice(4); return err }, 2},
+		{"length-encoded prefix", "", func(r *IncrementalReader) error { _, err := r.ReadLengthEncodedInteger(); return err }, 1},
+		{"length-encoded body", "\xfe\x01", func(r *IncrementalReader) error { _, err := r.ReadLengthEncodedInteger(); return err }, 7},
+		{"line", "partial", func(r *IncrementalReader) error { _, err := r.ReadLine(); return err }, 1},
+		{"ascii int", "12\r", func(r *IncrementalReader) error { _, err := r.ReadASCIIInt(); return err }, 1},
+		{"uvarint", "\x80\x80", func(r *IncrementalReader) error { _, err := r.ReadUvarint(); return err }, 1},
+		{"at", "abcd", func(r *IncrementalReader) error { _, err := r.ReadUint32BEAt(2); return err }, 2},
+		{"byte at", "ab", func(r *IncrementalReader) error { _, err := r.ReadByteAt(4); return err }, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewIncrementalReader([]byte(tt.data))
+			err := tt.read(r)
+			var nm *NeedMoreError
+			if !errors.As(err, &nm) || nm.N != tt.want {
+				t.Fatalf("error = %v, want NeedMoreError{N: %d}", err, tt.want)
+			}
+			if !errors.Is(err, ErrNeedMore) || !errors.Is(err, io.ErrUnexpectedEOF) {
+				t.Errorf("error %v does not match ErrNeedMore and io.ErrUnexpectedEOF", err)
+			}
+			if len(r.Bytes()) != len(tt.data) {
+				t.Errorf("failed read consumed %d bytes", len(tt.data)-len(r.Bytes()))
+			}
+		})
+	}
+
+	r := NewIncrementalReader([]byte("12x\n"))
+	if _, err := r.ReadASCIIInt(); err != ErrInvalidASCIIInt {
+		t.Errorf("ReadASCIIInt() on malformed line error = %v, want ErrInvalidASCIIInt", err)
+	}
+}
+
+func TestIncrementalReader_Append(t *testing.T) {
+	data := []byte("abcdef")
+	r := NewIncrementalReader(data[:3])
+	_ = r.Skip(1)
+	r.Commit()
+	_ = r.Skip(1)
+	r.Append([]byte("XY"))
+	if string(data) != "abcdef" {
+		t.Errorf("Append() modified the initial data: %q", data)
+	}
+	if got := string(r.Bytes()); got != "cXY" {
+		t.Errorf("Bytes() after Append() = %q, want \"cXY\"", got)
+	}
+	r.Rollback()
+	if got := string(r.Bytes()); got != "bcXY" {
+		t.Errorf("Bytes() after Rollback() = %q, want \"bcXY\"", got)
+	}
+
+	// Appends that fit reuse the buffer.
+	_ = r.Skip(3)
+	r.Commit()
+	buf := &r.sr.data[:1][0]
+	r.Append([]byte("Z"))
+	if got := string(r.Bytes()); got != "YZ" || &r.sr.data[0] != buf {
+		t.Errorf("Bytes() = %q, reused buffer %v; want \"YZ\", true", got, &r.sr.data[0] == buf)
+	}
+}
+
+func TestIncrementalReader_AppendReusesSlices(t *testing.T) {
+	r := NewIncrementalReader(nil)
+	r.Append([]byte("abcd"))
+	kept, _ := r.ReadSlice(2)
+	copied, _ := r.ReadBytes(2)
+	r.Commit()
+
+	// The committed bytes' space is reused, so only the copy survives.
+	r.Append([]byte("wxyz"))
+	if string(copied) != "cd" {
+		t.Errorf("ReadBytes() result after Append() = %q, want \"cd\"", copied)
+	}
+	if string(kept) != "wx" {
+		t.Errorf("ReadSlice() result after Append() = %q, want it overwritten with \"wx\"", kept)
+	}
+}
+
+func TestIncrementalReader_Reset(t *testing.T) {
+	r := NewIncrementalReader(nil)
+	r.Append([]byte("abc"))
+	_ = r.Skip(2)
+	r.Commit()
+
+	data := []byte("xyz")
+	r.Reset(data[:1])
+	r.Append([]byte("!"))
+	if string(data) != "xyz" {
+		t.Errorf("Append() after Reset() modified the data: %q", data)
+	}
+	if got := string(r.Bytes()); got != "x!" {
+		t.Errorf("Bytes() = %q, want \"x!\"", got)
+	}
+}
+
+func TestIncrementalReader_ImplementsReader(t *testing.T) {
+	var _ Reader = (*IncrementalReader)(nil)
+	var _ ASCIIIntReader = (*IncrementalReader)(nil)
+	var _ RandomAccessReader = (*IncrementalReader)(nil)
+
+	// The stream interfaces would read past a short buffer with io.EOF
+	// instead of a NeedMoreError
+	var r any = NewIncrementalReader(nil)
+	if _, ok := r.(io.Reader); ok {
+		t.Error("IncrementalReader implements io.Reader")
+	}
+	if _, ok := r.(io.Seeker); ok {
+		t.Error("IncrementalReader implements io.Seeker")
+	}
+	if _, ok := r.(io.ReaderAt); ok {
+		t.Error("IncrementalReader implements io.ReaderAt")
+	}
+}
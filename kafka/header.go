@@ -0,0 +1,160 @@
+package kafka
+
+import "github.com/nemohan/wireread"
+
+// API keys with special header handling.
+const (
+	APIKeyControlledShutdown int16 = 7
+	APIKeyAPIVersions        int16 = 18
+)
+
+// firstFlexibleVersion maps an API key to the first version using flexible
+// encoding (compact types and tagged fields), as of Kafka 3.x. A value of -1
+// marks APIs that never became flexible. Keys beyond the table were
+// introduced as flexible from version 0.
+var firstFlexibleVersion = [...]int16{
+	0:  9,  // Produce
+	1:  12, // Fetch
+	2:  6,  // ListOffsets
+	3:  9,  // Metadata
+	4:  4,  // LeaderAndIsr
+	5:  2,  // StopReplica
+	6:  6,  // UpdateMetadata
+	7:  3,  // ControlledShutdown
+	8:  8,  // OffsetCommit
+	9:  6,  // OffsetFetch
+	10: 3,  // FindCoordinator
+	11: 6,  // JoinGroup
+	12: 4,  // Heartbeat
+	13: 4,  // LeaveGroup
+	14: 4,  // SyncGroup
+	15: 5,  // DescribeGroups
+	16: 3,  // ListGroups
+	17: -1, // SaslHandshake
+	18: 3,  // ApiVersions
+	19: 5,  // CreateTopics
+	20: 4,  // DeleteTopics
+	21: 2,  // DeleteRecords
+	22: 2,  // InitProducerId
+	23: 4,  // OffsetForLeaderEpoch
+	24: 3,  // AddPartitionsToTxn
+	25: 3,  // AddOffsetsToTxn
+	26: 3,  // EndTxn
+	27: 1,  // WriteTxnMarkers
+	28: 3,  // TxnOffsetCommit
+	29: 2,  // DescribeAcls
+	30: 2,  // CreateAcls
+	31: 2,  // DeleteAcls
+	32: 4,  // DescribeConfigs
+	33: 2,  // AlterConfigs
+	34: 2,  // AlterReplicaLogDirs
+	35: 2,  // DescribeLogDirs
+	36: 2,  // SaslAuthenticate
+	37: 2,  // CreatePartitions
+	38: 2,  // CreateDelegationToken
+	39: 2,  // RenewDelegationToken
+	40: 2,  // ExpireDelegationToken
+	41: 2,  // DescribeDelegationToken
+	42: 2,  // DeleteGroups
+	43: 2,  // ElectLeaders
+	44: 1,  // IncrementalAlterConfigs
+	45: 0,  // AlterPartitionReassignments
+	46: 0,  // ListPartitionReassignments
+	47: -1, // OffsetDelete
+	48: 1,  // DescribeClientQuotas
+	49: 1,  // AlterClientQuotas
+}
+
+// IsFlexible reports whether the given API version uses flexible encoding.
+func IsFlexible(apiKey, apiVersion int16) bool {
+	if apiKey < 0 {
+		return false
+	}
+	if int(apiKey) >= len(firstFlexibleVersion) {
+		return true
+	}
+	first := firstFlexibleVersion[apiKey]
+	return first >= 0 && apiVersion >= first
+}
+
+// RequestHeaderVersion returns the request header version (0, 1 or 2) used
+// by the given API version.
+func RequestHeaderVersion(apiKey, apiVersion int16) int {
+	switch {
+	case IsFlexible(apiKey, apiVersion):
+		return 2
+	case apiKey == APIKeyControlledShutdown && apiVersion == 0:
+		return 0
+	}
+	return 1
+}
+
+// ResponseHeaderVersion returns the response header version (0 or 1) used
+// by the given API version. ApiVersions responses always use version 0 so
+// that clients can parse them before the version is negotiated.
+func ResponseHeaderVersion(apiKey, apiVersion int16) int {
+	if apiKey != APIKeyAPIVersions && IsFlexible(apiKey, apiVersion) {
+		return 1
+	}
+	return 0
+}
+
+// RequestHeader is the header preceding every request body.
+type RequestHeader struct {
+	APIKey        int16
+	APIVersion    int16
+	CorrelationID int32
+	// ClientID is empty when absent (header v0) or null.
+	ClientID     string
+	TaggedFields []TaggedField
+	// Version is the header version that was decoded.
+	Version int
+}
+
+// ReadRequestHeader reads a request header. The header version is derived
+// from the API key and version, which are always the leading fields.
+func ReadRequestHeader(r wireread.Reader) (RequestHeader, error) {
+	var h RequestHeader
+	var err error
+	if h.APIKey, err = ReadInt16(r); err != nil {
+		return h, err
+	}
+	if h.APIVersion, err = ReadInt16(r); err != nil {
+		return h, err
+	}
+	if h.CorrelationID, err = ReadInt32(r); err != nil {
+		return h, err
+	}
+	h.Version = RequestHeaderVersion(h.APIKey, h.APIVersion)
+	if h.Version == 0 {
+		return h, nil
+	}
+	// The client ID keeps its int16 prefix even in flexible headers.
+	if h.ClientID, _, err = ReadNullableString(r); err != nil {
+		return h, err
+	}
+	if h.Version == 2 {
+		h.TaggedFields, err = ReadTaggedFields(r)
+	}
+	return h, err
+}
+
+// ResponseHeader is the header preceding every response body.
+type ResponseHeader struct {
+	CorrelationID int32
+	TaggedFields  []TaggedField
+}
+
+// ReadResponseHeader reads a response header for the request with the given
+// API key and version, which the caller matches via the correlation ID.
+func ReadResponseHeader(r wireread.Reader, apiKey, apiVersion int16) (ResponseHeader, error) {
+	var h ResponseHeader
+	var err error
+	if h.CorrelationID, err = ReadInt32(r); err != nil {
+		return h, err
+	}
+	if ResponseHeaderVersion(apiKey, apiVersion) == 1 {
+		h.TaggedFields, err = ReadTaggedFields(r)
+	}
+	return h, err
+}
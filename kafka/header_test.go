@@ -0,0 +1,69 @@
+package kafka
+
+import (
+	"testing"
+
+	"github.com/nemohan/wireread"
+)
+
+func TestReadRequestHeader(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		version  int
+		clientID string
+		tagged   int
+	}{
+		{
+			"v0 controlled shutdown",
+			[]byte{0x00, 0x07, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01},
+			0, "", 0,
+		},
+		{
+			"v1 metadata",
+			[]byte{0x00, 0x03, 0x00, 0x08, 0x00, 0x00, 0x00, 0x02, 0x00, 0x03, 'c', 'l', 'i'},
+			1, "cli", 0,
+		},
+		{
+			"v2 fetch",
+			[]byte{0x00, 0x01, 0x00, 0x0C, 0x00, 0x00, 0x00, 0x03, 0x00, 0x01, 'c', 0x01, 0x00, 0x01, 0xFF},
+			2, "c", 1,
+		},
+		{
+			"v2 new api",
+			[]byte{0x00, 0x44, 0x00, 0x00, 0x00, 0x00, 0x00, 0x04, 0xFF, 0xFF, 0x00},
+			2, "", 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := wireread.NewSafeReader(tt.data)
+			h, err := ReadRequestHeader(r)
+			if err != nil {
+				t.Fatalf("ReadRequestHeader() error = %v", err)
+			}
+			if h.Version != tt.version || h.ClientID != tt.clientID || len(h.TaggedFields) != tt.tagged {
+				t.Errorf("ReadRequestHeader() = %+v", h)
+			}
+			if len(r.Bytes()) != 0 {
+				t.Errorf("ReadRequestHeader() left %d bytes", len(r.Bytes()))
+			}
+		})
+	}
+}
+
+func TestReadResponseHeader(t *testing.T) {
+	// ApiVersions v3 is flexible but its response header stays at v0.
+	r := wireread.NewSafeReader([]byte{0x00, 0x00, 0x00, 0x09, 0x00})
+	h, err := ReadResponseHeader(r, APIKeyAPIVersions, 3)
+	if err != nil || h.CorrelationID != 9 || len(r.Bytes()) != 1 {
+		t.Errorf("ReadResponseHeader(ApiVersions) = %+v, %v", h, err)
+	}
+
+	r = wireread.NewSafeReader([]byte{0x00, 0x00, 0x00, 0x09, 0x00})
+	h, err = ReadResponseHeader(r, 3, 9)
+	if err != nil || h.CorrelationID != 9 || len(r.Bytes()) != 0 {
+		t.Errorf("ReadResponseHeader(Metadata v9) = %+v, %v", h, err)
+	}
+}
@@ -0,0 +1,222 @@
+// Package kafka decodes Apache Kafka protocol headers and v2 record batches.
+//
+// All functions read from a wireread.Reader, so they work with both the
+// bounds-checked SafeReader and the FastReader for pre-validated frames.
+//
+// Example usage:
+//
+//	r := wireread.NewSafeReader(frame) // size prefix already stripped
+//	hdr, err := kafka.ReadRequestHeader(r)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	fmt.Println(hdr.APIKey, hdr.APIVersion, hdr.ClientID)
+package kafka
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/nemohan/wireread"
+)
+
+var (
+	// ErrInvalidLength is returned when a length prefix is negative where
+	// not allowed or exceeds the remaining data.
+	ErrInvalidLength = errors.New("kafka: invalid length")
+
+	// ErrVarintOverflow is returned when a varint does not fit its type.
+	ErrVarintOverflow = errors.New("kafka: varint overflows")
+)
+
+// TaggedField is an entry of a flexible-version tagged field section.
+type TaggedField struct {
+	Tag  uint64
+	Data []byte
+}
+
+// ReadInt8 reads a signed 8-bit integer.
+func ReadInt8(r wireread.Reader) (int8, error) {
+	b, err := r.ReadByte()
+	return int8(b), err
+}
+
+// ReadInt16 reads a big-endian signed 16-bit integer.
+func ReadInt16(r wireread.Reader) (int16, error) {
+	var v int16
+	err := r.ReadInt16BEInto(&v)
+	return v, err
+}
+
+// ReadInt32 reads a big-endian signed 32-bit integer.
+func ReadInt32(r wireread.Reader) (int32, error) {
+	var v int32
+	err := r.ReadInt32BEInto(&v)
+	return v, err
+}
+
+// ReadInt64 reads a big-endian signed 64-bit integer.
+func ReadInt64(r wireread.Reader) (int64, error) {
+	v, err := r.ReadUint64BE()
+	return int64(v), err
+}
+
+// ReadVarint reads a zigzag-encoded signed varint limited to 32 bits.
+func ReadVarint(r wireread.Reader) (int32, error) {
+	v, err := binary.ReadVarint(r)
+	if err != nil {
+		return 0, err
+	}
+	if v < math.MinInt32 || v > math.MaxInt32 {
+		return 0, ErrVarintOverflow
+	}
+	return int32(v), nil
+}
+
+// ReadVarlong reads a zigzag-encoded signed varint.
+func ReadVarlong(r wireread.Reader) (int64, error) {
+	return binary.ReadVarint(r)
+}
+
+// ReadUnsignedVarint reads an unsigned varint limited to 32 bits, as used
+// for compact lengths and tagged field headers.
+func ReadUnsignedVarint(r wireread.Reader) (uint32, error) {
+	v, err := r.ReadUvarint()
+	if err != nil {
+		return 0, err
+	}
+	if v > math.MaxUint32 {
+		return 0, ErrVarintOverflow
+	}
+	return uint32(v), nil
+}
+
+// checkLength verifies that n bytes remain in r.
+func checkLength(r wireread.Reader, n int64) error {
+	if n < 0 || n > int64(len(r.Bytes())) {
+		return fmt.Errorf("%w: %d", ErrInvalidLength, n)
+	}
+	return nil
+}
+
+// ReadString reads a string with an int16 length prefix.
+func ReadString(r wireread.Reader) (string, error) {
+	s, ok, err := ReadNullableString(r)
+	if err == nil && !ok {
+		err = fmt.Errorf("%w: null string", ErrInvalidLength)
+	}
+	return s, err
+}
+
+// ReadNullableString reads a string with an int16 length prefix. A length of
+// -1 denotes null and is reported with ok set to false.
+func ReadNullableString(r wireread.Reader) (s string, ok bool, err error) {
+	n, err := ReadInt16(r)
+	if err != nil || n == -1 {
+		return "", false, err
+	}
+	if err := checkLength(r, int64(n)); err != nil {
+		return "", false, err
+	}
+	s, err = r.ReadString(int(n))
+	return s, err == nil, err
+}
+
+// ReadCompactString reads a string whose length is encoded as an unsigned
+// varint holding length+1.
+func ReadCompactString(r wireread.Reader) (string, error) {
+	s, ok, err := ReadCompactNullableString(r)
+	if err == nil && !ok {
+		err = fmt.Errorf("%w: null string", ErrInvalidLength)
+	}
+	return s, err
+}
+
+// ReadCompactNullableString reads a compact string where a zero length
+// prefix denotes null, reported with ok set to false.
+func ReadCompactNullableString(r wireread.Reader) (s string, ok bool, err error) {
+	n, err := ReadUnsignedVarint(r)
+	if err != nil || n == 0 {
+		return "", false, err
+	}
+	if err := checkLength(r, int64(n)-1); err != nil {
+		return "", false, err
+	}
+	s, err = r.ReadString(int(n) - 1)
+	return s, err == nil, err
+}
+
+// ReadBytes reads a byte array with an int32 length prefix; -1 yields nil.
+func ReadBytes(r wireread.Reader) ([]byte, error) {
+	n, err := ReadInt32(r)
+	if err != nil || n == -1 {
+		return nil, err
+	}
+	if err := checkLength(r, int64(n)); err != nil {
+		return nil, err
+	}
+	return r.ReadBytes(int(n))
+}
+
+// ReadCompactBytes reads a byte array with a compact length prefix; a zero
+// prefix yields nil.
+func ReadCompactBytes(r wireread.Reader) ([]byte, error) {
+	n, err := ReadUnsignedVarint(r)
+	if err != nil || n == 0 {
+		return nil, err
+	}
+	if err := checkLength(r, int64(n)-1); err != nil {
+		return nil, err
+	}
+	return r.ReadBytes(int(n) - 1)
+}
+
+// ReadArrayLength reads an int32 array length; -1 denotes a null array.
+func ReadArrayLength(r wireread.Reader) (int32, error) {
+	return ReadInt32(r)
+}
+
+// ReadCompactArrayLength reads a compact array length (length+1 as an
+// unsigned varint); -1 denotes a null array.
+func ReadCompactArrayLength(r wireread.Reader) (int32, error) {
+	n, err := ReadUnsignedVarint(r)
+	if err != nil {
+		return 0, err
+	}
+	return int32(int64(n) - 1), nil
+}
+
+// ReadTaggedFields reads a tagged field section: a count followed by
+// tag, size and data for each field.
+func ReadTaggedFields(r wireread.Reader) ([]TaggedField, error) {
+	count, err := ReadUnsignedVarint(r)
+	if err != nil || count == 0 {
+		return nil, err
+	}
+	// Each field takes at least two bytes, which bounds the allocation.
+	if err := checkLength(r, 2*int64(count)); err != nil {
+		return nil, err
+	}
+	fields := make([]TaggedField, 0, count)
+	for i := uint32(0); i < count; i++ {
+		tag, err := ReadUnsignedVarint(r)
+		if err != nil {
+			return nil, err
+		}
+		size, err := ReadUnsignedVarint(r)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkLength(r, int64(size)); err != nil {
+			return nil, err
+		}
+		data, err := r.ReadBytes(int(size))
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, TaggedField{Tag: uint64(tag), Data: data})
+	}
+	return fields, nil
+}
@@ -0,0 +1,79 @@
+package kafka
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nemohan/wireread"
+)
+
+func TestReadVarint(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want int32
+	}{
+		{"zero", []byte{0x00}, 0},
+		{"minus one", []byte{0x01}, -1},
+		{"one", []byte{0x02}, 1},
+		{"300", []byte{0xD8, 0x04}, 300},
+		{"min int32", []byte{0xFF, 0xFF, 0xFF, 0xFF, 0x0F}, -2147483648},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadVarint(wireread.NewSafeReader(tt.data))
+			if err != nil || got != tt.want {
+				t.Errorf("ReadVarint() = %d, %v, want %d, nil", got, err, tt.want)
+			}
+		})
+	}
+
+	if _, err := ReadVarint(wireread.NewSafeReader([]byte{0x80, 0x80, 0x80, 0x80, 0x10})); err != ErrVarintOverflow {
+		t.Errorf("ReadVarint() error = %v, want ErrVarintOverflow", err)
+	}
+}
+
+func TestReadStrings(t *testing.T) {
+	r := wireread.NewSafeReader([]byte{
+		0x00, 0x02, 'h', 'i', // string
+		0xFF, 0xFF, // null string
+		0x03, 'y', 'o', // compact string
+		0x00, // compact null
+	})
+
+	if s, err := ReadString(r); err != nil || s != "hi" {
+		t.Errorf("ReadString() = %q, %v", s, err)
+	}
+	if _, ok, err := ReadNullableString(r); err != nil || ok {
+		t.Errorf("ReadNullableString() = ok %v, %v, want null", ok, err)
+	}
+	if s, err := ReadCompactString(r); err != nil || s != "yo" {
+		t.Errorf("ReadCompactString() = %q, %v", s, err)
+	}
+	if _, ok, err := ReadCompactNullableString(r); err != nil || ok {
+		t.Errorf("ReadCompactNullableString() = ok %v, %v, want null", ok, err)
+	}
+}
+
+func TestReadString_InvalidLength(t *testing.T) {
+	r := wireread.NewSafeReader([]byte{0x00, 0x10, 'a'})
+	if _, err := ReadString(r); !errors.Is(err, ErrInvalidLength) {
+		t.Errorf("ReadString() error = %v, want ErrInvalidLength", err)
+	}
+}
+
+func TestReadTaggedFields(t *testing.T) {
+	r := wireread.NewSafeReader([]byte{0x02, 0x00, 0x01, 0xAA, 0x05, 0x02, 0xBB, 0xCC})
+	fields, err := ReadTaggedFields(r)
+	if err != nil {
+		t.Fatalf("ReadTaggedFields() error = %v", err)
+	}
+	if len(fields) != 2 || fields[0].Tag != 0 || fields[1].Tag != 5 || !bytesEqual(fields[1].Data, []byte{0xBB, 0xCC}) {
+		t.Errorf("ReadTaggedFields() = %+v", fields)
+	}
+}
+
+func bytesEqual(a, b []byte) bool {
+	return string(a) == string(b)
+}
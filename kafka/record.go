@@ -0,0 +1,332 @@
+package kafka
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+
+	"github.com/nemohan/wireread"
+)
+
+var (
+	// ErrUnsupportedMagic is returned for record batches other than v2.
+	ErrUnsupportedMagic = errors.New("kafka: unsupported record batch magic")
+
+	// ErrCRCMismatch is returned when a batch fails CRC32C verification.
+	ErrCRCMismatch = errors.New("kafka: record batch CRC mismatch")
+
+	// ErrUnsupportedCompression is returned for codecs that are not
+	// available in the standard library.
+	ErrUnsupportedCompression = errors.New("kafka: unsupported compression codec")
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// Compression is the codec stored in the low bits of the batch attributes.
+type Compression int8
+
+// Compression codecs.
+const (
+	CompressionNone   Compression = 0
+	CompressionGzip   Compression = 1
+	CompressionSnappy Compression = 2
+	CompressionLZ4    Compression = 3
+	CompressionZstd   Compression = 4
+)
+
+// String returns the codec name as used in Kafka configuration.
+func (c Compression) String() string {
+	switch c {
+	case CompressionNone:
+		return "none"
+	case CompressionGzip:
+		return "gzip"
+	case CompressionSnappy:
+		return "snappy"
+	case CompressionLZ4:
+		return "lz4"
+	case CompressionZstd:
+		return "zstd"
+	}
+	return fmt.Sprintf("compression(%d)", int8(c))
+}
+
+// Batch attribute bits.
+const (
+	attrCompressionMask = 0x07
+	attrTimestampType   = 0x08
+	attrTransactional   = 0x10
+	attrControl         = 0x20
+	attrDeleteHorizon   = 0x40
+)
+
+// maxDecompressedSize bounds the size of a decompressed batch so that a
+// small compressed payload cannot exhaust memory.
+const maxDecompressedSize = 1 << 30
+
+// recordBatchOverhead is the size of the batch header following the base
+// offset and batch length fields.
+const recordBatchOverhead = 4 + 1 + 4 + 2 + 4 + 8 + 8 + 8 + 2 + 4 + 4
+
+// RecordBatch is a decoded v2 record batch (magic byte 2).
+type RecordBatch struct {
+	BaseOffset           int64
+	BatchLength          int32
+	PartitionLeaderEpoch int32
+	Magic                int8
+	CRC                  uint32
+	Attributes           int16
+	LastOffsetDelta      int32
+	BaseTimestamp        int64
+	MaxTimestamp         int64
+	ProducerID           int64
+	ProducerEpoch        int16
+	BaseSequence         int32
+	Records              []Record
+}
+
+// Compression returns the codec used for the batch's records.
+func (b *RecordBatch) Compression() Compression {
+	return Compression(b.Attributes & attrCompressionMask)
+}
+
+// LogAppendTime reports whether timestamps were assigned by the broker.
+func (b *RecordBatch) LogAppendTime() bool {
+	return b.Attributes&attrTimestampType != 0
+}
+
+// IsTransactional reports whether the batch is part of a transaction.
+func (b *RecordBatch) IsTransactional() bool {
+	return b.Attributes&attrTransactional != 0
+}
+
+// IsControl reports whether the batch holds control records.
+func (b *RecordBatch) IsControl() bool {
+	return b.Attributes&attrControl != 0
+}
+
+// HasDeleteHorizon reports whether BaseTimestamp holds the delete horizon
+// set by log compaction.
+func (b *RecordBatch) HasDeleteHorizon() bool {
+	return b.Attributes&attrDeleteHorizon != 0
+}
+
+// Record is a single record within a batch.
+type Record struct {
+	Attributes     int8
+	TimestampDelta int64
+	OffsetDelta    int32
+	// Key and Value are nil when null.
+	Key     []byte
+	Value   []byte
+	Headers []Header
+}
+
+// Header is a record header. Value is nil when null.
+type Header struct {
+	Key   string
+	Value []byte
+}
+
+// Offset returns the absolute offset of rec within batch b.
+func (b *RecordBatch) Offset(rec *Record) int64 {
+	return b.BaseOffset + int64(rec.OffsetDelta)
+}
+
+// Timestamp returns the absolute timestamp in milliseconds of rec within b.
+func (b *RecordBatch) Timestamp(rec *Record) int64 {
+	if b.LogAppendTime() {
+		return b.MaxTimestamp
+	}
+	return b.BaseTimestamp + rec.TimestampDelta
+}
+
+// ReadRecordBatch reads and verifies a v2 record batch, decompressing its
+// records when gzip compression is used.
+func ReadRecordBatch(r wireread.Reader) (*RecordBatch, error) {
+	b := new(RecordBatch)
+	var err error
+	if b.BaseOffset, err = ReadInt64(r); err != nil {
+		return nil, err
+	}
+	if b.BatchLength, err = ReadInt32(r); err != nil {
+		return nil, err
+	}
+	if b.BatchLength < recordBatchOverhead {
+		return nil, fmt.Errorf("%w: batch length %d", ErrInvalidLength, b.BatchLength)
+	}
+	if len(r.Bytes()) < int(b.BatchLength) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	body := r.Bytes()[:b.BatchLength]
+	if err := r.Skip(int(b.BatchLength)); err != nil {
+		return nil, err
+	}
+
+	br := wireread.NewSafeReader(body)
+	if b.PartitionLeaderEpoch, err = ReadInt32(br); err != nil {
+		return nil, err
+	}
+	if b.Magic, err = ReadInt8(br); err != nil {
+		return nil, err
+	}
+	if b.Magic != 2 {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedMagic, b.Magic)
+	}
+	if b.CRC, err = br.ReadUint32BE(); err != nil {
+		return nil, err
+	}
+	// The CRC covers everything from the attributes to the end of the batch.
+	if crc32.Checksum(br.Bytes(), castagnoli) != b.CRC {
+		return nil, ErrCRCMismatch
+	}
+
+	if b.Attributes, err = ReadInt16(br); err != nil {
+		return nil, err
+	}
+	if b.LastOffsetDelta, err = ReadInt32(br); err != nil {
+		return nil, err
+	}
+	if b.BaseTimestamp, err = ReadInt64(br); err != nil {
+		return nil, err
+	}
+	if b.MaxTimestamp, err = ReadInt64(br); err != nil {
+		return nil, err
+	}
+	if b.ProducerID, err = ReadInt64(br); err != nil {
+		return nil, err
+	}
+	if b.ProducerEpoch, err = ReadInt16(br); err != nil {
+		return nil, err
+	}
+	if b.BaseSequence, err = ReadInt32(br); err != nil {
+		return nil, err
+	}
+	count, err := ReadInt32(br)
+	if err != nil {
+		return nil, err
+	}
+
+	records := wireread.Reader(br)
+	switch c := b.Compression(); c {
+	case CompressionNone:
+	case CompressionGzip:
+		zr, err := gzip.NewReader(bytes.NewReader(br.Bytes()))
+		if err != nil {
+			return nil, err
+		}
+		raw, err := io.ReadAll(io.LimitReader(zr, maxDecompressedSize+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(raw) > maxDecompressedSize {
+			return nil, fmt.Errorf("%w: decompressed batch too large", ErrInvalidLength)
+		}
+		records = wireread.NewSafeReader(raw)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedCompression, c)
+	}
+
+	// Every record takes at least seven bytes, which bounds the allocation.
+	if count < 0 || int64(count)*7 > int64(len(records.Bytes())) {
+		return nil, fmt.Errorf("%w: record count %d", ErrInvalidLength, count)
+	}
+	b.Records = make([]Record, count)
+	for i := range b.Records {
+		if err := readRecord(records, &b.Records[i]); err != nil {
+			return nil, fmt.Errorf("record %d: %w", i, err)
+		}
+	}
+	return b, nil
+}
+
+// ReadRecordBatches reads consecutive record batches, as found in the
+// records field of a fetch response. A truncated trailing batch, which
+// brokers may return when the fetch size limit is hit, is silently dropped.
+func ReadRecordBatches(r wireread.Reader) ([]*RecordBatch, error) {
+	var batches []*RecordBatch
+	for len(r.Bytes()) > 0 {
+		b, err := ReadRecordBatch(r)
+		if err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return batches, err
+		}
+		batches = append(batches, b)
+	}
+	return batches, nil
+}
+
+func readRecord(r wireread.Reader, rec *Record) error {
+	length, err := ReadVarint(r)
+	if err != nil {
+		return err
+	}
+	if err := checkLength(r, int64(length)); err != nil {
+		return err
+	}
+	rr := wireread.NewSafeReader(r.Bytes()[:length])
+	if err := r.Skip(int(length)); err != nil {
+		return err
+	}
+
+	if rec.Attributes, err = ReadInt8(rr); err != nil {
+		return err
+	}
+	if rec.TimestampDelta, err = ReadVarlong(rr); err != nil {
+		return err
+	}
+	if rec.OffsetDelta, err = ReadVarint(rr); err != nil {
+		return err
+	}
+	if rec.Key, err = readVarintBytes(rr); err != nil {
+		return err
+	}
+	if rec.Value, err = readVarintBytes(rr); err != nil {
+		return err
+	}
+	n, err := ReadVarint(rr)
+	if err != nil {
+		return err
+	}
+	if err := checkLength(rr, 2*int64(n)); err != nil {
+		return err
+	}
+	if n > 0 {
+		rec.Headers = make([]Header, n)
+	}
+	for i := range rec.Headers {
+		key, err := readVarintBytes(rr)
+		if err != nil {
+			return err
+		}
+		if key == nil {
+			return fmt.Errorf("%w: null header key", ErrInvalidLength)
+		}
+		rec.Headers[i].Key = string(key)
+		if rec.Headers[i].Value, err = readVarintBytes(rr); err != nil {
+			return err
+		}
+	}
+	if len(rr.Bytes()) != 0 {
+		return fmt.Errorf("%w: %d trailing bytes in record", ErrInvalidLength, len(rr.Bytes()))
+	}
+	return nil
+}
+
+// readVarintBytes reads a byte array with a zigzag varint length, where -1
+// denotes null.
+func readVarintBytes(r wireread.Reader) ([]byte, error) {
+	n, err := ReadVarint(r)
+	if err != nil || n == -1 {
+		return nil, err
+	}
+	if err := checkLength(r, int64(n)); err != nil {
+		return nil, err
+	}
+	return r.ReadBytes(int(n))
+}
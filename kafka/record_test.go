@@ -0,0 +1,183 @@
+package kafka
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"testing"
+
+	"github.com/nemohan/wireread"
+)
+
+type testRecord struct {
+	key, value []byte
+	headers    []Header
+}
+
+func appendVarintBytes(b, v []byte) []byte {
+	if v == nil {
+		return binary.AppendVarint(b, -1)
+	}
+	b = binary.AppendVarint(b, int64(len(v)))
+	return append(b, v...)
+}
+
+func encodeRecords(recs []testRecord) []byte {
+	var out []byte
+	for i, rec := range recs {
+		var body []byte
+		body = append(body, 0)                        // attributes
+		body = binary.AppendVarint(body, int64(i*10)) // timestamp delta
+		body = binary.AppendVarint(body, int64(i))    // offset delta
+		body = appendVarintBytes(body, rec.key)
+		body = appendVarintBytes(body, rec.value)
+		body = binary.AppendVarint(body, int64(len(rec.headers)))
+		for _, h := range rec.headers {
+			body = appendVarintBytes(body, []byte(h.Key))
+			body = appendVarintBytes(body, h.Value)
+		}
+		out = binary.AppendVarint(out, int64(len(body)))
+		out = append(out, body...)
+	}
+	return out
+}
+
+func encodeBatch(attrs int16, recs []testRecord, compress bool) []byte {
+	payload := encodeRecords(recs)
+	if compress {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		zw.Write(payload)
+		zw.Close()
+		payload = buf.Bytes()
+	}
+
+	var tail []byte
+	tail = binary.BigEndian.AppendUint16(tail, uint16(attrs))
+	tail = binary.BigEndian.AppendUint32(tail, uint32(len(recs)-1))
+	tail = binary.BigEndian.AppendUint64(tail, 1000)
+	tail = binary.BigEndian.AppendUint64(tail, 1000+uint64(len(recs)-1)*10)
+	tail = binary.BigEndian.AppendUint64(tail, 0xFFFFFFFFFFFFFFFF)
+	tail = binary.BigEndian.AppendUint16(tail, 0xFFFF)
+	tail = binary.BigEndian.AppendUint32(tail, 0xFFFFFFFF)
+	tail = binary.BigEndian.AppendUint32(tail, uint32(len(recs)))
+	tail = append(tail, payload...)
+
+	var b []byte
+	b = binary.BigEndian.AppendUint64(b, 42)
+	b = binary.BigEndian.AppendUint32(b, uint32(4+1+4+len(tail)))
+	b = binary.BigEndian.AppendUint32(b, 7)
+	b = append(b, 2)
+	b = binary.BigEndian.AppendUint32(b, crc32.Checksum(tail, castagnoli))
+	return append(b, tail...)
+}
+
+var sampleRecords = []testRecord{
+	{key: []byte("k1"), value: []byte("v1"), headers: []Header{{Key: "trace", Value: []byte("abc")}}},
+	{key: nil, value: []byte("v2")},
+	{key: []byte(""), value: nil, headers: []Header{{Key: "h", Value: nil}}},
+}
+
+func checkRecords(t *testing.T, b *RecordBatch) {
+	t.Helper()
+	if len(b.Records) != len(sampleRecords) {
+		t.Fatalf("got %d records, want %d", len(b.Records), len(sampleRecords))
+	}
+	for i, want := range sampleRecords {
+		got := b.Records[i]
+		if (got.Key == nil) != (want.key == nil) || string(got.Key) != string(want.key) {
+			t.Errorf("record %d key = %q, want %q", i, got.Key, want.key)
+		}
+		if (got.Value == nil) != (want.value == nil) || string(got.Value) != string(want.value) {
+			t.Errorf("record %d value = %q, want %q", i, got.Value, want.value)
+		}
+		if len(got.Headers) != len(want.headers) {
+			t.Errorf("record %d headers = %+v, want %+v", i, got.Headers, want.headers)
+		}
+		if b.Offset(&got) != 42+int64(i) || b.Timestamp(&got) != 1000+int64(i)*10 {
+			t.Errorf("record %d offset = %d, timestamp = %d", i, b.Offset(&got), b.Timestamp(&got))
+		}
+	}
+	if h := b.Records[0].Headers[0]; h.Key != "trace" || string(h.Value) != "abc" {
+		t.Errorf("header = %+v", h)
+	}
+}
+
+func TestReadRecordBatch(t *testing.T) {
+	data := encodeBatch(int16(attrTransactional), sampleRecords, false)
+	r := wireread.NewSafeReader(data)
+
+	b, err := ReadRecordBatch(r)
+	if err != nil {
+		t.Fatalf("ReadRecordBatch() error = %v", err)
+	}
+	if b.BaseOffset != 42 || b.PartitionLeaderEpoch != 7 || b.ProducerID != -1 || !b.IsTransactional() || b.IsControl() {
+		t.Errorf("ReadRecordBatch() header = %+v", b)
+	}
+	checkRecords(t, b)
+	if len(r.Bytes()) != 0 {
+		t.Errorf("ReadRecordBatch() left %d bytes", len(r.Bytes()))
+	}
+}
+
+func TestReadRecordBatch_Gzip(t *testing.T) {
+	data := encodeBatch(int16(CompressionGzip), sampleRecords, true)
+	b, err := ReadRecordBatch(wireread.NewSafeReader(data))
+	if err != nil {
+		t.Fatalf("ReadRecordBatch() error = %v", err)
+	}
+	if b.Compression() != CompressionGzip {
+		t.Errorf("Compression() = %v, want gzip", b.Compression())
+	}
+	checkRecords(t, b)
+}
+
+func TestReadRecordBatch_FastReader(t *testing.T) {
+	data := encodeBatch(0, sampleRecords, false)
+	b, err := ReadRecordBatch(wireread.NewFastReader(data))
+	if err != nil {
+		t.Fatalf("ReadRecordBatch() error = %v", err)
+	}
+	checkRecords(t, b)
+}
+
+func TestReadRecordBatch_Errors(t *testing.T) {
+	corrupt := encodeBatch(0, sampleRecords, false)
+	corrupt[len(corrupt)-1] ^= 0xFF
+
+	badMagic := encodeBatch(0, sampleRecords, false)
+	badMagic[16] = 1
+
+	snappy := encodeBatch(int16(CompressionSnappy), sampleRecords, false)
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"crc mismatch", corrupt, ErrCRCMismatch},
+		{"bad magic", badMagic, ErrUnsupportedMagic},
+		{"snappy", snappy, ErrUnsupportedCompression},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ReadRecordBatch(wireread.NewSafeReader(tt.data)); !errors.Is(err, tt.want) {
+				t.Errorf("ReadRecordBatch() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadRecordBatches_TruncatedTail(t *testing.T) {
+	one := encodeBatch(0, sampleRecords, false)
+	data := append(append([]byte{}, one...), one...)
+	data = append(data, one[:len(one)/2]...)
+
+	batches, err := ReadRecordBatches(wireread.NewSafeReader(data))
+	if err != nil || len(batches) != 2 {
+		t.Errorf("ReadRecordBatches() = %d batches, %v, want 2, nil", len(batches), err)
+	}
+}
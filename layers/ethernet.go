@@ -0,0 +1,175 @@
+package layers
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+
+	"github.com/nemohan/wireread"
+)
+
+// EtherType is the protocol of an Ethernet payload.
+type EtherType uint16
+
+// Common EtherTypes.
+const (
+	EtherTypeIPv4        EtherType = 0x0800
+	EtherTypeARP         EtherType = 0x0806
+	EtherTypeVLAN        EtherType = 0x8100
+	EtherTypeIPv6        EtherType = 0x86dd
+	EtherTypeServiceVLAN EtherType = 0x88a8
+	EtherTypeQinQ        EtherType = 0x9100
+)
+
+func (t EtherType) String() string {
+	switch t {
+	case EtherTypeIPv4:
+		return "IPv4"
+	case EtherTypeARP:
+		return "ARP"
+	case EtherTypeVLAN:
+		return "VLAN"
+	case EtherTypeIPv6:
+		return "IPv6"
+	case EtherTypeServiceVLAN:
+		return "ServiceVLAN"
+	case EtherTypeQinQ:
+		return "QinQ"
+	}
+	return fmt.Sprintf("EtherType(0x%04x)", uint16(t))
+}
+
+// maxEtherLength is the largest value of the type field that is an IEEE
+// 802.3 length rather than an EtherType.
+const maxEtherLength = 1500
+
+// maxVLANTags bounds the tags read from one frame.
+const maxVLANTags = 8
+
+// VLAN is an IEEE 802.1Q tag.
+type VLAN struct {
+	// TPID is the EtherType that introduced the tag.
+	TPID EtherType
+
+	Priority     uint8
+	DropEligible bool
+	ID           uint16
+}
+
+// Ethernet is an Ethernet II frame header with its VLAN tags. The frame
+// check sequence, if captured, is left at the end of the payload.
+type Ethernet struct {
+	Dst, Src net.HardwareAddr
+	VLANs    []VLAN
+
+	// EtherType is the type after the VLAN tags. A value of 1500 or less
+	// is an IEEE 802.3 length: the payload is then cut to it and holds LLC
+	// data, which Decode does not follow.
+	EtherType EtherType
+
+	Payload []byte
+}
+
+// ParseEthernet parses an Ethernet frame.
+func ParseEthernet(data []byte) (*Ethernet, error) {
+	r := wireread.NewSafeReader(data)
+	dst, err := r.ReadSlice(6)
+	if err != nil {
+		return nil, err
+	}
+	src, err := r.ReadSlice(6)
+	if err != nil {
+		return nil, err
+	}
+	eth := &Ethernet{Dst: dst, Src: src}
+	for {
+		t, err := r.ReadUint16BE()
+		if err != nil {
+			return nil, err
+		}
+		eth.EtherType = EtherType(t)
+		if eth.EtherType != EtherTypeVLAN && eth.EtherType != EtherTypeServiceVLAN && eth.EtherType != EtherTypeQinQ {
+			break
+		}
+		if len(eth.VLANs) == maxVLANTags {
+			return nil, fmt.Errorf("%w: more than %d VLAN tags", ErrMalformed, maxVLANTags)
+		}
+		tci, err := r.ReadUint16BE()
+		if err != nil {
+			return nil, err
+		}
+		eth.VLANs = append(eth.VLANs, VLAN{
+			TPID:         eth.EtherType,
+			Priority:     uint8(tci >> 13),
+			DropEligible: tci&0x1000 != 0,
+			ID:           tci & 0x0fff,
+		})
+	}
+	eth.Payload = r.Bytes()
+	if eth.EtherType <= maxEtherLength {
+		n := min(len(eth.Payload), int(eth.EtherType))
+		eth.Payload = eth.Payload[:n:n]
+	}
+	return eth, nil
+}
+
+// ARP operations.
+const (
+	ARPRequest = 1
+	ARPReply   = 2
+)
+
+// ARP is an ARP packet. The protocol addresses are only decoded for IPv4
+// over hardware of any kind.
+type ARP struct {
+	HardwareType uint16
+	ProtocolType EtherType
+	Operation    uint16
+
+	SenderHardware, TargetHardware net.HardwareAddr
+	SenderAddr, TargetAddr         netip.Addr
+}
+
+// ParseARP parses an ARP packet. Trailing padding is ignored.
+func ParseARP(data []byte) (*ARP, error) {
+	r := wireread.NewSafeReader(data)
+	arp := &ARP{}
+	var err error
+	if arp.HardwareType, err = r.ReadUint16BE(); err != nil {
+		return nil, err
+	}
+	ptype, err := r.ReadUint16BE()
+	if err != nil {
+		return nil, err
+	}
+	arp.ProtocolType = EtherType(ptype)
+	hlen, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	plen, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if arp.Operation, err = r.ReadUint16BE(); err != nil {
+		return nil, err
+	}
+	if arp.ProtocolType == EtherTypeIPv4 && plen != 4 {
+		return nil, fmt.Errorf("%w: ARP IPv4 address length %d", ErrMalformed, plen)
+	}
+
+	var proto [2][]byte
+	for i, hw := range []*net.HardwareAddr{&arp.SenderHardware, &arp.TargetHardware} {
+		if *hw, err = r.ReadSlice(int(hlen)); err != nil {
+			return nil, err
+		}
+		if proto[i], err = r.ReadSlice(int(plen)); err != nil {
+			return nil, err
+		}
+	}
+	if arp.ProtocolType == EtherTypeIPv4 {
+		arp.SenderAddr = netip.AddrFrom4([4]byte(proto[0]))
+		arp.TargetAddr = netip.AddrFrom4([4]byte(proto[1]))
+	}
+	return arp, nil
+}
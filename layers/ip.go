@@ -0,0 +1,308 @@
+package layers
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/netip"
+
+	"github.com/nemohan/wireread"
+)
+
+// IPProtocol is an IP protocol number, as found in the IPv4 protocol field
+// and the IPv6 next header fields.
+type IPProtocol uint8
+
+// Common protocol numbers, including the IPv6 extension headers.
+const (
+	IPProtocolHopByHop    IPProtocol = 0
+	IPProtocolICMP        IPProtocol = 1
+	IPProtocolTCP         IPProtocol = 6
+	IPProtocolUDP         IPProtocol = 17
+	IPProtocolRouting     IPProtocol = 43
+	IPProtocolFragment    IPProtocol = 44
+	IPProtocolESP         IPProtocol = 50
+	IPProtocolAH          IPProtocol = 51
+	IPProtocolICMPv6      IPProtocol = 58
+	IPProtocolNoNext      IPProtocol = 59
+	IPProtocolDestOptions IPProtocol = 60
+)
+
+var protocolNames = map[IPProtocol]string{
+	IPProtocolHopByHop:    "HopByHop",
+	IPProtocolICMP:        "ICMP",
+	IPProtocolTCP:         "TCP",
+	IPProtocolUDP:         "UDP",
+	IPProtocolRouting:     "Routing",
+	IPProtocolFragment:    "Fragment",
+	IPProtocolESP:         "ESP",
+	IPProtocolAH:          "AH",
+	IPProtocolICMPv6:      "ICMPv6",
+	IPProtocolNoNext:      "NoNext",
+	IPProtocolDestOptions: "DestOptions",
+}
+
+func (p IPProtocol) String() string {
+	if s, ok := protocolNames[p]; ok {
+		return s
+	}
+	return fmt.Sprintf("IPProtocol(%d)", uint8(p))
+}
+
+// IPv4 flags.
+const (
+	IPv4DontFragment  = 0x2
+	IPv4MoreFragments = 0x1
+)
+
+// IPv4 option types with no length byte.
+const (
+	IPv4OptionEnd = 0
+	IPv4OptionNOP = 1
+)
+
+// IPv4Option is an option of an IPv4 header other than end of list and
+// no-operation. Data excludes the type and length bytes.
+type IPv4Option struct {
+	Type uint8
+	Data []byte
+}
+
+// IPv4 is an IPv4 header.
+type IPv4 struct {
+	TOS uint8
+
+	// Length is the total length of the datagram, header included.
+	Length uint16
+
+	ID         uint16
+	Flags      uint8
+	FragOffset uint16 // in 8-byte units
+	TTL        uint8
+	Protocol   IPProtocol
+	Checksum   uint16
+
+	Src, Dst netip.Addr
+	Options  []IPv4Option
+
+	// Payload is cut to Length, dropping any link-layer padding.
+	Payload []byte
+}
+
+// ParseIPv4 parses an IPv4 header and verifies its checksum. If only the
+// checksum is wrong, the parsed header is returned along with an error
+// wrapping ErrChecksum, for callers that accept offloaded checksums.
+func ParseIPv4(data []byte) (*IPv4, error) {
+	if len(data) == 0 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if v := data[0] >> 4; v != 4 {
+		return nil, fmt.Errorf("%w: IPv4 header with version %d", ErrMalformed, v)
+	}
+	hlen := int(data[0]&0x0f) * 4
+	if hlen < 20 {
+		return nil, fmt.Errorf("%w: IPv4 header length %d", ErrMalformed, hlen)
+	}
+	r := wireread.NewSafeReader(data)
+	hdr, err := r.ReadSlice(hlen)
+	if err != nil {
+		return nil, err
+	}
+
+	// The header is complete, so reading its fixed part cannot fail.
+	h := wireread.NewSafeReader(hdr[1:])
+	ip := &IPv4{}
+	ip.TOS, _ = h.ReadByte()
+	ip.Length, _ = h.ReadUint16BE()
+	ip.ID, _ = h.ReadUint16BE()
+	frag, _ := h.ReadUint16BE()
+	ip.Flags, ip.FragOffset = uint8(frag>>13), frag&0x1fff
+	ip.TTL, _ = h.ReadByte()
+	proto, _ := h.ReadByte()
+	ip.Protocol = IPProtocol(proto)
+	ip.Checksum, _ = h.ReadUint16BE()
+	src, _ := h.ReadSlice(4)
+	dst, _ := h.ReadSlice(4)
+	ip.Src, ip.Dst = netip.AddrFrom4([4]byte(src)), netip.AddrFrom4([4]byte(dst))
+
+	if int(ip.Length) < hlen {
+		return nil, fmt.Errorf("%w: IPv4 total length %d below header length %d", ErrMalformed, ip.Length, hlen)
+	}
+	if ip.Options, err = readIPv4Options(h); err != nil {
+		return nil, err
+	}
+	ip.Payload = r.Bytes()
+	n := min(len(ip.Payload), int(ip.Length)-hlen)
+	ip.Payload = ip.Payload[:n:n]
+
+	if checksum(hdr) != 0 {
+		return ip, fmt.Errorf("%w: IPv4 header checksum 0x%04x", ErrChecksum, ip.Checksum)
+	}
+	return ip, nil
+}
+
+func readIPv4Options(r *wireread.SafeReader) ([]IPv4Option, error) {
+	var opts []IPv4Option
+	for len(r.Bytes()) > 0 {
+		t, _ := r.ReadByte()
+		switch t {
+		case IPv4OptionEnd:
+			return opts, nil
+		case IPv4OptionNOP:
+			continue
+		}
+		n, err := r.ReadByte()
+		if err != nil || n < 2 || int(n)-2 > len(r.Bytes()) {
+			return nil, fmt.Errorf("%w: IPv4 option %d overruns the header", ErrMalformed, t)
+		}
+		data, _ := r.ReadSlice(int(n) - 2)
+		opts = append(opts, IPv4Option{Type: t, Data: data})
+	}
+	return opts, nil
+}
+
+// maxIPv6Extensions bounds the extension headers read from one packet.
+const maxIPv6Extensions = 16
+
+// IPv6Extension is an extension header. Data is the whole header,
+// including its next header and length bytes.
+type IPv6Extension struct {
+	Type IPProtocol
+	Data []byte
+}
+
+// IPv6Fragment is the content of a fragment extension header.
+type IPv6Fragment struct {
+	Offset uint16 // in 8-byte units
+	More   bool
+	ID     uint32
+}
+
+// IPv6 is an IPv6 header with its chain of extension headers.
+type IPv6 struct {
+	TrafficClass uint8
+	FlowLabel    uint32
+
+	// Length is the payload length, extension headers included. It is zero
+	// in jumbograms, whose payload then runs to the end of the data.
+	Length uint16
+
+	NextHeader IPProtocol
+	HopLimit   uint8
+	Src, Dst   netip.Addr
+
+	Extensions []IPv6Extension
+	Fragment   *IPv6Fragment
+
+	// Protocol is the next header after the extension headers: the upper
+	// layer protocol, IPProtocolESP, IPProtocolNoNext, or the protocol of a
+	// fragment whose offset is not zero.
+	Protocol IPProtocol
+
+	// Payload follows the extension headers and is cut to Length.
+	Payload []byte
+}
+
+// ParseIPv6 parses an IPv6 header and walks its extension headers:
+// hop-by-hop options, routing, fragment, destination options and
+// authentication headers. The chain stops at any other next header, and at
+// a fragment header with a non-zero offset, whose payload continues an
+// earlier fragment's.
+func ParseIPv6(data []byte) (*IPv6, error) {
+	r := wireread.NewSafeReader(data)
+	first, err := r.ReadUint32BE()
+	if err != nil {
+		return nil, err
+	}
+	if v := first >> 28; v != 6 {
+		return nil, fmt.Errorf("%w: IPv6 header with version %d", ErrMalformed, v)
+	}
+	ip := &IPv6{TrafficClass: uint8(first >> 20), FlowLabel: first & 0xfffff}
+	if ip.Length, err = r.ReadUint16BE(); err != nil {
+		return nil, err
+	}
+	next, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	ip.NextHeader = IPProtocol(next)
+	if ip.HopLimit, err = r.ReadByte(); err != nil {
+		return nil, err
+	}
+	src, err := r.ReadSlice(16)
+	if err != nil {
+		return nil, err
+	}
+	dst, err := r.ReadSlice(16)
+	if err != nil {
+		return nil, err
+	}
+	ip.Src, ip.Dst = netip.AddrFrom16([16]byte(src)), netip.AddrFrom16([16]byte(dst))
+
+	// Extension headers are bounded by the payload length.
+	payload := r.Bytes()
+	if ip.Length != 0 {
+		n := min(len(payload), int(ip.Length))
+		payload = payload[:n:n]
+	}
+	pr := wireread.NewSafeReader(payload)
+	ip.Protocol = ip.NextHeader
+	for ip.Protocol.isExtension() {
+		if ip.Protocol == IPProtocolHopByHop && len(ip.Extensions) > 0 {
+			return nil, fmt.Errorf("%w: IPv6 hop-by-hop options after another extension header", ErrMalformed)
+		}
+		if len(ip.Extensions) == maxIPv6Extensions {
+			return nil, fmt.Errorf("%w: more than %d IPv6 extension headers", ErrMalformed, maxIPv6Extensions)
+		}
+		ext, err := readIPv6Extension(pr, ip.Protocol)
+		if err != nil {
+			return nil, err
+		}
+		ip.Extensions = append(ip.Extensions, ext)
+		ip.Protocol = IPProtocol(ext.Data[0])
+		if ext.Type == IPProtocolFragment {
+			frag := binary.BigEndian.Uint16(ext.Data[2:])
+			ip.Fragment = &IPv6Fragment{
+				Offset: frag >> 3,
+				More:   frag&1 != 0,
+				ID:     binary.BigEndian.Uint32(ext.Data[4:]),
+			}
+			if ip.Fragment.Offset != 0 {
+				break
+			}
+		}
+	}
+	ip.Payload = pr.Bytes()
+	return ip, nil
+}
+
+func (p IPProtocol) isExtension() bool {
+	switch p {
+	case IPProtocolHopByHop, IPProtocolRouting, IPProtocolFragment, IPProtocolDestOptions, IPProtocolAH:
+		return true
+	}
+	return false
+}
+
+// readIPv6Extension reads an extension header of type t, whose length is in
+// 8-byte units not counting the first 8, or 4-byte units not counting the
+// first 8 for the authentication header.
+func readIPv6Extension(r *wireread.SafeReader, t IPProtocol) (IPv6Extension, error) {
+	b := r.Bytes()
+	if len(b) < 8 {
+		return IPv6Extension{}, io.ErrUnexpectedEOF
+	}
+	n := 8
+	switch t {
+	case IPProtocolFragment:
+	case IPProtocolAH:
+		n = (int(b[1]) + 2) * 4
+	default:
+		n = (int(b[1]) + 1) * 8
+	}
+	data, err := r.ReadSlice(n)
+	if err != nil {
+		return IPv6Extension{}, err
+	}
+	return IPv6Extension{Type: t, Data: data}, nil
+}
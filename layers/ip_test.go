@@ -0,0 +1,114 @@
+package layers
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestParseIPv4_Errors(t *testing.T) {
+	good := ipv4(IPProtocolUDP, nil, udp(nil))
+	withOpt := func(opt []byte) []byte { return ipv4(IPProtocolUDP, opt, nil) }
+	edit := func(b []byte, f func([]byte)) []byte {
+		b = bytes.Clone(b)
+		f(b)
+		return b
+	}
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"empty", nil, io.ErrUnexpectedEOF},
+		{"version", edit(good, func(b []byte) { b[0] = 0x65 }), ErrMalformed},
+		{"short header length", edit(good, func(b []byte) { b[0] = 0x44 }), ErrMalformed},
+		{"truncated header", good[:19], io.ErrUnexpectedEOF},
+		{"total length below header", edit(good, func(b []byte) { b[3] = 10 }), ErrMalformed},
+		{"option overrun", withOpt([]byte{0x94, 6, 0, 0}), ErrMalformed},
+		{"option length one", withOpt([]byte{0x94, 1, 0, 0}), ErrMalformed},
+		{"checksum", edit(good, func(b []byte) { b[8]-- }), ErrChecksum},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseIPv4(tt.data); !errors.Is(err, tt.want) {
+				t.Errorf("ParseIPv4() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseIPv4_Fragment(t *testing.T) {
+	data := ipv4(IPProtocolTCP, nil, []byte("fragment"))
+	data[6] = 0x20 // more fragments
+	data[10], data[11] = 0, 0
+	copy(data[10:], be16(checksum(data[:20])))
+
+	p, err := Decode(data, LayerIPv4)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if p.IPv4.Flags != IPv4MoreFragments || p.TCP != nil || string(p.Payload) != "fragment" {
+		t.Errorf("Decode(fragment) = %+v, IPv4 = %+v", p, p.IPv4)
+	}
+}
+
+func TestParseIPv4_TruncatedPayload(t *testing.T) {
+	data := ipv4(IPProtocolUDP, nil, []byte("0123456789"))
+	ip, err := ParseIPv4(data[:25])
+	if err != nil {
+		t.Fatalf("ParseIPv4() error = %v", err)
+	}
+	if ip.Length != 30 || string(ip.Payload) != "01234" {
+		t.Errorf("ParseIPv4() = Length %d, Payload %q", ip.Length, ip.Payload)
+	}
+}
+
+func TestParseIPv6_Fragment(t *testing.T) {
+	frag := []byte{byte(IPProtocolUDP), 0, 0, 1, 0xde, 0xad, 0xbe, 0xef} // offset 0, more
+	ip, err := ParseIPv6(ipv6(IPProtocolFragment, cat(frag, udp(nil))))
+	if err != nil {
+		t.Fatalf("ParseIPv6() error = %v", err)
+	}
+	if ip.Fragment == nil || *ip.Fragment != (IPv6Fragment{More: true, ID: 0xdeadbeef}) || ip.Protocol != IPProtocolUDP {
+		t.Errorf("ParseIPv6() Fragment = %+v, Protocol = %v", ip.Fragment, ip.Protocol)
+	}
+}
+
+func TestParseIPv6_Errors(t *testing.T) {
+	hop := []byte{byte(IPProtocolHopByHop), 0, 0, 0, 0, 0, 0, 0}
+	loop := bytes.Repeat([]byte{byte(IPProtocolDestOptions), 0, 0, 0, 0, 0, 0, 0}, maxIPv6Extensions+1)
+	good := ipv6(IPProtocolUDP, udp(nil))
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"version", append([]byte{0x40}, good[1:]...), ErrMalformed},
+		{"truncated header", good[:39], io.ErrUnexpectedEOF},
+		{"truncated extension", ipv6(IPProtocolRouting, []byte{6, 1, 0, 0, 0, 0, 0, 0}), io.ErrUnexpectedEOF},
+		{"late hop-by-hop", ipv6(IPProtocolDestOptions, cat([]byte{0, 0, 0, 0, 0, 0, 0, 0}, hop)), ErrMalformed},
+		{"too many extensions", ipv6(IPProtocolDestOptions, loop), ErrMalformed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseIPv6(tt.data); !errors.Is(err, tt.want) {
+				t.Errorf("ParseIPv6() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseIPv6_AH(t *testing.T) {
+	// An authentication header of 12 bytes: length (12/4)-2 = 1.
+	ah := []byte{byte(IPProtocolTCP), 1, 0, 0, 0, 0, 0, 1, 0, 0, 0, 7}
+	ip, err := ParseIPv6(ipv6(IPProtocolAH, cat(ah, tcp(TCPFlagACK, nil, nil))))
+	if err != nil {
+		t.Fatalf("ParseIPv6() error = %v", err)
+	}
+	if len(ip.Extensions) != 1 || len(ip.Extensions[0].Data) != 12 || ip.Protocol != IPProtocolTCP || len(ip.Payload) != 20 {
+		t.Errorf("ParseIPv6() = %+v", ip)
+	}
+}
@@ -0,0 +1,213 @@
+// Package layers decodes the link, network and transport layers of
+// captured packets: Ethernet II with VLAN tags, ARP, IPv4, IPv6 with its
+// extension headers, TCP and UDP.
+//
+// Each layer has a Parse function that reads its header from the start of a
+// buffer and returns the rest as the payload, cut to the length the header
+// declares. Decode chains them, choosing the next layer from the EtherType or
+// protocol number, and stops at the first layer it does not know. Addresses
+// are netip.Addr values and every byte slice, payloads included, aliases the
+// packet data.
+//
+// A header that runs past the end of the data yields io.ErrUnexpectedEOF. A
+// payload shorter than its header declares is not an error, since capture
+// files often truncate packets to a snap length.
+//
+// Example usage:
+//
+//	pkt, err := layers.Decode(data, layers.LayerEthernet)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	if pkt.IPv4 != nil && pkt.TCP != nil {
+//	    fmt.Println(pkt.IPv4.Src, pkt.TCP.SrcPort, "->", pkt.IPv4.Dst, pkt.TCP.DstPort)
+//	    fmt.Println(len(pkt.Payload), "bytes of payload")
+//	}
+package layers
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrMalformed is returned for headers with impossible lengths or
+	// values, such as an IPv4 header length below 20 bytes or an option
+	// running past its header.
+	ErrMalformed = errors.New("layers: malformed packet")
+
+	// ErrChecksum is returned when an IPv4 header checksum does not match.
+	ErrChecksum = errors.New("layers: bad checksum")
+)
+
+// LayerType identifies a layer for Decode.
+type LayerType uint8
+
+// Layer types.
+const (
+	LayerEthernet LayerType = iota + 1
+	LayerARP
+	LayerIPv4
+	LayerIPv6
+	LayerTCP
+	LayerUDP
+
+	// LayerIP selects IPv4 or IPv6 from the version in the first byte, for
+	// raw IP captures.
+	LayerIP
+)
+
+func (t LayerType) String() string {
+	switch t {
+	case LayerEthernet:
+		return "Ethernet"
+	case LayerARP:
+		return "ARP"
+	case LayerIPv4:
+		return "IPv4"
+	case LayerIPv6:
+		return "IPv6"
+	case LayerTCP:
+		return "TCP"
+	case LayerUDP:
+		return "UDP"
+	case LayerIP:
+		return "IP"
+	}
+	return fmt.Sprintf("LayerType(%d)", uint8(t))
+}
+
+// Packet holds the layers decoded from a packet. Layers that were not
+// present or not reached are nil.
+type Packet struct {
+	Ethernet *Ethernet
+	ARP      *ARP
+	IPv4     *IPv4
+	IPv6     *IPv6
+	TCP      *TCP
+	UDP      *UDP
+
+	// Payload is the payload of the last decoded layer.
+	Payload []byte
+}
+
+// Decode decodes data starting with the first layer and following each
+// layer's EtherType or protocol number. It stops without error at a layer it
+// does not decode, such as ICMP, or at the payload of an IP fragment other
+// than a complete datagram. On error the returned Packet holds the layers
+// decoded before the failing one.
+func Decode(data []byte, first LayerType) (*Packet, error) {
+	p := &Packet{Payload: data}
+	next := first
+	for next != 0 {
+		var err error
+		if next, err = p.decodeLayer(next, p.Payload); err != nil {
+			return p, err
+		}
+	}
+	return p, nil
+}
+
+// decodeLayer decodes one layer into p and returns the next one, or zero.
+func (p *Packet) decodeLayer(t LayerType, data []byte) (LayerType, error) {
+	switch t {
+	case LayerEthernet:
+		eth, err := ParseEthernet(data)
+		if err != nil {
+			return 0, err
+		}
+		p.Ethernet, p.Payload = eth, eth.Payload
+		return etherTypeLayer(eth.EtherType), nil
+	case LayerARP:
+		arp, err := ParseARP(data)
+		if err != nil {
+			return 0, err
+		}
+		p.ARP, p.Payload = arp, nil
+		return 0, nil
+	case LayerIP:
+		if len(data) == 0 {
+			return 0, fmt.Errorf("%w: empty IP packet", ErrMalformed)
+		}
+		switch data[0] >> 4 {
+		case 4:
+			return LayerIPv4, nil
+		case 6:
+			return LayerIPv6, nil
+		}
+		return 0, fmt.Errorf("%w: IP version %d", ErrMalformed, data[0]>>4)
+	case LayerIPv4:
+		ip, err := ParseIPv4(data)
+		if err != nil {
+			return 0, err
+		}
+		p.IPv4, p.Payload = ip, ip.Payload
+		if ip.Flags&IPv4MoreFragments != 0 || ip.FragOffset != 0 {
+			return 0, nil
+		}
+		return protocolLayer(ip.Protocol), nil
+	case LayerIPv6:
+		ip, err := ParseIPv6(data)
+		if err != nil {
+			return 0, err
+		}
+		p.IPv6, p.Payload = ip, ip.Payload
+		if f := ip.Fragment; f != nil && (f.More || f.Offset != 0) {
+			return 0, nil
+		}
+		return protocolLayer(ip.Protocol), nil
+	case LayerTCP:
+		tcp, err := ParseTCP(data)
+		if err != nil {
+			return 0, err
+		}
+		p.TCP, p.Payload = tcp, tcp.Payload
+		return 0, nil
+	case LayerUDP:
+		udp, err := ParseUDP(data)
+		if err != nil {
+			return 0, err
+		}
+		p.UDP, p.Payload = udp, udp.Payload
+		return 0, nil
+	}
+	return 0, fmt.Errorf("layers: unknown layer type %d", uint8(t))
+}
+
+func etherTypeLayer(t EtherType) LayerType {
+	switch t {
+	case EtherTypeIPv4:
+		return LayerIPv4
+	case EtherTypeIPv6:
+		return LayerIPv6
+	case EtherTypeARP:
+		return LayerARP
+	}
+	return 0
+}
+
+func protocolLayer(p IPProtocol) LayerType {
+	switch p {
+	case IPProtocolTCP:
+		return LayerTCP
+	case IPProtocolUDP:
+		return LayerUDP
+	}
+	return 0
+}
+
+// checksum returns the Internet checksum (RFC 1071) of b. It is zero when
+// computed over data that includes its own correct checksum.
+func checksum(b []byte) uint16 {
+	var sum uint32
+	for ; len(b) >= 2; b = b[2:] {
+		sum += uint32(b[0])<<8 | uint32(b[1])
+	}
+	if len(b) == 1 {
+		sum += uint32(b[0]) << 8
+	}
+	for sum > 0xffff {
+		sum = sum>>16 + sum&0xffff
+	}
+	return ^uint16(sum)
+}
@@ -0,0 +1,214 @@
+package layers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net/netip"
+	"testing"
+)
+
+func be16(v uint16) []byte { return binary.BigEndian.AppendUint16(nil, v) }
+func be32(v uint32) []byte { return binary.BigEndian.AppendUint32(nil, v) }
+
+func cat(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+// ethernet builds an Ethernet frame with the given VLAN IDs, each tagged
+// with 0x8100.
+func ethernet(t EtherType, payload []byte, vlans ...uint16) []byte {
+	b := cat([]byte{0, 1, 2, 3, 4, 5}, []byte{6, 7, 8, 9, 10, 11})
+	for _, id := range vlans {
+		b = cat(b, be16(uint16(EtherTypeVLAN)), be16(5<<13|id))
+	}
+	return cat(b, be16(uint16(t)), payload)
+}
+
+// ipv4 builds an IPv4 header with a correct checksum.
+func ipv4(proto IPProtocol, options, payload []byte) []byte {
+	hlen := 20 + len(options)
+	h := cat([]byte{0x40 | byte(hlen/4), 0}, be16(uint16(hlen+len(payload))),
+		be16(0x1234), be16(0x4000), []byte{64, byte(proto)}, be16(0),
+		[]byte{192, 0, 2, 1}, []byte{198, 51, 100, 2}, options)
+	binary.BigEndian.PutUint16(h[10:], checksum(h))
+	return cat(h, payload)
+}
+
+// ipv6 builds an IPv6 header followed by the extension headers and
+// payload in rest.
+func ipv6(next IPProtocol, rest []byte) []byte {
+	return cat(be32(6<<28|0xab<<20|0x12345), be16(uint16(len(rest))), []byte{byte(next), 32},
+		netip.MustParseAddr("2001:db8::1").AsSlice(), netip.MustParseAddr("2001:db8::2").AsSlice(), rest)
+}
+
+// tcp builds a TCP header with the given flags and options, which must be
+// padded to a multiple of 4 bytes.
+func tcp(flags TCPFlags, options, payload []byte) []byte {
+	hlen := 20 + len(options)
+	return cat(be16(40000), be16(443), be32(1000), be32(2000),
+		be16(uint16(hlen/4)<<12|uint16(flags)), be16(65535), be16(0), be16(0), options, payload)
+}
+
+func udp(payload []byte) []byte {
+	return cat(be16(5353), be16(53), be16(uint16(8+len(payload))), be16(0), payload)
+}
+
+func TestDecode_EthernetVLANIPv4TCP(t *testing.T) {
+	opts := []byte{
+		TCPOptionMSS, 4, 0x05, 0xb4,
+		TCPOptionNOP, TCPOptionWindowScale, 3, 7,
+		TCPOptionSACKPermitted, 2, TCPOptionNOP, TCPOptionNOP,
+		TCPOptionTimestamps, 10, 0, 0, 0, 1, 0, 0, 0, 2,
+		TCPOptionNOP, TCPOptionNOP,
+	}
+	ipOpts := []byte{IPv4OptionNOP, 0x94, 4, 0, 0, IPv4OptionEnd, 0, 0} // router alert
+	frame := ethernet(EtherTypeIPv4, ipv4(IPProtocolTCP, ipOpts, tcp(TCPFlagSYN|TCPFlagACK, opts, []byte("hi"))), 100, 200)
+	frame = append(frame, 0, 0, 0, 0) // link-layer padding
+
+	p, err := Decode(frame, LayerEthernet)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if p.Ethernet == nil || len(p.Ethernet.VLANs) != 2 || p.Ethernet.VLANs[1] != (VLAN{TPID: EtherTypeVLAN, Priority: 5, ID: 200}) {
+		t.Errorf("Ethernet = %+v", p.Ethernet)
+	}
+	ip := p.IPv4
+	if ip == nil || ip.Src != netip.MustParseAddr("192.0.2.1") || ip.Dst != netip.MustParseAddr("198.51.100.2") ||
+		ip.Flags != IPv4DontFragment || ip.TTL != 64 || ip.Protocol != IPProtocolTCP {
+		t.Fatalf("IPv4 = %+v", ip)
+	}
+	if len(ip.Options) != 1 || ip.Options[0].Type != 0x94 || !bytes.Equal(ip.Options[0].Data, []byte{0, 0}) {
+		t.Errorf("IPv4.Options = %+v", ip.Options)
+	}
+	tc := p.TCP
+	if tc == nil || tc.SrcPort != 40000 || tc.DstPort != 443 || tc.Seq != 1000 || tc.Ack != 2000 || tc.Flags != TCPFlagSYN|TCPFlagACK {
+		t.Fatalf("TCP = %+v", tc)
+	}
+	if mss, ok := tc.MSS(); !ok || mss != 1460 {
+		t.Errorf("MSS() = %d, %v, want 1460", mss, ok)
+	}
+	if ws, ok := tc.WindowScale(); !ok || ws != 7 {
+		t.Errorf("WindowScale() = %d, %v, want 7", ws, ok)
+	}
+	if !tc.SACKPermitted() {
+		t.Error("SACKPermitted() = false, want true")
+	}
+	if val, echo, ok := tc.Timestamps(); !ok || val != 1 || echo != 2 {
+		t.Errorf("Timestamps() = %d, %d, %v, want 1, 2", val, echo, ok)
+	}
+	if string(p.Payload) != "hi" {
+		t.Errorf("Payload = %q, want %q", p.Payload, "hi")
+	}
+	orig := bytes.Clone(frame)
+	for _, b := range [][]byte{p.Ethernet.Dst, p.Ethernet.Src, p.Ethernet.Payload, ip.Options[0].Data, ip.Payload, tc.Options[0].Data, p.Payload} {
+		_ = append(b, bytes.Repeat([]byte{0xEE}, 8)...)
+	}
+	if !bytes.Equal(frame, orig) {
+		t.Error("appending to a decoded field overwrote the frame")
+	}
+	if p, err := Decode(frame, LayerEthernet); err != nil || len(p.Ethernet.VLANs) != 2 || p.IPv4 == nil || len(p.IPv4.Options) != 1 {
+		t.Errorf("Decode() after appending = %+v, %v", p, err)
+	}
+	if p.IPv6 != nil || p.UDP != nil || p.ARP != nil {
+		t.Errorf("unexpected layers: %+v", p)
+	}
+}
+
+func TestDecode_IPv6UDP(t *testing.T) {
+	hopByHop := []byte{byte(IPProtocolDestOptions), 0, 1, 4, 0, 0, 0, 0}
+	destOpts := []byte{byte(IPProtocolUDP), 1, 1, 12, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	data := ipv6(IPProtocolHopByHop, cat(hopByHop, destOpts, udp([]byte("query")), []byte("pad")))
+	orig := bytes.Clone(data)
+
+	for _, first := range []LayerType{LayerIPv6, LayerIP} {
+		p, err := Decode(data, first)
+		if err != nil {
+			t.Fatalf("Decode(%v) error = %v", first, err)
+		}
+		ip := p.IPv6
+		if ip == nil || ip.TrafficClass != 0xab || ip.FlowLabel != 0x12345 || ip.HopLimit != 32 ||
+			ip.Src != netip.MustParseAddr("2001:db8::1") || ip.Protocol != IPProtocolUDP {
+			t.Fatalf("Decode(%v) IPv6 = %+v", first, ip)
+		}
+		if len(ip.Extensions) != 2 || ip.Extensions[0].Type != IPProtocolHopByHop || len(ip.Extensions[1].Data) != 16 {
+			t.Errorf("Decode(%v) IPv6.Extensions = %+v", first, ip.Extensions)
+		}
+		if p.UDP == nil || p.UDP.SrcPort != 5353 || p.UDP.DstPort != 53 || string(p.Payload) != "query" {
+			t.Errorf("Decode(%v) UDP = %+v, Payload = %q", first, p.UDP, p.Payload)
+		}
+		_ = append(p.UDP.Payload, 0xEE, 0xEE, 0xEE)
+		if !bytes.Equal(data, orig) {
+			t.Fatalf("Decode(%v): appending to UDP.Payload overwrote the packet", first)
+		}
+	}
+}
+
+func TestDecode_ARP(t *testing.T) {
+	arp := cat(be16(1), be16(uint16(EtherTypeIPv4)), []byte{6, 4}, be16(ARPReply),
+		[]byte{1, 2, 3, 4, 5, 6}, []byte{10, 0, 0, 1},
+		[]byte{6, 5, 4, 3, 2, 1}, []byte{10, 0, 0, 2})
+	p, err := Decode(ethernet(EtherTypeARP, arp), LayerEthernet)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	a := p.ARP
+	if a == nil || a.Operation != ARPReply || a.SenderAddr != netip.MustParseAddr("10.0.0.1") ||
+		a.TargetAddr != netip.MustParseAddr("10.0.0.2") || a.TargetHardware.String() != "06:05:04:03:02:01" {
+		t.Errorf("ARP = %+v", a)
+	}
+}
+
+func TestDecode_Stops(t *testing.T) {
+	icmp := []byte{8, 0, 0, 0}
+	tests := []struct {
+		name    string
+		data    []byte
+		first   LayerType
+		payload []byte
+	}{
+		{"unknown EtherType", ethernet(0x88cc, []byte("lldp")), LayerEthernet, []byte("lldp")},
+		{"802.3 length", ethernet(3, []byte("llcpad")), LayerEthernet, []byte("llc")},
+		{"ICMP", ipv4(IPProtocolICMP, nil, icmp), LayerIPv4, icmp},
+		{"IPv6 fragment", ipv6(IPProtocolFragment, cat([]byte{byte(IPProtocolUDP), 0, 0, 8, 0, 0, 0, 9}, []byte("rest"))), LayerIPv6, []byte("rest")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Decode(tt.data, tt.first)
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if p.TCP != nil || p.UDP != nil || !bytes.Equal(p.Payload, tt.payload) {
+				t.Errorf("Decode() = %+v, want payload %q", p, tt.payload)
+			}
+		})
+	}
+}
+
+func TestDecode_Errors(t *testing.T) {
+	good := ethernet(EtherTypeIPv4, ipv4(IPProtocolUDP, nil, udp(nil)))
+
+	badSum := bytes.Clone(good)
+	badSum[14+10] ^= 0xff
+	p, err := Decode(badSum, LayerEthernet)
+	if !errors.Is(err, ErrChecksum) || p.Ethernet == nil || p.IPv4 != nil {
+		t.Errorf("Decode(bad checksum) = %+v, %v, want Ethernet only and ErrChecksum", p, err)
+	}
+
+	if _, err := Decode(good[:14+20+4], LayerEthernet); err != io.ErrUnexpectedEOF {
+		t.Errorf("Decode(truncated UDP header) error = %v, want io.ErrUnexpectedEOF", err)
+	}
+	if _, err := Decode([]byte{0x50}, LayerIP); !errors.Is(err, ErrMalformed) {
+		t.Errorf("Decode(IP version 5) error = %v, want ErrMalformed", err)
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	// Example from RFC 1071, section 3.
+	b := []byte{0x00, 0x01, 0xf2, 0x03, 0xf4, 0xf5, 0xf6, 0xf7}
+	if got := checksum(b); got != ^uint16(0xddf2) {
+		t.Errorf("checksum() = 0x%04x, want 0x%04x", got, ^uint16(0xddf2))
+	}
+}
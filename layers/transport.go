@@ -0,0 +1,236 @@
+package layers
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/nemohan/wireread"
+)
+
+// TCPFlags are the control bits of a TCP header.
+type TCPFlags uint16
+
+// TCP flags.
+const (
+	TCPFlagFIN TCPFlags = 1 << iota
+	TCPFlagSYN
+	TCPFlagRST
+	TCPFlagPSH
+	TCPFlagACK
+	TCPFlagURG
+	TCPFlagECE
+	TCPFlagCWR
+	TCPFlagAE
+)
+
+var tcpFlagNames = []string{"FIN", "SYN", "RST", "PSH", "ACK", "URG", "ECE", "CWR", "AE"}
+
+// String returns the names of the set flags joined by '|', such as
+// "SYN|ACK".
+func (f TCPFlags) String() string {
+	var names []string
+	for i, name := range tcpFlagNames {
+		if f&(1<<i) != 0 {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, "|")
+}
+
+// TCP option kinds.
+const (
+	TCPOptionEnd           = 0
+	TCPOptionNOP           = 1
+	TCPOptionMSS           = 2
+	TCPOptionWindowScale   = 3
+	TCPOptionSACKPermitted = 4
+	TCPOptionSACK          = 5
+	TCPOptionTimestamps    = 8
+)
+
+// tcpOptionLens are the data lengths of the options with a fixed size.
+var tcpOptionLens = map[uint8]int{
+	TCPOptionMSS:           2,
+	TCPOptionWindowScale:   1,
+	TCPOptionSACKPermitted: 0,
+	TCPOptionTimestamps:    8,
+}
+
+// TCPOption is an option of a TCP header other than end of list and
+// no-operation. Data excludes the kind and length bytes.
+type TCPOption struct {
+	Kind uint8
+	Data []byte
+}
+
+// SACKBlock is a block of data acknowledged by a SACK option.
+type SACKBlock struct {
+	Left, Right uint32
+}
+
+// TCP is a TCP header.
+type TCP struct {
+	SrcPort, DstPort uint16
+	Seq, Ack         uint32
+	Flags            TCPFlags
+	Window           uint16
+	Checksum         uint16
+	Urgent           uint16
+	Options          []TCPOption
+
+	Payload []byte
+}
+
+// ParseTCP parses a TCP header. The options with a fixed size and SACK
+// blocks are checked to have the right length.
+func ParseTCP(data []byte) (*TCP, error) {
+	if len(data) < 13 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	hlen := int(data[12]>>4) * 4
+	if hlen < 20 {
+		return nil, fmt.Errorf("%w: TCP header length %d", ErrMalformed, hlen)
+	}
+	r := wireread.NewSafeReader(data)
+	hdr, err := r.ReadSlice(hlen)
+	if err != nil {
+		return nil, err
+	}
+
+	// The header is complete, so reading its fixed part cannot fail.
+	h := wireread.NewSafeReader(hdr)
+	tcp := &TCP{}
+	tcp.SrcPort, _ = h.ReadUint16BE()
+	tcp.DstPort, _ = h.ReadUint16BE()
+	tcp.Seq, _ = h.ReadUint32BE()
+	tcp.Ack, _ = h.ReadUint32BE()
+	flags, _ := h.ReadUint16BE()
+	tcp.Flags = TCPFlags(flags & 0x01ff)
+	tcp.Window, _ = h.ReadUint16BE()
+	tcp.Checksum, _ = h.ReadUint16BE()
+	tcp.Urgent, _ = h.ReadUint16BE()
+	if tcp.Options, err = readTCPOptions(h); err != nil {
+		return nil, err
+	}
+	tcp.Payload = r.Bytes()
+	return tcp, nil
+}
+
+func readTCPOptions(r *wireread.SafeReader) ([]TCPOption, error) {
+	var opts []TCPOption
+	for len(r.Bytes()) > 0 {
+		kind, _ := r.ReadByte()
+		switch kind {
+		case TCPOptionEnd:
+			return opts, nil
+		case TCPOptionNOP:
+			continue
+		}
+		n, err := r.ReadByte()
+		if err != nil || n < 2 || int(n)-2 > len(r.Bytes()) {
+			return nil, fmt.Errorf("%w: TCP option %d overruns the header", ErrMalformed, kind)
+		}
+		data, _ := r.ReadSlice(int(n) - 2)
+		want, fixed := tcpOptionLens[kind]
+		if fixed && len(data) != want || kind == TCPOptionSACK && (len(data) == 0 || len(data)%8 != 0) {
+			return nil, fmt.Errorf("%w: TCP option %d of length %d", ErrMalformed, kind, n)
+		}
+		opts = append(opts, TCPOption{Kind: kind, Data: data})
+	}
+	return opts, nil
+}
+
+// Option returns the data of the first option of the given kind.
+func (t *TCP) Option(kind uint8) ([]byte, bool) {
+	for _, o := range t.Options {
+		if o.Kind == kind {
+			return o.Data, true
+		}
+	}
+	return nil, false
+}
+
+// MSS returns the maximum segment size option.
+func (t *TCP) MSS() (uint16, bool) {
+	b, ok := t.Option(TCPOptionMSS)
+	if !ok {
+		return 0, false
+	}
+	return binary.BigEndian.Uint16(b), true
+}
+
+// WindowScale returns the shift count of the window scale option.
+func (t *TCP) WindowScale() (uint8, bool) {
+	b, ok := t.Option(TCPOptionWindowScale)
+	if !ok {
+		return 0, false
+	}
+	return b[0], true
+}
+
+// SACKPermitted reports whether the SACK-permitted option is present.
+func (t *TCP) SACKPermitted() bool {
+	_, ok := t.Option(TCPOptionSACKPermitted)
+	return ok
+}
+
+// SACK returns the blocks of the SACK option.
+func (t *TCP) SACK() []SACKBlock {
+	b, _ := t.Option(TCPOptionSACK)
+	var blocks []SACKBlock
+	for ; len(b) >= 8; b = b[8:] {
+		blocks = append(blocks, SACKBlock{
+			Left:  binary.BigEndian.Uint32(b),
+			Right: binary.BigEndian.Uint32(b[4:]),
+		})
+	}
+	return blocks
+}
+
+// Timestamps returns the values of the timestamps option.
+func (t *TCP) Timestamps() (val, echo uint32, ok bool) {
+	b, ok := t.Option(TCPOptionTimestamps)
+	if !ok {
+		return 0, 0, false
+	}
+	return binary.BigEndian.Uint32(b), binary.BigEndian.Uint32(b[4:]), true
+}
+
+// UDP is a UDP header.
+type UDP struct {
+	SrcPort, DstPort uint16
+
+	// Length covers the header and payload. It is zero in IPv6
+	// jumbograms, whose payload then runs to the end of the data.
+	Length   uint16
+	Checksum uint16
+
+	Payload []byte
+}
+
+// ParseUDP parses a UDP header.
+func ParseUDP(data []byte) (*UDP, error) {
+	r := wireread.NewSafeReader(data)
+	hdr, err := r.ReadSlice(8)
+	if err != nil {
+		return nil, err
+	}
+	h := wireread.NewSafeReader(hdr)
+	udp := &UDP{}
+	udp.SrcPort, _ = h.ReadUint16BE()
+	udp.DstPort, _ = h.ReadUint16BE()
+	udp.Length, _ = h.ReadUint16BE()
+	udp.Checksum, _ = h.ReadUint16BE()
+	udp.Payload = r.Bytes()
+	switch {
+	case udp.Length == 0:
+	case udp.Length < 8:
+		return nil, fmt.Errorf("%w: UDP length %d", ErrMalformed, udp.Length)
+	default:
+		n := min(len(udp.Payload), int(udp.Length)-8)
+		udp.Payload = udp.Payload[:n:n]
+	}
+	return udp, nil
+}
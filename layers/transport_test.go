@@ -0,0 +1,96 @@
+package layers
+
+import (
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestParseTCP_SACK(t *testing.T) {
+	opts := []byte{TCPOptionNOP, TCPOptionNOP, TCPOptionSACK, 18,
+		0, 0, 0, 10, 0, 0, 0, 20,
+		0, 0, 0, 30, 0, 0, 0, 40}
+	tc, err := ParseTCP(tcp(TCPFlagACK, opts, []byte("x")))
+	if err != nil {
+		t.Fatalf("ParseTCP() error = %v", err)
+	}
+	blocks := tc.SACK()
+	if len(blocks) != 2 || blocks[0] != (SACKBlock{10, 20}) || blocks[1] != (SACKBlock{30, 40}) {
+		t.Errorf("SACK() = %+v", blocks)
+	}
+	if _, ok := tc.MSS(); ok {
+		t.Error("MSS() ok = true without the option")
+	}
+	if string(tc.Payload) != "x" {
+		t.Errorf("Payload = %q, want %q", tc.Payload, "x")
+	}
+}
+
+func TestParseTCP_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"truncated", tcp(0, nil, nil)[:19], io.ErrUnexpectedEOF},
+		{"truncated options", tcp(0, []byte{1, 1, 1, 1}, nil)[:22], io.ErrUnexpectedEOF},
+		{"short data offset", append(tcp(0, nil, nil)[:12], 0x40, 0, 0, 0, 0, 0, 0, 0), ErrMalformed},
+		{"option overrun", tcp(0, []byte{TCPOptionMSS, 8, 0, 0}, nil), ErrMalformed},
+		{"MSS length", tcp(0, []byte{TCPOptionMSS, 3, 0, 0}, nil), ErrMalformed},
+		{"SACK length", tcp(0, []byte{TCPOptionSACK, 6, 0, 0, 0, 0, 0, 0}, nil), ErrMalformed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseTCP(tt.data); !errors.Is(err, tt.want) {
+				t.Errorf("ParseTCP() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestTCPFlags_String(t *testing.T) {
+	tests := []struct {
+		flags TCPFlags
+		want  string
+	}{
+		{0, ""},
+		{TCPFlagSYN, "SYN"},
+		{TCPFlagSYN | TCPFlagACK, "SYN|ACK"},
+		{TCPFlagFIN | TCPFlagPSH | TCPFlagACK | TCPFlagAE, "FIN|PSH|ACK|AE"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.flags.String(); got != tt.want {
+			t.Errorf("TCPFlags(%d).String() = %q, want %q", uint16(tt.flags), got, tt.want)
+		}
+	}
+}
+
+func TestParseUDP(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		payload string
+		err     error
+	}{
+		{"exact", udp([]byte("abc")), "abc", nil},
+		{"padded", append(udp([]byte("abc")), 0, 0), "abc", nil},
+		{"truncated payload", udp([]byte("abcdef"))[:10], "ab", nil},
+		{"jumbogram", append(cat(be16(1), be16(2), be16(0), be16(0)), "big"...), "big", nil},
+		{"short length", cat(be16(1), be16(2), be16(7), be16(0)), "", ErrMalformed},
+		{"truncated header", udp(nil)[:7], "", io.ErrUnexpectedEOF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := ParseUDP(tt.data)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("ParseUDP() error = %v, want %v", err, tt.err)
+			}
+			if err == nil && string(u.Payload) != tt.payload {
+				t.Errorf("ParseUDP() Payload = %q, want %q", u.Payload, tt.payload)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package mqtt
+
+import (
+	"bufio"
+	"io"
+	"slices"
+)
+
+// Decoder reads control packets from a stream.
+//
+// The protocol level is taken from the first CONNECT packet seen, which suits
+// the server side of a connection. Clients, which never receive CONNECT,
+// should call SetVersion with the level they connected with.
+type Decoder struct {
+	r       io.Reader
+	br      io.ByteReader
+	version byte
+	off     int64
+	maxSize int
+	buf     []byte
+}
+
+// NewDecoder creates a Decoder reading from r. If r does not implement
+// io.ByteReader it is wrapped in a bufio.Reader.
+func NewDecoder(r io.Reader) *Decoder {
+	br, ok := r.(io.ByteReader)
+	if !ok {
+		b := bufio.NewReader(r)
+		r, br = b, b
+	}
+	return &Decoder{r: r, br: br, maxSize: MaxRemainingLength + 5}
+}
+
+// SetVersion sets the protocol level used to decode packets.
+func (d *Decoder) SetVersion(v byte) {
+	d.version = v
+}
+
+// Version returns the protocol level in use, or zero if it is not yet known.
+func (d *Decoder) Version() byte {
+	return d.version
+}
+
+// SetMaxPacketSize limits the total size of a packet, including the fixed
+// header. Larger packets are rejected with ErrPacketTooLarge before their
+// body is read.
+func (d *Decoder) SetMaxPacketSize(n int) {
+	d.maxSize = n
+}
+
+// Offset returns the number of bytes consumed from the stream.
+func (d *Decoder) Offset() int64 {
+	return d.off
+}
+
+// bodyChunk bounds how far readBody allocates ahead of the bytes that have
+// arrived, so that a fixed header announcing a large packet on a stream that
+// then stalls or ends does not cost the whole remaining length.
+const bodyChunk = 64 << 10
+
+// readBody reads a packet body of n bytes into the decoder's buffer, growing
+// it as the body is read, at most doubling the part read so far each time.
+func (d *Decoder) readBody(n int) ([]byte, error) {
+	body := d.buf[:0]
+	for len(body) < n {
+		m := min(n-len(body), max(bodyChunk, len(body)))
+		end := len(body)
+		body = slices.Grow(body, m)[:end+m]
+		if _, err := io.ReadFull(d.r, body[end:]); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+	}
+	return body, nil
+}
+
+// Decode reads the next packet. It returns io.EOF when the stream ends
+// cleanly between packets and io.ErrUnexpectedEOF when it ends inside one.
+// The returned packet does not alias the decoder's internal buffer.
+func (d *Decoder) Decode() (Packet, error) {
+	start := d.off
+	first, err := d.br.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	length, n, err := ReadVarByteInt(d.br)
+	d.off += int64(1 + n)
+	if err == ErrMalformed {
+		return nil, &ParseError{Offset: d.off - 1, Type: PacketType(first >> 4), Msg: "remaining length exceeds four bytes"}
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, err
+	}
+	if 1+n+int(length) > d.maxSize {
+		return nil, ErrPacketTooLarge
+	}
+
+	if d.buf, err = d.readBody(int(length)); err != nil {
+		return nil, err
+	}
+	body := d.buf
+	base := d.off
+	d.off += int64(length)
+
+	hdr := FixedHeader{Type: PacketType(first >> 4), Flags: first & 0x0F, RemainingLength: int(length)}
+	pkt, err := parseBody(hdr, body, start, base, d.version)
+	if err != nil {
+		return nil, err
+	}
+	if c, ok := pkt.(*Connect); ok && d.version == 0 {
+		d.version = c.ProtocolLevel
+	}
+	return pkt, nil
+}
@@ -0,0 +1,107 @@
+package mqtt
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"runtime"
+	"testing"
+	"testing/iotest"
+)
+
+func TestDecoder_Stream(t *testing.T) {
+	stream := []byte{
+		0x10, 0x0F, 0x00, 0x04, 'M', 'Q', 'T', 'T', 0x05, 0x02, 0x00, 0x3C, 0x00, 0x00, 0x02, 'i', 'd', // CONNECT v5
+		0x30, 0x07, 0x00, 0x01, 't', 0x00, 'a', 'b', 'c', // PUBLISH QoS 0 with empty properties
+		0xC0, 0x00, // PINGREQ
+	}
+
+	dec := NewDecoder(iotest.OneByteReader(bytes.NewReader(stream)))
+	var types []PacketType
+	for {
+		pkt, err := dec.Decode()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Decode() error = %v", err)
+		}
+		types = append(types, pkt.Type())
+		if pub, ok := pkt.(*Publish); ok && string(pub.Payload) != "abc" {
+			t.Errorf("Publish payload = %q, want abc", pub.Payload)
+		}
+	}
+
+	if len(types) != 3 || types[0] != CONNECT || types[1] != PUBLISH || types[2] != PINGREQ {
+		t.Errorf("decoded %v", types)
+	}
+	if dec.Version() != Version5 {
+		t.Errorf("Version() = %d, want 5", dec.Version())
+	}
+	if dec.Offset() != int64(len(stream)) {
+		t.Errorf("Offset() = %d, want %d", dec.Offset(), len(stream))
+	}
+}
+
+func TestDecoder_ErrorOffsetIsAbsolute(t *testing.T) {
+	stream := []byte{
+		0xC0, 0x00, // PINGREQ
+		0x40, 0x02, 0x00, 0x00, // PUBACK with packet id 0
+	}
+	dec := NewDecoder(bytes.NewReader(stream))
+	dec.SetVersion(Version311)
+
+	if _, err := dec.Decode(); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	_, err := dec.Decode()
+	var pe *ParseError
+	if !errors.As(err, &pe) || pe.Offset != 4 || pe.Type != PUBACK {
+		t.Errorf("Decode() error = %v, want ParseError at offset 4", err)
+	}
+}
+
+func TestDecoder_Truncated(t *testing.T) {
+	dec := NewDecoder(bytes.NewReader([]byte{0x30, 0x05, 0x00, 0x01}))
+	if _, err := dec.Decode(); err != io.ErrUnexpectedEOF {
+		t.Errorf("Decode() error = %v, want io.ErrUnexpectedEOF", err)
+	}
+}
+
+func TestDecoder_TruncatedLargePacket(t *testing.T) {
+	// A fixed header announcing the largest remaining length must not
+	// allocate it before the body arrives.
+	stream := []byte{0x30, 0xFF, 0xFF, 0xFF, 0x7F}
+	stream = append(stream, make([]byte, 10)...)
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	_, err := NewDecoder(bytes.NewReader(stream)).Decode()
+	runtime.ReadMemStats(&after)
+	if err != io.ErrUnexpectedEOF {
+		t.Errorf("Decode() error = %v, want io.ErrUnexpectedEOF", err)
+	}
+	if n := after.TotalAlloc - before.TotalAlloc; n > 1<<20 {
+		t.Errorf("Decode() allocated %d bytes for a truncated packet", n)
+	}
+
+	// A body spanning several chunks is still read whole.
+	payload := bytes.Repeat([]byte{'p'}, 300<<10)
+	body := append([]byte{0x00, 0x01, 't'}, payload...)
+	stream = []byte{0x30, byte(len(body)) | 0x80, byte(len(body)>>7) | 0x80, byte(len(body) >> 14)}
+	stream = append(stream, body...)
+	dec := NewDecoder(bytes.NewReader(stream))
+	dec.SetVersion(Version311)
+	pkt, err := dec.Decode()
+	if pub, ok := pkt.(*Publish); err != nil || !ok || !bytes.Equal(pub.Payload, payload) {
+		t.Errorf("Decode() = %T, %v; want the %d byte PUBLISH", pkt, err, len(payload))
+	}
+}
+
+func TestDecoder_MaxPacketSize(t *testing.T) {
+	dec := NewDecoder(bytes.NewReader([]byte{0x30, 0x80, 0x01}))
+	dec.SetMaxPacketSize(64)
+	if _, err := dec.Decode(); err != ErrPacketTooLarge {
+		t.Errorf("Decode() error = %v, want ErrPacketTooLarge", err)
+	}
+}
@@ -0,0 +1,141 @@
+// Package mqtt decodes MQTT 3.1.1 and 5.0 control packets.
+//
+// Packets are read from an io.Reader by a streaming Decoder, or parsed from
+// a buffer with Parse. Malformed input is reported as a *ParseError carrying
+// the absolute stream offset of the offending byte.
+//
+// Example usage:
+//
+//	dec := mqtt.NewDecoder(conn)
+//	for {
+//	    pkt, err := dec.Decode()
+//	    if err != nil {
+//	        log.Fatal(err)
+//	    }
+//	    if pub, ok := pkt.(*mqtt.Publish); ok {
+//	        fmt.Println(pub.Topic, string(pub.Payload))
+//	    }
+//	}
+package mqtt
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Protocol levels carried in the CONNECT packet.
+const (
+	Version31  byte = 3
+	Version311 byte = 4
+	Version5   byte = 5
+)
+
+// MaxRemainingLength is the largest value a variable byte integer can hold.
+const MaxRemainingLength = 268435455
+
+var (
+	// ErrMalformed is matched by every *ParseError.
+	ErrMalformed = errors.New("mqtt: malformed packet")
+
+	// ErrPacketTooLarge is returned when a packet exceeds the decoder's
+	// maximum packet size.
+	ErrPacketTooLarge = errors.New("mqtt: packet too large")
+)
+
+// ParseError describes malformed input at a specific byte offset.
+type ParseError struct {
+	// Offset is the position of the offending byte counted from the start
+	// of the stream (Decoder) or buffer (Parse).
+	Offset int64
+	// Type is the type of the packet being decoded, if known.
+	Type PacketType
+	Msg  string
+}
+
+func (e *ParseError) Error() string {
+	if e.Type == 0 {
+		return fmt.Sprintf("mqtt: offset %d: %s", e.Offset, e.Msg)
+	}
+	return fmt.Sprintf("mqtt: %s at offset %d: %s", e.Type, e.Offset, e.Msg)
+}
+
+// Is reports whether target is ErrMalformed.
+func (e *ParseError) Is(target error) bool {
+	return target == ErrMalformed
+}
+
+// PacketType is the control packet type from the fixed header.
+type PacketType byte
+
+// Control packet types.
+const (
+	CONNECT     PacketType = 1
+	CONNACK     PacketType = 2
+	PUBLISH     PacketType = 3
+	PUBACK      PacketType = 4
+	PUBREC      PacketType = 5
+	PUBREL      PacketType = 6
+	PUBCOMP     PacketType = 7
+	SUBSCRIBE   PacketType = 8
+	SUBACK      PacketType = 9
+	UNSUBSCRIBE PacketType = 10
+	UNSUBACK    PacketType = 11
+	PINGREQ     PacketType = 12
+	PINGRESP    PacketType = 13
+	DISCONNECT  PacketType = 14
+	AUTH        PacketType = 15
+)
+
+var packetTypeNames = [...]string{
+	CONNECT:     "CONNECT",
+	CONNACK:     "CONNACK",
+	PUBLISH:     "PUBLISH",
+	PUBACK:      "PUBACK",
+	PUBREC:      "PUBREC",
+	PUBREL:      "PUBREL",
+	PUBCOMP:     "PUBCOMP",
+	SUBSCRIBE:   "SUBSCRIBE",
+	SUBACK:      "SUBACK",
+	UNSUBSCRIBE: "UNSUBSCRIBE",
+	UNSUBACK:    "UNSUBACK",
+	PINGREQ:     "PINGREQ",
+	PINGRESP:    "PINGRESP",
+	DISCONNECT:  "DISCONNECT",
+	AUTH:        "AUTH",
+}
+
+func (t PacketType) String() string {
+	if int(t) < len(packetTypeNames) && packetTypeNames[t] != "" {
+		return packetTypeNames[t]
+	}
+	return fmt.Sprintf("packet(%d)", byte(t))
+}
+
+// FixedHeader is the first part of every control packet.
+type FixedHeader struct {
+	Type            PacketType
+	Flags           byte
+	RemainingLength int
+}
+
+// ReadVarByteInt reads an MQTT variable byte integer of at most four bytes
+// and returns the value with the number of bytes consumed. A fifth
+// continuation byte is reported as ErrMalformed.
+func ReadVarByteInt(r io.ByteReader) (uint32, int, error) {
+	var v uint32
+	for i := 0; i < 4; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			if i > 0 && err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, i, err
+		}
+		v |= uint32(b&0x7F) << (7 * i)
+		if b&0x80 == 0 {
+			return v, i + 1, nil
+		}
+	}
+	return 0, 4, ErrMalformed
+}
@@ -0,0 +1,207 @@
+package mqtt
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestReadVarByteInt(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want uint32
+		n    int
+	}{
+		{"zero", []byte{0x00}, 0, 1},
+		{"127", []byte{0x7F}, 127, 1},
+		{"128", []byte{0x80, 0x01}, 128, 2},
+		{"16383", []byte{0xFF, 0x7F}, 16383, 2},
+		{"max", []byte{0xFF, 0xFF, 0xFF, 0x7F}, MaxRemainingLength, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, n, err := ReadVarByteInt(bytes.NewReader(tt.data))
+			if err != nil || got != tt.want || n != tt.n {
+				t.Errorf("ReadVarByteInt() = %d, %d, %v, want %d, %d, nil", got, n, err, tt.want, tt.n)
+			}
+		})
+	}
+
+	if _, _, err := ReadVarByteInt(bytes.NewReader([]byte{0x80, 0x80, 0x80, 0x80, 0x01})); err != ErrMalformed {
+		t.Errorf("ReadVarByteInt(5 bytes) error = %v, want ErrMalformed", err)
+	}
+	if _, _, err := ReadVarByteInt(bytes.NewReader([]byte{0x80})); err != io.ErrUnexpectedEOF {
+		t.Errorf("ReadVarByteInt(truncated) error = %v, want io.ErrUnexpectedEOF", err)
+	}
+}
+
+func TestParse_Connect311(t *testing.T) {
+	data := []byte{
+		0x10, 0x1C,
+		0x00, 0x04, 'M', 'Q', 'T', 'T', 0x04,
+		0xEE,       // username, password, will retain, will QoS 1, will, clean
+		0x00, 0x3C, // keep alive 60
+		0x00, 0x02, 'c', '1',
+		0x00, 0x01, 'w', 0x00, 0x02, 'b', 'y',
+		0x00, 0x01, 'u',
+		0x00, 0x02, 'p', 'w',
+	}
+	pkt, n, err := Parse(data, 0)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if n != len(data) {
+		t.Errorf("Parse() consumed %d bytes, want %d", n, len(data))
+	}
+	c, ok := pkt.(*Connect)
+	if !ok {
+		t.Fatalf("Parse() = %T, want *Connect", pkt)
+	}
+	if c.ProtocolLevel != Version311 || !c.CleanStart || c.KeepAlive != 60 || c.ClientID != "c1" {
+		t.Errorf("Connect = %+v", c)
+	}
+	if c.Will == nil || c.Will.QoS != 1 || !c.Will.Retain || c.Will.Topic != "w" || string(c.Will.Payload) != "by" {
+		t.Errorf("Will = %+v", c.Will)
+	}
+	if c.Username != "u" || string(c.Password) != "pw" {
+		t.Errorf("credentials = %q, %q", c.Username, c.Password)
+	}
+}
+
+func TestParse_Connect5(t *testing.T) {
+	data := []byte{
+		0x10, 0x18,
+		0x00, 0x04, 'M', 'Q', 'T', 'T', 0x05,
+		0x02, 0x00, 0x0A,
+		0x08,                         // properties
+		0x11, 0x00, 0x00, 0x00, 0x78, // session expiry 120
+		0x21, 0x00, 0x10, // receive maximum 16
+		0x00, 0x03, 'a', 'b', 'c',
+	}
+	pkt, _, err := Parse(data, 0)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	c := pkt.(*Connect)
+	if p, ok := c.Properties.Get(SessionExpiryInterval); !ok || p.Int != 120 {
+		t.Errorf("SessionExpiryInterval = %+v, %v", p, ok)
+	}
+	if p, ok := c.Properties.Get(ReceiveMaximum); !ok || p.Int != 16 {
+		t.Errorf("ReceiveMaximum = %+v, %v", p, ok)
+	}
+	if c.ClientID != "abc" {
+		t.Errorf("ClientID = %q", c.ClientID)
+	}
+}
+
+func TestParse_Publish5(t *testing.T) {
+	data := []byte{
+		0x3B, 0x16, // PUBLISH, DUP, QoS 1, retain
+		0x00, 0x03, 'a', '/', 'b',
+		0x00, 0x07,
+		0x0C,             // properties
+		0x23, 0x00, 0x02, // topic alias
+		0x26, 0x00, 0x01, 'k', 0x00, 0x01, 'v', // user property
+		0x0B, 0x05, // subscription identifier
+		'h', 'i',
+	}
+	pkt, _, err := Parse(data, Version5)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	p := pkt.(*Publish)
+	if !p.Dup || p.QoS != 1 || !p.Retain || p.Topic != "a/b" || p.PacketID != 7 || string(p.Payload) != "hi" {
+		t.Errorf("Publish = %+v", p)
+	}
+	if up := p.Properties.UserProperties(); len(up) != 1 || up[0] != [2]string{"k", "v"} {
+		t.Errorf("UserProperties() = %v", up)
+	}
+}
+
+func TestParse_OtherPackets(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		version byte
+		want    PacketType
+	}{
+		{"connack", []byte{0x20, 0x02, 0x01, 0x00}, Version311, CONNACK},
+		{"puback 311", []byte{0x40, 0x02, 0x00, 0x01}, Version311, PUBACK},
+		{"puback 5 short", []byte{0x40, 0x02, 0x00, 0x01}, Version5, PUBACK},
+		{"pubrec 5", []byte{0x50, 0x04, 0x00, 0x01, 0x10, 0x00}, Version5, PUBREC},
+		{"pubrel", []byte{0x62, 0x02, 0x00, 0x01}, Version311, PUBREL},
+		{"pubcomp", []byte{0x70, 0x02, 0x00, 0x01}, Version311, PUBCOMP},
+		{"subscribe", []byte{0x82, 0x06, 0x00, 0x01, 0x00, 0x01, '#', 0x01}, Version311, SUBSCRIBE},
+		{"suback", []byte{0x90, 0x03, 0x00, 0x01, 0x80}, Version311, SUBACK},
+		{"unsubscribe", []byte{0xA2, 0x05, 0x00, 0x01, 0x00, 0x01, 'x'}, Version311, UNSUBSCRIBE},
+		{"unsuback 311", []byte{0xB0, 0x02, 0x00, 0x01}, Version311, UNSUBACK},
+		{"unsuback 5", []byte{0xB0, 0x04, 0x00, 0x01, 0x00, 0x11}, Version5, UNSUBACK},
+		{"pingreq", []byte{0xC0, 0x00}, Version311, PINGREQ},
+		{"pingresp", []byte{0xD0, 0x00}, Version311, PINGRESP},
+		{"disconnect", []byte{0xE0, 0x00}, Version311, DISCONNECT},
+		{"disconnect 5", []byte{0xE0, 0x01, 0x8E}, Version5, DISCONNECT},
+		{"auth", []byte{0xF0, 0x02, 0x18, 0x00}, Version5, AUTH},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pkt, n, err := Parse(tt.data, tt.version)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if pkt.Type() != tt.want || n != len(tt.data) {
+				t.Errorf("Parse() = %v, %d, want %v, %d", pkt.Type(), n, tt.want, len(tt.data))
+			}
+		})
+	}
+}
+
+func TestParse_Malformed(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		version byte
+		offset  int64
+	}{
+		{"reserved type", []byte{0x00, 0x00}, Version311, 0},
+		{"bad pubrel flags", []byte{0x60, 0x02, 0x00, 0x01}, Version311, 0},
+		{"publish QoS 3", []byte{0x36, 0x03, 0x00, 0x01, 'a'}, Version311, 0},
+		{"zero packet id", []byte{0x40, 0x02, 0x00, 0x00}, Version311, 2},
+		{"wildcard topic", []byte{0x30, 0x05, 0x00, 0x03, 'a', '/', '+'}, Version311, 6},
+		{"invalid utf8", []byte{0x30, 0x04, 0x00, 0x02, 0xC3, 0x28}, Version311, 4},
+		{"null char", []byte{0x30, 0x05, 0x00, 0x03, 'a', 0x00, 'b'}, Version311, 5},
+		{"string overrun", []byte{0x30, 0x03, 0x00, 0x05, 'a'}, Version311, 4},
+		{"trailing bytes", []byte{0xC0, 0x01, 0x00}, Version311, 2},
+		{"subscribe reserved bits", []byte{0x82, 0x06, 0x00, 0x01, 0x00, 0x01, 'a', 0x04}, Version311, 7},
+		{"duplicate property", []byte{0x40, 0x0C, 0x00, 0x01, 0x00, 0x08, 0x1F, 0x00, 0x01, 'a', 0x1F, 0x00, 0x01, 'b'}, Version5, 10},
+		{"property not allowed", []byte{0x40, 0x07, 0x00, 0x01, 0x00, 0x03, 0x23, 0x00, 0x01}, Version5, 6},
+		{"auth in 311", []byte{0xF0, 0x00}, Version311, 0},
+		{"bad protocol level", []byte{0x10, 0x0A, 0x00, 0x04, 'M', 'Q', 'T', 'T', 0x06, 0x00, 0x00, 0x00}, 0, 8},
+		{"length too long", []byte{0x30, 0xFF, 0xFF, 0xFF, 0xFF, 0x01}, Version311, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := Parse(tt.data, tt.version)
+			var pe *ParseError
+			if !errors.As(err, &pe) {
+				t.Fatalf("Parse() error = %v, want *ParseError", err)
+			}
+			if !errors.Is(err, ErrMalformed) {
+				t.Errorf("Parse() error does not match ErrMalformed")
+			}
+			if pe.Offset != tt.offset {
+				t.Errorf("ParseError.Offset = %d, want %d (%v)", pe.Offset, tt.offset, err)
+			}
+		})
+	}
+}
+
+func TestParse_Incomplete(t *testing.T) {
+	if _, _, err := Parse([]byte{0x30, 0x05, 0x00}, Version311); err != io.ErrUnexpectedEOF {
+		t.Errorf("Parse() error = %v, want io.ErrUnexpectedEOF", err)
+	}
+}
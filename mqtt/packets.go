@@ -0,0 +1,619 @@
+package mqtt
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nemohan/wireread"
+)
+
+// Packet is a decoded control packet.
+type Packet interface {
+	Type() PacketType
+}
+
+// Connect is a CONNECT packet.
+type Connect struct {
+	ProtocolName  string
+	ProtocolLevel byte
+	CleanStart    bool
+	KeepAlive     uint16
+	Properties    Properties
+	ClientID      string
+	// Will is nil when the will flag is not set.
+	Will        *Will
+	HasUsername bool
+	Username    string
+	HasPassword bool
+	Password    []byte
+}
+
+// Will is the will message carried by a CONNECT packet.
+type Will struct {
+	QoS        byte
+	Retain     bool
+	Properties Properties
+	Topic      string
+	Payload    []byte
+}
+
+// Connack is a CONNACK packet. ReasonCode holds the return code in MQTT 3.1.1.
+type Connack struct {
+	SessionPresent bool
+	ReasonCode     byte
+	Properties     Properties
+}
+
+// Publish is a PUBLISH packet. PacketID is zero for QoS 0.
+type Publish struct {
+	Dup        bool
+	QoS        byte
+	Retain     bool
+	Topic      string
+	PacketID   uint16
+	Properties Properties
+	Payload    []byte
+}
+
+// Ack is a PUBACK, PUBREC, PUBREL or PUBCOMP packet, distinguished by Kind.
+type Ack struct {
+	Kind       PacketType
+	PacketID   uint16
+	ReasonCode byte
+	Properties Properties
+}
+
+// Subscription is a topic filter with its subscription options.
+type Subscription struct {
+	Topic             string
+	QoS               byte
+	NoLocal           bool
+	RetainAsPublished bool
+	RetainHandling    byte
+}
+
+// Subscribe is a SUBSCRIBE packet.
+type Subscribe struct {
+	PacketID      uint16
+	Properties    Properties
+	Subscriptions []Subscription
+}
+
+// Suback is a SUBACK packet.
+type Suback struct {
+	PacketID    uint16
+	Properties  Properties
+	ReasonCodes []byte
+}
+
+// Unsubscribe is an UNSUBSCRIBE packet.
+type Unsubscribe struct {
+	PacketID   uint16
+	Properties Properties
+	Topics     []string
+}
+
+// Unsuback is an UNSUBACK packet. ReasonCodes is empty in MQTT 3.1.1.
+type Unsuback struct {
+	PacketID    uint16
+	Properties  Properties
+	ReasonCodes []byte
+}
+
+// Pingreq is a PINGREQ packet.
+type Pingreq struct{}
+
+// Pingresp is a PINGRESP packet.
+type Pingresp struct{}
+
+// Disconnect is a DISCONNECT packet.
+type Disconnect struct {
+	ReasonCode byte
+	Properties Properties
+}
+
+// Auth is an MQTT 5 AUTH packet.
+type Auth struct {
+	ReasonCode byte
+	Properties Properties
+}
+
+func (*Connect) Type() PacketType     { return CONNECT }
+func (*Connack) Type() PacketType     { return CONNACK }
+func (*Publish) Type() PacketType     { return PUBLISH }
+func (a *Ack) Type() PacketType       { return a.Kind }
+func (*Subscribe) Type() PacketType   { return SUBSCRIBE }
+func (*Suback) Type() PacketType      { return SUBACK }
+func (*Unsubscribe) Type() PacketType { return UNSUBSCRIBE }
+func (*Unsuback) Type() PacketType    { return UNSUBACK }
+func (*Pingreq) Type() PacketType     { return PINGREQ }
+func (*Pingresp) Type() PacketType    { return PINGRESP }
+func (*Disconnect) Type() PacketType  { return DISCONNECT }
+func (*Auth) Type() PacketType        { return AUTH }
+
+// Parse decodes a single packet from the beginning of data using the given
+// protocol level and returns it with the number of bytes consumed. It
+// returns io.ErrUnexpectedEOF when data holds only part of a packet. When
+// version is zero it is taken from the packet if it is a CONNECT and
+// defaults to MQTT 3.1.1 otherwise.
+func Parse(data []byte, version byte) (Packet, int, error) {
+	r := wireread.NewSafeReader(data)
+	first, err := r.ReadByte()
+	if err != nil {
+		return nil, 0, err
+	}
+	length, n, err := ReadVarByteInt(r)
+	if err == ErrMalformed {
+		return nil, 0, &ParseError{Offset: 4, Type: PacketType(first >> 4), Msg: "remaining length exceeds four bytes"}
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	hdr := FixedHeader{Type: PacketType(first >> 4), Flags: first & 0x0F, RemainingLength: int(length)}
+	if len(r.Bytes()) < hdr.RemainingLength {
+		return nil, 0, io.ErrUnexpectedEOF
+	}
+	pkt, err := parseBody(hdr, r.Bytes()[:hdr.RemainingLength], 0, int64(1+n), version)
+	if err != nil {
+		return nil, 0, err
+	}
+	return pkt, 1 + n + hdr.RemainingLength, nil
+}
+
+// parser reads the variable header and payload of one packet. All reads are
+// bounded by the remaining length, so running out of data is malformed.
+type parser struct {
+	r       *wireread.SafeReader
+	body    []byte
+	base    int64
+	version byte
+	typ     PacketType
+}
+
+func (p *parser) pos() int64 {
+	return p.base + int64(len(p.body)-len(p.r.Bytes()))
+}
+
+func (p *parser) errAt(off int64, format string, args ...interface{}) error {
+	return &ParseError{Offset: off, Type: p.typ, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) truncated(what string) error {
+	return p.errAt(p.pos(), "%s exceeds remaining length", what)
+}
+
+func (p *parser) byte() (byte, error) {
+	b, err := p.r.ReadByte()
+	if err != nil {
+		return 0, p.truncated("byte")
+	}
+	return b, nil
+}
+
+func (p *parser) uint16() (uint16, error) {
+	v, err := p.r.ReadUint16BE()
+	if err != nil {
+		return 0, p.truncated("two byte integer")
+	}
+	return v, nil
+}
+
+func (p *parser) uint32() (uint32, error) {
+	v, err := p.r.ReadUint32BE()
+	if err != nil {
+		return 0, p.truncated("four byte integer")
+	}
+	return v, nil
+}
+
+func (p *parser) varInt() (uint32, error) {
+	at := p.pos()
+	v, _, err := ReadVarByteInt(p.r)
+	if err == ErrMalformed {
+		return 0, p.errAt(at, "variable byte integer exceeds four bytes")
+	}
+	if err != nil {
+		return 0, p.truncated("variable byte integer")
+	}
+	return v, nil
+}
+
+func (p *parser) binary() ([]byte, error) {
+	n, err := p.uint16()
+	if err != nil {
+		return nil, err
+	}
+	b, err := p.r.ReadBytes(int(n))
+	if err != nil {
+		return nil, p.truncated("binary data")
+	}
+	return b, nil
+}
+
+// string reads a UTF-8 encoded string, rejecting invalid UTF-8 (including
+// surrogates) and the null character as required by the specification.
+func (p *parser) string() (string, error) {
+	at := p.pos()
+	n, err := p.uint16()
+	if err != nil {
+		return "", err
+	}
+	s, err := p.r.ReadString(int(n))
+	if err != nil {
+		return "", p.truncated("string")
+	}
+	if !utf8.ValidString(s) {
+		return "", p.errAt(at+2, "string is not valid UTF-8")
+	}
+	if i := strings.IndexByte(s, 0); i >= 0 {
+		return "", p.errAt(at+2+int64(i), "string contains U+0000")
+	}
+	return s, nil
+}
+
+func (p *parser) packetID() (uint16, error) {
+	at := p.pos()
+	id, err := p.uint16()
+	if err == nil && id == 0 {
+		err = p.errAt(at, "packet identifier must not be 0")
+	}
+	return id, err
+}
+
+func (p *parser) v5() bool {
+	return p.version == Version5
+}
+
+func (p *parser) remaining() int {
+	return len(p.r.Bytes())
+}
+
+func (p *parser) end() error {
+	if p.remaining() != 0 {
+		return p.errAt(p.pos(), "%d unexpected trailing bytes", p.remaining())
+	}
+	return nil
+}
+
+// requiredFlags are the fixed header flags mandated for each packet type;
+// PUBLISH is validated separately.
+var requiredFlags = [16]byte{PUBREL: 0x02, SUBSCRIBE: 0x02, UNSUBSCRIBE: 0x02}
+
+// parseBody decodes a packet body. start and base are the offsets of the
+// fixed header and of body[0], used for error reporting.
+func parseBody(hdr FixedHeader, body []byte, start, base int64, version byte) (Packet, error) {
+	if version == 0 {
+		version = Version311
+		if hdr.Type == CONNECT {
+			version = 0 // decided by the protocol level
+		}
+	}
+	p := &parser{r: wireread.NewSafeReader(body), body: body, base: base, version: version, typ: hdr.Type}
+	flagsAt := start
+
+	if hdr.Type == 0 || (hdr.Type == AUTH && version != Version5) {
+		return nil, &ParseError{Offset: flagsAt, Msg: fmt.Sprintf("reserved packet type %d", hdr.Type)}
+	}
+	if hdr.Type != PUBLISH && hdr.Flags != requiredFlags[hdr.Type] {
+		return nil, p.errAt(flagsAt, "invalid fixed header flags 0x%x", hdr.Flags)
+	}
+
+	var pkt Packet
+	var err error
+	switch hdr.Type {
+	case CONNECT:
+		pkt, err = p.connect()
+	case CONNACK:
+		pkt, err = p.connack()
+	case PUBLISH:
+		pkt, err = p.publish(hdr.Flags, flagsAt)
+	case PUBACK, PUBREC, PUBREL, PUBCOMP:
+		pkt, err = p.ack()
+	case SUBSCRIBE:
+		pkt, err = p.subscribe()
+	case SUBACK:
+		pkt, err = p.suback()
+	case UNSUBSCRIBE:
+		pkt, err = p.unsubscribe()
+	case UNSUBACK:
+		pkt, err = p.unsuback()
+	case PINGREQ:
+		pkt, err = &Pingreq{}, p.end()
+	case PINGRESP:
+		pkt, err = &Pingresp{}, p.end()
+	case DISCONNECT:
+		pkt, err = p.disconnect()
+	case AUTH:
+		var d *Disconnect
+		if d, err = p.disconnect(); err == nil {
+			pkt = &Auth{ReasonCode: d.ReasonCode, Properties: d.Properties}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return pkt, nil
+}
+
+func (p *parser) connect() (*Connect, error) {
+	c := &Connect{}
+	var err error
+	nameAt := p.pos()
+	if c.ProtocolName, err = p.string(); err != nil {
+		return nil, err
+	}
+	levelAt := p.pos()
+	if c.ProtocolLevel, err = p.byte(); err != nil {
+		return nil, err
+	}
+	switch {
+	case c.ProtocolName == "MQTT" && (c.ProtocolLevel == Version311 || c.ProtocolLevel == Version5):
+	case c.ProtocolName == "MQIsdp" && c.ProtocolLevel == Version31:
+	case c.ProtocolName != "MQTT" && c.ProtocolName != "MQIsdp":
+		return nil, p.errAt(nameAt, "unknown protocol name %q", c.ProtocolName)
+	default:
+		return nil, p.errAt(levelAt, "unsupported protocol level %d", c.ProtocolLevel)
+	}
+	if p.version == 0 {
+		p.version = c.ProtocolLevel
+	}
+
+	flagsAt := p.pos()
+	flags, err := p.byte()
+	if err != nil {
+		return nil, err
+	}
+	willQoS := flags >> 3 & 0x03
+	switch {
+	case flags&0x01 != 0:
+		return nil, p.errAt(flagsAt, "reserved connect flag is set")
+	case flags&0x04 == 0 && flags&0x38 != 0:
+		return nil, p.errAt(flagsAt, "will QoS or retain set without will flag")
+	case willQoS == 3:
+		return nil, p.errAt(flagsAt, "invalid will QoS 3")
+	case !p.v5() && flags&0x40 != 0 && flags&0x80 == 0:
+		return nil, p.errAt(flagsAt, "password flag set without username flag")
+	}
+	c.CleanStart = flags&0x02 != 0
+	c.HasPassword = flags&0x40 != 0
+	c.HasUsername = flags&0x80 != 0
+
+	if c.KeepAlive, err = p.uint16(); err != nil {
+		return nil, err
+	}
+	if p.v5() {
+		if c.Properties, err = p.properties(CONNECT); err != nil {
+			return nil, err
+		}
+	}
+	if c.ClientID, err = p.string(); err != nil {
+		return nil, err
+	}
+	if flags&0x04 != 0 {
+		w := &Will{QoS: willQoS, Retain: flags&0x20 != 0}
+		if p.v5() {
+			if w.Properties, err = p.properties(willProperties); err != nil {
+				return nil, err
+			}
+		}
+		if w.Topic, err = p.string(); err != nil {
+			return nil, err
+		}
+		if w.Payload, err = p.binary(); err != nil {
+			return nil, err
+		}
+		c.Will = w
+	}
+	if c.HasUsername {
+		if c.Username, err = p.string(); err != nil {
+			return nil, err
+		}
+	}
+	if c.HasPassword {
+		if c.Password, err = p.binary(); err != nil {
+			return nil, err
+		}
+	}
+	return c, p.end()
+}
+
+func (p *parser) connack() (*Connack, error) {
+	c := &Connack{}
+	flagsAt := p.pos()
+	flags, err := p.byte()
+	if err != nil {
+		return nil, err
+	}
+	if flags&^0x01 != 0 {
+		return nil, p.errAt(flagsAt, "reserved acknowledge flags 0x%02x", flags)
+	}
+	c.SessionPresent = flags&0x01 != 0
+	if c.ReasonCode, err = p.byte(); err != nil {
+		return nil, err
+	}
+	if p.v5() {
+		if c.Properties, err = p.properties(CONNACK); err != nil {
+			return nil, err
+		}
+	}
+	return c, p.end()
+}
+
+func (p *parser) publish(flags byte, flagsAt int64) (*Publish, error) {
+	pub := &Publish{
+		Dup:    flags&0x08 != 0,
+		QoS:    flags >> 1 & 0x03,
+		Retain: flags&0x01 != 0,
+	}
+	if pub.QoS == 3 {
+		return nil, p.errAt(flagsAt, "invalid QoS 3")
+	}
+	if pub.QoS == 0 && pub.Dup {
+		return nil, p.errAt(flagsAt, "DUP flag set on QoS 0 message")
+	}
+	var err error
+	topicAt := p.pos()
+	if pub.Topic, err = p.string(); err != nil {
+		return nil, err
+	}
+	if i := strings.IndexAny(pub.Topic, "+#"); i >= 0 {
+		return nil, p.errAt(topicAt+2+int64(i), "wildcard in topic name")
+	}
+	if pub.QoS > 0 {
+		if pub.PacketID, err = p.packetID(); err != nil {
+			return nil, err
+		}
+	}
+	if p.v5() {
+		if pub.Properties, err = p.properties(PUBLISH); err != nil {
+			return nil, err
+		}
+	} else if pub.Topic == "" {
+		return nil, p.errAt(topicAt, "empty topic name")
+	}
+	pub.Payload, _ = p.r.ReadBytes(p.remaining())
+	return pub, nil
+}
+
+func (p *parser) ack() (*Ack, error) {
+	a := &Ack{Kind: p.typ}
+	var err error
+	if a.PacketID, err = p.packetID(); err != nil {
+		return nil, err
+	}
+	// MQTT 5 omits the reason code when it is 0 and there are no properties.
+	if p.v5() && p.remaining() > 0 {
+		if a.ReasonCode, err = p.byte(); err != nil {
+			return nil, err
+		}
+		if p.remaining() > 0 {
+			if a.Properties, err = p.properties(p.typ); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return a, p.end()
+}
+
+func (p *parser) subscribe() (*Subscribe, error) {
+	s := &Subscribe{}
+	var err error
+	if s.PacketID, err = p.packetID(); err != nil {
+		return nil, err
+	}
+	if p.v5() {
+		if s.Properties, err = p.properties(SUBSCRIBE); err != nil {
+			return nil, err
+		}
+	}
+	if p.remaining() == 0 {
+		return nil, p.errAt(p.pos(), "no topic filters")
+	}
+	for p.remaining() > 0 {
+		var sub Subscription
+		if sub.Topic, err = p.string(); err != nil {
+			return nil, err
+		}
+		optsAt := p.pos()
+		opts, err := p.byte()
+		if err != nil {
+			return nil, err
+		}
+		reserved := byte(0xFC)
+		if p.v5() {
+			reserved = 0xC0
+		}
+		if opts&reserved != 0 {
+			return nil, p.errAt(optsAt, "reserved subscription option bits 0x%02x", opts)
+		}
+		sub.QoS = opts & 0x03
+		sub.NoLocal = opts&0x04 != 0
+		sub.RetainAsPublished = opts&0x08 != 0
+		sub.RetainHandling = opts >> 4 & 0x03
+		if sub.QoS == 3 || sub.RetainHandling == 3 {
+			return nil, p.errAt(optsAt, "invalid subscription options 0x%02x", opts)
+		}
+		s.Subscriptions = append(s.Subscriptions, sub)
+	}
+	return s, nil
+}
+
+func (p *parser) suback() (*Suback, error) {
+	s := &Suback{}
+	var err error
+	if s.PacketID, err = p.packetID(); err != nil {
+		return nil, err
+	}
+	if p.v5() {
+		if s.Properties, err = p.properties(SUBACK); err != nil {
+			return nil, err
+		}
+	}
+	if p.remaining() == 0 {
+		return nil, p.errAt(p.pos(), "no reason codes")
+	}
+	s.ReasonCodes, _ = p.r.ReadBytes(p.remaining())
+	return s, nil
+}
+
+func (p *parser) unsubscribe() (*Unsubscribe, error) {
+	u := &Unsubscribe{}
+	var err error
+	if u.PacketID, err = p.packetID(); err != nil {
+		return nil, err
+	}
+	if p.v5() {
+		if u.Properties, err = p.properties(UNSUBSCRIBE); err != nil {
+			return nil, err
+		}
+	}
+	if p.remaining() == 0 {
+		return nil, p.errAt(p.pos(), "no topic filters")
+	}
+	for p.remaining() > 0 {
+		topic, err := p.string()
+		if err != nil {
+			return nil, err
+		}
+		u.Topics = append(u.Topics, topic)
+	}
+	return u, nil
+}
+
+func (p *parser) unsuback() (*Unsuback, error) {
+	u := &Unsuback{}
+	var err error
+	if u.PacketID, err = p.packetID(); err != nil {
+		return nil, err
+	}
+	if !p.v5() {
+		return u, p.end()
+	}
+	if u.Properties, err = p.properties(UNSUBACK); err != nil {
+		return nil, err
+	}
+	if p.remaining() == 0 {
+		return nil, p.errAt(p.pos(), "no reason codes")
+	}
+	u.ReasonCodes, _ = p.r.ReadBytes(p.remaining())
+	return u, nil
+}
+
+// disconnect parses DISCONNECT and AUTH, which share the same layout.
+func (p *parser) disconnect() (*Disconnect, error) {
+	d := &Disconnect{}
+	var err error
+	if p.v5() && p.remaining() > 0 {
+		if d.ReasonCode, err = p.byte(); err != nil {
+			return nil, err
+		}
+		if p.remaining() > 0 {
+			if d.Properties, err = p.properties(p.typ); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return d, p.end()
+}
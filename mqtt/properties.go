@@ -0,0 +1,204 @@
+package mqtt
+
+// PropertyID identifies an MQTT 5 property.
+type PropertyID byte
+
+// MQTT 5 property identifiers.
+const (
+	PayloadFormatIndicator          PropertyID = 0x01
+	MessageExpiryInterval           PropertyID = 0x02
+	ContentType                     PropertyID = 0x03
+	ResponseTopic                   PropertyID = 0x08
+	CorrelationData                 PropertyID = 0x09
+	SubscriptionIdentifier          PropertyID = 0x0B
+	SessionExpiryInterval           PropertyID = 0x11
+	AssignedClientIdentifier        PropertyID = 0x12
+	ServerKeepAlive                 PropertyID = 0x13
+	AuthenticationMethod            PropertyID = 0x15
+	AuthenticationData              PropertyID = 0x16
+	RequestProblemInformation       PropertyID = 0x17
+	WillDelayInterval               PropertyID = 0x18
+	RequestResponseInformation      PropertyID = 0x19
+	ResponseInformation             PropertyID = 0x1A
+	ServerReference                 PropertyID = 0x1C
+	ReasonString                    PropertyID = 0x1F
+	ReceiveMaximum                  PropertyID = 0x21
+	TopicAliasMaximum               PropertyID = 0x22
+	TopicAlias                      PropertyID = 0x23
+	MaximumQoS                      PropertyID = 0x24
+	RetainAvailable                 PropertyID = 0x25
+	UserProperty                    PropertyID = 0x26
+	MaximumPacketSize               PropertyID = 0x27
+	WildcardSubscriptionAvailable   PropertyID = 0x28
+	SubscriptionIdentifierAvailable PropertyID = 0x29
+	SharedSubscriptionAvailable     PropertyID = 0x2A
+)
+
+// Property value encodings.
+const (
+	propByte = iota + 1
+	propUint16
+	propUint32
+	propVarInt
+	propString
+	propBinary
+	propPair
+)
+
+// willProperties is the pseudo packet type used for will properties in the
+// allowed-packet masks below; packet type 0 is reserved on the wire.
+const willProperties PacketType = 0
+
+func mask(types ...PacketType) uint16 {
+	var m uint16
+	for _, t := range types {
+		m |= 1 << t
+	}
+	return m
+}
+
+type propertySpec struct {
+	kind    int
+	allowed uint16
+}
+
+var propertySpecs = map[PropertyID]propertySpec{
+	PayloadFormatIndicator:          {propByte, mask(PUBLISH, willProperties)},
+	MessageExpiryInterval:           {propUint32, mask(PUBLISH, willProperties)},
+	ContentType:                     {propString, mask(PUBLISH, willProperties)},
+	ResponseTopic:                   {propString, mask(PUBLISH, willProperties)},
+	CorrelationData:                 {propBinary, mask(PUBLISH, willProperties)},
+	SubscriptionIdentifier:          {propVarInt, mask(PUBLISH, SUBSCRIBE)},
+	SessionExpiryInterval:           {propUint32, mask(CONNECT, CONNACK, DISCONNECT)},
+	AssignedClientIdentifier:        {propString, mask(CONNACK)},
+	ServerKeepAlive:                 {propUint16, mask(CONNACK)},
+	AuthenticationMethod:            {propString, mask(CONNECT, CONNACK, AUTH)},
+	AuthenticationData:              {propBinary, mask(CONNECT, CONNACK, AUTH)},
+	RequestProblemInformation:       {propByte, mask(CONNECT)},
+	WillDelayInterval:               {propUint32, mask(willProperties)},
+	RequestResponseInformation:      {propByte, mask(CONNECT)},
+	ResponseInformation:             {propString, mask(CONNACK)},
+	ServerReference:                 {propString, mask(CONNACK, DISCONNECT)},
+	ReasonString:                    {propString, mask(CONNACK, PUBACK, PUBREC, PUBREL, PUBCOMP, SUBACK, UNSUBACK, DISCONNECT, AUTH)},
+	ReceiveMaximum:                  {propUint16, mask(CONNECT, CONNACK)},
+	TopicAliasMaximum:               {propUint16, mask(CONNECT, CONNACK)},
+	TopicAlias:                      {propUint16, mask(PUBLISH)},
+	MaximumQoS:                      {propByte, mask(CONNACK)},
+	RetainAvailable:                 {propByte, mask(CONNACK)},
+	UserProperty:                    {propPair, 0xFFFF},
+	MaximumPacketSize:               {propUint32, mask(CONNECT, CONNACK)},
+	WildcardSubscriptionAvailable:   {propByte, mask(CONNACK)},
+	SubscriptionIdentifierAvailable: {propByte, mask(CONNACK)},
+	SharedSubscriptionAvailable:     {propByte, mask(CONNACK)},
+}
+
+// Property is a single MQTT 5 property. Depending on the property type the
+// value is held in Int (byte, two byte, four byte and variable byte
+// integers), Str (UTF-8 strings and user property names), Value (user
+// property values) or Data (binary data).
+type Property struct {
+	ID    PropertyID
+	Int   uint32
+	Str   string
+	Value string
+	Data  []byte
+}
+
+// Properties is the property list of a packet in wire order.
+type Properties []Property
+
+// Get returns the first property with the given ID.
+func (ps Properties) Get(id PropertyID) (Property, bool) {
+	for _, p := range ps {
+		if p.ID == id {
+			return p, true
+		}
+	}
+	return Property{}, false
+}
+
+// UserProperties returns all user properties as name/value pairs in order.
+func (ps Properties) UserProperties() [][2]string {
+	var out [][2]string
+	for _, p := range ps {
+		if p.ID == UserProperty {
+			out = append(out, [2]string{p.Str, p.Value})
+		}
+	}
+	return out
+}
+
+// properties reads a property list that applies to packet type t.
+func (p *parser) properties(t PacketType) (Properties, error) {
+	start := p.pos()
+	n, err := p.varInt()
+	if err != nil {
+		return nil, err
+	}
+	if int64(n) > int64(len(p.r.Bytes())) {
+		return nil, p.errAt(start, "property length %d exceeds packet", n)
+	}
+	end := p.pos() + int64(n)
+
+	var props Properties
+	seen := make(map[PropertyID]bool)
+	for p.pos() < end {
+		at := p.pos()
+		v, err := p.varInt()
+		if err != nil {
+			return nil, err
+		}
+		id := PropertyID(v)
+		spec, ok := propertySpecs[id]
+		if !ok {
+			return nil, p.errAt(at, "unknown property 0x%02x", v)
+		}
+		if spec.allowed&(1<<t) == 0 {
+			return nil, p.errAt(at, "property 0x%02x not allowed here", v)
+		}
+		repeatable := id == UserProperty || (id == SubscriptionIdentifier && t == PUBLISH)
+		if seen[id] && !repeatable {
+			return nil, p.errAt(at, "duplicate property 0x%02x", v)
+		}
+		seen[id] = true
+
+		prop := Property{ID: id}
+		valueAt := p.pos()
+		switch spec.kind {
+		case propByte:
+			var b byte
+			b, err = p.byte()
+			prop.Int = uint32(b)
+			if err == nil && b > 1 {
+				err = p.errAt(valueAt, "property 0x%02x value %d is not 0 or 1", v, b)
+			}
+		case propUint16:
+			var u uint16
+			u, err = p.uint16()
+			prop.Int = uint32(u)
+		case propUint32:
+			prop.Int, err = p.uint32()
+		case propVarInt:
+			prop.Int, err = p.varInt()
+			if err == nil && prop.Int == 0 {
+				err = p.errAt(valueAt, "subscription identifier must not be 0")
+			}
+		case propString:
+			prop.Str, err = p.string()
+		case propBinary:
+			prop.Data, err = p.binary()
+		case propPair:
+			if prop.Str, err = p.string(); err == nil {
+				prop.Value, err = p.string()
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		props = append(props, prop)
+	}
+	if p.pos() != end {
+		return nil, p.errAt(end, "property overruns property length")
+	}
+	return props, nil
+}
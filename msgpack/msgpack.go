@@ -0,0 +1,449 @@
+// Package msgpack decodes MessagePack data.
+//
+// Decoder offers two levels of access over the same buffer. Next returns
+// one token at a time, where arrays and maps appear as headers carrying
+// their element count, which suits streaming and filtering without
+// building values. Decode returns a complete value as plain Go types.
+//
+// Example usage:
+//
+//	d := msgpack.NewDecoder(data)
+//	tok, err := d.Next()
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	if tok.Type == msgpack.Map {
+//	    for i := 0; i < tok.Len; i++ {
+//	        key, _ := d.Next()
+//	        val, _ := d.Decode()
+//	        fmt.Println(key.Str(), val)
+//	    }
+//	}
+package msgpack
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/nemohan/wireread"
+)
+
+var (
+	// ErrMalformed is returned for the reserved code 0xc1, invalid
+	// timestamps and map keys that cannot be used in a Go map.
+	ErrMalformed = errors.New("msgpack: malformed data")
+
+	// ErrDepthExceeded is returned by Decode when arrays and maps nest
+	// deeper than MaxDepth.
+	ErrDepthExceeded = errors.New("msgpack: nesting too deep")
+)
+
+// MaxDepth limits the nesting of arrays and maps decoded by Decode.
+const MaxDepth = 512
+
+// TimestampExt is the extension type of the timestamp extension.
+const TimestampExt int8 = -1
+
+// Type is the type of a token.
+type Type uint8
+
+// Token types.
+const (
+	Nil Type = iota
+	Bool
+	Int   // positive and negative fixint, int 8-64
+	Uint  // uint 8-64
+	Float // float 32 and 64
+	Str
+	Bin
+	Array
+	Map
+	Ext
+	Timestamp
+)
+
+var typeNames = [...]string{
+	Nil:       "nil",
+	Bool:      "bool",
+	Int:       "int",
+	Uint:      "uint",
+	Float:     "float",
+	Str:       "str",
+	Bin:       "bin",
+	Array:     "array",
+	Map:       "map",
+	Ext:       "ext",
+	Timestamp: "timestamp",
+}
+
+func (t Type) String() string {
+	if int(t) < len(typeNames) {
+		return typeNames[t]
+	}
+	return fmt.Sprintf("Type(%d)", uint8(t))
+}
+
+// Token is a single MessagePack item. Only the fields relevant to Type are
+// set. Bytes aliases the decoder's input.
+type Token struct {
+	Type Type
+	Bool bool
+	Int  int64
+	Uint uint64
+	// Float holds float 32 and float 64 values; Float32 tells them apart.
+	Float   float64
+	Float32 bool
+	// Bytes holds str, bin and ext payloads.
+	Bytes []byte
+	// Len is the element count of arrays and the pair count of maps.
+	Len     int
+	ExtType int8
+	Time    time.Time
+}
+
+// Str returns the payload of a str token as a string.
+func (t Token) Str() string {
+	return string(t.Bytes)
+}
+
+// ExtValue is an extension value other than a timestamp.
+type ExtValue struct {
+	Type int8
+	Data []byte
+}
+
+// Decoder decodes MessagePack items from a buffer.
+type Decoder struct {
+	data []byte
+	r    *wireread.SafeReader
+}
+
+// NewDecoder creates a Decoder reading data.
+func NewDecoder(data []byte) *Decoder {
+	return &Decoder{data: data, r: wireread.NewSafeReader(data)}
+}
+
+// Offset returns the number of bytes consumed.
+func (d *Decoder) Offset() int {
+	return len(d.data) - len(d.r.Bytes())
+}
+
+// More reports whether unread data remains.
+func (d *Decoder) More() bool {
+	return len(d.r.Bytes()) > 0
+}
+
+// Next reads the next token. It returns io.EOF when the input is exhausted
+// and io.ErrUnexpectedEOF when it ends inside an item. After an error the
+// position is undefined.
+func (d *Decoder) Next() (Token, error) {
+	c, err := d.r.ReadByte()
+	if err != nil {
+		return Token{}, io.EOF
+	}
+	switch {
+	case c <= 0x7f:
+		return Token{Type: Int, Int: int64(c)}, nil
+	case c >= 0xe0:
+		return Token{Type: Int, Int: int64(int8(c))}, nil
+	case c <= 0x8f:
+		return d.container(Map, int(c&0x0f))
+	case c <= 0x9f:
+		return d.container(Array, int(c&0x0f))
+	case c <= 0xbf:
+		return d.bytes(Str, int(c&0x1f))
+	}
+
+	switch c {
+	case 0xc0:
+		return Token{Type: Nil}, nil
+	case 0xc2, 0xc3:
+		return Token{Type: Bool, Bool: c == 0xc3}, nil
+
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.length(c - 0xc4)
+		if err != nil {
+			return Token{}, err
+		}
+		return d.bytes(Bin, n)
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.length(c - 0xd9)
+		if err != nil {
+			return Token{}, err
+		}
+		return d.bytes(Str, n)
+	case 0xdc, 0xdd:
+		n, err := d.length(c - 0xdc + 1)
+		if err != nil {
+			return Token{}, err
+		}
+		return d.container(Array, n)
+	case 0xde, 0xdf:
+		n, err := d.length(c - 0xde + 1)
+		if err != nil {
+			return Token{}, err
+		}
+		return d.container(Map, n)
+
+	case 0xc7, 0xc8, 0xc9:
+		n, err := d.length(c - 0xc7)
+		if err != nil {
+			return Token{}, err
+		}
+		return d.ext(n)
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return d.ext(1 << (c - 0xd4))
+
+	case 0xca:
+		v, err := d.r.ReadUint32BE()
+		return Token{Type: Float, Float: float64(math.Float32frombits(v)), Float32: true}, err
+	case 0xcb:
+		v, err := d.r.ReadUint64BE()
+		return Token{Type: Float, Float: math.Float64frombits(v)}, err
+
+	case 0xcc:
+		v, err := d.r.ReadByte()
+		return Token{Type: Uint, Uint: uint64(v)}, err
+	case 0xcd:
+		v, err := d.r.ReadUint16BE()
+		return Token{Type: Uint, Uint: uint64(v)}, err
+	case 0xce:
+		v, err := d.r.ReadUint32BE()
+		return Token{Type: Uint, Uint: uint64(v)}, err
+	case 0xcf:
+		v, err := d.r.ReadUint64BE()
+		return Token{Type: Uint, Uint: v}, err
+
+	case 0xd0:
+		v, err := d.r.ReadByte()
+		return Token{Type: Int, Int: int64(int8(v))}, err
+	case 0xd1:
+		v, err := d.r.ReadUint16BE()
+		return Token{Type: Int, Int: int64(int16(v))}, err
+	case 0xd2:
+		v, err := d.r.ReadUint32BE()
+		return Token{Type: Int, Int: int64(int32(v))}, err
+	case 0xd3:
+		v, err := d.r.ReadUint64BE()
+		return Token{Type: Int, Int: int64(v)}, err
+	}
+	return Token{}, fmt.Errorf("%w: reserved code 0x%02x at offset %d", ErrMalformed, c, d.Offset()-1)
+}
+
+// length reads a big-endian length of 1, 2 or 4 bytes (size 0, 1 or 2).
+func (d *Decoder) length(size byte) (int, error) {
+	switch size {
+	case 0:
+		n, err := d.r.ReadByte()
+		return int(n), err
+	case 1:
+		n, err := d.r.ReadUint16BE()
+		return int(n), err
+	}
+	n, err := d.r.ReadUint32BE()
+	return int(n), err
+}
+
+func (d *Decoder) bytes(t Type, n int) (Token, error) {
+	b, err := d.r.ReadSlice(n)
+	if err != nil {
+		return Token{}, err
+	}
+	return Token{Type: t, Bytes: b}, nil
+}
+
+// container returns an array or map header. Every element takes at least
+// one byte, which rejects impossible counts before anything is allocated.
+func (d *Decoder) container(t Type, n int) (Token, error) {
+	min := n
+	if t == Map {
+		min *= 2
+	}
+	if min > len(d.r.Bytes()) {
+		return Token{}, io.ErrUnexpectedEOF
+	}
+	return Token{Type: t, Len: n}, nil
+}
+
+func (d *Decoder) ext(n int) (Token, error) {
+	typ, err := d.r.ReadByte()
+	if err != nil {
+		return Token{}, err
+	}
+	data, err := d.r.ReadSlice(n)
+	if err != nil {
+		return Token{}, err
+	}
+	if int8(typ) != TimestampExt {
+		return Token{Type: Ext, ExtType: int8(typ), Bytes: data}, nil
+	}
+	ts, err := decodeTimestamp(data)
+	if err != nil {
+		return Token{}, err
+	}
+	return Token{Type: Timestamp, ExtType: TimestampExt, Bytes: data, Time: ts}, nil
+}
+
+// decodeTimestamp decodes the 32, 64 and 96-bit timestamp formats.
+func decodeTimestamp(b []byte) (time.Time, error) {
+	r := wireread.NewSafeReader(b)
+	var sec int64
+	var nsec uint32
+	switch len(b) {
+	case 4:
+		s, _ := r.ReadUint32BE()
+		sec = int64(s)
+	case 8:
+		v, _ := r.ReadUint64BE()
+		nsec, sec = uint32(v>>34), int64(v&(1<<34-1))
+	case 12:
+		nsec, _ = r.ReadUint32BE()
+		s, _ := r.ReadUint64BE()
+		sec = int64(s)
+	default:
+		return time.Time{}, fmt.Errorf("%w: timestamp of %d bytes", ErrMalformed, len(b))
+	}
+	if nsec >= 1e9 {
+		return time.Time{}, fmt.Errorf("%w: timestamp nanoseconds %d", ErrMalformed, nsec)
+	}
+	return time.Unix(sec, int64(nsec)).UTC(), nil
+}
+
+// Skip skips the next complete item, including all elements of arrays and
+// maps.
+func (d *Decoder) Skip() error {
+	for pending := 1; pending > 0; pending-- {
+		t, err := d.Next()
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+		switch t.Type {
+		case Array:
+			pending += t.Len
+		case Map:
+			pending += 2 * t.Len
+		}
+	}
+	return nil
+}
+
+// Decode reads the next complete item as a Go value:
+//
+//	nil                 nil
+//	bool                bool
+//	int family          int64
+//	uint family         uint64
+//	float 32 / 64       float32 / float64
+//	str                 string
+//	bin                 []byte (aliasing the input)
+//	array               []any
+//	map with str keys   map[string]any
+//	other maps          map[any]any
+//	timestamp           time.Time
+//	other ext           ExtValue
+//
+// Map keys other than str must be comparable, so bin, array, map and ext
+// keys fail with ErrMalformed. Duplicate keys keep the last value. Like
+// Next, Decode returns io.EOF when the input is exhausted.
+func (d *Decoder) Decode() (any, error) {
+	return d.decode(MaxDepth)
+}
+
+func (d *Decoder) decode(depth int) (any, error) {
+	t, err := d.Next()
+	if err != nil {
+		return nil, err
+	}
+	switch t.Type {
+	case Nil:
+		return nil, nil
+	case Bool:
+		return t.Bool, nil
+	case Int:
+		return t.Int, nil
+	case Uint:
+		return t.Uint, nil
+	case Float:
+		if t.Float32 {
+			return float32(t.Float), nil
+		}
+		return t.Float, nil
+	case Str:
+		return string(t.Bytes), nil
+	case Bin:
+		return t.Bytes, nil
+	case Timestamp:
+		return t.Time, nil
+	case Ext:
+		return ExtValue{Type: t.ExtType, Data: t.Bytes}, nil
+	}
+
+	if depth == 0 {
+		return nil, ErrDepthExceeded
+	}
+	if t.Type == Array {
+		arr := make([]any, t.Len)
+		for i := range arr {
+			if arr[i], err = d.decode(depth - 1); err != nil {
+				return nil, unexpectedEOF(err)
+			}
+		}
+		return arr, nil
+	}
+	return d.decodeMap(t.Len, depth)
+}
+
+func (d *Decoder) decodeMap(n, depth int) (any, error) {
+	m := make(map[string]any, n)
+	var other map[any]any
+	for i := 0; i < n; i++ {
+		k, err := d.decode(depth - 1)
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		v, err := d.decode(depth - 1)
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		if s, ok := k.(string); ok && other == nil {
+			m[s] = v
+			continue
+		}
+		switch k.(type) {
+		case []byte, []any, map[string]any, map[any]any, ExtValue:
+			return nil, fmt.Errorf("%w: unsupported map key type %T", ErrMalformed, k)
+		}
+		if other == nil {
+			other = make(map[any]any, n)
+			for ks, kv := range m {
+				other[ks] = kv
+			}
+		}
+		other[k] = v
+	}
+	if other != nil {
+		return other, nil
+	}
+	return m, nil
+}
+
+// Decode decodes the first item of data and returns it with the number of
+// bytes consumed.
+func Decode(data []byte) (any, int, error) {
+	d := NewDecoder(data)
+	v, err := d.Decode()
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return v, d.Offset(), err
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,208 @@
+package msgpack
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDecode_Scalars(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want any
+	}{
+		{"positive fixint", []byte{0x7f}, int64(127)},
+		{"negative fixint", []byte{0xe0}, int64(-32)},
+		{"nil", []byte{0xc0}, nil},
+		{"false", []byte{0xc2}, false},
+		{"true", []byte{0xc3}, true},
+		{"uint8", []byte{0xcc, 0xff}, uint64(255)},
+		{"uint16", []byte{0xcd, 0x01, 0x00}, uint64(256)},
+		{"uint32", []byte{0xce, 0xff, 0xff, 0xff, 0xff}, uint64(math.MaxUint32)},
+		{"uint64", []byte{0xcf, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, uint64(math.MaxUint64)},
+		{"int8", []byte{0xd0, 0x80}, int64(-128)},
+		{"int16", []byte{0xd1, 0x80, 0x00}, int64(math.MinInt16)},
+		{"int32", []byte{0xd2, 0xff, 0xff, 0xff, 0xfe}, int64(-2)},
+		{"int64", []byte{0xd3, 0x80, 0, 0, 0, 0, 0, 0, 0}, int64(math.MinInt64)},
+		{"float32", []byte{0xca, 0x3f, 0xc0, 0x00, 0x00}, float32(1.5)},
+		{"float64", []byte{0xcb, 0x40, 0x09, 0x21, 0xfb, 0x54, 0x44, 0x2d, 0x18}, math.Pi},
+		{"fixstr", []byte{0xa3, 'a', 'b', 'c'}, "abc"},
+		{"str8", append([]byte{0xd9, 0x03}, "xyz"...), "xyz"},
+		{"str16", append([]byte{0xda, 0x00, 0x01}, 'q'), "q"},
+		{"str32", []byte{0xdb, 0, 0, 0, 0}, ""},
+		{"bin8", []byte{0xc4, 0x02, 1, 2}, []byte{1, 2}},
+		{"bin16", []byte{0xc5, 0x00, 0x01, 9}, []byte{9}},
+		{"bin32", []byte{0xc6, 0, 0, 0, 1, 7}, []byte{7}},
+		{"fixext1", []byte{0xd4, 0x05, 0xaa}, ExtValue{Type: 5, Data: []byte{0xaa}}},
+		{"fixext16", append([]byte{0xd8, 0x7f}, make([]byte, 16)...), ExtValue{Type: 127, Data: make([]byte, 16)}},
+		{"ext8", []byte{0xc7, 0x03, 0xfe, 1, 2, 3}, ExtValue{Type: -2, Data: []byte{1, 2, 3}}},
+		{"ext16", []byte{0xc8, 0x00, 0x01, 0x01, 9}, ExtValue{Type: 1, Data: []byte{9}}},
+		{"ext32", []byte{0xc9, 0, 0, 0, 0, 0x02}, ExtValue{Type: 2, Data: []byte{}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, n, err := Decode(tt.data)
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if n != len(tt.data) {
+				t.Errorf("Decode() consumed %d bytes, want %d", n, len(tt.data))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Decode() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecode_Timestamp(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want time.Time
+	}{
+		{"32-bit", []byte{0xd6, 0xff, 0x5f, 0x5e, 0x10, 0x00}, time.Unix(1600000000, 0)},
+		{"64-bit", []byte{0xd7, 0xff, 0x00, 0x00, 0x00, 0x04, 0x5f, 0x5e, 0x10, 0x00}, time.Unix(1600000000, 1)},
+		{"96-bit negative", []byte{0xc7, 12, 0xff, 0, 0, 0, 5, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, time.Unix(-1, 5)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := Decode(tt.data)
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if ts, ok := got.(time.Time); !ok || !ts.Equal(tt.want) {
+				t.Errorf("Decode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	bad := []byte{0xd7, 0xff, 0xff, 0xff, 0xff, 0xfc, 0, 0, 0, 0} // nsec > 999999999
+	if _, _, err := Decode(bad); !errors.Is(err, ErrMalformed) {
+		t.Errorf("Decode(bad nsec) error = %v, want ErrMalformed", err)
+	}
+	if _, _, err := Decode([]byte{0xd5, 0xff, 0, 0}); !errors.Is(err, ErrMalformed) {
+		t.Errorf("Decode(2-byte timestamp) error = %v, want ErrMalformed", err)
+	}
+}
+
+func TestDecode_Containers(t *testing.T) {
+	data := []byte{
+		0x83,            // fixmap, 3 pairs
+		0xa1, 'a', 0x01, // "a": 1
+		0xa1, 'b', 0x92, 0xc3, 0xc0, // "b": [true, nil]
+		0xa1, 'c', 0xde, 0x00, 0x01, 0xa1, 'd', 0xdc, 0x00, 0x00, // "c": {"d": []}
+	}
+	got, n, err := Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"a": int64(1),
+		"b": []any{true, nil},
+		"c": map[string]any{"d": []any{}},
+	}
+	if !reflect.DeepEqual(got, want) || n != len(data) {
+		t.Errorf("Decode() = %#v, %d, want %#v, %d", got, n, want, len(data))
+	}
+
+	mixed := []byte{0x82, 0xa1, 'x', 0x01, 0x02, 0x03} // {"x": 1, 2: 3}
+	got, _, err = Decode(mixed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (map[any]any{"x": int64(1), int64(2): int64(3)}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Decode(mixed keys) = %#v, want %#v", got, want)
+	}
+
+	array32 := []byte{0xdd, 0, 0, 0, 2, 0x01, 0x02}
+	if got, _, _ := Decode(array32); !reflect.DeepEqual(got, []any{int64(1), int64(2)}) {
+		t.Errorf("Decode(array32) = %#v", got)
+	}
+	map32 := []byte{0xdf, 0, 0, 0, 1, 0xc2, 0xc3}
+	if got, _, _ := Decode(map32); !reflect.DeepEqual(got, map[any]any{false: true}) {
+		t.Errorf("Decode(map32) = %#v", got)
+	}
+}
+
+func TestDecode_Errors(t *testing.T) {
+	deep := bytes.Repeat([]byte{0x91}, MaxDepth+1)
+	deep = append(deep, 0xc0)
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"empty", nil, io.ErrUnexpectedEOF},
+		{"reserved", []byte{0xc1}, ErrMalformed},
+		{"truncated str", []byte{0xa5, 'a'}, io.ErrUnexpectedEOF},
+		{"truncated uint32", []byte{0xce, 0, 0}, io.ErrUnexpectedEOF},
+		{"truncated array", []byte{0x92, 0x01}, io.ErrUnexpectedEOF},
+		{"huge array", []byte{0xdd, 0xff, 0xff, 0xff, 0xff}, io.ErrUnexpectedEOF},
+		{"bin key", []byte{0x81, 0xc4, 0x00, 0x01}, ErrMalformed},
+		{"array key", []byte{0x81, 0x90, 0x01}, ErrMalformed},
+		{"too deep", deep, ErrDepthExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := Decode(tt.data); !errors.Is(err, tt.want) {
+				t.Errorf("Decode() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecoder_Tokens(t *testing.T) {
+	data := []byte{0x82, 0xa2, 'i', 'd', 0x07, 0xa4, 't', 'a', 'g', 's', 0x92, 0xa1, 'x', 0xa1, 'y', 0xc3}
+	d := NewDecoder(data)
+
+	var types []Type
+	for {
+		tok, err := d.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		types = append(types, tok.Type)
+		if tok.Type == Str {
+			// 0xc1 is never used, so decoding fails if this reaches the input.
+			_ = append(tok.Bytes, 0xc1)
+		}
+	}
+	want := []Type{Map, Str, Int, Str, Array, Str, Str, Bool}
+	if !reflect.DeepEqual(types, want) {
+		t.Errorf("token types = %v, want %v", types, want)
+	}
+}
+
+func TestDecoder_Skip(t *testing.T) {
+	data := []byte{0x82, 0xa1, 'a', 0x92, 0x01, 0x81, 0x02, 0x03, 0xa1, 'b', 0xc0, 0x2a}
+	d := NewDecoder(data)
+	if err := d.Skip(); err != nil {
+		t.Fatalf("Skip() error = %v", err)
+	}
+	v, err := d.Decode()
+	if err != nil || v != int64(42) {
+		t.Errorf("Decode() after Skip = %v, %v, want 42", v, err)
+	}
+	if d.More() {
+		t.Errorf("More() = true at end")
+	}
+	if _, err := d.Decode(); err != io.EOF {
+		t.Errorf("Decode() at end error = %v, want io.EOF", err)
+	}
+	if err := NewDecoder([]byte{0x92, 0x01}).Skip(); err != io.ErrUnexpectedEOF {
+		t.Errorf("Skip(truncated) error = %v, want io.ErrUnexpectedEOF", err)
+	}
+}
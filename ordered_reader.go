@@ -0,0 +1,144 @@
+package wireread
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+// OrderedReader is a SafeReader whose multi-byte reads use a byte order chosen
+// at runtime, for formats such as TIFF, ELF and pcap that declare their
+// endianness in a header. All SafeReader methods remain available, and the
+// fixed-order ones ignore the configured order.
+type OrderedReader struct {
+	*SafeReader
+	order binary.ByteOrder
+	kind  orderKind
+}
+
+// orderKind lets reads in the standard byte orders avoid calling through the
+// binary.ByteOrder interface.
+type orderKind uint8
+
+const (
+	otherEndian orderKind = iota
+	bigEndian
+	littleEndian
+)
+
+func kindOf(order binary.ByteOrder) orderKind {
+	switch order {
+	case binary.BigEndian:
+		return bigEndian
+	case binary.LittleEndian:
+		return littleEndian
+	}
+	return otherEndian
+}
+
+// NewOrderedReader creates an OrderedReader for the given data reading in the
+// given byte order.
+func NewOrderedReader(data []byte, order binary.ByteOrder) *OrderedReader {
+	return NewSafeReader(data).WithOrder(order)
+}
+
+// WithOrder returns an OrderedReader reading in the given byte order. It shares
+// the read position with sr, so reads through either advance both.
+func (sr *SafeReader) WithOrder(order binary.ByteOrder) *OrderedReader {
+	return &OrderedReader{SafeReader: sr, order: order, kind: kindOf(order)}
+}
+
+// Order returns the byte order in use
+func (or *OrderedReader) Order() binary.ByteOrder {
+	return or.order
+}
+
+// SetOrder switches the byte order used by subsequent reads
+func (or *OrderedReader) SetOrder(order binary.ByteOrder) {
+	or.order = order
+	or.kind = kindOf(order)
+}
+
+// ReadUint16 reads a 16-bit unsigned integer in the configured byte order
+func (or *OrderedReader) ReadUint16() (uint16, error) {
+	if or.rpos+2 > or.size {
+		return 0, io.ErrUnexpectedEOF
+	}
+	var tmp uint16
+	switch b := or.data[or.rpos:]; or.kind {
+	case bigEndian:
+		tmp = binary.BigEndian.Uint16(b)
+	case littleEndian:
+		tmp = binary.LittleEndian.Uint16(b)
+	default:
+		tmp = or.order.Uint16(b)
+	}
+	or.rpos += 2
+	return tmp, nil
+}
+
+// ReadUint32 reads a 32-bit unsigned integer in the configured byte order
+func (or *OrderedReader) ReadUint32() (uint32, error) {
+	if or.rpos+4 > or.size {
+		return 0, io.ErrUnexpectedEOF
+	}
+	var tmp uint32
+	switch b := or.data[or.rpos:]; or.kind {
+	case bigEndian:
+		tmp = binary.BigEndian.Uint32(b)
+	case littleEndian:
+		tmp = binary.LittleEndian.Uint32(b)
+	default:
+		tmp = or.order.Uint32(b)
+	}
+	or.rpos += 4
+	return tmp, nil
+}
+
+// ReadUint64 reads a 64-bit unsigned integer in the configured byte order
+func (or *OrderedReader) ReadUint64() (uint64, error) {
+	if or.rpos+8 > or.size {
+		return 0, io.ErrUnexpectedEOF
+	}
+	var tmp uint64
+	switch b := or.data[or.rpos:]; or.kind {
+	case bigEndian:
+		tmp = binary.BigEndian.Uint64(b)
+	case littleEndian:
+		tmp = binary.LittleEndian.Uint64(b)
+	default:
+		tmp = or.order.Uint64(b)
+	}
+	or.rpos += 8
+	return tmp, nil
+}
+
+// ReadInt16 reads a 16-bit signed integer in the configured byte order
+func (or *OrderedReader) ReadInt16() (int16, error) {
+	tmp, err := or.ReadUint16()
+	return int16(tmp), err
+}
+
+// ReadInt32 reads a 32-bit signed integer in the configured byte order
+func (or *OrderedReader) ReadInt32() (int32, error) {
+	tmp, err := or.ReadUint32()
+	return int32(tmp), err
+}
+
+// ReadInt64 reads a 64-bit signed integer in the configured byte order
+func (or *OrderedReader) ReadInt64() (int64, error) {
+	tmp, err := or.ReadUint64()
+	return int64(tmp), err
+}
+
+// ReadFloat32 reads an IEEE 754 single-precision float in the configured byte order
+func (or *OrderedReader) ReadFloat32() (float32, error) {
+	tmp, err := or.ReadUint32()
+	return math.Float32frombits(tmp), err
+}
+
+// ReadFloat64 reads an IEEE 754 double-precision float in the configured byte order
+func (or *OrderedReader) ReadFloat64() (float64, error) {
+	tmp, err := or.ReadUint64()
+	return math.Float64frombits(tmp), err
+}
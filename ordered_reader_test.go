@@ -0,0 +1,89 @@
+package wireread
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"testing"
+)
+
+func TestOrderedReader_Reads(t *testing.T) {
+	data := []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}
+	tests := []struct {
+		name   string
+		order  binary.ByteOrder
+		want16 uint16
+		want32 uint32
+		want64 uint64
+	}{
+		{"big-endian", binary.BigEndian, 0x0102, 0x01020304, 0x0102030405060708},
+		{"little-endian", binary.LittleEndian, 0x0201, 0x04030201, 0x0807060504030201},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewOrderedReader(data, tt.order)
+			if got, err := r.ReadUint16(); got != tt.want16 || err != nil {
+				t.Errorf("ReadUint16() = %#x, %v, want %#x", got, err, tt.want16)
+			}
+			r = NewOrderedReader(data, tt.order)
+			if got, err := r.ReadUint32(); got != tt.want32 || err != nil {
+				t.Errorf("ReadUint32() = %#x, %v, want %#x", got, err, tt.want32)
+			}
+			r = NewOrderedReader(data, tt.order)
+			if got, err := r.ReadUint64(); got != tt.want64 || err != nil {
+				t.Errorf("ReadUint64() = %#x, %v, want %#x", got, err, tt.want64)
+			}
+			if _, err := r.ReadUint16(); err != io.ErrUnexpectedEOF {
+				t.Errorf("ReadUint16() at end error = %v, want io.ErrUnexpectedEOF", err)
+			}
+		})
+	}
+}
+
+func TestOrderedReader_SignedAndFloat(t *testing.T) {
+	var data []byte
+	data = binary.LittleEndian.AppendUint16(data, 0xfffe)
+	data = binary.LittleEndian.AppendUint32(data, 0xfffffffd)
+	data = binary.LittleEndian.AppendUint64(data, 0xfffffffffffffffc)
+	data = binary.LittleEndian.AppendUint32(data, math.Float32bits(1.5))
+	data = binary.LittleEndian.AppendUint64(data, math.Float64bits(-2.25))
+
+	r := NewOrderedReader(data, binary.LittleEndian)
+	i16, _ := r.ReadInt16()
+	i32, _ := r.ReadInt32()
+	i64, _ := r.ReadInt64()
+	f32, _ := r.ReadFloat32()
+	f64, err := r.ReadFloat64()
+	if i16 != -2 || i32 != -3 || i64 != -4 || f32 != 1.5 || f64 != -2.25 || err != nil {
+		t.Errorf("reads = %d, %d, %d, %v, %v, %v", i16, i32, i64, f32, f64, err)
+	}
+	if _, err := r.ReadFloat32(); err != io.ErrUnexpectedEOF {
+		t.Errorf("ReadFloat32() at end error = %v, want io.ErrUnexpectedEOF", err)
+	}
+}
+
+func TestOrderedReader_SetOrder(t *testing.T) {
+	// A TIFF-style header: the byte order mark decides how the rest is read.
+	data := []byte{'I', 'I', 0x2a, 0x00, 'M', 'M', 0x00, 0x2a}
+	sr := NewSafeReader(data)
+	r := sr.WithOrder(binary.BigEndian)
+	for i, want := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		mark, _ := r.ReadString(2)
+		if mark == "II" {
+			r.SetOrder(binary.LittleEndian)
+		} else {
+			r.SetOrder(binary.BigEndian)
+		}
+		if r.Order() != want {
+			t.Errorf("header %d: Order() = %v, want %v", i, r.Order(), want)
+		}
+		if got, err := r.ReadUint16(); got != 42 || err != nil {
+			t.Errorf("header %d: ReadUint16() = %d, %v, want 42", i, got, err)
+		}
+	}
+	// The cursor is shared with the SafeReader.
+	if len(sr.Bytes()) != 0 {
+		t.Errorf("SafeReader has %d bytes left, want 0", len(sr.Bytes()))
+	}
+}
@@ -0,0 +1,340 @@
+// Package pcap reads packet capture files in the classic pcap and the
+// pcapng formats.
+//
+// NewReader detects the format and byte order from the first bytes of the
+// file, so the same loop handles captures from any writer. Both formats
+// yield Packets with their timestamp, interface and link type, and a slice
+// of the file as the data. Classic files are presented as having a single
+// interface, described by the file header.
+//
+// Example usage:
+//
+//	r, err := pcap.NewReader(data)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	for {
+//	    pkt, err := r.Next()
+//	    if err == io.EOF {
+//	        break
+//	    }
+//	    if err != nil {
+//	        log.Fatal(err)
+//	    }
+//	    fmt.Println(pkt.Timestamp, pkt.LinkType, len(pkt.Data))
+//	}
+package pcap
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+	"time"
+
+	"github.com/nemohan/wireread"
+)
+
+var (
+	// ErrUnknownFormat is returned by NewReader when the data starts with
+	// neither a pcap magic number nor a pcapng section header.
+	ErrUnknownFormat = errors.New("pcap: unknown file format")
+
+	// ErrMalformed is returned for inconsistent block lengths, options
+	// running past their block, and packets on undeclared interfaces.
+	ErrMalformed = errors.New("pcap: malformed capture")
+)
+
+// Format is a capture file format.
+type Format uint8
+
+// File formats.
+const (
+	FormatPcap Format = iota + 1
+	FormatPcapNG
+)
+
+func (f Format) String() string {
+	switch f {
+	case FormatPcap:
+		return "pcap"
+	case FormatPcapNG:
+		return "pcapng"
+	}
+	return fmt.Sprintf("Format(%d)", uint8(f))
+}
+
+// Classic pcap magic numbers, as read in the file's own byte order.
+const (
+	magicMicros = 0xa1b2c3d4
+	magicNanos  = 0xa1b23c4d
+)
+
+// LinkType is the link-layer header type of the captured packets.
+type LinkType uint16
+
+// Common link types.
+const (
+	LinkTypeNull      LinkType = 0
+	LinkTypeEthernet  LinkType = 1
+	LinkTypeRaw       LinkType = 101
+	LinkTypeIEEE80211 LinkType = 105
+	LinkTypeLoop      LinkType = 108
+	LinkTypeLinuxSLL  LinkType = 113
+	LinkTypeIPv4      LinkType = 228
+	LinkTypeIPv6      LinkType = 229
+	LinkTypeLinuxSLL2 LinkType = 276
+)
+
+var linkTypeNames = map[LinkType]string{
+	LinkTypeNull:      "NULL",
+	LinkTypeEthernet:  "ETHERNET",
+	LinkTypeRaw:       "RAW",
+	LinkTypeIEEE80211: "IEEE802_11",
+	LinkTypeLoop:      "LOOP",
+	LinkTypeLinuxSLL:  "LINUX_SLL",
+	LinkTypeIPv4:      "IPV4",
+	LinkTypeIPv6:      "IPV6",
+	LinkTypeLinuxSLL2: "LINUX_SLL2",
+}
+
+func (l LinkType) String() string {
+	if s, ok := linkTypeNames[l]; ok {
+		return s
+	}
+	return fmt.Sprintf("LinkType(%d)", uint16(l))
+}
+
+// Interface describes a capture interface. In pcapng files it comes from an
+// Interface Description Block; in classic files from the file header.
+type Interface struct {
+	LinkType LinkType
+	SnapLen  uint32
+
+	Name        string
+	Description string
+	Filter      string
+	OS          string
+
+	// FCSLen is the length in bytes of the frame check sequence at the
+	// end of each packet, or -1 if unknown.
+	FCSLen int
+
+	// Resolution is the timestamp unit: 10^-Exp seconds, or 2^-Exp
+	// seconds if Binary is set.
+	Resolution Resolution
+
+	// TSOffset is added, in seconds, to every timestamp.
+	TSOffset int64
+
+	Options []Option
+}
+
+// Resolution is a timestamp unit.
+type Resolution struct {
+	Exp    uint8
+	Binary bool
+}
+
+// Default timestamp resolutions.
+var (
+	Microseconds = Resolution{Exp: 6}
+	Nanoseconds  = Resolution{Exp: 9}
+)
+
+// valid reports whether units of r fit the uint64 arithmetic of time.
+func (r Resolution) valid() bool {
+	if r.Binary {
+		return r.Exp < 64
+	}
+	return r.Exp <= 19
+}
+
+// time converts a count of resolution units since the epoch.
+func (r Resolution) time(ts uint64, offset int64) time.Time {
+	var sec, nsec uint64
+	switch {
+	case r.Binary:
+		sec = ts >> r.Exp
+		hi, lo := bits.Mul64(ts&(1<<r.Exp-1), 1e9)
+		nsec, _ = bits.Div64(hi, lo, 1<<r.Exp)
+	default:
+		unit := pow10(r.Exp)
+		sec, nsec = ts/unit, ts%unit
+		if r.Exp <= 9 {
+			nsec *= pow10(9 - r.Exp)
+		} else {
+			nsec /= pow10(r.Exp - 9)
+		}
+	}
+	return time.Unix(int64(sec)+offset, int64(nsec)).UTC()
+}
+
+func pow10(n uint8) uint64 {
+	v := uint64(1)
+	for ; n > 0; n-- {
+		v *= 10
+	}
+	return v
+}
+
+// Packet is a captured packet.
+type Packet struct {
+	Timestamp time.Time
+
+	// Data is the captured bytes, which alias the file data. It may be
+	// shorter than Length if the capture was truncated to the snap length.
+	Data   []byte
+	Length int
+
+	// InterfaceIndex is the index in Reader.Interfaces of the interface
+	// the packet was captured on, and LinkType its link type.
+	InterfaceIndex int
+	LinkType       LinkType
+
+	// Flags is the epb_flags option of a pcapng Enhanced Packet Block.
+	Flags uint32
+
+	// Options are the options of a pcapng Enhanced Packet Block.
+	Options []Option
+}
+
+// Reader reads packets from a capture file held in memory.
+type Reader struct {
+	r      *wireread.OrderedReader
+	size   int
+	format Format
+
+	// Version of the classic file header or current pcapng section.
+	major, minor uint16
+
+	section Section
+	ifaces  []Interface
+	names   []NameRecord
+}
+
+// NewReader creates a Reader, detecting the format and byte order. For
+// pcapng files it reads the first Section Header Block.
+func NewReader(data []byte) (*Reader, error) {
+	// The byte order is set once the magic or section header is read.
+	r := &Reader{r: wireread.NewOrderedReader(data, binary.LittleEndian), size: len(data)}
+	if len(data) < 4 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if binary.LittleEndian.Uint32(data) == blockSHB {
+		r.format = FormatPcapNG
+		if err := r.readSectionHeader(); err != nil {
+			return nil, err
+		}
+		return r, nil
+	}
+
+	r.format = FormatPcap
+	var order binary.ByteOrder
+	var res Resolution
+	switch {
+	case binary.LittleEndian.Uint32(data) == magicMicros:
+		order, res = binary.LittleEndian, Microseconds
+	case binary.BigEndian.Uint32(data) == magicMicros:
+		order, res = binary.BigEndian, Microseconds
+	case binary.LittleEndian.Uint32(data) == magicNanos:
+		order, res = binary.LittleEndian, Nanoseconds
+	case binary.BigEndian.Uint32(data) == magicNanos:
+		order, res = binary.BigEndian, Nanoseconds
+	default:
+		return nil, fmt.Errorf("%w: magic % x", ErrUnknownFormat, data[:4])
+	}
+	r.r.SetOrder(order)
+	if err := r.r.Skip(4); err != nil {
+		return nil, err
+	}
+	var err error
+	if r.major, err = r.r.ReadUint16(); err != nil {
+		return nil, err
+	}
+	if r.minor, err = r.r.ReadUint16(); err != nil {
+		return nil, err
+	}
+	// thiszone and sigfigs are always zero in practice.
+	if err := r.r.Skip(8); err != nil {
+		return nil, err
+	}
+	snap, err := r.r.ReadUint32()
+	if err != nil {
+		return nil, err
+	}
+	network, err := r.r.ReadUint32()
+	if err != nil {
+		return nil, err
+	}
+	iface := Interface{LinkType: LinkType(network), SnapLen: snap, Resolution: res, FCSLen: -1}
+	// Bit 26 marks the FCS length, in 16-bit words, as present in bits
+	// 28-31.
+	if network&(1<<26) != 0 {
+		iface.FCSLen = int(network>>28) * 2
+	}
+	r.ifaces = []Interface{iface}
+	return r, nil
+}
+
+// Format returns the file format.
+func (r *Reader) Format() Format {
+	return r.format
+}
+
+// ByteOrder returns the byte order of the file or current section.
+func (r *Reader) ByteOrder() binary.ByteOrder {
+	return r.r.Order()
+}
+
+// Version returns the version of the classic file format or of the current
+// pcapng section.
+func (r *Reader) Version() (major, minor uint16) {
+	return r.major, r.minor
+}
+
+// Interfaces returns the interfaces declared so far in the current section.
+func (r *Reader) Interfaces() []Interface {
+	return r.ifaces
+}
+
+// Offset returns the number of bytes consumed so far.
+func (r *Reader) Offset() int {
+	return r.size - len(r.r.Bytes())
+}
+
+// Next returns the next packet. It returns io.EOF at the end of the file
+// and io.ErrUnexpectedEOF if the file ends inside a record or block.
+func (r *Reader) Next() (Packet, error) {
+	if len(r.r.Bytes()) == 0 {
+		return Packet{}, io.EOF
+	}
+	if r.format == FormatPcapNG {
+		return r.nextBlock()
+	}
+
+	var hdr [4]uint32
+	for i := range hdr {
+		v, err := r.r.ReadUint32()
+		if err != nil {
+			return Packet{}, err
+		}
+		hdr[i] = v
+	}
+	if uint64(hdr[2]) > uint64(len(r.r.Bytes())) {
+		return Packet{}, io.ErrUnexpectedEOF
+	}
+	data, err := r.r.ReadSlice(int(hdr[2]))
+	if err != nil {
+		return Packet{}, err
+	}
+	iface := &r.ifaces[0]
+	ts := uint64(hdr[0])*pow10(iface.Resolution.Exp) + uint64(hdr[1])
+	return Packet{
+		Timestamp: iface.Resolution.time(ts, 0),
+		Data:      data,
+		Length:    int(hdr[3]),
+		LinkType:  iface.LinkType,
+	}, nil
+}
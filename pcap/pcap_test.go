@@ -0,0 +1,114 @@
+package pcap
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+// byteOrder is a byte order that can also append, as the binary package's
+// LittleEndian and BigEndian do.
+type byteOrder interface {
+	binary.ByteOrder
+	binary.AppendByteOrder
+}
+
+// classic builds a classic pcap file with one record per packet, all
+// stamped ts.
+func classic(order byteOrder, magic, network uint32, ts time.Time, packets ...string) []byte {
+	b := order.AppendUint32(nil, magic)
+	b = order.AppendUint16(b, 2)
+	b = order.AppendUint16(b, 4)
+	b = append(b, make([]byte, 8)...)
+	b = order.AppendUint32(b, 65535)
+	b = order.AppendUint32(b, network)
+	frac := ts.Nanosecond() / 1000
+	if magic == magicNanos {
+		frac = ts.Nanosecond()
+	}
+	for _, p := range packets {
+		b = order.AppendUint32(b, uint32(ts.Unix()))
+		b = order.AppendUint32(b, uint32(frac))
+		b = order.AppendUint32(b, uint32(len(p)))
+		b = order.AppendUint32(b, uint32(len(p)+10))
+		b = append(b, p...)
+	}
+	return b
+}
+
+func TestReader_Classic(t *testing.T) {
+	ts := time.Date(2024, 5, 6, 7, 8, 9, 123456789, time.UTC)
+	tests := []struct {
+		name  string
+		order byteOrder
+		magic uint32
+		want  time.Time
+	}{
+		{"little-endian micros", binary.LittleEndian, magicMicros, ts.Truncate(time.Microsecond)},
+		{"big-endian micros", binary.BigEndian, magicMicros, ts.Truncate(time.Microsecond)},
+		{"little-endian nanos", binary.LittleEndian, magicNanos, ts},
+		{"big-endian nanos", binary.BigEndian, magicNanos, ts},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := classic(tt.order, tt.magic, uint32(LinkTypeEthernet), ts, "abc", "defg")
+			r, err := NewReader(data)
+			if err != nil {
+				t.Fatalf("NewReader() error = %v", err)
+			}
+			if r.Format() != FormatPcap || r.ByteOrder() != tt.order {
+				t.Errorf("Format(), ByteOrder() = %v, %v", r.Format(), r.ByteOrder())
+			}
+			if major, minor := r.Version(); major != 2 || minor != 4 {
+				t.Errorf("Version() = %d.%d, want 2.4", major, minor)
+			}
+			if ifs := r.Interfaces(); len(ifs) != 1 || ifs[0].SnapLen != 65535 || ifs[0].FCSLen != -1 {
+				t.Errorf("Interfaces() = %+v", ifs)
+			}
+			for _, want := range []string{"abc", "defg"} {
+				p, err := r.Next()
+				if err != nil {
+					t.Fatalf("Next() error = %v", err)
+				}
+				if string(p.Data) != want || p.Length != len(want)+10 || !p.Timestamp.Equal(tt.want) || p.LinkType != LinkTypeEthernet {
+					t.Errorf("Next() = %+v, want %q at %v", p, want, tt.want)
+				}
+				// Appending to the data must not overwrite the next record.
+				_ = append(p.Data, make([]byte, 16)...)
+			}
+			if _, err := r.Next(); err != io.EOF {
+				t.Errorf("Next() at end error = %v, want io.EOF", err)
+			}
+		})
+	}
+}
+
+func TestReader_ClassicFCS(t *testing.T) {
+	// Four bytes of FCS: the P bit and two 16-bit words.
+	network := uint32(LinkTypeEthernet) | 1<<26 | 2<<28
+	r, err := NewReader(classic(binary.LittleEndian, magicMicros, network, time.Unix(0, 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ifc := r.Interfaces()[0]; ifc.LinkType != LinkTypeEthernet || ifc.FCSLen != 4 {
+		t.Errorf("Interfaces()[0] = %+v, want ETHERNET with FCSLen 4", ifc)
+	}
+}
+
+func TestReader_Errors(t *testing.T) {
+	if _, err := NewReader([]byte("GIF89a..")); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("NewReader(gif) error = %v, want ErrUnknownFormat", err)
+	}
+	if _, err := NewReader([]byte{0xd4, 0xc3}); err != io.ErrUnexpectedEOF {
+		t.Errorf("NewReader(short) error = %v, want io.ErrUnexpectedEOF", err)
+	}
+
+	data := classic(binary.LittleEndian, magicMicros, 1, time.Unix(0, 0), "abcdef")
+	r, _ := NewReader(data[:len(data)-1])
+	if _, err := r.Next(); err != io.ErrUnexpectedEOF {
+		t.Errorf("Next(truncated) error = %v, want io.ErrUnexpectedEOF", err)
+	}
+}
@@ -0,0 +1,394 @@
+package pcap
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/netip"
+
+	"github.com/nemohan/wireread"
+)
+
+// pcapng block types.
+const (
+	blockIDB = 0x00000001
+	blockSPB = 0x00000003
+	blockNRB = 0x00000004
+	blockEPB = 0x00000006
+	blockSHB = 0x0a0d0d0a // the same in both byte orders
+
+	byteOrderMagic uint32 = 0x1a2b3c4d
+
+	// minBlockLen is the size of an empty block: type and two lengths.
+	minBlockLen = 12
+)
+
+// Option codes. OptComment applies to every block; the others to the
+// block named by their prefix.
+const (
+	OptEndOfOpt    = 0
+	OptComment     = 1
+	OptSHBHardware = 2
+	OptSHBOS       = 3
+	OptSHBUserAppl = 4
+	OptIfName      = 2
+	OptIfDesc      = 3
+	OptIfTSResol   = 9
+	OptIfFilter    = 11
+	OptIfOS        = 12
+	OptIfFCSLen    = 13
+	OptIfTSOffset  = 14
+	OptEPBFlags    = 2
+)
+
+// Option is a pcapng option. Value aliases the file data.
+type Option struct {
+	Code  uint16
+	Value []byte
+}
+
+// Section is the content of a Section Header Block.
+type Section struct {
+	Hardware string
+	OS       string
+	UserAppl string
+	Options  []Option
+}
+
+// NameRecord is an entry of a Name Resolution Block.
+type NameRecord struct {
+	Addr  netip.Addr
+	Names []string
+}
+
+// Section returns the header of the current pcapng section.
+func (r *Reader) Section() Section {
+	return r.section
+}
+
+// Names returns the name resolution records read so far in the current
+// section.
+func (r *Reader) Names() []NameRecord {
+	return r.names
+}
+
+// readSectionHeader reads a Section Header Block, taking the byte order
+// from its byte-order magic and resetting the per-section state.
+func (r *Reader) readSectionHeader() error {
+	off := r.Offset()
+	b := r.r.Bytes()
+	if len(b) < minBlockLen+4 {
+		return io.ErrUnexpectedEOF
+	}
+	switch byteOrderMagic {
+	case binary.LittleEndian.Uint32(b[8:]):
+		r.r.SetOrder(binary.LittleEndian)
+	case binary.BigEndian.Uint32(b[8:]):
+		r.r.SetOrder(binary.BigEndian)
+	default:
+		return fmt.Errorf("%w: byte-order magic % x at offset %d", ErrMalformed, b[8:12], off)
+	}
+	body, err := r.block()
+	if err != nil {
+		return err
+	}
+	br := wireread.NewOrderedReader(body, r.r.Order())
+	_ = br.Skip(4)
+	if r.major, err = br.ReadUint16(); err != nil {
+		return r.errorf(off, "short section header")
+	}
+	if r.minor, err = br.ReadUint16(); err != nil {
+		return r.errorf(off, "short section header")
+	}
+	if r.major != 1 {
+		return fmt.Errorf("%w: pcapng version %d.%d", ErrUnknownFormat, r.major, r.minor)
+	}
+	// The section length is advisory and usually -1.
+	if err := br.Skip(8); err != nil {
+		return r.errorf(off, "short section header")
+	}
+	opts, err := r.options(br.Bytes(), off)
+	if err != nil {
+		return err
+	}
+	r.section = Section{Options: opts}
+	for _, o := range opts {
+		switch o.Code {
+		case OptSHBHardware:
+			r.section.Hardware = string(o.Value)
+		case OptSHBOS:
+			r.section.OS = string(o.Value)
+		case OptSHBUserAppl:
+			r.section.UserAppl = string(o.Value)
+		}
+	}
+	r.ifaces, r.names = nil, nil
+	return nil
+}
+
+// block reads a whole block, checks its two length fields and returns the
+// body between them.
+func (r *Reader) block() ([]byte, error) {
+	off := r.Offset()
+	b := r.r.Bytes()
+	if len(b) < 8 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	n := r.r.Order().Uint32(b[4:])
+	if n < minBlockLen || n%4 != 0 {
+		return nil, r.errorf(off, "block length %d", n)
+	}
+	if uint64(n) > uint64(len(b)) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if r.r.Order().Uint32(b[n-4:]) != n {
+		return nil, r.errorf(off, "trailing block length %d, want %d", r.r.Order().Uint32(b[n-4:]), n)
+	}
+	_ = r.r.Skip(int(n))
+	return b[8 : n-4], nil
+}
+
+// nextBlock reads blocks until one holds a packet.
+func (r *Reader) nextBlock() (Packet, error) {
+	for len(r.r.Bytes()) > 0 {
+		off := r.Offset()
+		b := r.r.Bytes()
+		if len(b) < 4 {
+			return Packet{}, io.ErrUnexpectedEOF
+		}
+		typ := r.r.Order().Uint32(b)
+		if typ == blockSHB {
+			if err := r.readSectionHeader(); err != nil {
+				return Packet{}, err
+			}
+			continue
+		}
+		body, err := r.block()
+		if err != nil {
+			return Packet{}, err
+		}
+		switch typ {
+		case blockIDB:
+			if err := r.readInterface(body, off); err != nil {
+				return Packet{}, err
+			}
+		case blockNRB:
+			if err := r.readNames(body, off); err != nil {
+				return Packet{}, err
+			}
+		case blockEPB:
+			return r.readEnhancedPacket(body, off)
+		case blockSPB:
+			return r.readSimplePacket(body, off)
+		}
+		// Statistics, decryption secrets, custom and obsolete blocks are
+		// skipped.
+	}
+	return Packet{}, io.EOF
+}
+
+func (r *Reader) readInterface(body []byte, off int) error {
+	if len(body) < 8 {
+		return r.errorf(off, "short interface description")
+	}
+	iface := Interface{
+		LinkType:   LinkType(r.r.Order().Uint16(body)),
+		SnapLen:    r.r.Order().Uint32(body[4:]),
+		Resolution: Microseconds,
+		FCSLen:     -1,
+	}
+	opts, err := r.options(body[8:], off)
+	if err != nil {
+		return err
+	}
+	iface.Options = opts
+	for _, o := range opts {
+		switch o.Code {
+		case OptIfName:
+			iface.Name = string(o.Value)
+		case OptIfDesc:
+			iface.Description = string(o.Value)
+		case OptIfOS:
+			iface.OS = string(o.Value)
+		case OptIfFilter:
+			// The first byte is the filter type; 0 is a libpcap string.
+			if len(o.Value) > 0 {
+				iface.Filter = string(o.Value[1:])
+			}
+		case OptIfTSResol:
+			if len(o.Value) != 1 {
+				return r.errorf(off, "if_tsresol of %d bytes", len(o.Value))
+			}
+			iface.Resolution = Resolution{Exp: o.Value[0] & 0x7f, Binary: o.Value[0]&0x80 != 0}
+			if !iface.Resolution.valid() {
+				return r.errorf(off, "if_tsresol 0x%02x", o.Value[0])
+			}
+		case OptIfFCSLen:
+			if len(o.Value) != 1 {
+				return r.errorf(off, "if_fcslen of %d bytes", len(o.Value))
+			}
+			iface.FCSLen = int(o.Value[0])
+		case OptIfTSOffset:
+			if len(o.Value) != 8 {
+				return r.errorf(off, "if_tsoffset of %d bytes", len(o.Value))
+			}
+			iface.TSOffset = int64(r.r.Order().Uint64(o.Value))
+		}
+	}
+	r.ifaces = append(r.ifaces, iface)
+	return nil
+}
+
+func (r *Reader) readEnhancedPacket(body []byte, off int) (Packet, error) {
+	if len(body) < 20 {
+		return Packet{}, r.errorf(off, "short enhanced packet block")
+	}
+	id := r.r.Order().Uint32(body)
+	if uint64(id) >= uint64(len(r.ifaces)) {
+		return Packet{}, r.errorf(off, "packet on undeclared interface %d", id)
+	}
+	iface := &r.ifaces[id]
+	ts := uint64(r.r.Order().Uint32(body[4:]))<<32 | uint64(r.r.Order().Uint32(body[8:]))
+	capLen := r.r.Order().Uint32(body[12:])
+	padded := (uint64(capLen) + 3) &^ 3
+	if padded > uint64(len(body)-20) {
+		return Packet{}, r.errorf(off, "captured length %d exceeds block", capLen)
+	}
+	pkt := Packet{
+		Timestamp:      iface.Resolution.time(ts, iface.TSOffset),
+		Data:           body[20 : 20+capLen : 20+capLen],
+		Length:         int(r.r.Order().Uint32(body[16:])),
+		InterfaceIndex: int(id),
+		LinkType:       iface.LinkType,
+	}
+	opts, err := r.options(body[20+padded:], off)
+	if err != nil {
+		return Packet{}, err
+	}
+	pkt.Options = opts
+	for _, o := range opts {
+		if o.Code == OptEPBFlags && len(o.Value) == 4 {
+			pkt.Flags = r.r.Order().Uint32(o.Value)
+		}
+	}
+	return pkt, nil
+}
+
+// readSimplePacket reads a Simple Packet Block, which always belongs to the
+// first interface and has no timestamp. Its captured length is the smaller
+// of the original length and the interface's snap length.
+func (r *Reader) readSimplePacket(body []byte, off int) (Packet, error) {
+	if len(r.ifaces) == 0 {
+		return Packet{}, r.errorf(off, "simple packet block before any interface")
+	}
+	if len(body) < 4 {
+		return Packet{}, r.errorf(off, "short simple packet block")
+	}
+	iface := &r.ifaces[0]
+	orig := r.r.Order().Uint32(body)
+	capLen := uint64(orig)
+	if iface.SnapLen != 0 && capLen > uint64(iface.SnapLen) {
+		capLen = uint64(iface.SnapLen)
+	}
+	if capLen > uint64(len(body)-4) {
+		return Packet{}, r.errorf(off, "packet length %d exceeds block", orig)
+	}
+	return Packet{
+		Data:     body[4 : 4+capLen : 4+capLen],
+		Length:   int(orig),
+		LinkType: iface.LinkType,
+	}, nil
+}
+
+// Name resolution record types.
+const (
+	nrbEnd  = 0
+	nrbIPv4 = 1
+	nrbIPv6 = 2
+)
+
+func (r *Reader) readNames(body []byte, off int) error {
+	br := wireread.NewOrderedReader(body, r.r.Order())
+	for {
+		typ, err := br.ReadUint16()
+		if err != nil {
+			return r.errorf(off, "truncated name resolution record")
+		}
+		n, err := br.ReadUint16()
+		if err != nil {
+			return r.errorf(off, "truncated name resolution record")
+		}
+		v, err := br.ReadSlice(int(n))
+		if err != nil {
+			return r.errorf(off, "name resolution record of %d bytes exceeds block", n)
+		}
+		_ = br.Skip(min(padLen(int(n)), len(br.Bytes())))
+		var addrLen int
+		switch typ {
+		case nrbEnd:
+			_, err := r.options(br.Bytes(), off)
+			return err
+		case nrbIPv4:
+			addrLen = 4
+		case nrbIPv6:
+			addrLen = 16
+		default:
+			continue
+		}
+		if len(v) < addrLen {
+			return r.errorf(off, "short name resolution record")
+		}
+		addr, _ := netip.AddrFromSlice(v[:addrLen])
+		r.names = append(r.names, NameRecord{Addr: addr, Names: splitNUL(v[addrLen:])})
+	}
+}
+
+// options parses the option list at the end of a block body.
+func (r *Reader) options(b []byte, off int) ([]Option, error) {
+	var opts []Option
+	br := wireread.NewOrderedReader(b, r.r.Order())
+	for len(br.Bytes()) > 0 {
+		code, err := br.ReadUint16()
+		if err != nil {
+			return nil, r.errorf(off, "truncated option")
+		}
+		n, err := br.ReadUint16()
+		if err != nil {
+			return nil, r.errorf(off, "truncated option")
+		}
+		if code == OptEndOfOpt {
+			break
+		}
+		v, err := br.ReadSlice(int(n))
+		if err != nil {
+			return nil, r.errorf(off, "option %d of %d bytes exceeds block", code, n)
+		}
+		// Some writers omit the padding of the last option.
+		_ = br.Skip(min(padLen(int(n)), len(br.Bytes())))
+		opts = append(opts, Option{Code: code, Value: v})
+	}
+	return opts, nil
+}
+
+func (r *Reader) errorf(off int, format string, args ...any) error {
+	return fmt.Errorf("%w: %s at offset %d", ErrMalformed, fmt.Sprintf(format, args...), off)
+}
+
+// padLen returns the padding after n bytes to reach a 32-bit boundary.
+func padLen(n int) int {
+	return -n & 3
+}
+
+// splitNUL splits NUL-terminated strings, ignoring a missing final NUL.
+func splitNUL(b []byte) []string {
+	var out []string
+	for len(b) > 0 {
+		i := 0
+		for i < len(b) && b[i] != 0 {
+			i++
+		}
+		out = append(out, string(b[:i]))
+		b = b[min(i+1, len(b)):]
+	}
+	return out
+}
@@ -0,0 +1,243 @@
+package pcap
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+// ngWriter builds pcapng test files.
+type ngWriter struct {
+	order byteOrder
+	b     []byte
+}
+
+func pad(b []byte) []byte {
+	return append(b, make([]byte, padLen(len(b)))...)
+}
+
+func (w *ngWriter) option(b []byte, code uint16, v []byte) []byte {
+	b = w.order.AppendUint16(b, code)
+	b = w.order.AppendUint16(b, uint16(len(v)))
+	return pad(append(b, v...))
+}
+
+func (w *ngWriter) endOptions(b []byte) []byte {
+	return append(b, 0, 0, 0, 0)
+}
+
+func (w *ngWriter) block(typ uint32, body []byte) {
+	n := uint32(12 + len(body))
+	w.b = w.order.AppendUint32(w.b, typ)
+	w.b = w.order.AppendUint32(w.b, n)
+	w.b = append(w.b, body...)
+	w.b = w.order.AppendUint32(w.b, n)
+}
+
+func (w *ngWriter) section(hardware string) {
+	body := w.order.AppendUint32(nil, byteOrderMagic)
+	body = w.order.AppendUint16(body, 1)
+	body = w.order.AppendUint16(body, 0)
+	body = w.order.AppendUint64(body, ^uint64(0))
+	body = w.endOptions(w.option(body, OptSHBHardware, []byte(hardware)))
+	w.block(blockSHB, body)
+}
+
+func (w *ngWriter) iface(link LinkType, snap uint32, opts ...Option) {
+	body := w.order.AppendUint16(nil, uint16(link))
+	body = append(body, 0, 0)
+	body = w.order.AppendUint32(body, snap)
+	for _, o := range opts {
+		body = w.option(body, o.Code, o.Value)
+	}
+	if len(opts) > 0 {
+		body = w.endOptions(body)
+	}
+	w.block(blockIDB, body)
+}
+
+func (w *ngWriter) enhanced(id uint32, ts uint64, data string, opts ...Option) {
+	body := w.order.AppendUint32(nil, id)
+	body = w.order.AppendUint32(body, uint32(ts>>32))
+	body = w.order.AppendUint32(body, uint32(ts))
+	body = w.order.AppendUint32(body, uint32(len(data)))
+	body = w.order.AppendUint32(body, uint32(len(data)))
+	body = pad(append(body, data...))
+	for _, o := range opts {
+		body = w.option(body, o.Code, o.Value)
+	}
+	w.block(blockEPB, body)
+}
+
+func (w *ngWriter) simple(orig uint32, data string) {
+	body := pad(append(w.order.AppendUint32(nil, orig), data...))
+	w.block(blockSPB, body)
+}
+
+func (w *ngWriter) names() {
+	var body []byte
+	rec := append([]byte{192, 0, 2, 1}, "a.example\x00b.example\x00"...)
+	body = w.order.AppendUint16(body, nrbIPv4)
+	body = w.order.AppendUint16(body, uint16(len(rec)))
+	body = pad(append(body, rec...))
+	rec = append(netip.MustParseAddr("2001:db8::1").AsSlice(), "c.example\x00"...)
+	body = w.order.AppendUint16(body, nrbIPv6)
+	body = w.order.AppendUint16(body, uint16(len(rec)))
+	body = pad(append(body, rec...))
+	body = append(body, 0, 0, 0, 0)
+	w.block(blockNRB, body)
+}
+
+func TestReader_PcapNG(t *testing.T) {
+	for _, order := range []byteOrder{binary.LittleEndian, binary.BigEndian} {
+		t.Run(order.String(), func(t *testing.T) {
+			w := &ngWriter{order: order}
+			w.section("x86")
+			w.iface(LinkTypeEthernet, 4, Option{OptIfName, []byte("eth0")})
+			w.iface(LinkTypeRaw, 0,
+				Option{OptIfTSResol, []byte{9}},
+				Option{OptIfTSOffset, order.AppendUint64(nil, 100)},
+				Option{OptIfFilter, []byte("\x00tcp port 80")},
+			)
+			w.b = append(w.b, order.AppendUint32(nil, 5)...) // interface statistics
+			w.b = append(w.b, order.AppendUint32(nil, 12)...)
+			w.b = append(w.b, order.AppendUint32(nil, 12)...)
+			w.enhanced(0, 1_700_000_000_123_456, "hello", Option{OptEPBFlags, order.AppendUint32(nil, 1)}, Option{OptComment, []byte("hi")})
+			w.names()
+			w.enhanced(1, 1_500_000_000, "ip")
+			w.simple(10, "simple")
+
+			r, err := NewReader(w.b)
+			if err != nil {
+				t.Fatalf("NewReader() error = %v", err)
+			}
+			if r.Format() != FormatPcapNG || r.ByteOrder() != order || r.Section().Hardware != "x86" {
+				t.Errorf("Format(), ByteOrder(), Section() = %v, %v, %+v", r.Format(), r.ByteOrder(), r.Section())
+			}
+
+			p, err := r.Next()
+			if err != nil {
+				t.Fatalf("Next() error = %v", err)
+			}
+			if string(p.Data) != "hello" || p.InterfaceIndex != 0 || p.Flags != 1 || len(p.Options) != 2 ||
+				!p.Timestamp.Equal(time.Unix(1_700_000_000, 123_456_000)) {
+				t.Errorf("first packet = %+v", p)
+			}
+			// Appending to the data or an option must not overwrite the
+			// blocks that follow.
+			_ = append(p.Data, make([]byte, 64)...)
+			for _, o := range p.Options {
+				_ = append(o.Value, make([]byte, 64)...)
+			}
+			ifs := r.Interfaces()
+			if len(ifs) != 2 || ifs[0].Name != "eth0" || ifs[1].Resolution != Nanoseconds || ifs[1].Filter != "tcp port 80" {
+				t.Errorf("Interfaces() = %+v", ifs)
+			}
+
+			p, err = r.Next()
+			if err != nil {
+				t.Fatalf("Next() error = %v", err)
+			}
+			if string(p.Data) != "ip" || p.LinkType != LinkTypeRaw || !p.Timestamp.Equal(time.Unix(101, 500_000_000)) {
+				t.Errorf("second packet = %+v", p)
+			}
+			names := r.Names()
+			if len(names) != 2 || names[0].Addr != netip.MustParseAddr("192.0.2.1") || len(names[0].Names) != 2 ||
+				names[1].Names[0] != "c.example" {
+				t.Errorf("Names() = %+v", names)
+			}
+
+			// The simple packet is cut to the first interface's snap length.
+			p, err = r.Next()
+			if err != nil || string(p.Data) != "simp" || p.Length != 10 {
+				t.Errorf("simple packet = %+v, %v", p, err)
+			}
+			if _, err := r.Next(); err != io.EOF {
+				t.Errorf("Next() at end error = %v, want io.EOF", err)
+			}
+		})
+	}
+}
+
+func TestReader_PcapNGSections(t *testing.T) {
+	w := &ngWriter{order: binary.LittleEndian}
+	w.section("first")
+	w.iface(LinkTypeEthernet, 0)
+	w.enhanced(0, 0, "a")
+	be := &ngWriter{order: binary.BigEndian}
+	be.section("second")
+	be.iface(LinkTypeIPv4, 0)
+	be.enhanced(0, 0, "b")
+	data := append(w.b, be.b...)
+
+	r, err := NewReader(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Next()
+	p, err := r.Next()
+	if err != nil || string(p.Data) != "b" || p.LinkType != LinkTypeIPv4 {
+		t.Errorf("packet in second section = %+v, %v", p, err)
+	}
+	if r.ByteOrder() != binary.BigEndian || r.Section().Hardware != "second" || len(r.Interfaces()) != 1 {
+		t.Errorf("second section state = %v, %+v, %d interfaces", r.ByteOrder(), r.Section(), len(r.Interfaces()))
+	}
+}
+
+func TestReader_PcapNGErrors(t *testing.T) {
+	build := func(f func(w *ngWriter)) []byte {
+		w := &ngWriter{order: binary.LittleEndian}
+		w.section("x")
+		f(w)
+		return w.b
+	}
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"undeclared interface", build(func(w *ngWriter) { w.enhanced(0, 0, "a") }), ErrMalformed},
+		{"simple before interface", build(func(w *ngWriter) { w.simple(1, "a") }), ErrMalformed},
+		{"bad tsresol", build(func(w *ngWriter) { w.iface(1, 0, Option{OptIfTSResol, []byte{20}}) }), ErrMalformed},
+		{"option overrun", build(func(w *ngWriter) { w.block(blockIDB, []byte{1, 0, 0, 0, 0, 0, 0, 0, 2, 0, 9, 0}) }), ErrMalformed},
+		{"trailer mismatch", build(func(w *ngWriter) {
+			w.iface(1, 0)
+			w.b[len(w.b)-4]++
+		}), ErrMalformed},
+		{"odd length", build(func(w *ngWriter) {
+			w.iface(1, 0)
+			w.b[len(w.b)-16]++
+		}), ErrMalformed},
+		{"truncated", build(func(w *ngWriter) {
+			w.iface(1, 0)
+			w.b = w.b[:len(w.b)-1]
+		}), io.ErrUnexpectedEOF},
+		{"capture length overrun", build(func(w *ngWriter) {
+			w.iface(1, 0)
+			w.enhanced(0, 0, "abcd")
+			binary.LittleEndian.PutUint32(w.b[len(w.b)-16:], 9) // captured length
+		}), ErrMalformed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewReader(tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := r.Next(); !errors.Is(err, tt.want) {
+				t.Errorf("Next() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	w := &ngWriter{order: binary.LittleEndian}
+	w.section("x")
+	w.b[8] = 0
+	if _, err := NewReader(w.b); !errors.Is(err, ErrMalformed) {
+		t.Errorf("NewReader(bad byte-order magic) error = %v, want ErrMalformed", err)
+	}
+}
@@ -0,0 +1,37 @@
+package wireread
+
+import "sync"
+
+var (
+	safeReaderPool = sync.Pool{New: func() any { return new(SafeReader) }}
+	fastReaderPool = sync.Pool{New: func() any { return new(FastReader) }}
+)
+
+// GetSafeReader returns a SafeReader for data, taken from a pool when one is
+// available. Return it with PutSafeReader once the frame has been parsed.
+func GetSafeReader(data []byte) *SafeReader {
+	sr := safeReaderPool.Get().(*SafeReader)
+	sr.Reset(data)
+	return sr
+}
+
+// PutSafeReader returns sr to the pool. sr must not be used afterwards.
+func PutSafeReader(sr *SafeReader) {
+	// Drop the reference to the data so that the pool does not keep it alive.
+	sr.Reset(nil)
+	safeReaderPool.Put(sr)
+}
+
+// GetFastReader returns a FastReader for data, taken from a pool when one is
+// available. Return it with PutFastReader once the frame has been parsed.
+func GetFastReader(data []byte) *FastReader {
+	fr := fastReaderPool.Get().(*FastReader)
+	fr.Reset(data)
+	return fr
+}
+
+// PutFastReader returns fr to the pool. fr must not be used afterwards.
+func PutFastReader(fr *FastReader) {
+	fr.Reset(nil)
+	fastReaderPool.Put(fr)
+}
@@ -0,0 +1,37 @@
+package wireread
+
+import "testing"
+
+func TestSafeReaderPool(t *testing.T) {
+	r := GetSafeReader([]byte{0x01, 0x02})
+	if got, err := r.ReadUint16BE(); err != nil || got != 0x0102 {
+		t.Errorf("ReadUint16BE() = 0x%04x, %v; want 0x0102, nil", got, err)
+	}
+	PutSafeReader(r)
+	if r.data != nil {
+		t.Error("PutSafeReader() kept a reference to the data")
+	}
+
+	r = GetSafeReader([]byte{0x03})
+	defer PutSafeReader(r)
+	if got, err := r.ReadByte(); err != nil || got != 0x03 {
+		t.Errorf("ReadByte() on reused reader = %v, %v; want 3, nil", got, err)
+	}
+}
+
+func TestFastReaderPool(t *testing.T) {
+	r := GetFastReader([]byte{0x01, 0x02})
+	if got, _ := r.ReadUint16BE(); got != 0x0102 {
+		t.Errorf("ReadUint16BE() = 0x%04x, want 0x0102", got)
+	}
+	PutFastReader(r)
+	if r.data != nil {
+		t.Error("PutFastReader() kept a reference to the data")
+	}
+
+	r = GetFastReader([]byte{0x03})
+	defer PutFastReader(r)
+	if got, _ := r.ReadByte(); got != 0x03 {
+		t.Errorf("ReadByte() on reused reader = %v, want 3", got)
+	}
+}
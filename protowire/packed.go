@@ -0,0 +1,47 @@
+package protowire
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/nemohan/wireread"
+)
+
+// AppendPackedVarint decodes the payload of a packed repeated varint field
+// (int32, int64, uint32, uint64, sint32, sint64, bool, enum) and appends the
+// raw values to dst.
+func AppendPackedVarint(dst []uint64, b []byte) ([]uint64, error) {
+	r := wireread.NewSafeReader(b)
+	for len(r.Bytes()) > 0 {
+		v, err := ReadVarint(r)
+		if err != nil {
+			return dst, err
+		}
+		dst = append(dst, v)
+	}
+	return dst, nil
+}
+
+// AppendPackedFixed32 decodes the payload of a packed repeated fixed32,
+// sfixed32 or float field and appends the values to dst.
+func AppendPackedFixed32(dst []uint32, b []byte) ([]uint32, error) {
+	if len(b)%4 != 0 {
+		return dst, fmt.Errorf("%w: packed fixed32 length %d", ErrMalformed, len(b))
+	}
+	for ; len(b) > 0; b = b[4:] {
+		dst = append(dst, binary.LittleEndian.Uint32(b))
+	}
+	return dst, nil
+}
+
+// AppendPackedFixed64 decodes the payload of a packed repeated fixed64,
+// sfixed64 or double field and appends the values to dst.
+func AppendPackedFixed64(dst []uint64, b []byte) ([]uint64, error) {
+	if len(b)%8 != 0 {
+		return dst, fmt.Errorf("%w: packed fixed64 length %d", ErrMalformed, len(b))
+	}
+	for ; len(b) > 0; b = b[8:] {
+		dst = append(dst, binary.LittleEndian.Uint64(b))
+	}
+	return dst, nil
+}
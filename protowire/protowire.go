@@ -0,0 +1,230 @@
+// Package protowire reads the Protocol Buffers wire format without
+// generated code or .proto files.
+//
+// The Read functions decode single tags and field values from a
+// wireread.Reader and SkipField steps over a field of any type, including
+// nested groups. Parse goes further and turns an unknown message into a
+// tree of fields, treating length-delimited values that decode as messages
+// as nested messages, in the manner of protoc --decode_raw. Recursion
+// through groups and nested messages is bounded.
+//
+// Example usage:
+//
+//	r := wireread.NewSafeReader(payload)
+//	for len(r.Bytes()) > 0 {
+//	    num, typ, err := protowire.ReadTag(r)
+//	    if err != nil {
+//	        log.Fatal(err)
+//	    }
+//	    if num == 2 && typ == protowire.BytesType {
+//	        name, _ := protowire.ReadBytes(r)
+//	        fmt.Println(string(name))
+//	        continue
+//	    }
+//	    if err := protowire.SkipField(r, num, typ); err != nil {
+//	        log.Fatal(err)
+//	    }
+//	}
+package protowire
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/nemohan/wireread"
+)
+
+var (
+	// ErrMalformed is returned for invalid tags, varints longer than 10
+	// bytes, lengths running past the end of the data and unbalanced groups.
+	ErrMalformed = errors.New("protowire: malformed data")
+
+	// ErrDepthExceeded is returned when groups nest deeper than the
+	// recursion limit.
+	ErrDepthExceeded = errors.New("protowire: recursion limit exceeded")
+)
+
+// DefaultMaxDepth is the default recursion limit for nested groups and
+// messages, the same as the reference C++ implementation.
+const DefaultMaxDepth = 100
+
+// Number is a field number.
+type Number int32
+
+// Valid field numbers.
+const (
+	MinValidNumber Number = 1
+	MaxValidNumber Number = 1<<29 - 1
+)
+
+// Type is a wire type.
+type Type int8
+
+// Wire types.
+const (
+	VarintType     Type = 0
+	Fixed64Type    Type = 1
+	BytesType      Type = 2
+	StartGroupType Type = 3
+	EndGroupType   Type = 4
+	Fixed32Type    Type = 5
+)
+
+func (t Type) String() string {
+	switch t {
+	case VarintType:
+		return "varint"
+	case Fixed64Type:
+		return "fixed64"
+	case BytesType:
+		return "bytes"
+	case StartGroupType:
+		return "start group"
+	case EndGroupType:
+		return "end group"
+	case Fixed32Type:
+		return "fixed32"
+	}
+	return fmt.Sprintf("wire type %d", int8(t))
+}
+
+// ReadVarint reads a base-128 varint of at most 10 bytes.
+func ReadVarint(r wireread.Reader) (uint64, error) {
+	v, err := r.ReadUvarint()
+	switch {
+	case err == io.EOF:
+		return 0, io.ErrUnexpectedEOF
+	case err != nil && err != io.ErrUnexpectedEOF:
+		return 0, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	return v, err
+}
+
+// ReadTag reads a field tag and splits it into field number and wire type.
+func ReadTag(r wireread.Reader) (Number, Type, error) {
+	v, err := ReadVarint(r)
+	if err != nil {
+		return 0, 0, err
+	}
+	num, typ := v>>3, Type(v&7)
+	if num < uint64(MinValidNumber) || num > uint64(MaxValidNumber) {
+		return 0, 0, fmt.Errorf("%w: field number %d", ErrMalformed, num)
+	}
+	if typ > Fixed32Type {
+		return 0, 0, fmt.Errorf("%w: wire type %d", ErrMalformed, typ)
+	}
+	return Number(num), typ, nil
+}
+
+// ReadFixed32 reads a little-endian 32-bit value.
+func ReadFixed32(r wireread.Reader) (uint32, error) {
+	return r.ReadUint32LE()
+}
+
+// ReadFixed64 reads a little-endian 64-bit value.
+func ReadFixed64(r wireread.Reader) (uint64, error) {
+	return r.ReadUint64LE()
+}
+
+// ReadBytes reads a length-delimited value. The result aliases the reader's
+// buffer.
+func ReadBytes(r wireread.Reader) ([]byte, error) {
+	n, err := ReadVarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(len(r.Bytes())) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	b := r.Bytes()[:n:n]
+	if err := r.Skip(int(n)); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// ReadGroup reads the contents of group num, whose start tag has already
+// been read, and consumes the matching end tag. The result aliases the
+// reader's buffer.
+func ReadGroup(r wireread.Reader, num Number) ([]byte, error) {
+	start := r.Bytes()
+	n, err := skipGroup(r, num, DefaultMaxDepth)
+	if err != nil {
+		return nil, err
+	}
+	return start[:n:n], nil
+}
+
+// SkipField skips the value of a field whose tag has already been read.
+func SkipField(r wireread.Reader, num Number, typ Type) error {
+	return skipField(r, num, typ, DefaultMaxDepth)
+}
+
+func skipField(r wireread.Reader, num Number, typ Type, depth int) error {
+	var err error
+	switch typ {
+	case VarintType:
+		_, err = ReadVarint(r)
+	case Fixed32Type:
+		err = r.Skip(4)
+	case Fixed64Type:
+		err = r.Skip(8)
+	case BytesType:
+		_, err = ReadBytes(r)
+	case StartGroupType:
+		_, err = skipGroup(r, num, depth)
+	default:
+		err = fmt.Errorf("%w: unexpected %s for field %d", ErrMalformed, typ, num)
+	}
+	return err
+}
+
+// skipGroup skips to the end tag of group num and returns the length of the
+// group's contents.
+func skipGroup(r wireread.Reader, num Number, depth int) (int, error) {
+	if depth <= 0 {
+		return 0, ErrDepthExceeded
+	}
+	start := len(r.Bytes())
+	for {
+		if len(r.Bytes()) == 0 {
+			return 0, io.ErrUnexpectedEOF
+		}
+		end := start - len(r.Bytes())
+		n, typ, err := ReadTag(r)
+		if err != nil {
+			return 0, err
+		}
+		if typ == EndGroupType {
+			if n != num {
+				return 0, fmt.Errorf("%w: end of group %d inside group %d", ErrMalformed, n, num)
+			}
+			return end, nil
+		}
+		if err := skipField(r, n, typ, depth-1); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// DecodeZigZag decodes a zigzag-encoded sint32 or sint64 value.
+func DecodeZigZag(v uint64) int64 {
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+// DecodeBool decodes a bool varint; any non-zero value is true.
+func DecodeBool(v uint64) bool {
+	return v != 0
+}
+
+// DecodeFloat32 reinterprets a fixed32 value as a float.
+func DecodeFloat32(v uint32) float32 {
+	return math.Float32frombits(v)
+}
+
+// DecodeFloat64 reinterprets a fixed64 value as a double.
+func DecodeFloat64(v uint64) float64 {
+	return math.Float64frombits(v)
+}
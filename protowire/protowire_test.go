@@ -0,0 +1,180 @@
+package protowire
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/nemohan/wireread"
+)
+
+func TestReadTag(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		num  Number
+		typ  Type
+		err  error
+	}{
+		{"varint", []byte{0x08}, 1, VarintType, nil},
+		{"bytes", []byte{0x12}, 2, BytesType, nil},
+		{"max number", []byte{0xFD, 0xFF, 0xFF, 0xFF, 0x0F}, MaxValidNumber, Fixed32Type, nil},
+		{"number zero", []byte{0x00}, 0, 0, ErrMalformed},
+		{"number too large", []byte{0x80, 0x80, 0x80, 0x80, 0x20}, 0, 0, ErrMalformed},
+		{"wire type 6", []byte{0x0E}, 0, 0, ErrMalformed},
+		{"truncated", []byte{0x80}, 0, 0, io.ErrUnexpectedEOF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			num, typ, err := ReadTag(wireread.NewSafeReader(tt.data))
+			if !errors.Is(err, tt.err) || num != tt.num || typ != tt.typ {
+				t.Errorf("ReadTag() = %d, %v, %v, want %d, %v, %v", num, typ, err, tt.num, tt.typ, tt.err)
+			}
+		})
+	}
+}
+
+func TestReadValues(t *testing.T) {
+	data := []byte{
+		0x96, 0x01, // varint 150
+		0x78, 0x56, 0x34, 0x12, // fixed32
+		0x01, 0, 0, 0, 0, 0, 0, 0x80, // fixed64
+		0x03, 'a', 'b', 'c', // bytes
+		0x2A, // varint 42
+	}
+	r := wireread.NewSafeReader(data)
+	if v, err := ReadVarint(r); v != 150 || err != nil {
+		t.Errorf("ReadVarint() = %d, %v", v, err)
+	}
+	if v, err := ReadFixed32(r); v != 0x12345678 || err != nil {
+		t.Errorf("ReadFixed32() = %#x, %v", v, err)
+	}
+	if v, err := ReadFixed64(r); v != 0x8000000000000001 || err != nil {
+		t.Errorf("ReadFixed64() = %#x, %v", v, err)
+	}
+	if v, err := ReadBytes(r); string(v) != "abc" || err != nil {
+		t.Errorf("ReadBytes() = %q, %v", v, err)
+	} else if cap(v) != len(v) {
+		t.Errorf("ReadBytes() cap = %d, want %d", cap(v), len(v))
+	} else {
+		_ = append(v, 0xEE)
+	}
+	if v, err := ReadVarint(r); v != 42 || err != nil {
+		t.Errorf("ReadVarint() after appending to ReadBytes() = %d, %v, want 42", v, err)
+	}
+
+	if _, err := ReadVarint(wireread.NewSafeReader([]byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x01})); !errors.Is(err, ErrMalformed) {
+		t.Errorf("ReadVarint(11 bytes) error = %v, want ErrMalformed", err)
+	}
+	if _, err := ReadBytes(wireread.NewSafeReader([]byte{0x05, 'a'})); err != io.ErrUnexpectedEOF {
+		t.Errorf("ReadBytes(overrun) error = %v, want io.ErrUnexpectedEOF", err)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	if v := DecodeZigZag(3); v != -2 {
+		t.Errorf("DecodeZigZag(3) = %d, want -2", v)
+	}
+	if v := DecodeZigZag(4); v != 2 {
+		t.Errorf("DecodeZigZag(4) = %d, want 2", v)
+	}
+	if v := DecodeZigZag(1<<64 - 1); v != -1<<63 {
+		t.Errorf("DecodeZigZag(max) = %d, want min int64", v)
+	}
+	if v := DecodeFloat32(0x3FC00000); v != 1.5 {
+		t.Errorf("DecodeFloat32() = %v, want 1.5", v)
+	}
+	if v := DecodeFloat64(0x3FF8000000000000); v != 1.5 {
+		t.Errorf("DecodeFloat64() = %v, want 1.5", v)
+	}
+}
+
+func TestSkipField(t *testing.T) {
+	// Field 1 varint, group 2 containing group 3 with a varint, field 4 bytes.
+	data := []byte{0x08, 0x01, 0x13, 0x1B, 0x08, 0x05, 0x1C, 0x14, 0x22, 0x01, 'x'}
+	r := wireread.NewSafeReader(data)
+	var nums []Number
+	for len(r.Bytes()) > 0 {
+		num, typ, err := ReadTag(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nums = append(nums, num)
+		if err := SkipField(r, num, typ); err != nil {
+			t.Fatalf("SkipField(%d) error = %v", num, err)
+		}
+	}
+	if len(nums) != 3 || nums[0] != 1 || nums[1] != 2 || nums[2] != 4 {
+		t.Errorf("fields = %v, want [1 2 4]", nums)
+	}
+
+	r = wireread.NewSafeReader(data[2:])
+	ReadTag(r)
+	g, err := ReadGroup(r, 2)
+	if err != nil || string(g) != "\x1B\x08\x05\x1C" || cap(g) != len(g) {
+		t.Errorf("ReadGroup() = %x (cap %d), %v", g, cap(g), err)
+	}
+}
+
+func TestSkipField_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"mismatched end group", []byte{0x13, 0x1C}, ErrMalformed},
+		{"stray end group", []byte{0x14}, ErrMalformed},
+		{"unterminated group", []byte{0x13, 0x08, 0x01}, io.ErrUnexpectedEOF},
+		{"truncated fixed64", []byte{0x09, 1, 2, 3}, io.ErrUnexpectedEOF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := wireread.NewSafeReader(tt.data)
+			num, typ, err := ReadTag(r)
+			if err == nil {
+				err = SkipField(r, num, typ)
+			}
+			if !errors.Is(err, tt.want) {
+				t.Errorf("SkipField() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSkipField_Depth(t *testing.T) {
+	var data []byte
+	for i := 0; i < DefaultMaxDepth+1; i++ {
+		data = append(data, 0x0B) // start group 1
+	}
+	for i := 0; i < DefaultMaxDepth+1; i++ {
+		data = append(data, 0x0C)
+	}
+	r := wireread.NewSafeReader(data)
+	ReadTag(r)
+	if err := SkipField(r, 1, StartGroupType); err != ErrDepthExceeded {
+		t.Errorf("SkipField() error = %v, want ErrDepthExceeded", err)
+	}
+}
+
+func TestAppendPacked(t *testing.T) {
+	v, err := AppendPackedVarint(nil, []byte{0x03, 0x8E, 0x02, 0x9E, 0xA7, 0x05})
+	if err != nil || len(v) != 3 || v[0] != 3 || v[1] != 270 || v[2] != 86942 {
+		t.Errorf("AppendPackedVarint() = %v, %v", v, err)
+	}
+	f32, err := AppendPackedFixed32(nil, []byte{1, 0, 0, 0, 2, 0, 0, 0})
+	if err != nil || len(f32) != 2 || f32[1] != 2 {
+		t.Errorf("AppendPackedFixed32() = %v, %v", f32, err)
+	}
+	f64, err := AppendPackedFixed64([]uint64{9}, []byte{1, 0, 0, 0, 0, 0, 0, 0})
+	if err != nil || len(f64) != 2 || f64[1] != 1 {
+		t.Errorf("AppendPackedFixed64() = %v, %v", f64, err)
+	}
+	if _, err := AppendPackedFixed32(nil, []byte{1, 2, 3}); !errors.Is(err, ErrMalformed) {
+		t.Errorf("AppendPackedFixed32(3 bytes) error = %v, want ErrMalformed", err)
+	}
+	if _, err := AppendPackedVarint(nil, []byte{0x80}); err != io.ErrUnexpectedEOF {
+		t.Errorf("AppendPackedVarint(truncated) error = %v, want io.ErrUnexpectedEOF", err)
+	}
+}
@@ -0,0 +1,150 @@
+package protowire
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/nemohan/wireread"
+)
+
+// Field is a decoded field of a message parsed without its schema.
+type Field struct {
+	Number Number
+	Type   Type
+	// Value holds varint, fixed32 and fixed64 values.
+	Value uint64
+	// Bytes holds the payload of length-delimited fields.
+	Bytes []byte
+	// Fields holds the contents of groups, and of length-delimited fields
+	// whose payload decodes as a non-empty message.
+	Fields []Field
+}
+
+// IsMessage reports whether the field holds nested fields.
+func (f *Field) IsMessage() bool {
+	return f.Fields != nil
+}
+
+// Parse decodes b as a message of unknown type. Length-delimited payloads
+// that decode as messages become nested messages; this is a guess, since a
+// string can also be a valid message, and callers that know better should
+// use Bytes. Nesting is limited to maxDepth levels (DefaultMaxDepth if not
+// positive): groups deeper than that fail with ErrDepthExceeded, while
+// length-delimited payloads at that depth are left undecoded.
+func Parse(b []byte, maxDepth int) ([]Field, error) {
+	if maxDepth <= 0 {
+		maxDepth = DefaultMaxDepth
+	}
+	return parseMessage(wireread.NewSafeReader(b), maxDepth, 0)
+}
+
+// parseMessage decodes fields until the reader is empty or, when group is
+// non-zero, until the end tag of that group.
+func parseMessage(r *wireread.SafeReader, depth int, group Number) ([]Field, error) {
+	fields := []Field{}
+	for {
+		if len(r.Bytes()) == 0 {
+			if group != 0 {
+				return nil, fmt.Errorf("%w: group %d not terminated", ErrMalformed, group)
+			}
+			return fields, nil
+		}
+		num, typ, err := ReadTag(r)
+		if err != nil {
+			return nil, err
+		}
+		f := Field{Number: num, Type: typ}
+		switch typ {
+		case VarintType:
+			f.Value, err = ReadVarint(r)
+		case Fixed32Type:
+			var v uint32
+			v, err = ReadFixed32(r)
+			f.Value = uint64(v)
+		case Fixed64Type:
+			f.Value, err = ReadFixed64(r)
+		case BytesType:
+			if f.Bytes, err = ReadBytes(r); err == nil && len(f.Bytes) > 0 && depth > 1 {
+				// A failed guess simply leaves the payload as bytes.
+				if sub, err := parseMessage(wireread.NewSafeReader(f.Bytes), depth-1, 0); err == nil {
+					f.Fields = sub
+				}
+			}
+		case StartGroupType:
+			if depth <= 1 {
+				return nil, ErrDepthExceeded
+			}
+			f.Fields, err = parseMessage(r, depth-1, num)
+		case EndGroupType:
+			if num != group {
+				return nil, fmt.Errorf("%w: unexpected end of group %d", ErrMalformed, num)
+			}
+			return fields, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, f)
+	}
+}
+
+// Format renders fields in the text format of protoc --decode_raw, with
+// nested messages and groups indented by two spaces.
+func Format(fields []Field) string {
+	var b strings.Builder
+	format(&b, fields, 0)
+	return b.String()
+}
+
+func format(b *strings.Builder, fields []Field, indent int) {
+	for _, f := range fields {
+		b.WriteString(strings.Repeat("  ", indent))
+		b.WriteString(strconv.Itoa(int(f.Number)))
+		switch {
+		case f.IsMessage():
+			b.WriteString(" {\n")
+			format(b, f.Fields, indent+1)
+			b.WriteString(strings.Repeat("  ", indent))
+			b.WriteString("}\n")
+			continue
+		case f.Type == VarintType:
+			b.WriteString(": ")
+			b.WriteString(strconv.FormatUint(f.Value, 10))
+		case f.Type == Fixed32Type:
+			fmt.Fprintf(b, ": 0x%08x", f.Value)
+		case f.Type == Fixed64Type:
+			fmt.Fprintf(b, ": 0x%016x", f.Value)
+		default:
+			b.WriteString(": ")
+			b.WriteString(quote(f.Bytes))
+		}
+		b.WriteByte('\n')
+	}
+}
+
+// quote renders a payload as a C-escaped string, escaping bytes that are
+// not printable ASCII in octal like protoc does.
+func quote(p []byte) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, c := range p {
+		switch {
+		case c == '"' || c == '\\' || c == '\'':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c == '\n':
+			b.WriteString(`\n`)
+		case c == '\r':
+			b.WriteString(`\r`)
+		case c == '\t':
+			b.WriteString(`\t`)
+		case c < 0x20 || c >= 0x7F:
+			fmt.Fprintf(&b, "\\%03o", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,83 @@
+package protowire
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	data := []byte{
+		0x08, 0x96, 0x01, // 1: 150
+		0x12, 0x07, 't', 'e', 's', 't', 'i', 'n', 'g', // 2: "testing"
+		0x1A, 0x03, 0x08, 0x96, 0x01, // 3 { 1: 150 }
+		0x25, 0x64, 0, 0, 0, // 4: fixed32
+		0x29, 0x01, 0, 0, 0, 0, 0, 0, 0, // 5: fixed64
+		0x33, 0x08, 0x01, 0x34, // group 6 { 1: 1 }
+		0x3A, 0x02, 0xFF, 0x0A, // 7: not a message
+		0x42, 0x00, // 8: ""
+	}
+	fields, err := Parse(data, 0)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	const want = `1: 150
+2: "testing"
+3 {
+  1: 150
+}
+4: 0x00000064
+5: 0x0000000000000001
+6 {
+  1: 1
+}
+7: "\377\n"
+8: ""
+`
+	if got := Format(fields); got != want {
+		t.Errorf("Format() =\n%s\nwant\n%s", got, want)
+	}
+	if !fields[2].IsMessage() || fields[1].IsMessage() || fields[7].IsMessage() {
+		t.Errorf("IsMessage() guesses wrong: %+v", fields)
+	}
+}
+
+func TestParse_Depth(t *testing.T) {
+	// Nested length-delimited messages beyond the limit stay as bytes.
+	msg := []byte{0x08, 0x01}
+	for i := 0; i < 5; i++ {
+		msg = append([]byte{0x0A, byte(len(msg))}, msg...)
+	}
+	fields, err := Parse(msg, 3)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	depth := 0
+	for f := fields; len(f) > 0 && f[0].IsMessage(); f = f[0].Fields {
+		depth++
+	}
+	if depth != 2 {
+		t.Errorf("decoded %d nested levels, want 2", depth)
+	}
+
+	groups := []byte{0x0B, 0x0B, 0x0B, 0x0C, 0x0C, 0x0C}
+	if _, err := Parse(groups, 3); err != ErrDepthExceeded {
+		t.Errorf("Parse(groups) error = %v, want ErrDepthExceeded", err)
+	}
+	if _, err := Parse(groups, 4); err != nil {
+		t.Errorf("Parse(groups, 4) error = %v", err)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	for _, data := range [][]byte{
+		{0x0B, 0x08, 0x01}, // unterminated group
+		{0x0C},             // stray end group
+		{0x12, 0x05, 'a'},  // bytes overrun
+		{0x08},             // missing varint
+		{0x07},             // invalid wire type
+	} {
+		if _, err := Parse(data, 0); err == nil || errors.Is(err, ErrDepthExceeded) {
+			t.Errorf("Parse(%x) error = %v, want a malformed-data error", data, err)
+		}
+	}
+}
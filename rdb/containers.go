@@ -0,0 +1,231 @@
+package rdb
+
+import (
+	"io"
+
+	"github.com/nemohan/wireread"
+)
+
+// ziplistLen walks a ziplist blob and returns the number of entries.
+// The 16-bit entry count in the header saturates at 65535, so the entries
+// are always traversed to validate the structure.
+func ziplistLen(b []byte) (int64, error) {
+	r := wireread.NewSafeReader(b)
+	zlbytes, err := r.ReadUint32LE()
+	if err != nil {
+		return 0, err
+	}
+	if int(zlbytes) != len(b) {
+		return 0, corrupt("ziplist size %d, blob is %d bytes", zlbytes, len(b))
+	}
+	if err := r.Skip(4 + 2); err != nil { // zltail, zllen
+		return 0, err
+	}
+
+	var n int64
+	for {
+		prev, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if prev == 0xFF {
+			return n, nil
+		}
+		if prev == 0xFE {
+			if err := r.Skip(4); err != nil {
+				return 0, err
+			}
+		}
+
+		enc, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		var size int
+		switch {
+		case enc>>6 == 0: // 6-bit string length
+			size = int(enc & 0x3F)
+		case enc>>6 == 1: // 14-bit string length
+			lo, err := r.ReadByte()
+			if err != nil {
+				return 0, err
+			}
+			size = int(enc&0x3F)<<8 | int(lo)
+		case enc == 0x80: // 32-bit string length
+			l, err := r.ReadUint32BE()
+			if err != nil {
+				return 0, err
+			}
+			size = int(l)
+		case enc == 0xC0: // int16
+			size = 2
+		case enc == 0xD0: // int32
+			size = 4
+		case enc == 0xE0: // int64
+			size = 8
+		case enc == 0xF0: // int24
+			size = 3
+		case enc == 0xFE: // int8
+			size = 1
+		case enc >= 0xF1 && enc <= 0xFD: // 4-bit immediate
+			size = 0
+		default:
+			return 0, corrupt("invalid ziplist entry encoding 0x%02x", enc)
+		}
+		if _, err := readRaw(r, uint64(size)); err != nil {
+			return 0, err
+		}
+		n++
+	}
+}
+
+// listpackLen walks a listpack blob and returns the number of entries.
+func listpackLen(b []byte) (int64, error) {
+	r := wireread.NewSafeReader(b)
+	total, err := r.ReadUint32LE()
+	if err != nil {
+		return 0, err
+	}
+	if int(total) != len(b) {
+		return 0, corrupt("listpack size %d, blob is %d bytes", total, len(b))
+	}
+	if err := r.Skip(2); err != nil { // element count
+		return 0, err
+	}
+
+	var n int64
+	for {
+		enc, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if enc == 0xFF {
+			return n, nil
+		}
+		var hdr, size int
+		switch {
+		case enc&0x80 == 0: // 7-bit uint
+			hdr, size = 1, 0
+		case enc&0xC0 == 0x80: // 6-bit string length
+			hdr, size = 1, int(enc&0x3F)
+		case enc&0xE0 == 0xC0: // 13-bit int
+			hdr, size = 1, 1
+		case enc&0xF0 == 0xE0: // 12-bit string length
+			lo, err := r.ReadByte()
+			if err != nil {
+				return 0, err
+			}
+			hdr, size = 2, int(enc&0x0F)<<8|int(lo)
+		case enc == 0xF0: // 32-bit string length
+			l, err := r.ReadUint32LE()
+			if err != nil {
+				return 0, err
+			}
+			hdr, size = 5, int(l)
+		case enc == 0xF1: // int16
+			hdr, size = 1, 2
+		case enc == 0xF2: // int24
+			hdr, size = 1, 3
+		case enc == 0xF3: // int32
+			hdr, size = 1, 4
+		case enc == 0xF4: // int64
+			hdr, size = 1, 8
+		default:
+			return 0, corrupt("invalid listpack entry encoding 0x%02x", enc)
+		}
+		if _, err := readRaw(r, uint64(size)); err != nil {
+			return 0, err
+		}
+		if err := r.Skip(listpackBacklen(hdr + size)); err != nil {
+			return 0, err
+		}
+		n++
+	}
+}
+
+// listpackBacklen returns the number of bytes used to store the backwards
+// length of an entry whose encoding and data take l bytes.
+func listpackBacklen(l int) int {
+	switch {
+	case l < 1<<7:
+		return 1
+	case l < 1<<14:
+		return 2
+	case l < 1<<21:
+		return 3
+	case l < 1<<28:
+		return 4
+	}
+	return 5
+}
+
+// intsetLen validates an intset blob and returns its member count.
+func intsetLen(b []byte) (int64, error) {
+	r := wireread.NewSafeReader(b)
+	width, err := r.ReadUint32LE()
+	if err != nil {
+		return 0, err
+	}
+	if width != 2 && width != 4 && width != 8 {
+		return 0, corrupt("invalid intset encoding %d", width)
+	}
+	n, err := r.ReadUint32LE()
+	if err != nil {
+		return 0, err
+	}
+	if uint64(n)*uint64(width) != uint64(len(r.Bytes())) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	return int64(n), nil
+}
+
+// zipmapLen walks a legacy zipmap blob and returns the number of fields.
+func zipmapLen(b []byte) (int64, error) {
+	r := wireread.NewSafeReader(b)
+	if err := r.Skip(1); err != nil { // zmlen, saturates at 254
+		return 0, err
+	}
+	var n int64
+	for {
+		klen, end, err := zipmapEntryLen(r)
+		if err != nil {
+			return 0, err
+		}
+		if end {
+			return n, nil
+		}
+		if _, err := readRaw(r, klen); err != nil {
+			return 0, err
+		}
+		vlen, end, err := zipmapEntryLen(r)
+		if err != nil {
+			return 0, err
+		}
+		if end {
+			return 0, corrupt("zipmap key without value")
+		}
+		free, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if _, err := readRaw(r, vlen+uint64(free)); err != nil {
+			return 0, err
+		}
+		n++
+	}
+}
+
+func zipmapEntryLen(r *wireread.SafeReader) (uint64, bool, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, false, err
+	}
+	switch b {
+	case 0xFF:
+		return 0, true, nil
+	case 0xFE:
+		l, err := r.ReadUint32LE()
+		return uint64(l), false, err
+	}
+	return uint64(b), false, nil
+}
@@ -0,0 +1,512 @@
+package rdb
+
+import (
+	"fmt"
+	"hash/crc64"
+	"io"
+	"strconv"
+
+	"github.com/nemohan/wireread"
+)
+
+// Special string encodings selected by the 0b11 length prefix.
+const (
+	encInt8  = 0
+	encInt16 = 1
+	encInt32 = 2
+	encLZF   = 3
+)
+
+// maxStringLen bounds the uncompressed size of LZF strings so that a corrupt
+// length cannot trigger an arbitrarily large allocation.
+const maxStringLen = 512 << 20
+
+// lzfMaxRatio is the most an LZF block can expand: a three-byte back
+// reference copies 264 bytes.
+const lzfMaxRatio = 88
+
+// crcTable is the reflected Jones polynomial used by Redis.
+var crcTable = crc64.MakeTable(0x95AC9329AC4BC9B5)
+
+// CRC64 computes the Redis flavour of CRC-64/Jones (zero initial value, no
+// final XOR) over data.
+func CRC64(data []byte) uint64 {
+	// The standard library inverts the value on entry and exit, so start
+	// from all ones and invert the result to cancel both.
+	return ^crc64.Update(^uint64(0), crcTable, data)
+}
+
+func corrupt(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrCorrupt, fmt.Sprintf(format, args...))
+}
+
+// readLengthEncoded reads an RDB length. When the two high bits of the first
+// byte are set the value is a special string encoding and encoded is true.
+func readLengthEncoded(r *wireread.SafeReader) (uint64, bool, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, false, err
+	}
+	switch b >> 6 {
+	case 0: // 6-bit length
+		return uint64(b & 0x3F), false, nil
+	case 1: // 14-bit length
+		lo, err := r.ReadByte()
+		if err != nil {
+			return 0, false, err
+		}
+		return uint64(b&0x3F)<<8 | uint64(lo), false, nil
+	case 2:
+		switch b {
+		case 0x80: // 32-bit length
+			n, err := r.ReadUint32BE()
+			return uint64(n), false, err
+		case 0x81: // 64-bit length
+			n, err := r.ReadUint64BE()
+			return n, false, err
+		}
+		return 0, false, corrupt("invalid length prefix 0x%02x", b)
+	default:
+		return uint64(b & 0x3F), true, nil
+	}
+}
+
+// readLength reads an RDB length that must not use a special encoding.
+func readLength(r *wireread.SafeReader) (uint64, error) {
+	n, encoded, err := readLengthEncoded(r)
+	if err != nil {
+		return 0, err
+	}
+	if encoded {
+		return 0, corrupt("unexpected string encoding %d", n)
+	}
+	return n, nil
+}
+
+// readRaw returns the next n bytes without copying them.
+func readRaw(r *wireread.SafeReader, n uint64) ([]byte, error) {
+	b := r.Bytes()
+	if n > uint64(len(b)) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return b[:n], r.Skip(int(n))
+}
+
+// readStringBytes reads a string object, expanding integer and LZF encodings.
+// Plain strings alias the underlying buffer.
+func readStringBytes(r *wireread.SafeReader) ([]byte, error) {
+	n, encoded, err := readLengthEncoded(r)
+	if err != nil {
+		return nil, err
+	}
+	if !encoded {
+		return readRaw(r, n)
+	}
+	switch n {
+	case encInt8:
+		b, err := r.ReadByte()
+		return strconv.AppendInt(nil, int64(int8(b)), 10), err
+	case encInt16:
+		v, err := r.ReadUint16LE()
+		return strconv.AppendInt(nil, int64(int16(v)), 10), err
+	case encInt32:
+		v, err := r.ReadUint32LE()
+		return strconv.AppendInt(nil, int64(int32(v)), 10), err
+	case encLZF:
+		clen, err := readLength(r)
+		if err != nil {
+			return nil, err
+		}
+		ulen, err := readLength(r)
+		if err != nil {
+			return nil, err
+		}
+		in, err := readRaw(r, clen)
+		if err != nil {
+			return nil, err
+		}
+		return lzfDecompress(in, ulen)
+	}
+	return nil, corrupt("unknown string encoding %d", n)
+}
+
+func readString(r *wireread.SafeReader) (string, error) {
+	b, err := readStringBytes(r)
+	return string(b), err
+}
+
+// skipString skips a string object without decompressing it.
+func skipString(r *wireread.SafeReader) error {
+	n, encoded, err := readLengthEncoded(r)
+	if err != nil {
+		return err
+	}
+	if !encoded {
+		_, err = readRaw(r, n)
+		return err
+	}
+	switch n {
+	case encInt8:
+		return r.Skip(1)
+	case encInt16:
+		return r.Skip(2)
+	case encInt32:
+		return r.Skip(4)
+	case encLZF:
+		clen, err := readLength(r)
+		if err != nil {
+			return err
+		}
+		if _, err := readLength(r); err != nil {
+			return err
+		}
+		_, err = readRaw(r, clen)
+		return err
+	}
+	return corrupt("unknown string encoding %d", n)
+}
+
+// lzfDecompress expands an LZF compressed block into exactly ulen bytes.
+func lzfDecompress(in []byte, ulen uint64) ([]byte, error) {
+	if ulen > maxStringLen {
+		return nil, corrupt("LZF length %d exceeds limit", ulen)
+	}
+	if ulen > uint64(len(in))*lzfMaxRatio {
+		return nil, corrupt("LZF length %d too large for %d compressed bytes", ulen, len(in))
+	}
+	out := make([]byte, 0, ulen)
+	for i := 0; i < len(in); {
+		ctrl := int(in[i])
+		i++
+		if ctrl < 32 { // literal run of ctrl+1 bytes
+			n := ctrl + 1
+			if i+n > len(in) || uint64(len(out)+n) > ulen {
+				return nil, corrupt("LZF literal overrun")
+			}
+			out = append(out, in[i:i+n]...)
+			i += n
+			continue
+		}
+		n := ctrl >> 5 // back reference
+		if n == 7 {
+			if i >= len(in) {
+				return nil, corrupt("LZF truncated back reference")
+			}
+			n += int(in[i])
+			i++
+		}
+		if i >= len(in) {
+			return nil, corrupt("LZF truncated back reference")
+		}
+		ref := len(out) - (ctrl&0x1F)<<8 - int(in[i]) - 1
+		i++
+		n += 2
+		if ref < 0 || uint64(len(out)+n) > ulen {
+			return nil, corrupt("LZF invalid back reference")
+		}
+		// Copy byte by byte: the reference may overlap the output.
+		for j := 0; j < n; j++ {
+			out = append(out, out[ref+j])
+		}
+	}
+	if uint64(len(out)) != ulen {
+		return nil, corrupt("LZF produced %d bytes, want %d", len(out), ulen)
+	}
+	return out, nil
+}
+
+// skipDouble skips a sorted set score in the legacy string format, where
+// lengths 253, 254 and 255 stand for NaN, +Inf and -Inf.
+func skipDouble(r *wireread.SafeReader) error {
+	n, err := r.ReadByte()
+	if err != nil {
+		return err
+	}
+	if n >= 253 {
+		return nil
+	}
+	return r.Skip(int(n))
+}
+
+// skipStrings skips n string objects.
+func skipStrings(r *wireread.SafeReader, n uint64) error {
+	for i := uint64(0); i < n; i++ {
+		if err := skipString(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// skipValue skips the value of a key and returns its element count.
+func skipValue(r *wireread.SafeReader, enc Encoding) (int64, error) {
+	switch enc {
+	case EncString:
+		return 1, skipString(r)
+
+	case EncList, EncSet:
+		n, err := readLength(r)
+		if err != nil {
+			return 0, err
+		}
+		return int64(n), skipStrings(r, n)
+
+	case EncHash:
+		n, err := readLength(r)
+		if err != nil {
+			return 0, err
+		}
+		return int64(n), skipStrings(r, 2*n)
+
+	case EncZSet, EncZSet2:
+		n, err := readLength(r)
+		if err != nil {
+			return 0, err
+		}
+		for i := uint64(0); i < n; i++ {
+			if err := skipString(r); err != nil {
+				return 0, err
+			}
+			if enc == EncZSet2 {
+				err = r.Skip(8)
+			} else {
+				err = skipDouble(r)
+			}
+			if err != nil {
+				return 0, err
+			}
+		}
+		return int64(n), nil
+
+	case EncHashZipmap:
+		b, err := readStringBytes(r)
+		if err != nil {
+			return 0, err
+		}
+		return zipmapLen(b)
+
+	case EncListZiplist, EncZSetZiplist, EncHashZiplist:
+		b, err := readStringBytes(r)
+		if err != nil {
+			return 0, err
+		}
+		n, err := ziplistLen(b)
+		if enc != EncListZiplist {
+			n /= 2
+		}
+		return n, err
+
+	case EncSetIntset:
+		b, err := readStringBytes(r)
+		if err != nil {
+			return 0, err
+		}
+		return intsetLen(b)
+
+	case EncHashListpack, EncZSetListpack, EncSetListpack:
+		b, err := readStringBytes(r)
+		if err != nil {
+			return 0, err
+		}
+		n, err := listpackLen(b)
+		if enc != EncSetListpack {
+			n /= 2
+		}
+		return n, err
+
+	case EncListQuicklist, EncListQuicklist2:
+		return skipQuicklist(r, enc)
+
+	case EncStreamListpacks, EncStreamListpacks2, EncStreamListpacks3:
+		return skipStream(r, enc)
+
+	case EncModule2:
+		if _, err := readLength(r); err != nil {
+			return 0, err
+		}
+		return 1, skipModuleValue(r)
+	}
+	return 0, fmt.Errorf("%w: value type %d", ErrUnsupported, enc)
+}
+
+// Quicklist v2 node containers.
+const (
+	containerPlain  = 1
+	containerPacked = 2
+)
+
+func skipQuicklist(r *wireread.SafeReader, enc Encoding) (int64, error) {
+	nodes, err := readLength(r)
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	for i := uint64(0); i < nodes; i++ {
+		container := uint64(containerPacked)
+		if enc == EncListQuicklist2 {
+			if container, err = readLength(r); err != nil {
+				return 0, err
+			}
+		}
+		b, err := readStringBytes(r)
+		if err != nil {
+			return 0, err
+		}
+		var n int64
+		switch {
+		case container == containerPlain:
+			n = 1
+		case container != containerPacked:
+			return 0, corrupt("unknown quicklist container %d", container)
+		case enc == EncListQuicklist:
+			n, err = ziplistLen(b)
+		default:
+			n, err = listpackLen(b)
+		}
+		if err != nil {
+			return 0, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// skipStream skips a stream value and returns the number of entries.
+func skipStream(r *wireread.SafeReader, enc Encoding) (int64, error) {
+	nodes, err := readLength(r)
+	if err != nil {
+		return 0, err
+	}
+	for i := uint64(0); i < nodes; i++ {
+		// Each node is a 128-bit master ID followed by a listpack.
+		if err := skipStrings(r, 2); err != nil {
+			return 0, err
+		}
+	}
+	length, err := readLength(r)
+	if err != nil {
+		return 0, err
+	}
+	// Last ID; version 2 adds first ID, max deleted ID and entries added.
+	ids := 2
+	if enc >= EncStreamListpacks2 {
+		ids = 7
+	}
+	if err := skipLengths(r, ids); err != nil {
+		return 0, err
+	}
+
+	groups, err := readLength(r)
+	if err != nil {
+		return 0, err
+	}
+	for i := uint64(0); i < groups; i++ {
+		if err := skipString(r); err != nil {
+			return 0, err
+		}
+		// Last delivered ID; version 2 adds the entries-read counter.
+		ids := 2
+		if enc >= EncStreamListpacks2 {
+			ids = 3
+		}
+		if err := skipLengths(r, ids); err != nil {
+			return 0, err
+		}
+
+		pel, err := readLength(r)
+		if err != nil {
+			return 0, err
+		}
+		for j := uint64(0); j < pel; j++ {
+			// Raw 128-bit ID and delivery time, then the delivery count.
+			if err := r.Skip(16 + 8); err != nil {
+				return 0, err
+			}
+			if _, err := readLength(r); err != nil {
+				return 0, err
+			}
+		}
+
+		consumers, err := readLength(r)
+		if err != nil {
+			return 0, err
+		}
+		for j := uint64(0); j < consumers; j++ {
+			if err := skipString(r); err != nil {
+				return 0, err
+			}
+			// Seen time; version 3 adds the active time.
+			times := 8
+			if enc >= EncStreamListpacks3 {
+				times = 16
+			}
+			if err := r.Skip(times); err != nil {
+				return 0, err
+			}
+			pel, err := readLength(r)
+			if err != nil {
+				return 0, err
+			}
+			if _, err := readRaw(r, pel*16); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return int64(length), nil
+}
+
+func skipLengths(r *wireread.SafeReader, n int) error {
+	for i := 0; i < n; i++ {
+		if _, err := readLength(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Module type value opcodes used by RDB_TYPE_MODULE_2 payloads.
+const (
+	moduleOpEOF    = 0
+	moduleOpSInt   = 1
+	moduleOpUInt   = 2
+	moduleOpFloat  = 3
+	moduleOpDouble = 4
+	moduleOpString = 5
+)
+
+// skipModuleValue skips a self-describing module payload up to its EOF
+// marker.
+func skipModuleValue(r *wireread.SafeReader) error {
+	for {
+		op, err := readLength(r)
+		if err != nil {
+			return err
+		}
+		switch op {
+		case moduleOpEOF:
+			return nil
+		case moduleOpSInt, moduleOpUInt:
+			_, err = readLength(r)
+		case moduleOpFloat:
+			err = r.Skip(4)
+		case moduleOpDouble:
+			err = r.Skip(8)
+		case moduleOpString:
+			err = skipString(r)
+		default:
+			return corrupt("unknown module opcode %d", op)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// skipModuleAux skips a MODULE_AUX opcode payload.
+func skipModuleAux(r *wireread.SafeReader) error {
+	// Module ID, then the "when" opcode and value.
+	if err := skipLengths(r, 3); err != nil {
+		return err
+	}
+	return skipModuleValue(r)
+}
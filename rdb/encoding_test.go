@@ -0,0 +1,114 @@
+package rdb
+
+import (
+	"errors"
+	"runtime"
+	"testing"
+
+	"github.com/nemohan/wireread"
+)
+
+func TestReadLength(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		want    uint64
+		encoded bool
+	}{
+		{"6-bit", []byte{0x0A}, 10, false},
+		{"14-bit", []byte{0x42, 0x00}, 0x200, false},
+		{"32-bit", []byte{0x80, 0x00, 0x01, 0x00, 0x00}, 0x10000, false},
+		{"64-bit", []byte{0x81, 0, 0, 0, 1, 0, 0, 0, 0}, 1 << 32, false},
+		{"special", []byte{0xC3}, encLZF, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, encoded, err := readLengthEncoded(wireread.NewSafeReader(tt.data))
+			if err != nil {
+				t.Fatalf("readLengthEncoded() error = %v", err)
+			}
+			if got != tt.want || encoded != tt.encoded {
+				t.Errorf("readLengthEncoded() = %d, %v, want %d, %v", got, encoded, tt.want, tt.encoded)
+			}
+		})
+	}
+}
+
+func TestReadString(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"plain", []byte{0x03, 'a', 'b', 'c'}, "abc"},
+		{"int8", []byte{0xC0, 0xFE}, "-2"},
+		{"int16", []byte{0xC1, 0x39, 0x30}, "12345"},
+		{"int32", []byte{0xC2, 0x00, 0x00, 0x00, 0x80}, "-2147483648"},
+		{"lzf", []byte{0xC3, 0x07, 0x0C, 0x02, 'a', 'b', 'c', 0xE0, 0x00, 0x02}, "abcabcabcabc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readString(wireread.NewSafeReader(tt.data))
+			if err != nil {
+				t.Fatalf("readString() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("readString() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLZFDecompress_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []byte
+		ulen uint64
+	}{
+		{"reference before start", []byte{0x20, 0x05}, 3},
+		{"literal overrun", []byte{0x05, 'a'}, 6},
+		{"length mismatch", []byte{0x00, 'a'}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := lzfDecompress(tt.in, tt.ulen); !errors.Is(err, ErrCorrupt) {
+				t.Errorf("lzfDecompress() error = %v, want ErrCorrupt", err)
+			}
+		})
+	}
+}
+
+func TestLZFDecompress_LengthBound(t *testing.T) {
+	in := []byte{0xE0, 0xFF, 0x00} // one maximal back reference
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	_, err := lzfDecompress(in, maxStringLen)
+	runtime.ReadMemStats(&after)
+	if !errors.Is(err, ErrCorrupt) {
+		t.Errorf("lzfDecompress() error = %v, want ErrCorrupt", err)
+	}
+	if n := after.TotalAlloc - before.TotalAlloc; n > 1<<20 {
+		t.Errorf("lzfDecompress() allocated %d bytes for a %d byte block", n, len(in))
+	}
+}
+
+func TestListpackLen_Encodings(t *testing.T) {
+	lp := []byte{
+		0, 0, 0, 0, 5, 0, // header, patched below
+		0x05, 0x01, // 7-bit uint
+		0xC1, 0x00, 0x02, // 13-bit int
+		0xF1, 0x01, 0x02, 0x03, // int16
+		0xE0, 0x01, 'x', 0x03, // 12-bit string
+		0x81, 'y', 0x02, // 6-bit string
+		0xFF,
+	}
+	lp[0] = byte(len(lp))
+
+	n, err := listpackLen(lp)
+	if err != nil || n != 5 {
+		t.Errorf("listpackLen() = %d, %v, want 5, nil", n, err)
+	}
+}
@@ -0,0 +1,357 @@
+// Package rdb iterates over the keys stored in a Redis RDB snapshot file.
+//
+// The iterator walks the dump without materializing values: each key is
+// reported with its type, on-disk encoding, element count, serialized size
+// and expiry, which is what memory usage reports need. The trailing CRC64
+// checksum is verified once the end-of-file opcode is reached.
+//
+// Example usage:
+//
+//	it, err := rdb.NewIterator(data)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	for {
+//	    k, err := it.Next()
+//	    if err == io.EOF {
+//	        break
+//	    }
+//	    if err != nil {
+//	        log.Fatal(err)
+//	    }
+//	    fmt.Println(k.DB, k.Key, k.Type, k.Len, k.Size)
+//	}
+package rdb
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/nemohan/wireread"
+)
+
+var (
+	// ErrInvalidHeader is returned when the data does not start with a
+	// REDIS magic string followed by a supported version.
+	ErrInvalidHeader = errors.New("rdb: invalid header")
+
+	// ErrChecksum is returned when the trailing CRC64 does not match.
+	ErrChecksum = errors.New("rdb: checksum mismatch")
+
+	// ErrCorrupt is wrapped by errors caused by malformed content.
+	ErrCorrupt = errors.New("rdb: corrupt data")
+
+	// ErrUnsupported is wrapped by errors for value types or opcodes the
+	// iterator cannot skip over.
+	ErrUnsupported = errors.New("rdb: unsupported")
+)
+
+// MaxVersion is the highest RDB format version the iterator understands.
+const MaxVersion = 12
+
+// Opcodes that may appear in place of a value type.
+const (
+	opSlotInfo      = 0xF4
+	opFunctionPreGA = 0xF5
+	opFunction2     = 0xF6
+	opModuleAux     = 0xF7
+	opIdle          = 0xF8
+	opFreq          = 0xF9
+	opAux           = 0xFA
+	opResizeDB      = 0xFB
+	opExpireTimeMS  = 0xFC
+	opExpireTime    = 0xFD
+	opSelectDB      = 0xFE
+	opEOF           = 0xFF
+)
+
+// Encoding is the on-disk value type byte of a key.
+type Encoding byte
+
+// Value encodings as written by the various Redis versions.
+const (
+	EncString           Encoding = 0
+	EncList             Encoding = 1
+	EncSet              Encoding = 2
+	EncZSet             Encoding = 3
+	EncHash             Encoding = 4
+	EncZSet2            Encoding = 5
+	EncModule           Encoding = 6
+	EncModule2          Encoding = 7
+	EncHashZipmap       Encoding = 9
+	EncListZiplist      Encoding = 10
+	EncSetIntset        Encoding = 11
+	EncZSetZiplist      Encoding = 12
+	EncHashZiplist      Encoding = 13
+	EncListQuicklist    Encoding = 14
+	EncStreamListpacks  Encoding = 15
+	EncHashListpack     Encoding = 16
+	EncZSetListpack     Encoding = 17
+	EncListQuicklist2   Encoding = 18
+	EncStreamListpacks2 Encoding = 19
+	EncSetListpack      Encoding = 20
+	EncStreamListpacks3 Encoding = 21
+)
+
+// Type is the logical Redis data type of a key.
+type Type int
+
+// Logical data types.
+const (
+	TypeString Type = iota
+	TypeList
+	TypeSet
+	TypeZSet
+	TypeHash
+	TypeStream
+	TypeModule
+)
+
+var typeNames = [...]string{"string", "list", "set", "zset", "hash", "stream", "module"}
+
+// String returns the name used by the Redis TYPE command.
+func (t Type) String() string {
+	if t >= 0 && int(t) < len(typeNames) {
+		return typeNames[t]
+	}
+	return "type(" + strconv.Itoa(int(t)) + ")"
+}
+
+// Type returns the logical data type stored with encoding e.
+func (e Encoding) Type() Type {
+	switch e {
+	case EncString:
+		return TypeString
+	case EncList, EncListZiplist, EncListQuicklist, EncListQuicklist2:
+		return TypeList
+	case EncSet, EncSetIntset, EncSetListpack:
+		return TypeSet
+	case EncZSet, EncZSet2, EncZSetZiplist, EncZSetListpack:
+		return TypeZSet
+	case EncHash, EncHashZipmap, EncHashZiplist, EncHashListpack:
+		return TypeHash
+	case EncStreamListpacks, EncStreamListpacks2, EncStreamListpacks3:
+		return TypeStream
+	}
+	return TypeModule
+}
+
+// Key describes a single key found in the dump.
+type Key struct {
+	DB       int
+	Key      string
+	Type     Type
+	Encoding Encoding
+
+	// Len is the number of elements: 1 for strings, members for lists,
+	// sets and sorted sets, fields for hashes and entries for streams.
+	Len int64
+	// Size is the number of bytes the value occupies in the dump.
+	Size int
+
+	// ExpireAt is the absolute expiry time, or the zero Time if the key
+	// does not expire.
+	ExpireAt time.Time
+	// Idle is the LRU idle time in seconds, if recorded.
+	Idle uint64
+	// Freq is the LFU access frequency counter, if recorded.
+	Freq byte
+}
+
+// TTL returns the time to live relative to now, or zero if the key does not
+// expire. Already expired keys report a negative duration.
+func (k Key) TTL(now time.Time) time.Duration {
+	if k.ExpireAt.IsZero() {
+		return 0
+	}
+	return k.ExpireAt.Sub(now)
+}
+
+// Aux is an auxiliary field from the dump header such as redis-ver.
+type Aux struct {
+	Key   string
+	Value string
+}
+
+// DBSize holds the hash table sizes announced by a RESIZEDB opcode.
+type DBSize struct {
+	DB      int
+	Keys    uint64
+	Expires uint64
+}
+
+// Iterator walks the keys of an RDB dump.
+type Iterator struct {
+	r       *wireread.SafeReader
+	data    []byte
+	version int
+	db      int
+	aux     []Aux
+	sizes   []DBSize
+	done    bool
+}
+
+// NewIterator validates the header of an RDB dump and returns an iterator
+// positioned at the first opcode.
+func NewIterator(data []byte) (*Iterator, error) {
+	r := wireread.NewSafeReader(data)
+	magic, err := r.ReadString(9)
+	if err != nil || magic[:5] != "REDIS" {
+		return nil, ErrInvalidHeader
+	}
+	version, err := strconv.Atoi(magic[5:])
+	if err != nil || version < 1 || version > MaxVersion {
+		return nil, fmt.Errorf("%w: version %q", ErrInvalidHeader, magic[5:])
+	}
+	return &Iterator{r: r, data: data, version: version}, nil
+}
+
+// Version returns the RDB format version from the header.
+func (it *Iterator) Version() int {
+	return it.version
+}
+
+// Aux returns the auxiliary fields seen so far.
+func (it *Iterator) Aux() []Aux {
+	return it.aux
+}
+
+// DBSizes returns the RESIZEDB hints seen so far.
+func (it *Iterator) DBSizes() []DBSize {
+	return it.sizes
+}
+
+// offset returns the absolute position of the cursor within the dump.
+func (it *Iterator) offset() int {
+	return len(it.data) - len(it.r.Bytes())
+}
+
+// Next returns the next key in the dump. It returns io.EOF after the
+// end-of-file opcode has been read and the checksum verified.
+func (it *Iterator) Next() (Key, error) {
+	if it.done {
+		return Key{}, io.EOF
+	}
+	var key Key
+	for {
+		op, err := it.r.ReadByte()
+		if err != nil {
+			return Key{}, err
+		}
+		switch op {
+		case opEOF:
+			if err := it.verifyChecksum(); err != nil {
+				return Key{}, err
+			}
+			it.done = true
+			return Key{}, io.EOF
+
+		case opSelectDB:
+			db, err := readLength(it.r)
+			if err != nil {
+				return Key{}, err
+			}
+			it.db = int(db)
+
+		case opResizeDB:
+			keys, err := readLength(it.r)
+			if err != nil {
+				return Key{}, err
+			}
+			expires, err := readLength(it.r)
+			if err != nil {
+				return Key{}, err
+			}
+			it.sizes = append(it.sizes, DBSize{DB: it.db, Keys: keys, Expires: expires})
+
+		case opAux:
+			k, err := readString(it.r)
+			if err != nil {
+				return Key{}, err
+			}
+			v, err := readString(it.r)
+			if err != nil {
+				return Key{}, err
+			}
+			it.aux = append(it.aux, Aux{Key: k, Value: v})
+
+		case opExpireTime:
+			sec, err := it.r.ReadUint32LE()
+			if err != nil {
+				return Key{}, err
+			}
+			key.ExpireAt = time.Unix(int64(sec), 0)
+
+		case opExpireTimeMS:
+			ms, err := it.r.ReadUint64LE()
+			if err != nil {
+				return Key{}, err
+			}
+			key.ExpireAt = time.UnixMilli(int64(ms))
+
+		case opFreq:
+			if key.Freq, err = it.r.ReadByte(); err != nil {
+				return Key{}, err
+			}
+
+		case opIdle:
+			if key.Idle, err = readLength(it.r); err != nil {
+				return Key{}, err
+			}
+
+		case opModuleAux:
+			if err := skipModuleAux(it.r); err != nil {
+				return Key{}, err
+			}
+
+		case opFunction2:
+			if err := skipString(it.r); err != nil {
+				return Key{}, err
+			}
+
+		case opSlotInfo:
+			for i := 0; i < 3; i++ {
+				if _, err := readLength(it.r); err != nil {
+					return Key{}, err
+				}
+			}
+
+		case opFunctionPreGA:
+			return Key{}, fmt.Errorf("%w: opcode 0x%02x", ErrUnsupported, op)
+
+		default:
+			key.DB = it.db
+			key.Encoding = Encoding(op)
+			key.Type = key.Encoding.Type()
+			if key.Key, err = readString(it.r); err != nil {
+				return Key{}, err
+			}
+			start := it.offset()
+			if key.Len, err = skipValue(it.r, key.Encoding); err != nil {
+				return Key{}, fmt.Errorf("key %q: %w", key.Key, err)
+			}
+			key.Size = it.offset() - start
+			return key, nil
+		}
+	}
+}
+
+// verifyChecksum checks the CRC64 trailer written by RDB version 5 and later.
+// A stored checksum of zero means checksumming was disabled on the server.
+func (it *Iterator) verifyChecksum() error {
+	if it.version < 5 {
+		return nil
+	}
+	end := it.offset()
+	stored, err := it.r.ReadUint64LE()
+	if err != nil {
+		return err
+	}
+	if stored != 0 && stored != CRC64(it.data[:end]) {
+		return ErrChecksum
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+package rdb
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readAll(t *testing.T, path string) (*Iterator, []Key) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	it, err := NewIterator(data)
+	if err != nil {
+		t.Fatalf("NewIterator() error = %v", err)
+	}
+	var keys []Key
+	for {
+		k, err := it.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		keys = append(keys, k)
+	}
+	return it, keys
+}
+
+func TestIterator_Version9(t *testing.T) {
+	it, keys := readAll(t, "testdata/memory.rdb")
+
+	if it.Version() != 9 {
+		t.Errorf("Version() = %d, want 9", it.Version())
+	}
+	if aux := it.Aux(); len(aux) != 5 || aux[0].Key != "redis-ver" || aux[0].Value != "6.0.6" {
+		t.Errorf("Aux() = %+v", aux)
+	}
+	if sizes := it.DBSizes(); len(sizes) != 1 || sizes[0].Keys != 7 || sizes[0].Expires != 1 {
+		t.Errorf("DBSizes() = %+v", sizes)
+	}
+
+	want := []struct {
+		key string
+		typ Type
+		enc Encoding
+		len int64
+	}{
+		{"hash", TypeHash, EncHashZiplist, 2},
+		{"s", TypeString, EncString, 1},
+		{"e", TypeString, EncString, 1},
+		{"list", TypeList, EncListQuicklist, 4},
+		{"zset", TypeZSet, EncZSetZiplist, 2},
+		{"large", TypeString, EncString, 1},
+		{"set", TypeSet, EncSet, 2},
+	}
+	if len(keys) != len(want) {
+		t.Fatalf("got %d keys, want %d", len(keys), len(want))
+	}
+	for i, w := range want {
+		k := keys[i]
+		if k.DB != 0 || k.Key != w.key || k.Type != w.typ || k.Encoding != w.enc || k.Len != w.len {
+			t.Errorf("key %d = %+v, want %+v", i, k, w)
+		}
+	}
+	// "aaaaaaa" and "zxcvb" behind a one-byte length prefix.
+	if keys[1].Size != 8 || keys[2].Size != 6 {
+		t.Errorf("Size = %d, %d, want 8, 6", keys[1].Size, keys[2].Size)
+	}
+
+	if !keys[1].ExpireAt.IsZero() {
+		t.Errorf("s ExpireAt = %v, want none", keys[1].ExpireAt)
+	}
+	expire := time.Date(2022, 2, 17, 22, 15, 29, 180e6, time.UTC)
+	if !keys[2].ExpireAt.Equal(expire) {
+		t.Errorf("e ExpireAt = %v, want %v", keys[2].ExpireAt, expire)
+	}
+	if ttl := keys[2].TTL(expire.Add(-time.Minute)); ttl != time.Minute {
+		t.Errorf("TTL() = %v, want 1m", ttl)
+	}
+}
+
+func TestIterator_Dumps(t *testing.T) {
+	type want struct {
+		key string
+		typ Type
+		enc Encoding
+		len int64
+	}
+	tests := []struct {
+		file    string
+		version int
+		keys    []want
+	}{
+		{"quicklist.rdb", 9, []want{
+			{"list", TypeList, EncListQuicklist, 6},
+		}},
+		{"listpack.rdb", 10, []want{
+			{"l", TypeList, EncListQuicklist2, 9},
+			{"z", TypeZSet, EncZSetListpack, 12},
+			{"h", TypeHash, EncHashListpack, 11},
+		}},
+		{"stream_listpacks_2.rdb", 10, []want{
+			{"astream", TypeStream, EncStreamListpacks2, 2},
+		}},
+		{"set_listpack.rdb", 11, []want{
+			{"s", TypeSet, EncSetListpack, 4},
+		}},
+		{"intset_16.rdb", 3, []want{
+			{"intset_16", TypeSet, EncSetIntset, 3},
+		}},
+		{"intset_32.rdb", 3, []want{
+			{"intset_32", TypeSet, EncSetIntset, 3},
+		}},
+		{"intset_64.rdb", 3, []want{
+			{"intset_64", TypeSet, EncSetIntset, 3},
+		}},
+		{"easily_compressible_string_key.rdb", 3, []want{
+			{strings.Repeat("a", 200), TypeString, EncString, 1},
+		}},
+		{"ziplist_that_compresses_easily.rdb", 3, []want{
+			{"ziplist_compresses_easily", TypeList, EncListZiplist, 6},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			it, keys := readAll(t, "testdata/"+tt.file)
+			if it.Version() != tt.version {
+				t.Errorf("Version() = %d, want %d", it.Version(), tt.version)
+			}
+			if len(keys) != len(tt.keys) {
+				t.Fatalf("got %d keys, want %d", len(keys), len(tt.keys))
+			}
+			for i, w := range tt.keys {
+				k := keys[i]
+				if k.Key != w.key || k.Type != w.typ || k.Encoding != w.enc || k.Len != w.len {
+					t.Errorf("key %d = %+v, want %+v", i, k, w)
+				}
+			}
+		})
+	}
+}
+
+func TestIterator_ExpireMS(t *testing.T) {
+	_, keys := readAll(t, "testdata/keys_with_expiry.rdb")
+
+	expire := time.Date(2022, 12, 25, 10, 11, 12, 573e6, time.UTC)
+	if len(keys) != 1 || keys[0].Key != "expires_ms_precision" || !keys[0].ExpireAt.Equal(expire) {
+		t.Errorf("keys = %+v, want expires_ms_precision at %v", keys, expire)
+	}
+}
+
+func TestIterator_IdleFreq(t *testing.T) {
+	// The sample dumps carry no LRU or LFU opcodes, so build one here.
+	// A stored checksum of zero disables verification.
+	data := []byte("REDIS0011")
+	data = append(data, opFreq, 5, 0, 1, 'a', 1, 'x')
+	data = append(data, opIdle, 0x40, 100, 0, 1, 'b', 1, 'y')
+	data = append(data, opEOF, 0, 0, 0, 0, 0, 0, 0, 0)
+
+	it, err := NewIterator(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := it.Next()
+	if err != nil || a.Key != "a" || a.Freq != 5 {
+		t.Errorf("Next() = %+v, %v, want a with Freq 5", a, err)
+	}
+	b, err := it.Next()
+	if err != nil || b.Key != "b" || b.Idle != 100 || b.Freq != 0 {
+		t.Errorf("Next() = %+v, %v, want b with Idle 100", b, err)
+	}
+	if _, err := it.Next(); err != io.EOF {
+		t.Errorf("Next() error = %v, want io.EOF", err)
+	}
+}
+
+func TestIterator_Checksum(t *testing.T) {
+	data, err := os.ReadFile("testdata/set_listpack.rdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[len(data)-1] ^= 0xFF
+
+	it, err := NewIterator(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for err == nil {
+		_, err = it.Next()
+	}
+	if err != ErrChecksum {
+		t.Errorf("Next() error = %v, want ErrChecksum", err)
+	}
+}
+
+func TestIterator_Truncated(t *testing.T) {
+	data, err := os.ReadFile("testdata/memory.rdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for n := 9; n < len(data)-1; n++ {
+		it, err := NewIterator(data[:n])
+		if err != nil {
+			t.Fatal(err)
+		}
+		for err == nil {
+			_, err = it.Next()
+		}
+		if err == io.EOF {
+			t.Fatalf("truncated at %d: iteration ended cleanly", n)
+		}
+	}
+}
+
+func TestNewIterator_InvalidHeader(t *testing.T) {
+	for _, data := range []string{"", "REDIS", "RADIS0009", "REDIS0099", "REDISabcd"} {
+		if _, err := NewIterator([]byte(data)); !errors.Is(err, ErrInvalidHeader) {
+			t.Errorf("NewIterator(%q) error = %v, want ErrInvalidHeader", data, err)
+		}
+	}
+}
+
+func TestCRC64(t *testing.T) {
+	// Check value from the Redis source tree (src/crc64.c).
+	if got := CRC64([]byte("123456789")); got != 0xe9c6d914c4b8d9ca {
+		t.Errorf("CRC64() = %x, want e9c6d914c4b8d9ca", got)
+	}
+}
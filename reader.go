@@ -24,6 +24,34 @@
 //	value, _ := reader.ReadUint16BE() // No error checking for performance
 package wireread
 
+import (
+	"errors"
+	"io"
+)
+
+// ErrInvalidASCIIInt is returned by ReadASCIIInt when the line does not hold a
+// well-formed base-10 integer or the value does not fit in an int64.
+var ErrInvalidASCIIInt = errors.New("wireread: invalid ASCII integer")
+
+// ErrInvalidOffset is returned by the random-access methods when the offset
+// or length is negative.
+var ErrInvalidOffset = errors.New("wireread: negative offset or length")
+
+// ErrInvalidWhence is returned by Seek for a whence other than io.SeekStart,
+// io.SeekCurrent or io.SeekEnd.
+var ErrInvalidWhence = errors.New("wireread: invalid whence")
+
+// ErrInvalidWrite is returned by WriteTo when the writer reports a negative
+// count or more bytes than it was given. The read position is left unchanged.
+var ErrInvalidWrite = errors.New("wireread: invalid Write count")
+
+// ErrNeedMore is matched by the *NeedMoreError an IncrementalReader returns
+// when a read needs bytes that have not been appended yet.
+var ErrNeedMore = errors.New("wireread: need more data")
+
+// ErrUnreadByte is returned by UnreadByte at the start of the data.
+var ErrUnreadByte = errors.New("wireread: UnreadByte at beginning of data")
+
 // Reader defines the interface for reading wire protocol data.
 // It provides methods for reading various data types from a byte buffer
 // with support for different byte orders and protocol-specific formats.
@@ -93,3 +121,46 @@ type Reader interface {
 	// ReadUint64LEInto reads a 64-bit unsigned integer in little-endian byte order into the provided pointer
 	ReadUint64LEInto(out *uint64) error
 }
+
+// ASCIIIntReader is implemented by the readers that parse line-terminated
+// decimal integers, as RESP uses. It is separate from Reader so that existing
+// implementations of Reader keep satisfying it.
+type ASCIIIntReader interface {
+	// ReadASCIIInt reads a signed base-10 integer terminated by \n (handles \r\n)
+	ReadASCIIInt() (int64, error)
+}
+
+// RandomAccessReader is implemented by the readers that can read at an
+// absolute offset from the start of the data, leaving the read position
+// unchanged. Reader leaves these out so that it can be implemented over data
+// that is only ever consumed front to back.
+type RandomAccessReader interface {
+	// ReadByteAt reads the byte at offset off
+	ReadByteAt(off int) (byte, error)
+	// ReadBytesAt returns the n bytes at offset off, aliasing the underlying data
+	ReadBytesAt(off, n int) ([]byte, error)
+	// ReadUint16BEAt reads a 16-bit unsigned integer in big-endian byte order at offset off
+	ReadUint16BEAt(off int) (uint16, error)
+	// ReadUint32BEAt reads a 32-bit unsigned integer in big-endian byte order at offset off
+	ReadUint32BEAt(off int) (uint32, error)
+	// ReadUint64BEAt reads a 64-bit unsigned integer in big-endian byte order at offset off
+	ReadUint64BEAt(off int) (uint64, error)
+	// ReadUint16LEAt reads a 16-bit unsigned integer in little-endian byte order at offset off
+	ReadUint16LEAt(off int) (uint16, error)
+	// ReadUint32LEAt reads a 32-bit unsigned integer in little-endian byte order at offset off
+	ReadUint32LEAt(off int) (uint32, error)
+	// ReadUint64LEAt reads a 64-bit unsigned integer in little-endian byte order at offset off
+	ReadUint64LEAt(off int) (uint64, error)
+}
+
+// StreamReader is implemented by the readers that also satisfy the standard
+// stream interfaces. These share the read position with the Reader methods,
+// so the reader can be handed to code expecting them and picked up again
+// afterwards.
+type StreamReader interface {
+	io.Reader
+	io.ByteScanner
+	io.RuneReader
+	io.Seeker
+	io.WriterTo
+}
@@ -0,0 +1,196 @@
+// Package reassembly puts captured TCP segments back in order and exposes
+// each direction of a connection as an io.Reader, so that the streaming
+// decoders of this module can parse application protocols from captures.
+//
+// An Assembler is fed the TCP layer of every captured packet. It tracks each
+// direction, keyed by its 4-tuple, as a Stream: retransmitted and
+// overlapping bytes are dropped, out-of-order segments are buffered until
+// the missing data arrives, and FIN or RST ends the stream. Streams are
+// meant to be read from their own goroutine; Read blocks until data arrives
+// or the stream ends.
+//
+// Missing data is never waited for forever. When a stream's buffered bytes
+// exceed its limit, and for segments older than the time passed to
+// FlushOlderThan, the stream skips ahead to the next buffered segment and
+// Read reports the gap with an error wrapping ErrGap.
+//
+// Example usage:
+//
+//	a := reassembly.NewAssembler(func(s *reassembly.Stream) {
+//	    if s.Flow.Dst.Port() != 1883 {
+//	        s.Discard()
+//	        return
+//	    }
+//	    go func() {
+//	        d := mqtt.NewDecoder(s)
+//	        for {
+//	            pkt, err := d.Decode()
+//	            if err != nil {
+//	                return
+//	            }
+//	            fmt.Println(s.Flow, pkt.Type())
+//	        }
+//	    }()
+//	})
+//	for {
+//	    pkt, err := r.Next() // a pcap.Reader
+//	    if err != nil {
+//	        break
+//	    }
+//	    if p, err := layers.Decode(pkt.Data, layers.LayerEthernet); err == nil {
+//	        a.AssemblePacket(p, pkt.Timestamp)
+//	    }
+//	}
+//	a.Close()
+package reassembly
+
+import (
+	"errors"
+	"io"
+	"net/netip"
+	"time"
+
+	"github.com/nemohan/wireread/layers"
+)
+
+var (
+	// ErrGap is wrapped by the error Read returns where missing data was
+	// skipped. Reading may continue after it.
+	ErrGap = errors.New("reassembly: data missing from stream")
+
+	// ErrBufferFull ends a stream whose reader fell behind by more than
+	// the stream's buffer limit.
+	ErrBufferFull = errors.New("reassembly: stream buffer full")
+
+	// ErrReset ends a stream whose connection was reset.
+	ErrReset = errors.New("reassembly: connection reset")
+)
+
+// DefaultMaxBufferedBytes is the default limit on the out-of-order bytes,
+// and separately on the unread bytes, held for one direction of a
+// connection.
+const DefaultMaxBufferedBytes = 4 << 20
+
+// Flow identifies one direction of a TCP connection.
+type Flow struct {
+	Src, Dst netip.AddrPort
+}
+
+// Reverse returns the flow of the other direction.
+func (f Flow) Reverse() Flow {
+	return Flow{Src: f.Dst, Dst: f.Src}
+}
+
+func (f Flow) String() string {
+	return f.Src.String() + "->" + f.Dst.String()
+}
+
+// Assembler reassembles TCP streams. It is not safe for concurrent use, but
+// the Streams it creates may be read concurrently with it.
+type Assembler struct {
+	onStream func(*Stream)
+	maxBytes int
+	streams  map[Flow]*Stream
+}
+
+// NewAssembler creates an Assembler that calls onStream, from Assemble, for
+// every new direction of a connection before handing it any data.
+func NewAssembler(onStream func(*Stream)) *Assembler {
+	return &Assembler{
+		onStream: onStream,
+		maxBytes: DefaultMaxBufferedBytes,
+		streams:  make(map[Flow]*Stream),
+	}
+}
+
+// SetMaxBufferedBytes limits the out-of-order bytes and the unread bytes
+// held for each new stream. Out-of-order data over the limit makes the
+// stream skip its oldest gaps; unread data over the limit ends the stream
+// with ErrBufferFull.
+func (a *Assembler) SetMaxBufferedBytes(n int) {
+	a.maxBytes = n
+}
+
+// AssemblePacket assembles the TCP layer of a decoded packet. It reports
+// whether the packet had one.
+func (a *Assembler) AssemblePacket(p *layers.Packet, ts time.Time) bool {
+	if p.TCP == nil {
+		return false
+	}
+	switch {
+	case p.IPv4 != nil:
+		a.Assemble(p.IPv4.Src, p.IPv4.Dst, p.TCP, ts)
+	case p.IPv6 != nil:
+		a.Assemble(p.IPv6.Src, p.IPv6.Dst, p.TCP, ts)
+	default:
+		return false
+	}
+	return true
+}
+
+// Assemble adds a segment sent from src to dst, captured at ts. The payload
+// is copied, so the packet data may be reused afterwards.
+func (a *Assembler) Assemble(src, dst netip.Addr, tcp *layers.TCP, ts time.Time) {
+	flow := Flow{
+		Src: netip.AddrPortFrom(src, tcp.SrcPort),
+		Dst: netip.AddrPortFrom(dst, tcp.DstPort),
+	}
+	syn := tcp.Flags&layers.TCPFlagSYN != 0
+	s := a.streams[flow]
+	// A new SYN on a finished flow starts a new connection on the same
+	// ports, unless it is a retransmission of the old one's.
+	if s != nil && s.done && syn && s.isn != tcp.Seq {
+		s = nil
+	}
+	if s == nil {
+		s = newStream(flow, a.maxBytes)
+		a.streams[flow] = s
+		if a.onStream != nil {
+			a.onStream(s)
+		}
+	}
+	if tcp.Flags&layers.TCPFlagRST != 0 {
+		s.close(ErrReset)
+		if rev := a.streams[flow.Reverse()]; rev != nil {
+			rev.close(ErrReset)
+		}
+		return
+	}
+	s.segment(tcp.Seq, tcp.Payload, syn, tcp.Flags&layers.TCPFlagFIN != 0, ts)
+}
+
+// FlushOlderThan makes every stream skip the gaps before segments that have
+// been buffered since before t, as well as a gap before a FIN seen before
+// t.
+func (a *Assembler) FlushOlderThan(t time.Time) {
+	for _, s := range a.streams {
+		s.flush(t)
+	}
+}
+
+// CloseOlderThan ends the streams that have seen no segment since before t,
+// after skipping any gaps, and forgets them. Streams still being read get
+// io.EOF once their data is consumed. It returns the number of streams
+// forgotten.
+func (a *Assembler) CloseOlderThan(t time.Time) int {
+	n := 0
+	for flow, s := range a.streams {
+		if s.lastSeen.Before(t) {
+			s.flushAll()
+			s.close(io.EOF)
+			delete(a.streams, flow)
+			n++
+		}
+	}
+	return n
+}
+
+// Close ends every stream, after skipping any gaps, and forgets them. It is
+// meant for the end of a capture.
+func (a *Assembler) Close() {
+	for flow, s := range a.streams {
+		s.flushAll()
+		s.close(io.EOF)
+		delete(a.streams, flow)
+	}
+}
@@ -0,0 +1,263 @@
+package reassembly
+
+import (
+	"errors"
+	"io"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/nemohan/wireread/layers"
+)
+
+var (
+	client = netip.MustParseAddr("192.0.2.1")
+	server = netip.MustParseAddr("192.0.2.2")
+	epoch  = time.Unix(1700000000, 0)
+)
+
+func seg(seq uint32, flags layers.TCPFlags, payload string) *layers.TCP {
+	return &layers.TCP{SrcPort: 40000, DstPort: 80, Seq: seq, Flags: flags, Payload: []byte(payload)}
+}
+
+func reply(seq uint32, flags layers.TCPFlags, payload string) *layers.TCP {
+	return &layers.TCP{SrcPort: 80, DstPort: 40000, Seq: seq, Flags: flags, Payload: []byte(payload)}
+}
+
+// collect records the streams an Assembler creates.
+type collect []*Stream
+
+func (c *collect) add(s *Stream) { *c = append(*c, s) }
+
+// readAll reads s to its end, marking gaps with '~'.
+func readAll(s *Stream) (string, error) {
+	var out []byte
+	buf := make([]byte, 3)
+	for {
+		n, err := s.Read(buf)
+		out = append(out, buf[:n]...)
+		switch {
+		case errors.Is(err, ErrGap):
+			out = append(out, '~')
+		case err != nil:
+			return string(out), err
+		}
+	}
+}
+
+func TestAssembler_Segments(t *testing.T) {
+	const isn = 0xfffffff0 // sequence numbers wrap within the stream
+	cases := []struct {
+		name string
+		segs []*layers.TCP
+		want string
+	}{
+		{"in order", []*layers.TCP{
+			seg(isn, layers.TCPFlagSYN, ""),
+			seg(isn+1, layers.TCPFlagACK, "hello "),
+			seg(isn+7, layers.TCPFlagACK|layers.TCPFlagFIN, "world"),
+		}, "hello world"},
+		{"retransmission and overlap", []*layers.TCP{
+			seg(isn, layers.TCPFlagSYN, ""),
+			seg(isn+1, layers.TCPFlagACK, "hello "),
+			seg(isn+1, layers.TCPFlagACK, "hello "),
+			seg(isn+4, layers.TCPFlagACK, "lo wor"),
+			seg(isn+7, layers.TCPFlagACK|layers.TCPFlagFIN, "world"),
+		}, "hello world"},
+		{"out of order", []*layers.TCP{
+			seg(isn, layers.TCPFlagSYN, ""),
+			seg(isn+7, layers.TCPFlagACK|layers.TCPFlagFIN, "world"),
+			seg(isn+4, layers.TCPFlagACK, "lo "),
+			seg(isn+4, layers.TCPFlagACK, "lo"),
+			seg(isn+1, layers.TCPFlagACK, "hel"),
+		}, "hello world"},
+		{"overlapping out of order", []*layers.TCP{
+			seg(isn, layers.TCPFlagSYN, ""),
+			seg(isn+9, layers.TCPFlagACK|layers.TCPFlagFIN, "rld"),
+			seg(isn+5, layers.TCPFlagACK, "o wor"),
+			seg(isn+1, layers.TCPFlagACK, "hello"),
+		}, "hello world"},
+		{"no SYN", []*layers.TCP{
+			seg(100, layers.TCPFlagACK, "mid"),
+			seg(103, layers.TCPFlagACK|layers.TCPFlagFIN, "stream"),
+		}, "midstream"},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var streams collect
+			a := NewAssembler(streams.add)
+			for _, s := range tt.segs {
+				a.Assemble(client, server, s, epoch)
+			}
+			if len(streams) != 1 {
+				t.Fatalf("got %d streams, want 1", len(streams))
+			}
+			got, err := readAll(streams[0])
+			if got != tt.want || err != io.EOF {
+				t.Errorf("read %q, %v, want %q, io.EOF", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestAssembler_BothDirections(t *testing.T) {
+	var streams collect
+	a := NewAssembler(streams.add)
+	a.Assemble(client, server, seg(1000, layers.TCPFlagSYN, ""), epoch)
+	a.Assemble(server, client, reply(5000, layers.TCPFlagSYN|layers.TCPFlagACK, ""), epoch)
+	a.Assemble(client, server, seg(1001, layers.TCPFlagACK, "ping"), epoch)
+	a.Assemble(server, client, reply(5001, layers.TCPFlagACK, "pong"), epoch)
+	a.Assemble(server, client, reply(5005, layers.TCPFlagRST, ""), epoch)
+
+	if len(streams) != 2 {
+		t.Fatalf("got %d streams, want 2", len(streams))
+	}
+	want := Flow{Src: netip.AddrPortFrom(client, 40000), Dst: netip.AddrPortFrom(server, 80)}
+	if streams[0].Flow != want || streams[1].Flow != want.Reverse() {
+		t.Errorf("flows = %v, %v", streams[0].Flow, streams[1].Flow)
+	}
+	for i, data := range []string{"ping", "pong"} {
+		if got, err := readAll(streams[i]); got != data || err != ErrReset {
+			t.Errorf("stream %d read %q, %v, want %q, ErrReset", i, got, err, data)
+		}
+	}
+}
+
+func TestAssembler_Gaps(t *testing.T) {
+	var streams collect
+	a := NewAssembler(streams.add)
+	a.Assemble(client, server, seg(0, layers.TCPFlagSYN, ""), epoch)
+	a.Assemble(client, server, seg(1, layers.TCPFlagACK, "ab"), epoch)
+	a.Assemble(client, server, seg(5, layers.TCPFlagACK, "ef"), epoch)
+	a.Assemble(client, server, seg(9, layers.TCPFlagACK, "ij"), epoch.Add(time.Minute))
+	a.Assemble(client, server, seg(13, layers.TCPFlagFIN, ""), epoch.Add(time.Minute))
+
+	// Only the gap before "ef" is old enough to skip.
+	a.FlushOlderThan(epoch.Add(time.Second))
+	s := streams[0]
+	buf := make([]byte, 10)
+	if n, err := s.Read(buf); string(buf[:n]) != "ab" || err != nil {
+		t.Errorf("Read() = %q, %v, want %q", buf[:n], err, "ab")
+	}
+	if _, err := s.Read(buf); !errors.Is(err, ErrGap) {
+		t.Errorf("Read() error = %v, want ErrGap", err)
+	}
+	if n, err := s.Read(buf); string(buf[:n]) != "ef" || err != nil {
+		t.Errorf("Read() = %q, %v, want %q", buf[:n], err, "ef")
+	}
+
+	a.Close()
+	if got, err := readAll(s); got != "~ij~" || err != io.EOF {
+		t.Errorf("read %q, %v, want %q, io.EOF", got, err, "~ij~")
+	}
+}
+
+func TestAssembler_MaxBufferedBytes(t *testing.T) {
+	var streams collect
+	a := NewAssembler(streams.add)
+	a.SetMaxBufferedBytes(4)
+	a.Assemble(client, server, seg(0, layers.TCPFlagSYN, ""), epoch)
+	a.Assemble(client, server, seg(1, layers.TCPFlagACK, "ab"), epoch)
+	a.Assemble(client, server, seg(5, layers.TCPFlagACK, "ef"), epoch)
+	// Holding 5 bytes out of order is too many, so the gap before "ef" is
+	// skipped.
+	a.Assemble(client, server, seg(9, layers.TCPFlagACK, "ijk"), epoch)
+	s := streams[0]
+	if s.pendingBytes != 3 || s.written != 4 {
+		t.Errorf("pendingBytes, written = %d, %d, want 3, 4", s.pendingBytes, s.written)
+	}
+	// Six unread bytes are too many and end the stream.
+	a.Assemble(client, server, seg(7, layers.TCPFlagACK, "gh"), epoch)
+	if got, err := readAll(s); got != "ab~ef" || err != ErrBufferFull {
+		t.Errorf("read %q, %v, want %q, ErrBufferFull", got, err, "ab~ef")
+	}
+}
+
+func TestAssembler_BlockingRead(t *testing.T) {
+	streams := make(chan *Stream, 1)
+	a := NewAssembler(func(s *Stream) { streams <- s })
+	a.Assemble(client, server, seg(0, layers.TCPFlagSYN, ""), epoch)
+
+	done := make(chan string)
+	go func() {
+		b, err := io.ReadAll(<-streams)
+		if err != nil {
+			t.Errorf("ReadAll() error = %v", err)
+		}
+		done <- string(b)
+	}()
+	a.Assemble(client, server, seg(1, layers.TCPFlagACK, "one "), epoch)
+	a.Assemble(client, server, seg(5, layers.TCPFlagACK, "two"), epoch)
+	a.Assemble(client, server, seg(8, layers.TCPFlagFIN, ""), epoch)
+	if got := <-done; got != "one two" {
+		t.Errorf("ReadAll() = %q, want %q", got, "one two")
+	}
+}
+
+func TestAssembler_CloseOlderThan(t *testing.T) {
+	var streams collect
+	a := NewAssembler(streams.add)
+	a.Assemble(client, server, seg(0, layers.TCPFlagSYN, ""), epoch)
+	a.Assemble(client, server, seg(1, layers.TCPFlagACK, "idle"), epoch)
+	a.Assemble(server, client, reply(0, layers.TCPFlagSYN|layers.TCPFlagACK, ""), epoch.Add(time.Minute))
+
+	if n := a.CloseOlderThan(epoch.Add(time.Second)); n != 1 {
+		t.Errorf("CloseOlderThan() = %d, want 1", n)
+	}
+	if got, err := readAll(streams[0]); got != "idle" || err != io.EOF {
+		t.Errorf("read %q, %v, want %q, io.EOF", got, err, "idle")
+	}
+
+	// A new connection on the same ports gets a new stream.
+	a.Assemble(client, server, seg(500, layers.TCPFlagSYN, ""), epoch.Add(time.Hour))
+	if len(streams) != 3 {
+		t.Errorf("got %d streams, want 3", len(streams))
+	}
+}
+
+func TestAssembler_PortReuse(t *testing.T) {
+	var streams collect
+	a := NewAssembler(streams.add)
+	a.Assemble(client, server, seg(0, layers.TCPFlagSYN, ""), epoch)
+	a.Assemble(client, server, seg(1, layers.TCPFlagFIN, "x"), epoch)
+	a.Assemble(client, server, seg(0, layers.TCPFlagSYN, ""), epoch) // retransmitted SYN
+	a.Assemble(client, server, seg(900, layers.TCPFlagSYN, ""), epoch)
+	a.Assemble(client, server, seg(901, layers.TCPFlagFIN, "y"), epoch)
+
+	if len(streams) != 2 {
+		t.Fatalf("got %d streams, want 2", len(streams))
+	}
+	for i, want := range []string{"x", "y"} {
+		if got, err := readAll(streams[i]); got != want || err != io.EOF {
+			t.Errorf("stream %d read %q, %v, want %q, io.EOF", i, got, err, want)
+		}
+	}
+}
+
+func TestStream_Discard(t *testing.T) {
+	a := NewAssembler(func(s *Stream) { s.Discard() })
+	a.Assemble(client, server, seg(0, layers.TCPFlagSYN, ""), epoch)
+	s := a.streams[Flow{Src: netip.AddrPortFrom(client, 40000), Dst: netip.AddrPortFrom(server, 80)}]
+	a.Assemble(client, server, seg(1, layers.TCPFlagACK, "data"), epoch)
+	a.Assemble(client, server, seg(9, layers.TCPFlagACK, "later"), epoch)
+	if !s.done || len(s.pending) != 0 {
+		t.Errorf("discarded stream done = %v with %d pending segments", s.done, len(s.pending))
+	}
+	if n, err := s.Read(make([]byte, 4)); n != 0 || err != io.EOF {
+		t.Errorf("Read() = %d, %v, want 0, io.EOF", n, err)
+	}
+}
+
+func TestAssembler_AssemblePacket(t *testing.T) {
+	var streams collect
+	a := NewAssembler(streams.add)
+	v6 := netip.MustParseAddr("2001:db8::1")
+	p := &layers.Packet{IPv6: &layers.IPv6{Src: v6, Dst: v6}, TCP: seg(0, layers.TCPFlagSYN, "")}
+	if !a.AssemblePacket(p, epoch) || len(streams) != 1 || streams[0].Flow.Src.Addr() != v6 {
+		t.Errorf("AssemblePacket(IPv6) streams = %v", streams)
+	}
+	if a.AssemblePacket(&layers.Packet{IPv4: &layers.IPv4{}, UDP: &layers.UDP{}}, epoch) {
+		t.Error("AssemblePacket(UDP) = true, want false")
+	}
+}
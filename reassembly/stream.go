@@ -0,0 +1,272 @@
+package reassembly
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Stream is one direction of a TCP connection. It implements io.Reader
+// over the reassembled bytes.
+type Stream struct {
+	Flow Flow
+
+	// Sequence state, only used by the Assembler.
+	max          int
+	started      bool
+	done         bool
+	isn          uint32
+	next         uint32
+	pending      []segment
+	pendingBytes int
+	fin          bool
+	finSeq       uint32
+	finSeen      time.Time
+	lastSeen     time.Time
+
+	// Data handed to the reader, guarded by mu.
+	mu      sync.Mutex
+	cond    sync.Cond
+	buf     []byte
+	off     int
+	read    int64
+	written int64
+	gaps    []gap
+	err     error
+}
+
+// segment is an out-of-order segment waiting for the data before it.
+type segment struct {
+	seq  uint32
+	data []byte
+	seen time.Time
+}
+
+// gap records n bytes skipped before stream offset at.
+type gap struct {
+	at, n int64
+}
+
+func newStream(flow Flow, max int) *Stream {
+	s := &Stream{Flow: flow, max: max}
+	s.cond.L = &s.mu
+	return s
+}
+
+// Read reads reassembled data, blocking until some is available. At a gap
+// it returns an error wrapping ErrGap, after which reading may continue.
+// Once the stream has ended and its data has been read, Read returns io.EOF
+// after a FIN or an idle timeout, ErrReset after a RST and ErrBufferFull if
+// the reader fell too far behind.
+func (s *Stream) Read(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for s.off == len(s.buf) && s.err == nil && !s.atGap() {
+		s.cond.Wait()
+	}
+	if s.atGap() {
+		g := s.gaps[0]
+		s.gaps = s.gaps[1:]
+		return 0, fmt.Errorf("%w: %d bytes at offset %d", ErrGap, g.n, g.at)
+	}
+	if s.off == len(s.buf) {
+		return 0, s.err
+	}
+	avail := s.buf[s.off:]
+	if len(s.gaps) > 0 {
+		avail = avail[:s.gaps[0].at-s.read]
+	}
+	n := copy(p, avail)
+	s.off += n
+	s.read += int64(n)
+	if s.off == len(s.buf) {
+		s.buf, s.off = s.buf[:0], 0
+	}
+	return n, nil
+}
+
+func (s *Stream) atGap() bool {
+	return len(s.gaps) > 0 && s.gaps[0].at == s.read
+}
+
+// Discard drops the stream's data, now and from then on, for streams that
+// will not be read. Read returns io.EOF afterwards.
+func (s *Stream) Discard() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.buf, s.off, s.gaps = nil, 0, nil
+	if s.err == nil {
+		s.err = io.EOF
+	}
+	s.cond.Broadcast()
+}
+
+// segment handles a segment's sequence number, payload and flags.
+func (s *Stream) segment(seq uint32, data []byte, syn, fin bool, ts time.Time) {
+	if s.done {
+		return
+	}
+	s.lastSeen = ts
+	if syn {
+		if !s.started {
+			s.isn = seq
+		}
+		seq++
+	}
+	if !s.started {
+		s.started = true
+		s.next = seq
+	}
+	if fin && !s.fin {
+		s.fin, s.finSeq, s.finSeen = true, seq+uint32(len(data)), ts
+	}
+	s.add(seq, data, ts)
+	s.checkFIN()
+}
+
+// add delivers data if it is next in sequence, or buffers it if it comes
+// after a gap. Bytes already delivered are dropped.
+func (s *Stream) add(seq uint32, data []byte, ts time.Time) {
+	if len(data) == 0 {
+		return
+	}
+	off := int64(int32(seq - s.next))
+	if off+int64(len(data)) <= 0 {
+		return // retransmission
+	}
+	if off <= 0 {
+		s.deliver(data[-off:])
+		s.drain()
+		return
+	}
+	s.insert(segment{seq: seq, data: bytes.Clone(data), seen: ts})
+	for s.pendingBytes > s.max {
+		s.skipGap()
+	}
+}
+
+// insert adds seg to the pending segments, kept in sequence order, unless
+// one of them already covers it.
+func (s *Stream) insert(seg segment) {
+	i := 0
+	for ; i < len(s.pending); i++ {
+		p := s.pending[i]
+		d := int32(seg.seq - p.seq)
+		if d >= 0 && int(d)+len(seg.data) <= len(p.data) {
+			return
+		}
+		if d < 0 {
+			break
+		}
+	}
+	s.pending = append(s.pending, segment{})
+	copy(s.pending[i+1:], s.pending[i:])
+	s.pending[i] = seg
+	s.pendingBytes += len(seg.data)
+}
+
+// drain delivers the pending segments that the data so far has reached.
+func (s *Stream) drain() {
+	for len(s.pending) > 0 {
+		p := s.pending[0]
+		off := int64(int32(p.seq - s.next))
+		if off > 0 {
+			return
+		}
+		s.pending = s.pending[1:]
+		s.pendingBytes -= len(p.data)
+		if off+int64(len(p.data)) > 0 {
+			s.deliver(p.data[-off:])
+		}
+	}
+}
+
+// skipGap gives up on the data missing before the first pending segment,
+// or before the FIN if none is pending. It reports whether there was a gap.
+func (s *Stream) skipGap() bool {
+	var to uint32
+	switch {
+	case len(s.pending) > 0:
+		to = s.pending[0].seq
+	case s.fin:
+		to = s.finSeq
+	default:
+		return false
+	}
+	n := int32(to - s.next)
+	if n <= 0 {
+		return false
+	}
+	s.mu.Lock()
+	if s.err == nil {
+		s.gaps = append(s.gaps, gap{at: s.written, n: int64(n)})
+		s.cond.Broadcast()
+	}
+	s.mu.Unlock()
+	s.next = to
+	s.drain()
+	return true
+}
+
+// flush skips the gaps before data or a FIN seen before t.
+func (s *Stream) flush(t time.Time) {
+	for !s.done {
+		old := s.fin && len(s.pending) == 0 && s.finSeen.Before(t)
+		for _, p := range s.pending {
+			old = old || p.seen.Before(t)
+		}
+		if !old || !s.skipGap() {
+			return
+		}
+		s.checkFIN()
+	}
+}
+
+// flushAll skips every gap.
+func (s *Stream) flushAll() {
+	for !s.done && s.skipGap() {
+		s.checkFIN()
+	}
+}
+
+func (s *Stream) checkFIN() {
+	if s.fin && int32(s.next-s.finSeq) >= 0 {
+		s.close(io.EOF)
+	}
+}
+
+// deliver hands in-order data to the reader.
+func (s *Stream) deliver(data []byte) {
+	s.next += uint32(len(data))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err == nil && len(s.buf)-s.off+len(data) > s.max {
+		s.err = ErrBufferFull
+		s.cond.Broadcast()
+	}
+	if s.err != nil {
+		// Nothing more will be read, so stop buffering.
+		s.done, s.pending, s.pendingBytes = true, nil, 0
+		return
+	}
+	if s.off > 0 && s.off >= len(s.buf)/2 {
+		s.buf = s.buf[:copy(s.buf, s.buf[s.off:])]
+		s.off = 0
+	}
+	s.buf = append(s.buf, data...)
+	s.written += int64(len(data))
+	s.cond.Broadcast()
+}
+
+// close ends the stream with err once its data has been read.
+func (s *Stream) close(err error) {
+	s.done, s.pending, s.pendingBytes = true, nil, 0
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err == nil {
+		s.err = err
+	}
+	s.cond.Broadcast()
+}
@@ -0,0 +1,136 @@
+package resp
+
+import (
+	"io"
+
+	"github.com/nemohan/wireread"
+)
+
+// frame is an aggregate whose nested values are still being decoded.
+type frame struct {
+	val       Value
+	remaining int
+	key       *Value // pending map key awaiting its value
+	attrs     []Pair // attribute to attach to the next nested value
+}
+
+// Decoder incrementally decodes a stream of RESP values. Data is supplied
+// with Feed in arbitrary pieces; Next returns ErrNeedMore until a complete
+// value has been buffered. Fully decoded nested values and aggregate headers
+// are kept across calls, so only the incomplete trailing item is re-read.
+type Decoder struct {
+	buf    []byte
+	off    int
+	stack  []frame
+	attrs  []Pair
+	limits Limits
+}
+
+// NewDecoder creates a Decoder using the default size limits.
+func NewDecoder() *Decoder {
+	return &Decoder{limits: defaultLimits}
+}
+
+// SetLimits replaces the size limits applied to subsequently decoded items.
+func (d *Decoder) SetLimits(lim Limits) {
+	d.limits = lim
+}
+
+// Feed appends data to the decoder's buffer. The data is copied.
+func (d *Decoder) Feed(data []byte) {
+	if d.off > 0 && d.off == len(d.buf) {
+		d.buf = d.buf[:0]
+		d.off = 0
+	} else if d.off > cap(d.buf)/2 {
+		n := copy(d.buf, d.buf[d.off:])
+		d.buf = d.buf[:n]
+		d.off = 0
+	}
+	d.buf = append(d.buf, data...)
+}
+
+// Buffered returns the number of bytes fed but not yet consumed.
+func (d *Decoder) Buffered() int {
+	return len(d.buf) - d.off
+}
+
+// Depth returns the nesting depth of the aggregate currently being decoded,
+// or zero when the decoder is between values.
+func (d *Decoder) Depth() int {
+	return len(d.stack)
+}
+
+// Reset discards all buffered data and partially decoded state.
+func (d *Decoder) Reset() {
+	d.buf = d.buf[:0]
+	d.off = 0
+	d.stack = d.stack[:0]
+	d.attrs = nil
+}
+
+// Next returns the next complete value. It returns ErrNeedMore when more data
+// must be fed first; any other error leaves the decoder in an undefined state
+// and it should be Reset before further use.
+func (d *Decoder) Next() (Value, error) {
+	for {
+		r := wireread.NewSafeReader(d.buf[d.off:])
+		v, n, err := readItem(r, &d.limits)
+		if err == io.ErrUnexpectedEOF {
+			return Value{}, ErrNeedMore
+		}
+		if err != nil {
+			return Value{}, err
+		}
+		d.off = len(d.buf) - len(r.Bytes())
+
+		if n > 0 {
+			d.stack = append(d.stack, frame{val: v, remaining: n})
+			continue
+		}
+		if v, ok := d.complete(v); ok {
+			return v, nil
+		}
+	}
+}
+
+// complete attaches a finished value to its parent aggregate, unwinding
+// every aggregate that becomes complete as a result. It reports true when a
+// top-level value is finished.
+func (d *Decoder) complete(v Value) (Value, bool) {
+	for {
+		if len(d.stack) == 0 {
+			if v.Kind == Attribute {
+				d.attrs = v.Pairs
+				return Value{}, false
+			}
+			v.Attrs, d.attrs = d.attrs, nil
+			return v, true
+		}
+
+		top := &d.stack[len(d.stack)-1]
+		if v.Kind == Attribute {
+			// Attributes are not counted as elements of the enclosing aggregate.
+			top.attrs = v.Pairs
+			return Value{}, false
+		}
+		v.Attrs, top.attrs = top.attrs, nil
+
+		if top.val.Kind == Map || top.val.Kind == Attribute {
+			if top.key == nil {
+				key := v
+				top.key = &key
+			} else {
+				top.val.Pairs = append(top.val.Pairs, Pair{Key: *top.key, Value: v})
+				top.key = nil
+			}
+		} else {
+			top.val.Elems = append(top.val.Elems, v)
+		}
+		top.remaining--
+		if top.remaining > 0 {
+			return Value{}, false
+		}
+		v = top.val
+		d.stack = d.stack[:len(d.stack)-1]
+	}
+}
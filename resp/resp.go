@@ -0,0 +1,323 @@
+// Package resp decodes the Redis serialization protocol (RESP2 and RESP3).
+//
+// Two APIs are provided on top of the same parser:
+//   - Parse decodes a single value from a complete buffer
+//   - Decoder accepts data in arbitrary pieces and reports ErrNeedMore until a
+//     full value is available, keeping already decoded elements of partially
+//     received aggregates so that no work is repeated
+//
+// Example usage:
+//
+//	v, n, err := resp.Parse([]byte("*2\r\n$3\r\nGET\r\n$3\r\nkey\r\n"))
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	fmt.Println(v.Elems[0].Str, n) // GET 22
+package resp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+
+	"github.com/nemohan/wireread"
+)
+
+// Kind identifies a RESP type by its leading type byte.
+type Kind byte
+
+// RESP2 types.
+const (
+	SimpleString Kind = '+'
+	Error        Kind = '-'
+	Integer      Kind = ':'
+	BulkString   Kind = '$'
+	Array        Kind = '*'
+)
+
+// RESP3 types.
+const (
+	Null           Kind = '_'
+	Double         Kind = ','
+	Boolean        Kind = '#'
+	BlobError      Kind = '!'
+	VerbatimString Kind = '='
+	BigNumber      Kind = '('
+	Map            Kind = '%'
+	Set            Kind = '~'
+	Attribute      Kind = '|'
+	Push           Kind = '>'
+)
+
+// String returns the name of the kind.
+func (k Kind) String() string {
+	switch k {
+	case SimpleString:
+		return "simple-string"
+	case Error:
+		return "error"
+	case Integer:
+		return "integer"
+	case BulkString:
+		return "bulk-string"
+	case Array:
+		return "array"
+	case Null:
+		return "null"
+	case Double:
+		return "double"
+	case Boolean:
+		return "boolean"
+	case BlobError:
+		return "blob-error"
+	case VerbatimString:
+		return "verbatim-string"
+	case BigNumber:
+		return "big-number"
+	case Map:
+		return "map"
+	case Set:
+		return "set"
+	case Attribute:
+		return "attribute"
+	case Push:
+		return "push"
+	}
+	return fmt.Sprintf("kind(%q)", byte(k))
+}
+
+// IsAggregate reports whether values of kind k contain nested values.
+func (k Kind) IsAggregate() bool {
+	switch k {
+	case Array, Map, Set, Attribute, Push:
+		return true
+	}
+	return false
+}
+
+// Pair is a single key/value entry of a Map or Attribute value.
+type Pair struct {
+	Key   Value
+	Value Value
+}
+
+// Value is a decoded RESP value. Only the fields relevant to Kind are set.
+type Value struct {
+	Kind Kind
+
+	// Null is set for RESP3 nulls and for RESP2 null bulk strings ($-1) and
+	// null arrays (*-1), in which case Kind keeps the original type.
+	Null bool
+
+	// Str holds simple strings, errors, bulk strings, blob errors and the
+	// text of verbatim strings.
+	Str string
+	// Format is the three-letter encoding of a verbatim string (e.g. "txt").
+	Format string
+
+	Int   int64
+	Float float64
+	Bool  bool
+	Big   *big.Int
+
+	// Elems holds the members of arrays, sets and pushes.
+	Elems []Value
+	// Pairs holds the entries of maps and attributes.
+	Pairs []Pair
+
+	// Attrs holds the RESP3 attribute map that preceded this value, if any.
+	Attrs []Pair
+}
+
+var (
+	// ErrNeedMore is returned by Decoder.Next when the buffered data does not
+	// yet contain a complete value.
+	ErrNeedMore = errors.New("resp: need more data")
+
+	// ErrProtocol is wrapped by all errors caused by malformed input.
+	ErrProtocol = errors.New("resp: protocol error")
+)
+
+// DefaultMaxBulkLen is the default upper bound for bulk string payloads,
+// matching the proto-max-bulk-len default of the Redis server.
+const DefaultMaxBulkLen = 512 << 20
+
+// DefaultMaxElems is the default upper bound for the element count announced
+// by an aggregate header.
+const DefaultMaxElems = 1 << 24
+
+// Limits bounds the sizes a parser accepts from untrusted input.
+type Limits struct {
+	MaxBulkLen int64
+	MaxElems   int64
+}
+
+var defaultLimits = Limits{MaxBulkLen: DefaultMaxBulkLen, MaxElems: DefaultMaxElems}
+
+func protocolError(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrProtocol, fmt.Sprintf(format, args...))
+}
+
+// Parse decodes a single value from the beginning of data and returns it
+// together with the number of bytes consumed. It returns io.ErrUnexpectedEOF
+// when data holds only a prefix of a value.
+func Parse(data []byte) (Value, int, error) {
+	d := Decoder{buf: data, limits: defaultLimits}
+	v, err := d.Next()
+	if err == ErrNeedMore {
+		return Value{}, 0, io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return Value{}, 0, err
+	}
+	return v, d.off, nil
+}
+
+// ParseAll decodes every value contained in data, as found in a pipelined
+// request or an AOF file.
+func ParseAll(data []byte) ([]Value, error) {
+	var values []Value
+	for len(data) > 0 {
+		v, n, err := Parse(data)
+		if err != nil {
+			return values, err
+		}
+		values = append(values, v)
+		data = data[n:]
+	}
+	return values, nil
+}
+
+// readItem reads one scalar value or aggregate header. For aggregates it
+// returns the number of nested values that follow (twice the entry count for
+// maps and attributes); for scalars and null aggregates the count is zero.
+// On io.ErrUnexpectedEOF the caller must discard any progress made on r.
+func readItem(r *wireread.SafeReader, lim *Limits) (Value, int, error) {
+	t, err := r.ReadByte()
+	if err != nil {
+		return Value{}, 0, err
+	}
+	v := Value{Kind: Kind(t)}
+	switch v.Kind {
+	case SimpleString, Error:
+		v.Str, err = r.ReadLine()
+		return v, 0, err
+
+	case Integer:
+		v.Int, err = readInt(r)
+		return v, 0, err
+
+	case BulkString, BlobError, VerbatimString:
+		n, err := readInt(r)
+		if err != nil {
+			return v, 0, err
+		}
+		if n == -1 && v.Kind == BulkString {
+			v.Null = true
+			return v, 0, nil
+		}
+		if n < 0 || n > lim.MaxBulkLen {
+			return v, 0, protocolError("invalid %s length %d", v.Kind, n)
+		}
+		if v.Str, err = r.ReadString(int(n)); err != nil {
+			return v, 0, err
+		}
+		if err = readCRLF(r); err != nil {
+			return v, 0, err
+		}
+		if v.Kind == VerbatimString {
+			if len(v.Str) < 4 || v.Str[3] != ':' {
+				return v, 0, protocolError("malformed verbatim string")
+			}
+			v.Format, v.Str = v.Str[:3], v.Str[4:]
+		}
+		return v, 0, nil
+
+	case Null:
+		v.Null = true
+		return v, 0, readCRLF(r)
+
+	case Double:
+		line, err := r.ReadLine()
+		if err != nil {
+			return v, 0, err
+		}
+		if v.Float, err = strconv.ParseFloat(line, 64); err != nil {
+			return v, 0, protocolError("invalid double %q", line)
+		}
+		return v, 0, nil
+
+	case Boolean:
+		line, err := r.ReadLine()
+		if err != nil {
+			return v, 0, err
+		}
+		switch line {
+		case "t":
+			v.Bool = true
+		case "f":
+		default:
+			return v, 0, protocolError("invalid boolean %q", line)
+		}
+		return v, 0, nil
+
+	case BigNumber:
+		line, err := r.ReadLine()
+		if err != nil {
+			return v, 0, err
+		}
+		var ok bool
+		if v.Big, ok = new(big.Int).SetString(line, 10); !ok {
+			return v, 0, protocolError("invalid big number %q", line)
+		}
+		return v, 0, nil
+
+	case Array, Set, Push, Map, Attribute:
+		n, err := readInt(r)
+		if err != nil {
+			return v, 0, err
+		}
+		if n == -1 && v.Kind == Array {
+			v.Null = true
+			return v, 0, nil
+		}
+		if n < 0 || n > lim.MaxElems {
+			return v, 0, protocolError("invalid %s length %d", v.Kind, n)
+		}
+		// Announced lengths are untrusted, so preallocation is capped.
+		hint := n
+		if hint > 64 {
+			hint = 64
+		}
+		if v.Kind == Map || v.Kind == Attribute {
+			v.Pairs = make([]Pair, 0, hint)
+			return v, int(n) * 2, nil
+		}
+		v.Elems = make([]Value, 0, hint)
+		return v, int(n), nil
+	}
+	return v, 0, protocolError("unknown type byte %q", t)
+}
+
+// readInt reads a CRLF-terminated decimal integer, translating malformed
+// input into a protocol error.
+func readInt(r *wireread.SafeReader) (int64, error) {
+	n, err := r.ReadASCIIInt()
+	if err == wireread.ErrInvalidASCIIInt {
+		return 0, protocolError("invalid integer")
+	}
+	return n, err
+}
+
+func readCRLF(r *wireread.SafeReader) error {
+	b := r.Bytes()
+	if len(b) < 2 {
+		return io.ErrUnexpectedEOF
+	}
+	if b[0] != '\r' || b[1] != '\n' {
+		return protocolError("missing CRLF terminator")
+	}
+	return r.Skip(2)
+}
@@ -0,0 +1,183 @@
+package resp
+
+import (
+	"errors"
+	"io"
+	"math"
+	"testing"
+)
+
+func TestParse_Scalars(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  string
+		check func(Value) bool
+	}{
+		{"simple string", "+OK\r\n", func(v Value) bool { return v.Kind == SimpleString && v.Str == "OK" }},
+		{"error", "-ERR unknown\r\n", func(v Value) bool { return v.Kind == Error && v.Str == "ERR unknown" }},
+		{"integer", ":-42\r\n", func(v Value) bool { return v.Kind == Integer && v.Int == -42 }},
+		{"bulk string", "$5\r\nhello\r\n", func(v Value) bool { return v.Kind == BulkString && v.Str == "hello" }},
+		{"empty bulk", "$0\r\n\r\n", func(v Value) bool { return v.Kind == BulkString && v.Str == "" && !v.Null }},
+		{"null bulk", "$-1\r\n", func(v Value) bool { return v.Kind == BulkString && v.Null }},
+		{"null array", "*-1\r\n", func(v Value) bool { return v.Kind == Array && v.Null }},
+		{"null", "_\r\n", func(v Value) bool { return v.Kind == Null && v.Null }},
+		{"double", ",3.25\r\n", func(v Value) bool { return v.Kind == Double && v.Float == 3.25 }},
+		{"double inf", ",-inf\r\n", func(v Value) bool { return math.IsInf(v.Float, -1) }},
+		{"boolean", "#t\r\n", func(v Value) bool { return v.Kind == Boolean && v.Bool }},
+		{"blob error", "!10\r\nSYNTAX bad\r\n", func(v Value) bool { return v.Kind == BlobError && v.Str == "SYNTAX bad" }},
+		{"verbatim", "=15\r\ntxt:Some string\r\n", func(v Value) bool {
+			return v.Kind == VerbatimString && v.Format == "txt" && v.Str == "Some string"
+		}},
+		{"big number", "(3492890328409238509324850943850943825024385\r\n", func(v Value) bool {
+			return v.Kind == BigNumber && v.Big.String() == "3492890328409238509324850943850943825024385"
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, n, err := Parse([]byte(tt.data))
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if n != len(tt.data) {
+				t.Errorf("Parse() consumed %d bytes, want %d", n, len(tt.data))
+			}
+			if !tt.check(v) {
+				t.Errorf("Parse() = %+v", v)
+			}
+		})
+	}
+}
+
+func TestParse_Aggregates(t *testing.T) {
+	data := "*3\r\n$3\r\nSET\r\n%1\r\n+k\r\n:1\r\n~2\r\n#f\r\n_\r\n"
+	v, _, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if v.Kind != Array || len(v.Elems) != 3 {
+		t.Fatalf("Parse() = %+v, want 3-element array", v)
+	}
+	if m := v.Elems[1]; m.Kind != Map || len(m.Pairs) != 1 || m.Pairs[0].Key.Str != "k" || m.Pairs[0].Value.Int != 1 {
+		t.Errorf("map element = %+v", m)
+	}
+	if s := v.Elems[2]; s.Kind != Set || len(s.Elems) != 2 || s.Elems[1].Kind != Null {
+		t.Errorf("set element = %+v", s)
+	}
+}
+
+func TestParse_Attributes(t *testing.T) {
+	data := "|1\r\n+ttl\r\n:3600\r\n*2\r\n:1\r\n|1\r\n+hint\r\n#t\r\n:2\r\n"
+	v, _, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(v.Attrs) != 1 || v.Attrs[0].Key.Str != "ttl" || v.Attrs[0].Value.Int != 3600 {
+		t.Errorf("top-level Attrs = %+v", v.Attrs)
+	}
+	if len(v.Elems) != 2 {
+		t.Fatalf("Elems = %+v, want 2 elements", v.Elems)
+	}
+	if len(v.Elems[1].Attrs) != 1 || v.Elems[1].Int != 2 {
+		t.Errorf("nested attribute not attached: %+v", v.Elems[1])
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want error
+	}{
+		{"truncated bulk", "$5\r\nhel", io.ErrUnexpectedEOF},
+		{"truncated array", "*2\r\n:1\r\n", io.ErrUnexpectedEOF},
+		{"unknown type", "?\r\n", ErrProtocol},
+		{"bad integer", ":12x\r\n", ErrProtocol},
+		{"bad bulk terminator", "$2\r\nhixx", ErrProtocol},
+		{"negative length", "$-2\r\n", ErrProtocol},
+		{"bad boolean", "#x\r\n", ErrProtocol},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := Parse([]byte(tt.data))
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Parse() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAll(t *testing.T) {
+	values, err := ParseAll([]byte("+PONG\r\n:1\r\n$1\r\nx\r\n"))
+	if err != nil {
+		t.Fatalf("ParseAll() error = %v", err)
+	}
+	if len(values) != 3 || values[2].Str != "x" {
+		t.Errorf("ParseAll() = %+v", values)
+	}
+}
+
+func TestDecoder_ByteAtATime(t *testing.T) {
+	data := "*2\r\n$3\r\nfoo\r\n%1\r\n+a\r\n,1.5\r\n>1\r\n+msg\r\n"
+	d := NewDecoder()
+
+	var got []Value
+	for i := 0; i < len(data); i++ {
+		d.Feed([]byte{data[i]})
+		for {
+			v, err := d.Next()
+			if err == ErrNeedMore {
+				break
+			}
+			if err != nil {
+				t.Fatalf("Next() error = %v", err)
+			}
+			got = append(got, v)
+		}
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("decoded %d values, want 2", len(got))
+	}
+	if got[0].Elems[0].Str != "foo" || got[0].Elems[1].Pairs[0].Value.Float != 1.5 {
+		t.Errorf("first value = %+v", got[0])
+	}
+	if got[1].Kind != Push || got[1].Elems[0].Str != "msg" {
+		t.Errorf("second value = %+v", got[1])
+	}
+	if d.Buffered() != 0 || d.Depth() != 0 {
+		t.Errorf("Buffered() = %d, Depth() = %d, want 0, 0", d.Buffered(), d.Depth())
+	}
+}
+
+func TestDecoder_KeepsPartialAggregate(t *testing.T) {
+	d := NewDecoder()
+	d.Feed([]byte("*2\r\n:1\r\n:"))
+
+	if _, err := d.Next(); err != ErrNeedMore {
+		t.Fatalf("Next() error = %v, want ErrNeedMore", err)
+	}
+	if d.Depth() != 1 || d.Buffered() != 1 {
+		t.Errorf("Depth() = %d, Buffered() = %d, want 1, 1", d.Depth(), d.Buffered())
+	}
+
+	d.Feed([]byte("2\r\n"))
+	v, err := d.Next()
+	if err != nil {
+		t.Fatalf("Next() error = %v", err)
+	}
+	if len(v.Elems) != 2 || v.Elems[1].Int != 2 {
+		t.Errorf("Next() = %+v", v)
+	}
+}
+
+func TestDecoder_Limits(t *testing.T) {
+	d := NewDecoder()
+	d.SetLimits(Limits{MaxBulkLen: 4, MaxElems: 4})
+	d.Feed([]byte("$5\r\nhello\r\n"))
+
+	if _, err := d.Next(); !errors.Is(err, ErrProtocol) {
+		t.Errorf("Next() error = %v, want ErrProtocol", err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"io"
+	"unicode/utf8"
 )
 
 // SafeReader is a safe implementation of Reader with complete boundary checking.
@@ -24,6 +25,14 @@ func NewSafeReader(data []byte) *SafeReader {
 	}
 }
 
+// Reset makes sr read from data, so that one SafeReader can be reused for
+// many frames instead of allocating a new one for each.
+func (sr *SafeReader) Reset(data []byte) {
+	sr.data = data
+	sr.size = len(data)
+	sr.rpos = 0
+}
+
 // Bytes returns the remaining unparsed bytes from the current read position
 func (sr *SafeReader) Bytes() []byte {
 	return sr.data[sr.rpos:]
@@ -39,6 +48,20 @@ func (sr *SafeReader) ReadBytes(n int) ([]byte, error) {
 	return dest, nil
 }
 
+// ReadSlice reads n bytes without copying. The result aliases the underlying
+// data and its capacity is n, so appending to it cannot overwrite the bytes
+// that follow
+func (sr *SafeReader) ReadSlice(n int) ([]byte, error) {
+	if n < 0 {
+		return nil, ErrInvalidOffset
+	}
+	if n > sr.size-sr.rpos {
+		return nil, io.ErrUnexpectedEOF
+	}
+	sr.rpos += n
+	return sr.data[sr.rpos-n : sr.rpos : sr.rpos], nil
+}
+
 func (sr *SafeReader) ReadByte() (byte, error) {
 	if sr.rpos+1 > sr.size {
 		return 0, io.ErrUnexpectedEOF
@@ -145,6 +168,25 @@ func (sr *SafeReader) ReadLine() (string, error) {
 	return string(sr.data[begin:end]), nil
 }
 
+// ReadASCIIInt reads a signed base-10 integer terminated by \n (handles \r\n).
+// The cursor is left untouched when the line is incomplete or malformed.
+func (sr *SafeReader) ReadASCIIInt() (int64, error) {
+	idx := bytes.IndexByte(sr.data[sr.rpos:], '\n')
+	if idx < 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	line := sr.data[sr.rpos : sr.rpos+idx]
+	if idx > 0 && line[idx-1] == '\r' {
+		line = line[:idx-1]
+	}
+	val, ok := parseASCIIInt(line)
+	if !ok {
+		return 0, ErrInvalidASCIIInt
+	}
+	sr.rpos += idx + 1
+	return val, nil
+}
+
 // ReadUint16BE reads a 16-bit unsigned integer in big-endian byte order
 func (sr *SafeReader) ReadUint16BE() (uint16, error) {
 	if sr.rpos+2 > sr.size {
@@ -284,3 +326,175 @@ func (sr *SafeReader) ReadUint64LEInto(out *uint64) error {
 	*out = tmp
 	return nil
 }
+
+// checkAt validates that n bytes are available at offset off
+func (sr *SafeReader) checkAt(off, n int) error {
+	if off < 0 || n < 0 {
+		return ErrInvalidOffset
+	}
+	if off > sr.size-n {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
+// ReadAt implements io.ReaderAt. It copies from the data at offset off without
+// moving the read position, returning io.EOF if fewer than len(p) bytes remain
+func (sr *SafeReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, ErrInvalidOffset
+	}
+	if off >= int64(sr.size) {
+		return 0, io.EOF
+	}
+	n := copy(p, sr.data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// ReadByteAt reads the byte at offset off without moving the read position
+func (sr *SafeReader) ReadByteAt(off int) (byte, error) {
+	if err := sr.checkAt(off, 1); err != nil {
+		return 0, err
+	}
+	return sr.data[off], nil
+}
+
+// ReadBytesAt returns the n bytes at offset off without moving the read position.
+// Unlike ReadBytes, the result aliases the underlying data
+func (sr *SafeReader) ReadBytesAt(off, n int) ([]byte, error) {
+	if err := sr.checkAt(off, n); err != nil {
+		return nil, err
+	}
+	return sr.data[off : off+n : off+n], nil
+}
+
+// ReadUint16BEAt reads a 16-bit unsigned integer in big-endian byte order at offset off
+func (sr *SafeReader) ReadUint16BEAt(off int) (uint16, error) {
+	if err := sr.checkAt(off, 2); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint16(sr.data[off:]), nil
+}
+
+// ReadUint16LEAt reads a 16-bit unsigned integer in little-endian byte order at offset off
+func (sr *SafeReader) ReadUint16LEAt(off int) (uint16, error) {
+	if err := sr.checkAt(off, 2); err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint16(sr.data[off:]), nil
+}
+
+// ReadUint32BEAt reads a 32-bit unsigned integer in big-endian byte order at offset off
+func (sr *SafeReader) ReadUint32BEAt(off int) (uint32, error) {
+	if err := sr.checkAt(off, 4); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint32(sr.data[off:]), nil
+}
+
+// ReadUint32LEAt reads a 32-bit unsigned integer in little-endian byte order at offset off
+func (sr *SafeReader) ReadUint32LEAt(off int) (uint32, error) {
+	if err := sr.checkAt(off, 4); err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(sr.data[off:]), nil
+}
+
+// ReadUint64BEAt reads a 64-bit unsigned integer in big-endian byte order at offset off
+func (sr *SafeReader) ReadUint64BEAt(off int) (uint64, error) {
+	if err := sr.checkAt(off, 8); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(sr.data[off:]), nil
+}
+
+// ReadUint64LEAt reads a 64-bit unsigned integer in little-endian byte order at offset off
+func (sr *SafeReader) ReadUint64LEAt(off int) (uint64, error) {
+	if err := sr.checkAt(off, 8); err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(sr.data[off:]), nil
+}
+
+// Read implements io.Reader, copying from the current read position. It
+// returns io.EOF once the data is exhausted
+func (sr *SafeReader) Read(p []byte) (int, error) {
+	if sr.rpos >= sr.size {
+		if len(p) == 0 {
+			return 0, nil
+		}
+		return 0, io.EOF
+	}
+	n := copy(p, sr.data[sr.rpos:])
+	sr.rpos += n
+	return n, nil
+}
+
+// UnreadByte implements io.ByteScanner, moving the read position back one byte
+func (sr *SafeReader) UnreadByte() error {
+	if sr.rpos <= 0 {
+		return ErrUnreadByte
+	}
+	sr.rpos--
+	return nil
+}
+
+// ReadRune implements io.RuneReader, decoding one UTF-8 encoded rune. Invalid
+// encodings are returned as utf8.RuneError of size 1
+func (sr *SafeReader) ReadRune() (rune, int, error) {
+	if sr.rpos >= sr.size {
+		return 0, 0, io.EOF
+	}
+	if c := sr.data[sr.rpos]; c < utf8.RuneSelf {
+		sr.rpos++
+		return rune(c), 1, nil
+	}
+	r, size := utf8.DecodeRune(sr.data[sr.rpos:])
+	sr.rpos += size
+	return r, size, nil
+}
+
+// Seek implements io.Seeker. Seeking before the start returns ErrInvalidOffset
+// and past the end io.ErrUnexpectedEOF, leaving the read position unchanged
+func (sr *SafeReader) Seek(offset int64, whence int) (int64, error) {
+	var abs int64
+	switch whence {
+	case io.SeekStart:
+		abs = offset
+	case io.SeekCurrent:
+		abs = int64(sr.rpos) + offset
+	case io.SeekEnd:
+		abs = int64(sr.size) + offset
+	default:
+		return 0, ErrInvalidWhence
+	}
+	if abs < 0 {
+		return 0, ErrInvalidOffset
+	}
+	if abs > int64(sr.size) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	sr.rpos = int(abs)
+	return abs, nil
+}
+
+// WriteTo implements io.WriterTo, writing the remaining bytes to w and
+// advancing the read position past those written
+func (sr *SafeReader) WriteTo(w io.Writer) (int64, error) {
+	b := sr.data[sr.rpos:]
+	if len(b) == 0 {
+		return 0, nil
+	}
+	n, err := w.Write(b)
+	if n < 0 || n > len(b) {
+		return 0, ErrInvalidWrite
+	}
+	sr.rpos += n
+	if err == nil && n != len(b) {
+		err = io.ErrShortWrite
+	}
+	return int64(n), err
+}
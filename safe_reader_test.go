@@ -1,6 +1,7 @@
 package wireread
 
 import (
+	"bytes"
 	"io"
 	"testing"
 )
@@ -59,6 +60,32 @@ func TestSafeReader_ReadBytes(t *testing.T) {
 	}
 }
 
+func TestSafeReader_ReadSlice(t *testing.T) {
+	data := []byte{1, 2, 3, 4, 5}
+	r := NewSafeReader(data)
+	got, err := r.ReadSlice(2)
+	if err != nil || !bytesEqual(got, []byte{1, 2}) || &got[0] != &data[0] {
+		t.Fatalf("ReadSlice(2) = %v, %v; want [1 2] aliasing the input", got, err)
+	}
+	if cap(got) != 2 {
+		t.Errorf("ReadSlice(2) cap = %d, want 2", cap(got))
+	}
+	_ = append(got, 0xEE)
+	if b, _ := r.ReadByte(); b != 3 {
+		t.Errorf("ReadByte() after appending to ReadSlice() = %d, want 3", b)
+	}
+
+	if _, err := r.ReadSlice(3); err != io.ErrUnexpectedEOF {
+		t.Errorf("ReadSlice(3) error = %v, want io.ErrUnexpectedEOF", err)
+	}
+	if _, err := r.ReadSlice(-1); err != ErrInvalidOffset {
+		t.Errorf("ReadSlice(-1) error = %v, want ErrInvalidOffset", err)
+	}
+	if got, err := r.ReadSlice(2); err != nil || !bytesEqual(got, []byte{4, 5}) {
+		t.Errorf("ReadSlice(2) = %v, %v; want [4 5], nil", got, err)
+	}
+}
+
 func TestSafeReader_ReadUint16BE(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -276,6 +303,41 @@ func TestSafeReader_ReadLine(t *testing.T) {
 	}
 }
 
+func TestSafeReader_ReadASCIIInt(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		want    int64
+		wantErr error
+	}{
+		{"positive", []byte("1234\r\n"), 1234, nil},
+		{"negative", []byte("-1\r\n"), -1, nil},
+		{"explicit plus", []byte("+7\n"), 7, nil},
+		{"min int64", []byte("-9223372036854775808\r\n"), -9223372036854775808, nil},
+		{"overflow", []byte("9223372036854775808\r\n"), 0, ErrInvalidASCIIInt},
+		{"not a number", []byte("12a\r\n"), 0, ErrInvalidASCIIInt},
+		{"empty line", []byte("\r\n"), 0, ErrInvalidASCIIInt},
+		{"no newline", []byte("42"), 0, io.ErrUnexpectedEOF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewSafeReader(tt.data)
+			got, err := r.ReadASCIIInt()
+			if err != tt.wantErr {
+				t.Errorf("ReadASCIIInt() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ReadASCIIInt() = %d, want %d", got, tt.want)
+			}
+			if err != nil && len(r.Bytes()) != len(tt.data) {
+				t.Errorf("ReadASCIIInt() consumed input on error")
+			}
+		})
+	}
+}
+
 func TestSafeReader_ReadLengthEncodedInteger(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -382,7 +444,230 @@ func TestSafeReader_Into_Methods(t *testing.T) {
 	}
 }
 
+func TestSafeReader_ReadAtMethods(t *testing.T) {
+	data := []byte{0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}
+	r := NewSafeReader(data)
+	_ = r.Skip(3)
+
+	if got, err := r.ReadUint16BEAt(1); err != nil || got != 0x0102 {
+		t.Errorf("ReadUint16BEAt(1) = 0x%04x, %v; want 0x0102, nil", got, err)
+	}
+	if got, err := r.ReadUint16LEAt(1); err != nil || got != 0x0201 {
+		t.Errorf("ReadUint16LEAt(1) = 0x%04x, %v; want 0x0201, nil", got, err)
+	}
+	if got, err := r.ReadUint32BEAt(5); err != nil || got != 0x05060708 {
+		t.Errorf("ReadUint32BEAt(5) = 0x%08x, %v; want 0x05060708, nil", got, err)
+	}
+	if got, err := r.ReadUint32LEAt(0); err != nil || got != 0x03020100 {
+		t.Errorf("ReadUint32LEAt(0) = 0x%08x, %v; want 0x03020100, nil", got, err)
+	}
+	if got, err := r.ReadUint64BEAt(1); err != nil || got != 0x0102030405060708 {
+		t.Errorf("ReadUint64BEAt(1) = 0x%016x, %v; want 0x0102030405060708, nil", got, err)
+	}
+	if got, err := r.ReadUint64LEAt(0); err != nil || got != 0x0706050403020100 {
+		t.Errorf("ReadUint64LEAt(0) = 0x%016x, %v; want 0x0706050403020100, nil", got, err)
+	}
+	if got, err := r.ReadByteAt(1); err != nil || got != 0x01 {
+		t.Errorf("ReadByteAt(1) = 0x%02x, %v; want 0x01, nil", got, err)
+	}
+	if got, err := r.ReadBytesAt(7, 2); err != nil || !bytesEqual(got, []byte{7, 8}) {
+		t.Errorf("ReadBytesAt(7, 2) = %v, %v; want [7 8], nil", got, err)
+	}
+
+	// The read position is unaffected.
+	if got, _ := r.ReadByte(); got != 0x03 {
+		t.Errorf("ReadByte() after random access = %v, want 3", got)
+	}
+}
+
+func TestSafeReader_ReadAtBounds(t *testing.T) {
+	r := NewSafeReader([]byte{1, 2, 3, 4})
+	tests := []struct {
+		name    string
+		off, n  int
+		wantErr error
+	}{
+		{"whole data", 0, 4, nil},
+		{"empty at end", 4, 0, nil},
+		{"past end", 2, 3, io.ErrUnexpectedEOF},
+		{"offset past end", 5, 0, io.ErrUnexpectedEOF},
+		{"negative offset", -1, 1, ErrInvalidOffset},
+		{"negative length", 0, -1, ErrInvalidOffset},
+		{"overflow", 1, maxInt, io.ErrUnexpectedEOF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := r.ReadBytesAt(tt.off, tt.n); err != tt.wantErr {
+				t.Errorf("ReadBytesAt(%d, %d) error = %v, want %v", tt.off, tt.n, err, tt.wantErr)
+			}
+		})
+	}
+	if _, err := r.ReadUint32BEAt(1); err != io.ErrUnexpectedEOF {
+		t.Errorf("ReadUint32BEAt(1) error = %v, want io.ErrUnexpectedEOF", err)
+	}
+	if _, err := r.ReadByteAt(4); err != io.ErrUnexpectedEOF {
+		t.Errorf("ReadByteAt(4) error = %v, want io.ErrUnexpectedEOF", err)
+	}
+	if _, err := r.ReadUint16LEAt(-2); err != ErrInvalidOffset {
+		t.Errorf("ReadUint16LEAt(-2) error = %v, want ErrInvalidOffset", err)
+	}
+}
+
+func TestSafeReader_ReadAt(t *testing.T) {
+	var _ io.ReaderAt = (*SafeReader)(nil)
+
+	r := NewSafeReader([]byte("hello"))
+	tests := []struct {
+		name    string
+		size    int
+		off     int64
+		want    string
+		wantErr error
+	}{
+		{"inside", 3, 1, "ell", nil},
+		{"to end", 2, 3, "lo", nil},
+		{"short", 4, 3, "lo", io.EOF},
+		{"at end", 1, 5, "", io.EOF},
+		{"negative", 1, -1, "", ErrInvalidOffset},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := make([]byte, tt.size)
+			n, err := r.ReadAt(p, tt.off)
+			if err != tt.wantErr || string(p[:n]) != tt.want {
+				t.Errorf("ReadAt(%d, %d) = %q, %v; want %q, %v", tt.size, tt.off, p[:n], err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSafeReader_IOInterfaces(t *testing.T) {
+	data := []byte("\x00\x05héllo, world")
+	r := NewSafeReader(data)
+
+	if n, _ := r.ReadUint16BE(); n != 5 {
+		t.Fatalf("ReadUint16BE() = %d, want 5", n)
+	}
+	if c, size, err := r.ReadRune(); err != nil || c != 'h' || size != 1 {
+		t.Errorf("ReadRune() = %q, %d, %v; want 'h', 1, nil", c, size, err)
+	}
+	if c, size, err := r.ReadRune(); err != nil || c != 'é' || size != 2 {
+		t.Errorf("ReadRune() = %q, %d, %v; want 'é', 2, nil", c, size, err)
+	}
+	p := make([]byte, 3)
+	if n, err := io.ReadFull(r, p); err != nil || string(p[:n]) != "llo" {
+		t.Errorf("ReadFull() = %q, %v; want \"llo\", nil", p[:n], err)
+	}
+	if err := r.UnreadByte(); err != nil {
+		t.Errorf("UnreadByte() error = %v", err)
+	}
+	if b, _ := r.ReadByte(); b != 'o' {
+		t.Errorf("ReadByte() after UnreadByte() = %q, want 'o'", b)
+	}
+
+	if pos, err := r.Seek(-5, io.SeekEnd); err != nil || pos != int64(len(data)-5) {
+		t.Errorf("Seek(-5, io.SeekEnd) = %d, %v; want %d, nil", pos, err, len(data)-5)
+	}
+	var buf bytes.Buffer
+	if n, err := r.WriteTo(&buf); err != nil || n != 5 || buf.String() != "world" {
+		t.Errorf("WriteTo() = %d, %v, wrote %q; want 5, nil, \"world\"", n, err, buf.String())
+	}
+	if n, err := r.Read(p); n != 0 || err != io.EOF {
+		t.Errorf("Read() at end = %d, %v; want 0, io.EOF", n, err)
+	}
+	if _, _, err := r.ReadRune(); err != io.EOF {
+		t.Errorf("ReadRune() at end error = %v, want io.EOF", err)
+	}
+
+	if pos, err := r.Seek(2, io.SeekStart); err != nil || pos != 2 {
+		t.Errorf("Seek(2, io.SeekStart) = %d, %v; want 2, nil", pos, err)
+	}
+	if pos, err := r.Seek(-2, io.SeekCurrent); err != nil || pos != 0 {
+		t.Errorf("Seek(-2, io.SeekCurrent) = %d, %v; want 0, nil", pos, err)
+	}
+	if err := r.UnreadByte(); err != ErrUnreadByte {
+		t.Errorf("UnreadByte() at start error = %v, want ErrUnreadByte", err)
+	}
+	if _, err := r.Seek(-1, io.SeekStart); err != ErrInvalidOffset {
+		t.Errorf("Seek(-1, io.SeekStart) error = %v, want ErrInvalidOffset", err)
+	}
+	if _, err := r.Seek(0, 3); err != ErrInvalidWhence {
+		t.Errorf("Seek(0, 3) error = %v, want ErrInvalidWhence", err)
+	}
+	if _, err := r.Seek(1, io.SeekEnd); err != io.ErrUnexpectedEOF {
+		t.Errorf("Seek(1, io.SeekEnd) error = %v, want io.ErrUnexpectedEOF", err)
+	}
+	if b, _ := r.ReadByte(); b != 0 {
+		t.Errorf("failed Seek() moved the read position")
+	}
+}
+
+// countWriter accepts any write and reports n bytes written
+type countWriter struct{ n int }
+
+func (w countWriter) Write(p []byte) (int, error) { return w.n, nil }
+
+// Test that every reader handles short and invalid write counts alike
+func TestWriteTo_WriteCounts(t *testing.T) {
+	type reader interface {
+		io.WriterTo
+		Bytes() []byte
+	}
+	readers := map[string]func([]byte) reader{
+		"SafeReader":      func(b []byte) reader { return NewSafeReader(b) },
+		"FastReader":      func(b []byte) reader { return NewFastReader(b) },
+		"SegmentedReader": func(b []byte) reader { return NewSegmentedReader([][]byte{b}) },
+	}
+	tests := []struct {
+		n    int
+		want int64
+		err  error
+		left string
+	}{
+		{2, 2, io.ErrShortWrite, "llo"},
+		{-1, 0, ErrInvalidWrite, "hello"},
+		{6, 0, ErrInvalidWrite, "hello"},
+	}
+
+	for name, newReader := range readers {
+		for _, tt := range tests {
+			r := newReader([]byte("hello"))
+			n, err := r.WriteTo(countWriter{tt.n})
+			if n != tt.want || err != tt.err || string(r.Bytes()) != tt.left {
+				t.Errorf("%s: WriteTo(writer reporting %d) = %d, %v, left %q; want %d, %v, %q",
+					name, tt.n, n, err, r.Bytes(), tt.want, tt.err, tt.left)
+			}
+		}
+	}
+}
+
+func TestSafeReader_Reset(t *testing.T) {
+	r := NewSafeReader([]byte{0x01, 0x02, 0x03})
+	r.ReadUint16BE()
+
+	r.Reset([]byte{0x0A, 0x0B})
+	if got, _ := r.ReadUint16BE(); got != 0x0A0B {
+		t.Errorf("ReadUint16BE() after Reset() = 0x%04x, want 0x0a0b", got)
+	}
+	if len(r.Bytes()) != 0 {
+		t.Errorf("Bytes() after Reset() = %v, want empty", r.Bytes())
+	}
+
+	data := []byte{0x00, 0x01}
+	allocs := testing.AllocsPerRun(100, func() {
+		r.Reset(data)
+		r.ReadUint16BE()
+	})
+	if allocs != 0 {
+		t.Errorf("Reset() and read allocated %v times, want 0", allocs)
+	}
+}
+
 // Helper function
+const maxInt = int(^uint(0) >> 1)
+
 func bytesEqual(a, b []byte) bool {
 	if len(a) != len(b) {
 		return false
@@ -0,0 +1,652 @@
+package wireread
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"sort"
+	"unicode/utf8"
+)
+
+// SegmentedReader implements Reader over data held in several byte slices,
+// such as the chunks of a net.Buffers, without first copying them into one.
+// Fields that span a segment boundary are stitched together; fields within a
+// single segment are read in place. Like SafeReader it checks every read and
+// returns io.ErrUnexpectedEOF, leaving the read position unchanged, when the
+// data is insufficient.
+//
+// ReadBytes and ReadBytesAt return a slice of the segment when the bytes lie
+// within one, and a new slice otherwise.
+type SegmentedReader struct {
+	segs   [][]byte
+	starts []int // starts[i] is the offset of segs[i] from the start of the data
+	size   int
+
+	// seg is the index of the current segment, len(segs) at the end, and
+	// off the read position within it. off is always less than the length
+	// of the current segment.
+	seg int
+	off int
+}
+
+// NewSegmentedReader creates a new SegmentedReader over the concatenation of
+// segs. The segments are not copied and must not be modified while reading.
+func NewSegmentedReader(segs [][]byte) *SegmentedReader {
+	r := &SegmentedReader{
+		segs:   make([][]byte, 0, len(segs)),
+		starts: make([]int, 0, len(segs)),
+	}
+	r.Reset(segs)
+	return r
+}
+
+// Reset makes r read from segs, reusing its segment lists.
+func (r *SegmentedReader) Reset(segs [][]byte) {
+	clear(r.segs)
+	r.segs, r.starts = r.segs[:0], r.starts[:0]
+	r.size, r.seg, r.off = 0, 0, 0
+	for _, s := range segs {
+		if len(s) == 0 {
+			continue
+		}
+		r.segs = append(r.segs, s)
+		r.starts = append(r.starts, r.size)
+		r.size += len(s)
+	}
+}
+
+// pos returns the read position as an offset from the start of the data
+func (r *SegmentedReader) pos() int {
+	if r.seg == len(r.segs) {
+		return r.size
+	}
+	return r.starts[r.seg] + r.off
+}
+
+// cur returns the unread part of the current segment
+func (r *SegmentedReader) cur() []byte {
+	if r.seg == len(r.segs) {
+		return nil
+	}
+	return r.segs[r.seg][r.off:]
+}
+
+// advance moves the read position n bytes forward. The caller checks that
+// n bytes remain.
+func (r *SegmentedReader) advance(n int) {
+	for n > 0 {
+		left := len(r.segs[r.seg]) - r.off
+		if n < left {
+			r.off += n
+			return
+		}
+		n -= left
+		r.seg++
+		r.off = 0
+	}
+}
+
+// locate returns the segment and offset within it of the absolute offset abs,
+// which must be in [0, size].
+func (r *SegmentedReader) locate(abs int) (seg, off int) {
+	if abs == r.size {
+		return len(r.segs), 0
+	}
+	seg = sort.Search(len(r.starts), func(i int) bool { return r.starts[i] > abs }) - 1
+	return seg, abs - r.starts[seg]
+}
+
+// copyFrom copies into dst from offset off of segment seg onwards and returns
+// the number of bytes copied
+func (r *SegmentedReader) copyFrom(dst []byte, seg, off int) int {
+	n := 0
+	for ; n < len(dst) && seg < len(r.segs); seg, off = seg+1, 0 {
+		n += copy(dst[n:], r.segs[seg][off:])
+	}
+	return n
+}
+
+// slice returns the n bytes at offset off of segment seg. They alias the
+// segment if they lie within it and are otherwise copied into buf, or into a
+// new slice if buf is too small. The caller checks that n bytes remain.
+func (r *SegmentedReader) slice(seg, off, n int, buf []byte) []byte {
+	if seg < len(r.segs) && off+n <= len(r.segs[seg]) {
+		return r.segs[seg][off : off+n : off+n]
+	}
+	if n == 0 {
+		return nil
+	}
+	if cap(buf) < n {
+		buf = make([]byte, n)
+	}
+	buf = buf[:n]
+	r.copyFrom(buf, seg, off)
+	return buf
+}
+
+// peek returns the next n bytes without advancing, as described for slice
+func (r *SegmentedReader) peek(n int, buf []byte) ([]byte, error) {
+	if n < 0 {
+		return nil, ErrInvalidOffset
+	}
+	if n > r.size-r.pos() {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return r.slice(r.seg, r.off, n, buf), nil
+}
+
+// take is peek followed by advancing past the bytes returned. The common case
+// of bytes ending before the current segment does is kept small enough to be
+// inlined.
+func (r *SegmentedReader) take(n int, buf []byte) ([]byte, error) {
+	if r.seg < len(r.segs) && n >= 0 {
+		if s := r.segs[r.seg]; n < len(s)-r.off {
+			b := s[r.off : r.off+n : r.off+n]
+			r.off += n
+			return b, nil
+		}
+	}
+	return r.takeSlow(n, buf)
+}
+
+func (r *SegmentedReader) takeSlow(n int, buf []byte) ([]byte, error) {
+	b, err := r.peek(n, buf)
+	if err != nil {
+		return nil, err
+	}
+	r.advance(n)
+	return b, nil
+}
+
+// indexByte returns the distance from the read position to the first c, or -1
+func (r *SegmentedReader) indexByte(c byte) int {
+	n := 0
+	for seg, off := r.seg, r.off; seg < len(r.segs); seg, off = seg+1, 0 {
+		if i := bytes.IndexByte(r.segs[seg][off:], c); i >= 0 {
+			return n + i
+		}
+		n += len(r.segs[seg]) - off
+	}
+	return -1
+}
+
+// Len returns the number of unread bytes
+func (r *SegmentedReader) Len() int {
+	return r.size - r.pos()
+}
+
+// Bytes returns the remaining unparsed bytes from the current read position.
+// They alias the data if they lie within the last segment; otherwise the
+// segments are copied into a new slice
+func (r *SegmentedReader) Bytes() []byte {
+	b, _ := r.peek(r.Len(), nil)
+	return b
+}
+
+// ReadBytes reads n bytes, aliasing the segment when they lie within one
+func (r *SegmentedReader) ReadBytes(n int) ([]byte, error) {
+	return r.take(n, nil)
+}
+
+// ReadByte reads a single byte
+func (r *SegmentedReader) ReadByte() (byte, error) {
+	if r.seg == len(r.segs) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	b := r.segs[r.seg][r.off]
+	r.advance(1)
+	return b, nil
+}
+
+// Skip skips n bytes
+func (r *SegmentedReader) Skip(n int) error {
+	if n < 0 {
+		return ErrInvalidOffset
+	}
+	if n > r.Len() {
+		return io.ErrUnexpectedEOF
+	}
+	r.advance(n)
+	return nil
+}
+
+// ReadUvarint reads a variable-length unsigned integer
+func (r *SegmentedReader) ReadUvarint() (uint64, error) {
+	return binary.ReadUvarint(r)
+}
+
+// ReadString reads n bytes and returns them as a string
+func (r *SegmentedReader) ReadString(n int) (string, error) {
+	b, err := r.take(n, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// ReadStringInto reads n bytes into the provided string pointer
+func (r *SegmentedReader) ReadStringInto(out *string, n int) error {
+	result, err := r.ReadString(n)
+	if err != nil {
+		return err
+	}
+	*out = result
+	return nil
+}
+
+// ReadNullTerminatedString reads a null-terminated string (C-style string)
+func (r *SegmentedReader) ReadNullTerminatedString() (string, error) {
+	i := r.indexByte(0)
+	if i < 0 {
+		return "", io.ErrUnexpectedEOF
+	}
+	b, _ := r.take(i, nil)
+	r.advance(1)
+	return string(b), nil
+}
+
+// ReadLengthEncodedInteger reads a MySQL length-encoded integer
+func (r *SegmentedReader) ReadLengthEncodedInteger() (uint64, error) {
+	c := r.cur()
+	if len(c) == 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	var n int
+	switch c[0] {
+	case 0xFB: // NULL
+		r.advance(1)
+		return 0, nil
+	case 0xFC: // 2-byte integer
+		n = 2
+	case 0xFD: // 3-byte integer
+		n = 3
+	case 0xFE: // 8-byte integer
+		n = 8
+	default: // 1-byte integer
+		r.advance(1)
+		return uint64(c[0]), nil
+	}
+	var buf [9]byte
+	b, err := r.take(1+n, buf[:])
+	if err != nil {
+		return 0, err
+	}
+	var val uint64
+	for i := n; i > 0; i-- {
+		val = val<<8 | uint64(b[i])
+	}
+	return val, nil
+}
+
+// ReadLine reads a line terminated by \n (handles \r\n)
+func (r *SegmentedReader) ReadLine() (string, error) {
+	i := r.indexByte('\n')
+	if i < 0 {
+		return "", io.ErrUnexpectedEOF
+	}
+	b, _ := r.take(i, nil)
+	r.advance(1)
+	if len(b) > 0 && b[len(b)-1] == '\r' {
+		b = b[:len(b)-1]
+	}
+	return string(b), nil
+}
+
+// ReadASCIIInt reads a signed base-10 integer terminated by \n (handles \r\n).
+// The cursor is left untouched when the line is incomplete or malformed.
+func (r *SegmentedReader) ReadASCIIInt() (int64, error) {
+	i := r.indexByte('\n')
+	if i < 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	var buf [24]byte
+	line, _ := r.peek(i, buf[:])
+	if i > 0 && line[i-1] == '\r' {
+		line = line[:i-1]
+	}
+	val, ok := parseASCIIInt(line)
+	if !ok {
+		return 0, ErrInvalidASCIIInt
+	}
+	r.advance(i + 1)
+	return val, nil
+}
+
+// ReadUint16BE reads a 16-bit unsigned integer in big-endian byte order
+func (r *SegmentedReader) ReadUint16BE() (uint16, error) {
+	var buf [2]byte
+	b, err := r.take(2, buf[:])
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint16(b), nil
+}
+
+// ReadUint16BEInto reads a 16-bit unsigned integer in big-endian byte order into the provided pointer
+func (r *SegmentedReader) ReadUint16BEInto(out *uint16) error {
+	tmp, err := r.ReadUint16BE()
+	if err != nil {
+		return err
+	}
+	*out = tmp
+	return nil
+}
+
+// ReadInt16BEInto reads a 16-bit signed integer in big-endian byte order into the provided pointer
+func (r *SegmentedReader) ReadInt16BEInto(out *int16) error {
+	tmp, err := r.ReadUint16BE()
+	if err != nil {
+		return err
+	}
+	*out = int16(tmp)
+	return nil
+}
+
+// ReadUint32BE reads a 32-bit unsigned integer in big-endian byte order
+func (r *SegmentedReader) ReadUint32BE() (uint32, error) {
+	var buf [4]byte
+	b, err := r.take(4, buf[:])
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint32(b), nil
+}
+
+// ReadUint32BEInto reads a 32-bit unsigned integer in big-endian byte order into the provided pointer
+func (r *SegmentedReader) ReadUint32BEInto(out *uint32) error {
+	tmp, err := r.ReadUint32BE()
+	if err != nil {
+		return err
+	}
+	*out = tmp
+	return nil
+}
+
+// ReadInt32BEInto reads a 32-bit signed integer in big-endian byte order into the provided pointer
+func (r *SegmentedReader) ReadInt32BEInto(out *int32) error {
+	tmp, err := r.ReadUint32BE()
+	if err != nil {
+		return err
+	}
+	*out = int32(tmp)
+	return nil
+}
+
+// ReadUint64BE reads a 64-bit unsigned integer in big-endian byte order
+func (r *SegmentedReader) ReadUint64BE() (uint64, error) {
+	var buf [8]byte
+	b, err := r.take(8, buf[:])
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(b), nil
+}
+
+// ReadUint64BEInto reads a 64-bit unsigned integer in big-endian byte order into the provided pointer
+func (r *SegmentedReader) ReadUint64BEInto(out *uint64) error {
+	tmp, err := r.ReadUint64BE()
+	if err != nil {
+		return err
+	}
+	*out = tmp
+	return nil
+}
+
+// ReadUint16LE reads a 16-bit unsigned integer in little-endian byte order
+func (r *SegmentedReader) ReadUint16LE() (uint16, error) {
+	var buf [2]byte
+	b, err := r.take(2, buf[:])
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint16(b), nil
+}
+
+// ReadUint16LEInto reads a 16-bit unsigned integer in little-endian byte order into the provided pointer
+func (r *SegmentedReader) ReadUint16LEInto(out *uint16) error {
+	tmp, err := r.ReadUint16LE()
+	if err != nil {
+		return err
+	}
+	*out = tmp
+	return nil
+}
+
+// ReadUint32LE reads a 32-bit unsigned integer in little-endian byte order
+func (r *SegmentedReader) ReadUint32LE() (uint32, error) {
+	var buf [4]byte
+	b, err := r.take(4, buf[:])
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(b), nil
+}
+
+// ReadUint32LEInto reads a 32-bit unsigned integer in little-endian byte order into the provided pointer
+func (r *SegmentedReader) ReadUint32LEInto(out *uint32) error {
+	tmp, err := r.ReadUint32LE()
+	if err != nil {
+		return err
+	}
+	*out = tmp
+	return nil
+}
+
+// ReadUint64LE reads a 64-bit unsigned integer in little-endian byte order
+func (r *SegmentedReader) ReadUint64LE() (uint64, error) {
+	var buf [8]byte
+	b, err := r.take(8, buf[:])
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(b), nil
+}
+
+// ReadUint64LEInto reads a 64-bit unsigned integer in little-endian byte order into the provided pointer
+func (r *SegmentedReader) ReadUint64LEInto(out *uint64) error {
+	tmp, err := r.ReadUint64LE()
+	if err != nil {
+		return err
+	}
+	*out = tmp
+	return nil
+}
+
+// at returns the n bytes at offset off, as described for slice
+func (r *SegmentedReader) at(off, n int, buf []byte) ([]byte, error) {
+	if off < 0 || n < 0 {
+		return nil, ErrInvalidOffset
+	}
+	if off > r.size-n {
+		return nil, io.ErrUnexpectedEOF
+	}
+	seg, segOff := r.locate(off)
+	return r.slice(seg, segOff, n, buf), nil
+}
+
+// ReadAt implements io.ReaderAt. It copies from the data at offset off without
+// moving the read position, returning io.EOF if fewer than len(p) bytes remain
+func (r *SegmentedReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, ErrInvalidOffset
+	}
+	if off >= int64(r.size) {
+		return 0, io.EOF
+	}
+	seg, segOff := r.locate(int(off))
+	n := r.copyFrom(p, seg, segOff)
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// ReadByteAt reads the byte at offset off without moving the read position
+func (r *SegmentedReader) ReadByteAt(off int) (byte, error) {
+	var buf [1]byte
+	b, err := r.at(off, 1, buf[:])
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+// ReadBytesAt returns the n bytes at offset off without moving the read position,
+// aliasing the segment when they lie within one
+func (r *SegmentedReader) ReadBytesAt(off, n int) ([]byte, error) {
+	return r.at(off, n, nil)
+}
+
+// ReadUint16BEAt reads a 16-bit unsigned integer in big-endian byte order at offset off
+func (r *SegmentedReader) ReadUint16BEAt(off int) (uint16, error) {
+	var buf [2]byte
+	b, err := r.at(off, 2, buf[:])
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint16(b), nil
+}
+
+// ReadUint16LEAt reads a 16-bit unsigned integer in little-endian byte order at offset off
+func (r *SegmentedReader) ReadUint16LEAt(off int) (uint16, error) {
+	var buf [2]byte
+	b, err := r.at(off, 2, buf[:])
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint16(b), nil
+}
+
+// ReadUint32BEAt reads a 32-bit unsigned integer in big-endian byte order at offset off
+func (r *SegmentedReader) ReadUint32BEAt(off int) (uint32, error) {
+	var buf [4]byte
+	b, err := r.at(off, 4, buf[:])
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint32(b), nil
+}
+
+// ReadUint32LEAt reads a 32-bit unsigned integer in little-endian byte order at offset off
+func (r *SegmentedReader) ReadUint32LEAt(off int) (uint32, error) {
+	var buf [4]byte
+	b, err := r.at(off, 4, buf[:])
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(b), nil
+}
+
+// ReadUint64BEAt reads a 64-bit unsigned integer in big-endian byte order at offset off
+func (r *SegmentedReader) ReadUint64BEAt(off int) (uint64, error) {
+	var buf [8]byte
+	b, err := r.at(off, 8, buf[:])
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(b), nil
+}
+
+// ReadUint64LEAt reads a 64-bit unsigned integer in little-endian byte order at offset off
+func (r *SegmentedReader) ReadUint64LEAt(off int) (uint64, error) {
+	var buf [8]byte
+	b, err := r.at(off, 8, buf[:])
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(b), nil
+}
+
+// Read implements io.Reader, copying from the current read position. It
+// returns io.EOF once the data is exhausted
+func (r *SegmentedReader) Read(p []byte) (int, error) {
+	if r.seg == len(r.segs) {
+		if len(p) == 0 {
+			return 0, nil
+		}
+		return 0, io.EOF
+	}
+	n := r.copyFrom(p, r.seg, r.off)
+	r.advance(n)
+	return n, nil
+}
+
+// UnreadByte implements io.ByteScanner, moving the read position back one byte
+func (r *SegmentedReader) UnreadByte() error {
+	switch {
+	case r.pos() == 0:
+		return ErrUnreadByte
+	case r.off == 0:
+		r.seg--
+		r.off = len(r.segs[r.seg]) - 1
+	default:
+		r.off--
+	}
+	return nil
+}
+
+// ReadRune implements io.RuneReader, decoding one UTF-8 encoded rune. Invalid
+// encodings are returned as utf8.RuneError of size 1
+func (r *SegmentedReader) ReadRune() (rune, int, error) {
+	c := r.cur()
+	if len(c) == 0 {
+		return 0, 0, io.EOF
+	}
+	if c[0] < utf8.RuneSelf {
+		r.advance(1)
+		return rune(c[0]), 1, nil
+	}
+	if !utf8.FullRune(c) {
+		var buf [utf8.UTFMax]byte
+		c, _ = r.peek(min(utf8.UTFMax, r.Len()), buf[:])
+	}
+	ch, size := utf8.DecodeRune(c)
+	r.advance(size)
+	return ch, size, nil
+}
+
+// Seek implements io.Seeker. Seeking before the start returns ErrInvalidOffset
+// and past the end io.ErrUnexpectedEOF, leaving the read position unchanged
+func (r *SegmentedReader) Seek(offset int64, whence int) (int64, error) {
+	var abs int64
+	switch whence {
+	case io.SeekStart:
+		abs = offset
+	case io.SeekCurrent:
+		abs = int64(r.pos()) + offset
+	case io.SeekEnd:
+		abs = int64(r.size) + offset
+	default:
+		return 0, ErrInvalidWhence
+	}
+	if abs < 0 {
+		return 0, ErrInvalidOffset
+	}
+	if abs > int64(r.size) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	r.seg, r.off = r.locate(int(abs))
+	return abs, nil
+}
+
+// WriteTo implements io.WriterTo, writing the remaining segments to w and
+// advancing the read position past the bytes written
+func (r *SegmentedReader) WriteTo(w io.Writer) (int64, error) {
+	var total int64
+	for r.seg < len(r.segs) {
+		c := r.cur()
+		n, err := w.Write(c)
+		if n < 0 || n > len(c) {
+			return total, ErrInvalidWrite
+		}
+		r.advance(n)
+		total += int64(n)
+		if err == nil && n != len(c) {
+			err = io.ErrShortWrite
+		}
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
@@ -0,0 +1,182 @@
+package wireread
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// splits returns data cut into segments at every pair of split points, with
+// an empty segment thrown in.
+func splits(data []byte) [][][]byte {
+	var out [][][]byte
+	for i := 0; i <= len(data); i++ {
+		for j := i; j <= len(data); j++ {
+			out = append(out, [][]byte{data[:i], {}, data[i:j], data[j:]})
+		}
+	}
+	return out
+}
+
+func TestSegmentedReader_MatchesSafeReader(t *testing.T) {
+	data := []byte("\x01\x02\x03\x04\x05\x06\x07\x08\x09\xfb\x34\x12héllo\x00-42\r\nline\n\x96\x01tail")
+
+	type result struct {
+		v   any
+		err error
+	}
+	ops := func(r interface {
+		Reader
+		ASCIIIntReader
+		RandomAccessReader
+		StreamReader
+		io.ReaderAt
+	}) []result {
+		var out []result
+		add := func(v any, err error) { out = append(out, result{v, err}) }
+		add(r.ReadUint16BE())
+		add(r.ReadUint32LE())
+		add(r.ReadUint16LEAt(7))
+		add(r.ReadUint64BEAt(1))
+		add(r.ReadByteAt(9))
+		add(r.ReadByteAt(len(data)))
+		add(r.ReadByte())
+		add(r.ReadUint16LE())
+		add(r.ReadLengthEncodedInteger())
+		c, size, err := r.ReadRune()
+		add([2]int{int(c), size}, err)
+		add(r.ReadNullTerminatedString())
+		add(r.ReadASCIIInt())
+		add(r.ReadLine())
+		add(r.ReadUvarint())
+		b, err := r.ReadBytes(2)
+		add(string(b), err)
+		add(string(r.Bytes()), r.UnreadByte())
+		add(r.ReadString(3))
+		add(r.ReadUint64LE())
+		add(r.Seek(-4, io.SeekEnd))
+		var buf bytes.Buffer
+		add(r.WriteTo(&buf))
+		add(buf.String(), nil)
+		p := make([]byte, 6)
+		n, err := r.ReadAt(p, int64(len(data)-4))
+		add(string(p[:n]), err)
+		return out
+	}
+
+	want := ops(NewSafeReader(data))
+	for _, segs := range splits(data) {
+		got := ops(NewSegmentedReader(segs))
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("segments %d/%d/%d: op %d = %v, want %v", len(segs[0]), len(segs[2]), len(segs[3]), i, got[i], want[i])
+			}
+		}
+	}
+}
+
+func TestSegmentedReader_ZeroCopy(t *testing.T) {
+	a, b := []byte("abcd"), []byte("efgh")
+	r := NewSegmentedReader([][]byte{a, b})
+
+	got, _ := r.ReadBytes(3)
+	if string(got) != "abc" || &got[0] != &a[0] {
+		t.Errorf("ReadBytes(3) = %q, aliasing %v; want \"abc\" aliasing the first segment", got, &got[0] == &a[0])
+	}
+	got, _ = r.ReadBytes(2)
+	if string(got) != "de" || &got[0] == &a[3] {
+		t.Errorf("ReadBytes(2) across segments = %q, aliasing %v; want a copy of \"de\"", got, &got[0] == &a[3])
+	}
+	got, _ = r.ReadBytesAt(5, 3)
+	if string(got) != "fgh" || &got[0] != &b[1] {
+		t.Errorf("ReadBytesAt(5, 3) = %q, want \"fgh\" aliasing the second segment", got)
+	}
+	if got := r.Bytes(); string(got) != "fgh" || &got[0] != &b[1] {
+		t.Errorf("Bytes() = %q, want \"fgh\" aliasing the second segment", got)
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		r := NewSegmentedReader([][]byte{a, b})
+		_ = r.Skip(1)
+		_, _ = r.ReadUint64BE()
+		_, _ = r.ReadUint32LEAt(2)
+	})
+	// Only NewSegmentedReader allocates.
+	if allocs > 3 {
+		t.Errorf("reads across segments allocated %v times", allocs)
+	}
+}
+
+func TestSegmentedReader_ReadLengthEncodedInteger(t *testing.T) {
+	data := []byte{0xFC, 0x01, 0x02, 0xFD, 0x01, 0x02, 0x03, 0xFE, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x07}
+	want := []uint64{0x0201, 0x030201, 0x0807060504030201, 7}
+	for _, segs := range splits(data) {
+		r := NewSegmentedReader(segs)
+		for i, w := range want {
+			if got, err := r.ReadLengthEncodedInteger(); err != nil || got != w {
+				t.Fatalf("segments %d/%d/%d: value %d = 0x%x, %v; want 0x%x, nil", len(segs[0]), len(segs[2]), len(segs[3]), i, got, err, w)
+			}
+		}
+	}
+
+	r := NewSegmentedReader([][]byte{{0xFC, 0x01}, {}})
+	if _, err := r.ReadLengthEncodedInteger(); err != io.ErrUnexpectedEOF {
+		t.Errorf("ReadLengthEncodedInteger() on short data error = %v, want io.ErrUnexpectedEOF", err)
+	}
+	if r.Len() != 2 {
+		t.Errorf("failed ReadLengthEncodedInteger() consumed %d bytes", 2-r.Len())
+	}
+}
+
+func TestSegmentedReader_Errors(t *testing.T) {
+	r := NewSegmentedReader([][]byte{{0x01}, {0x02, 0x03}})
+	if _, err := r.ReadUint32BE(); err != io.ErrUnexpectedEOF {
+		t.Errorf("ReadUint32BE() error = %v, want io.ErrUnexpectedEOF", err)
+	}
+	if _, err := r.ReadBytes(-1); err != ErrInvalidOffset {
+		t.Errorf("ReadBytes(-1) error = %v, want ErrInvalidOffset", err)
+	}
+	if _, err := r.ReadNullTerminatedString(); err != io.ErrUnexpectedEOF {
+		t.Errorf("ReadNullTerminatedString() error = %v, want io.ErrUnexpectedEOF", err)
+	}
+	if err := r.Skip(4); err != io.ErrUnexpectedEOF {
+		t.Errorf("Skip(4) error = %v, want io.ErrUnexpectedEOF", err)
+	}
+	if _, err := r.ReadUint16BEAt(2); err != io.ErrUnexpectedEOF {
+		t.Errorf("ReadUint16BEAt(2) error = %v, want io.ErrUnexpectedEOF", err)
+	}
+	if got, _ := r.ReadUint16BE(); got != 0x0102 {
+		t.Errorf("ReadUint16BE() after failed reads = 0x%04x, want 0x0102", got)
+	}
+	if r.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", r.Len())
+	}
+
+	empty := NewSegmentedReader(nil)
+	if _, err := empty.ReadByte(); err != io.ErrUnexpectedEOF {
+		t.Errorf("ReadByte() on empty reader error = %v, want io.ErrUnexpectedEOF", err)
+	}
+	if n, err := empty.Read(make([]byte, 1)); n != 0 || err != io.EOF {
+		t.Errorf("Read() on empty reader = %d, %v; want 0, io.EOF", n, err)
+	}
+}
+
+func TestSegmentedReader_Reset(t *testing.T) {
+	r := NewSegmentedReader([][]byte{{0x01}, {0x02, 0x03}, {0x04}})
+	r.ReadUint16BE()
+
+	r.Reset([][]byte{{0x0A}, {}, {0x0B}})
+	if got, err := r.ReadUint16BE(); err != nil || got != 0x0A0B {
+		t.Errorf("ReadUint16BE() after Reset() = 0x%04x, %v; want 0x0a0b, nil", got, err)
+	}
+	if r.Len() != 0 {
+		t.Errorf("Len() after Reset() = %d, want 0", r.Len())
+	}
+}
+
+func TestSegmentedReader_ImplementsReader(t *testing.T) {
+	var _ Reader = (*SegmentedReader)(nil)
+	var _ ASCIIIntReader = (*SegmentedReader)(nil)
+	var _ RandomAccessReader = (*SegmentedReader)(nil)
+	var _ StreamReader = (*SegmentedReader)(nil)
+}
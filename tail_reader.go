@@ -0,0 +1,158 @@
+package wireread
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+// TailReader reads fields backwards from the end of the data, for formats
+// whose trailer locates the rest of the file, such as ZIP's end of central
+// directory record and Parquet's footer. Each read consumes the field that
+// ends at the current position; the bytes of a field are still in their
+// usual order, so ReadUint32LE decodes the same value as SafeReader would.
+type TailReader struct {
+	data []byte
+	end  int
+}
+
+// NewTailReader creates a TailReader positioned at the end of data.
+func NewTailReader(data []byte) *TailReader {
+	return &TailReader{data: data, end: len(data)}
+}
+
+// Tail returns a TailReader over the bytes sr has not read yet. The two
+// readers have independent positions, and the TailReader's offsets are
+// relative to sr's read position.
+func (sr *SafeReader) Tail() *TailReader {
+	return NewTailReader(sr.Bytes())
+}
+
+// Bytes returns the bytes before the current position, which are still to
+// be read
+func (tr *TailReader) Bytes() []byte {
+	return tr.data[:tr.end]
+}
+
+// Offset returns the current position as an offset from the start of the data
+func (tr *TailReader) Offset() int {
+	return tr.end
+}
+
+// ReadBytes returns the n bytes before the current position. The result
+// aliases the underlying data
+func (tr *TailReader) ReadBytes(n int) ([]byte, error) {
+	if n < 0 {
+		return nil, ErrInvalidOffset
+	}
+	if n > tr.end {
+		return nil, io.ErrUnexpectedEOF
+	}
+	tr.end -= n
+	return tr.data[tr.end : tr.end+n : tr.end+n], nil
+}
+
+// ReadByte reads the byte before the current position
+func (tr *TailReader) ReadByte() (byte, error) {
+	if tr.end < 1 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	tr.end--
+	return tr.data[tr.end], nil
+}
+
+// Skip moves the position n bytes towards the start of the data
+func (tr *TailReader) Skip(n int) error {
+	if n < 0 {
+		return ErrInvalidOffset
+	}
+	if n > tr.end {
+		return io.ErrUnexpectedEOF
+	}
+	tr.end -= n
+	return nil
+}
+
+// ReadUint16BE reads the 16-bit big-endian unsigned integer before the current position
+func (tr *TailReader) ReadUint16BE() (uint16, error) {
+	if tr.end < 2 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	tr.end -= 2
+	return binary.BigEndian.Uint16(tr.data[tr.end:]), nil
+}
+
+// ReadUint32BE reads the 32-bit big-endian unsigned integer before the current position
+func (tr *TailReader) ReadUint32BE() (uint32, error) {
+	if tr.end < 4 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	tr.end -= 4
+	return binary.BigEndian.Uint32(tr.data[tr.end:]), nil
+}
+
+// ReadUint64BE reads the 64-bit big-endian unsigned integer before the current position
+func (tr *TailReader) ReadUint64BE() (uint64, error) {
+	if tr.end < 8 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	tr.end -= 8
+	return binary.BigEndian.Uint64(tr.data[tr.end:]), nil
+}
+
+// ReadUint16LE reads the 16-bit little-endian unsigned integer before the current position
+func (tr *TailReader) ReadUint16LE() (uint16, error) {
+	if tr.end < 2 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	tr.end -= 2
+	return binary.LittleEndian.Uint16(tr.data[tr.end:]), nil
+}
+
+// ReadUint32LE reads the 32-bit little-endian unsigned integer before the current position
+func (tr *TailReader) ReadUint32LE() (uint32, error) {
+	if tr.end < 4 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	tr.end -= 4
+	return binary.LittleEndian.Uint32(tr.data[tr.end:]), nil
+}
+
+// ReadUint64LE reads the 64-bit little-endian unsigned integer before the current position
+func (tr *TailReader) ReadUint64LE() (uint64, error) {
+	if tr.end < 8 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	tr.end -= 8
+	return binary.LittleEndian.Uint64(tr.data[tr.end:]), nil
+}
+
+// LastIndex returns the offset from the start of the data of the last
+// occurrence of sig that lies entirely within the window bytes before the
+// current position, or -1 if there is none. Bounding the search matters for
+// trailers with a variable-length comment, such as ZIP's, where scanning a
+// large file for a short signature would be slow and could match file data.
+func (tr *TailReader) LastIndex(sig []byte, window int) int {
+	if window < 0 {
+		return -1
+	}
+	start := max(tr.end-window, 0)
+	i := bytes.LastIndex(tr.data[start:tr.end], sig)
+	if i < 0 {
+		return -1
+	}
+	return start + i
+}
+
+// SeekLast moves the position to just before the last occurrence of sig
+// within the window bytes before the current position, so that the bytes
+// from Offset onwards start with sig. It returns false and leaves the
+// position unchanged if sig is not found.
+func (tr *TailReader) SeekLast(sig []byte, window int) bool {
+	i := tr.LastIndex(sig, window)
+	if i < 0 {
+		return false
+	}
+	tr.end = i
+	return true
+}
@@ -0,0 +1,134 @@
+package wireread
+
+import (
+	"encoding/binary"
+	"io"
+	"testing"
+)
+
+func TestTailReader_Reads(t *testing.T) {
+	data := []byte{0xAA, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0A, 0x0B, 0x0C, 0x0D, 0x0E, 0x0F}
+	r := NewTailReader(data)
+
+	if got, err := r.ReadUint16LE(); err != nil || got != 0x0F0E {
+		t.Errorf("ReadUint16LE() = 0x%04x, %v; want 0x0f0e, nil", got, err)
+	}
+	if got, err := r.ReadUint32BE(); err != nil || got != 0x0A0B0C0D {
+		t.Errorf("ReadUint32BE() = 0x%08x, %v; want 0x0a0b0c0d, nil", got, err)
+	}
+	if got, err := r.ReadUint64LE(); err != nil || got != 0x0908070605040302 {
+		t.Errorf("ReadUint64LE() = 0x%016x, %v; want 0x0908070605040302, nil", got, err)
+	}
+	if got, err := r.ReadByte(); err != nil || got != 0x01 {
+		t.Errorf("ReadByte() = %v, %v; want 1, nil", got, err)
+	}
+	if r.Offset() != 1 || !bytesEqual(r.Bytes(), []byte{0xAA}) {
+		t.Errorf("Offset() = %d, Bytes() = %v; want 1, [170]", r.Offset(), r.Bytes())
+	}
+	if _, err := r.ReadUint16BE(); err != io.ErrUnexpectedEOF {
+		t.Errorf("ReadUint16BE() past start error = %v, want io.ErrUnexpectedEOF", err)
+	}
+	if r.Offset() != 1 {
+		t.Errorf("Offset() after failed read = %d, want 1", r.Offset())
+	}
+	if err := r.Skip(1); err != nil || r.Offset() != 0 {
+		t.Errorf("Skip(1) = %v, Offset() = %d; want nil, 0", err, r.Offset())
+	}
+	if _, err := r.ReadByte(); err != io.ErrUnexpectedEOF {
+		t.Errorf("ReadByte() at start error = %v, want io.ErrUnexpectedEOF", err)
+	}
+}
+
+func TestTailReader_ReadBytes(t *testing.T) {
+	data := []byte("headerPAR1")
+	r := NewTailReader(data)
+	got, err := r.ReadBytes(4)
+	if err != nil || string(got) != "PAR1" {
+		t.Fatalf("ReadBytes(4) = %q, %v; want \"PAR1\", nil", got, err)
+	}
+	if &got[0] != &data[6] {
+		t.Error("ReadBytes() does not alias the input")
+	}
+	if _, err := r.ReadBytes(7); err != io.ErrUnexpectedEOF {
+		t.Errorf("ReadBytes(7) error = %v, want io.ErrUnexpectedEOF", err)
+	}
+	if _, err := r.ReadBytes(-1); err != ErrInvalidOffset {
+		t.Errorf("ReadBytes(-1) error = %v, want ErrInvalidOffset", err)
+	}
+}
+
+func TestSafeReader_Tail(t *testing.T) {
+	// A Parquet-style file: magic, body, footer, footer length, magic.
+	data := []byte("PAR1bodyfooter\x06\x00\x00\x00PAR1")
+	sr := NewSafeReader(data)
+	if magic, _ := sr.ReadString(4); magic != "PAR1" {
+		t.Fatalf("leading magic = %q", magic)
+	}
+
+	tr := sr.Tail()
+	if magic, _ := tr.ReadBytes(4); string(magic) != "PAR1" {
+		t.Errorf("trailing magic = %q, want \"PAR1\"", magic)
+	}
+	n, _ := tr.ReadUint32LE()
+	footer, err := tr.ReadBytes(int(n))
+	if err != nil || string(footer) != "footer" {
+		t.Errorf("footer = %q, %v; want \"footer\", nil", footer, err)
+	}
+	if string(tr.Bytes()) != "body" {
+		t.Errorf("Bytes() = %q, want \"body\"", tr.Bytes())
+	}
+	if len(sr.Bytes()) != len(data)-4 {
+		t.Errorf("Tail() moved the SafeReader to %d bytes left", len(sr.Bytes()))
+	}
+}
+
+func TestTailReader_LastIndex(t *testing.T) {
+	sig := []byte("PK\x05\x06")
+	// An end of central directory record followed by a comment that itself
+	// contains the signature further back.
+	eocd := make([]byte, 22)
+	copy(eocd, sig)
+	binary.LittleEndian.PutUint16(eocd[20:], 9)
+	data := append([]byte("PK\x05\x06 file data "), eocd...)
+	data = append(data, "a comment"...)
+	at := len(data) - 9 - 22
+
+	tests := []struct {
+		name   string
+		window int
+		want   int
+	}{
+		{"whole record", 22 + 9, at},
+		{"generous window", 1 << 16, at},
+		{"comment only", 9, -1},
+		{"signature straddles window", 22 + 9 - 1, -1},
+		{"zero window", 0, -1},
+		{"negative window", -1, -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewTailReader(data)
+			if got := r.LastIndex(sig, tt.window); got != tt.want {
+				t.Errorf("LastIndex(sig, %d) = %d, want %d", tt.window, got, tt.want)
+			}
+		})
+	}
+
+	r := NewTailReader(data)
+	if !r.SeekLast(sig, 1<<16) || r.Offset() != at {
+		t.Fatalf("SeekLast() moved to %d, want %d", r.Offset(), at)
+	}
+	rec := NewSafeReader(data[r.Offset():])
+	_ = rec.Skip(20)
+	if n, _ := rec.ReadUint16LE(); n != 9 {
+		t.Errorf("comment length = %d, want 9", n)
+	}
+	// Searching again from the record finds the earlier signature.
+	if !r.SeekLast(sig, 1<<16) || r.Offset() != 0 {
+		t.Errorf("second SeekLast() moved to %d, want 0", r.Offset())
+	}
+	if r.SeekLast(sig, 1<<16) || r.Offset() != 0 {
+		t.Errorf("SeekLast() with no match moved to %d", r.Offset())
+	}
+}
@@ -0,0 +1,248 @@
+package thrift
+
+import (
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/nemohan/wireread"
+)
+
+// Binary protocol message header constants. Strict headers start with the
+// version in the high 16 bits of a negative i32 and the message type in the
+// low byte; older writers send the name length first instead.
+const (
+	binaryVersionMask = 0xffff0000
+	binaryVersion1    = 0x80010000
+)
+
+// reader holds what BinaryReader and CompactReader share.
+type reader struct {
+	r     wireread.Reader
+	start int
+}
+
+func newReader(r wireread.Reader) reader {
+	return reader{r: r, start: len(r.Bytes())}
+}
+
+// Offset returns the number of bytes consumed so far.
+func (r *reader) Offset() int {
+	return r.start - len(r.r.Bytes())
+}
+
+// errorf returns an ErrMalformed error located at offset off.
+func (r *reader) errorf(off int, format string, args ...any) error {
+	return fmt.Errorf("%w: %s at offset %d", ErrMalformed, fmt.Sprintf(format, args...), off)
+}
+
+// checkSize rejects a collection of n elements of at least min bytes each
+// that cannot fit in the remaining data.
+func (r *reader) checkSize(off int, n int64, min int) error {
+	if n < 0 || n > int64(len(r.r.Bytes())/min) {
+		return r.errorf(off, "collection size %d", n)
+	}
+	return nil
+}
+
+func (r *reader) checkType(off int, t Type) error {
+	if !t.isValue() {
+		return r.errorf(off, "invalid type %d", byte(t))
+	}
+	return nil
+}
+
+// ReadUUID reads a UUID, which both encodings store as 16 raw bytes.
+func (r *reader) ReadUUID() ([16]byte, error) {
+	b, err := r.r.ReadBytes(16)
+	if err != nil {
+		return [16]byte{}, err
+	}
+	return [16]byte(b), nil
+}
+
+// BinaryReader reads the TBinaryProtocol encoding, in which integers are
+// big-endian and fixed-size.
+type BinaryReader struct {
+	reader
+}
+
+// NewBinaryReader creates a BinaryReader reading from r.
+func NewBinaryReader(r wireread.Reader) *BinaryReader {
+	return &BinaryReader{newReader(r)}
+}
+
+// ReadMessageBegin reads a message header in either the strict or the
+// older non-strict form.
+func (p *BinaryReader) ReadMessageBegin() (MessageHeader, error) {
+	off := p.Offset()
+	v, err := p.ReadI32()
+	if err != nil {
+		return MessageHeader{}, err
+	}
+	var h MessageHeader
+	if v < 0 {
+		if uint32(v)&binaryVersionMask != binaryVersion1 {
+			return MessageHeader{}, fmt.Errorf("%w: version 0x%08x at offset %d", ErrBadVersion, uint32(v), off)
+		}
+		h.Type = MessageType(v)
+		if h.Name, err = p.ReadString(); err != nil {
+			return MessageHeader{}, err
+		}
+	} else {
+		b, err := p.r.ReadBytes(int(v))
+		if err != nil {
+			return MessageHeader{}, err
+		}
+		h.Name = string(b)
+		t, err := p.r.ReadByte()
+		if err != nil {
+			return MessageHeader{}, err
+		}
+		h.Type = MessageType(t)
+	}
+	if h.SeqID, err = p.ReadI32(); err != nil {
+		return MessageHeader{}, err
+	}
+	return h, nil
+}
+
+// ReadStructBegin does nothing; binary structs have no header.
+func (p *BinaryReader) ReadStructBegin() error { return nil }
+
+// ReadStructEnd does nothing; the end of a struct is its Stop field.
+func (p *BinaryReader) ReadStructEnd() error { return nil }
+
+// ReadFieldBegin reads a field type and, unless it is Stop, a field id.
+func (p *BinaryReader) ReadFieldBegin() (FieldHeader, error) {
+	off := p.Offset()
+	t, err := p.r.ReadByte()
+	if err != nil {
+		return FieldHeader{}, err
+	}
+	if Type(t) == Stop {
+		return FieldHeader{}, nil
+	}
+	if err := p.checkType(off, Type(t)); err != nil {
+		return FieldHeader{}, err
+	}
+	id, err := p.ReadI16()
+	return FieldHeader{Type: Type(t), ID: id}, err
+}
+
+// ReadListBegin reads an element type and an i32 size.
+func (p *BinaryReader) ReadListBegin() (ListHeader, error) {
+	off := p.Offset()
+	t, err := p.r.ReadByte()
+	if err != nil {
+		return ListHeader{}, err
+	}
+	if err := p.checkType(off, Type(t)); err != nil {
+		return ListHeader{}, err
+	}
+	n, err := p.ReadI32()
+	if err != nil {
+		return ListHeader{}, err
+	}
+	if err := p.checkSize(off, int64(n), 1); err != nil {
+		return ListHeader{}, err
+	}
+	return ListHeader{Elem: Type(t), Size: int(n)}, nil
+}
+
+// ReadSetBegin reads a set header, which is encoded like a list header.
+func (p *BinaryReader) ReadSetBegin() (ListHeader, error) {
+	return p.ReadListBegin()
+}
+
+// ReadMapBegin reads the key and value types and an i32 size.
+func (p *BinaryReader) ReadMapBegin() (MapHeader, error) {
+	off := p.Offset()
+	b, err := p.r.ReadBytes(2)
+	if err != nil {
+		return MapHeader{}, err
+	}
+	h := MapHeader{Key: Type(b[0]), Value: Type(b[1])}
+	if err := p.checkType(off, h.Key); err != nil {
+		return MapHeader{}, err
+	}
+	if err := p.checkType(off+1, h.Value); err != nil {
+		return MapHeader{}, err
+	}
+	n, err := p.ReadI32()
+	if err != nil {
+		return MapHeader{}, err
+	}
+	if err := p.checkSize(off, int64(n), 2); err != nil {
+		return MapHeader{}, err
+	}
+	h.Size = int(n)
+	return h, nil
+}
+
+// ReadBool reads a bool stored as one byte.
+func (p *BinaryReader) ReadBool() (bool, error) {
+	off := p.Offset()
+	b, err := p.r.ReadByte()
+	if err != nil {
+		return false, err
+	}
+	if b > 1 {
+		return false, p.errorf(off, "bool value %d", b)
+	}
+	return b == 1, nil
+}
+
+// ReadI8 reads a byte.
+func (p *BinaryReader) ReadI8() (int8, error) {
+	b, err := p.r.ReadByte()
+	return int8(b), err
+}
+
+// ReadI16 reads a big-endian i16.
+func (p *BinaryReader) ReadI16() (int16, error) {
+	var v int16
+	err := p.r.ReadInt16BEInto(&v)
+	return v, err
+}
+
+// ReadI32 reads a big-endian i32.
+func (p *BinaryReader) ReadI32() (int32, error) {
+	var v int32
+	err := p.r.ReadInt32BEInto(&v)
+	return v, err
+}
+
+// ReadI64 reads a big-endian i64.
+func (p *BinaryReader) ReadI64() (int64, error) {
+	v, err := p.r.ReadUint64BE()
+	return int64(v), err
+}
+
+// ReadDouble reads a big-endian IEEE 754 double.
+func (p *BinaryReader) ReadDouble() (float64, error) {
+	v, err := p.r.ReadUint64BE()
+	return math.Float64frombits(v), err
+}
+
+// ReadBinary reads an i32 length followed by that many bytes.
+func (p *BinaryReader) ReadBinary() ([]byte, error) {
+	off := p.Offset()
+	n, err := p.ReadI32()
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 {
+		return nil, p.errorf(off, "negative length %d", n)
+	}
+	if int(n) > len(p.r.Bytes()) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return p.r.ReadBytes(int(n))
+}
+
+// ReadString reads a string encoded like binary.
+func (p *BinaryReader) ReadString() (string, error) {
+	b, err := p.ReadBinary()
+	return string(b), err
+}
@@ -0,0 +1,223 @@
+package thrift
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/nemohan/wireread"
+)
+
+// binWriter builds TBinaryProtocol test data.
+type binWriter []byte
+
+func (w *binWriter) i8(v byte)    { *w = append(*w, v) }
+func (w *binWriter) i16(v int16)  { *w = binary.BigEndian.AppendUint16(*w, uint16(v)) }
+func (w *binWriter) i32(v int32)  { *w = binary.BigEndian.AppendUint32(*w, uint32(v)) }
+func (w *binWriter) i64(v int64)  { *w = binary.BigEndian.AppendUint64(*w, uint64(v)) }
+func (w *binWriter) str(s string) { w.i32(int32(len(s))); *w = append(*w, s...) }
+
+func (w *binWriter) field(t Type, id int16) {
+	w.i8(byte(t))
+	w.i16(id)
+}
+
+var testUUID = [16]byte{0: 0x12, 15: 0x34}
+
+// sampleBinary is a strict call of ping with these arguments:
+//
+//	1: i32 42, 2: string "hi", 3: list<i16> [1, -2],
+//	4: map<string, bool> {"a": true}, 5: struct {1: double 1.5},
+//	6: bool false, 7: i64 -1, 8: byte -2, 9: uuid
+func sampleBinary() []byte {
+	var w binWriter
+	w = binary.BigEndian.AppendUint32(w, binaryVersion1|uint32(Call))
+	w.str("ping")
+	w.i32(7)
+	w.field(I32, 1)
+	w.i32(42)
+	w.field(String, 2)
+	w.str("hi")
+	w.field(List, 3)
+	w.i8(byte(I16))
+	w.i32(2)
+	w.i16(1)
+	w.i16(-2)
+	w.field(Map, 4)
+	w.i8(byte(String))
+	w.i8(byte(Bool))
+	w.i32(1)
+	w.str("a")
+	w.i8(1)
+	w.field(Struct, 5)
+	w.field(Double, 1)
+	w.i64(int64(math.Float64bits(1.5)))
+	w.i8(byte(Stop))
+	w.field(Bool, 6)
+	w.i8(0)
+	w.field(I64, 7)
+	w.i64(-1)
+	w.field(Byte, 8)
+	w.i8(0xfe)
+	w.field(UUID, 9)
+	w = append(w, testUUID[:]...)
+	w.i8(byte(Stop))
+	return w
+}
+
+// checkSample reads the message produced by sampleBinary or sampleCompact.
+func checkSample(t *testing.T, p Protocol) {
+	t.Helper()
+	fail := func(what string, got, want any) {
+		t.Helper()
+		t.Fatalf("%s = %v, want %v (offset %d)", what, got, want, p.Offset())
+	}
+
+	h, err := p.ReadMessageBegin()
+	if err != nil || h != (MessageHeader{Name: "ping", Type: Call, SeqID: 7}) {
+		fail("ReadMessageBegin()", h, err)
+	}
+	if err := p.ReadStructBegin(); err != nil {
+		fail("ReadStructBegin()", err, nil)
+	}
+	next := func(typ Type, id int16) {
+		t.Helper()
+		f, err := p.ReadFieldBegin()
+		if err != nil || f != (FieldHeader{Type: typ, ID: id}) {
+			fail("ReadFieldBegin()", f, FieldHeader{Type: typ, ID: id})
+		}
+	}
+
+	next(I32, 1)
+	if v, err := p.ReadI32(); v != 42 || err != nil {
+		fail("ReadI32()", v, 42)
+	}
+	next(String, 2)
+	if v, err := p.ReadString(); v != "hi" || err != nil {
+		fail("ReadString()", v, "hi")
+	}
+	next(List, 3)
+	if l, err := p.ReadListBegin(); l != (ListHeader{Elem: I16, Size: 2}) || err != nil {
+		fail("ReadListBegin()", l, err)
+	}
+	for _, want := range []int16{1, -2} {
+		if v, err := p.ReadI16(); v != want || err != nil {
+			fail("ReadI16()", v, want)
+		}
+	}
+	next(Map, 4)
+	if m, err := p.ReadMapBegin(); m != (MapHeader{Key: String, Value: Bool, Size: 1}) || err != nil {
+		fail("ReadMapBegin()", m, err)
+	}
+	if k, err := p.ReadString(); k != "a" || err != nil {
+		fail("ReadString()", k, "a")
+	}
+	if v, err := p.ReadBool(); !v || err != nil {
+		fail("ReadBool()", v, true)
+	}
+	next(Struct, 5)
+	if err := p.ReadStructBegin(); err != nil {
+		fail("ReadStructBegin()", err, nil)
+	}
+	next(Double, 1)
+	if v, err := p.ReadDouble(); v != 1.5 || err != nil {
+		fail("ReadDouble()", v, 1.5)
+	}
+	next(Stop, 0)
+	if err := p.ReadStructEnd(); err != nil {
+		fail("ReadStructEnd()", err, nil)
+	}
+	next(Bool, 6)
+	if v, err := p.ReadBool(); v || err != nil {
+		fail("ReadBool()", v, false)
+	}
+	next(I64, 7)
+	if v, err := p.ReadI64(); v != -1 || err != nil {
+		fail("ReadI64()", v, -1)
+	}
+	next(Byte, 8)
+	if v, err := p.ReadI8(); v != -2 || err != nil {
+		fail("ReadI8()", v, -2)
+	}
+	next(UUID, 9)
+	if v, err := p.ReadUUID(); v != testUUID || err != nil {
+		fail("ReadUUID()", v, testUUID)
+	}
+	next(Stop, 0)
+	if err := p.ReadStructEnd(); err != nil {
+		fail("ReadStructEnd()", err, nil)
+	}
+}
+
+func TestBinaryReader(t *testing.T) {
+	data := sampleBinary()
+	p := NewBinaryReader(wireread.NewSafeReader(data))
+	checkSample(t, p)
+	if p.Offset() != len(data) {
+		t.Errorf("Offset() = %d, want %d", p.Offset(), len(data))
+	}
+}
+
+func TestBinaryReader_NonStrictMessage(t *testing.T) {
+	var w binWriter
+	w.str("ping")
+	w.i8(byte(Oneway))
+	w.i32(9)
+	h, err := NewBinaryReader(wireread.NewSafeReader(w)).ReadMessageBegin()
+	if err != nil || h != (MessageHeader{Name: "ping", Type: Oneway, SeqID: 9}) {
+		t.Errorf("ReadMessageBegin() = %+v, %v", h, err)
+	}
+}
+
+func TestBinaryReader_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		read func(Protocol) error
+		want error
+	}{
+		{"bad version", []byte{0x80, 0x02, 0, 1}, func(p Protocol) error {
+			_, err := p.ReadMessageBegin()
+			return err
+		}, ErrBadVersion},
+		{"invalid field type", []byte{5, 0, 1}, func(p Protocol) error {
+			_, err := p.ReadFieldBegin()
+			return err
+		}, ErrMalformed},
+		{"negative list size", []byte{byte(I32), 0xff, 0xff, 0xff, 0xff}, func(p Protocol) error {
+			_, err := p.ReadListBegin()
+			return err
+		}, ErrMalformed},
+		{"list larger than data", []byte{byte(I32), 0, 0, 0, 9, 1, 2}, func(p Protocol) error {
+			_, err := p.ReadListBegin()
+			return err
+		}, ErrMalformed},
+		{"void map value", []byte{byte(I32), byte(Void), 0, 0, 0, 0}, func(p Protocol) error {
+			_, err := p.ReadMapBegin()
+			return err
+		}, ErrMalformed},
+		{"bool value", []byte{2}, func(p Protocol) error {
+			_, err := p.ReadBool()
+			return err
+		}, ErrMalformed},
+		{"negative length", []byte{0xff, 0xff, 0xff, 0xfe}, func(p Protocol) error {
+			_, err := p.ReadBinary()
+			return err
+		}, ErrMalformed},
+		{"truncated string", []byte{0, 0, 0, 5, 'a'}, func(p Protocol) error {
+			_, err := p.ReadString()
+			return err
+		}, io.ErrUnexpectedEOF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.read(NewBinaryReader(wireread.NewSafeReader(tt.data)))
+			if !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,345 @@
+package thrift
+
+import (
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/nemohan/wireread"
+)
+
+// Compact protocol message header constants.
+const (
+	compactProtocolID  = 0x82
+	compactVersion     = 1
+	compactVersionMask = 0x1f
+	compactTypeShift   = 5
+)
+
+// Compact protocol type codes, as they appear in field headers and
+// collection headers.
+const (
+	compactStop      = 0
+	compactTrue      = 1
+	compactFalse     = 2
+	compactByte      = 3
+	compactI16       = 4
+	compactI32       = 5
+	compactI64       = 6
+	compactDouble    = 7
+	compactBinary    = 8
+	compactList      = 9
+	compactSet       = 10
+	compactMap       = 11
+	compactStruct    = 12
+	compactUUID      = 13
+	compactTypeCount = 14
+)
+
+var compactTypes = [compactTypeCount]Type{
+	compactStop:   Stop,
+	compactTrue:   Bool,
+	compactFalse:  Bool,
+	compactByte:   Byte,
+	compactI16:    I16,
+	compactI32:    I32,
+	compactI64:    I64,
+	compactDouble: Double,
+	compactBinary: String,
+	compactList:   List,
+	compactSet:    Set,
+	compactMap:    Map,
+	compactStruct: Struct,
+	compactUUID:   UUID,
+}
+
+// CompactReader reads the TCompactProtocol encoding, in which integers are
+// zigzag varints, field ids are deltas from the previous field, and bool
+// fields carry their value in the field header.
+type CompactReader struct {
+	reader
+
+	// lastField is the id of the previous field in the current struct and
+	// stack holds those of the enclosing structs.
+	lastField int16
+	stack     []int16
+
+	// boolField holds the value of a bool field whose header has been read.
+	boolField   bool
+	pendingBool bool
+}
+
+// NewCompactReader creates a CompactReader reading from r.
+func NewCompactReader(r wireread.Reader) *CompactReader {
+	return &CompactReader{reader: newReader(r)}
+}
+
+// ReadMessageBegin reads the protocol id, version and message type byte,
+// sequence id and method name. The sequence id is a plain varint, not a
+// zigzag one.
+func (p *CompactReader) ReadMessageBegin() (MessageHeader, error) {
+	off := p.Offset()
+	b, err := p.r.ReadBytes(2)
+	if err != nil {
+		return MessageHeader{}, err
+	}
+	if b[0] != compactProtocolID || b[1]&compactVersionMask != compactVersion {
+		return MessageHeader{}, fmt.Errorf("%w: header 0x%02x%02x at offset %d", ErrBadVersion, b[0], b[1], off)
+	}
+	h := MessageHeader{Type: MessageType(b[1] >> compactTypeShift)}
+	seq, err := p.r.ReadUvarint()
+	if err != nil {
+		return MessageHeader{}, p.varintError(off+2, err)
+	}
+	if seq > math.MaxUint32 {
+		return MessageHeader{}, p.errorf(off+2, "sequence id %d", seq)
+	}
+	h.SeqID = int32(seq)
+	if h.Name, err = p.ReadString(); err != nil {
+		return MessageHeader{}, err
+	}
+	return h, nil
+}
+
+// ReadStructBegin starts a struct, saving the field id state of the
+// enclosing one.
+func (p *CompactReader) ReadStructBegin() error {
+	if len(p.stack) >= MaxDepth {
+		return ErrDepthExceeded
+	}
+	p.stack = append(p.stack, p.lastField)
+	p.lastField = 0
+	return nil
+}
+
+// ReadStructEnd ends a struct, restoring the field id state of the
+// enclosing one.
+func (p *CompactReader) ReadStructEnd() error {
+	if len(p.stack) == 0 {
+		return fmt.Errorf("%w: ReadStructEnd without ReadStructBegin", ErrMalformed)
+	}
+	p.lastField = p.stack[len(p.stack)-1]
+	p.stack = p.stack[:len(p.stack)-1]
+	return nil
+}
+
+// ReadFieldBegin reads a field header. The high nibble is the field id
+// delta from the previous field, or zero if a zigzag varint id follows; the
+// low nibble is the type. For bool fields the type is the value, which the
+// next ReadBool returns.
+func (p *CompactReader) ReadFieldBegin() (FieldHeader, error) {
+	off := p.Offset()
+	b, err := p.r.ReadByte()
+	if err != nil {
+		return FieldHeader{}, err
+	}
+	if b == compactStop {
+		return FieldHeader{}, nil
+	}
+	t, err := p.compactType(off, b&0x0f)
+	if err != nil {
+		return FieldHeader{}, err
+	}
+	id := p.lastField + int16(b>>4)
+	if b>>4 == 0 {
+		v, err := p.readVarint32()
+		if err != nil {
+			return FieldHeader{}, err
+		}
+		if int64(int16(v)) != v {
+			return FieldHeader{}, p.errorf(off, "field id %d", v)
+		}
+		id = int16(v)
+	}
+	p.lastField = id
+	if t == Bool {
+		p.pendingBool = true
+		p.boolField = b&0x0f == compactTrue
+	}
+	return FieldHeader{Type: t, ID: id}, nil
+}
+
+// ReadListBegin reads a list header: the size in the high nibble, or 15 and
+// a varint size, and the element type in the low nibble.
+func (p *CompactReader) ReadListBegin() (ListHeader, error) {
+	off := p.Offset()
+	b, err := p.r.ReadByte()
+	if err != nil {
+		return ListHeader{}, err
+	}
+	t, err := p.compactType(off, b&0x0f)
+	if err != nil {
+		return ListHeader{}, err
+	}
+	n := int64(b >> 4)
+	if n == 15 {
+		v, err := p.r.ReadUvarint()
+		if err != nil {
+			return ListHeader{}, p.varintError(off, err)
+		}
+		if v > math.MaxInt32 {
+			return ListHeader{}, p.errorf(off, "collection size %d", v)
+		}
+		n = int64(v)
+	}
+	if err := p.checkSize(off, n, 1); err != nil {
+		return ListHeader{}, err
+	}
+	return ListHeader{Elem: t, Size: int(n)}, nil
+}
+
+// ReadSetBegin reads a set header, which is encoded like a list header.
+func (p *CompactReader) ReadSetBegin() (ListHeader, error) {
+	return p.ReadListBegin()
+}
+
+// ReadMapBegin reads a varint size and, for non-empty maps, a byte with the
+// key type in the high nibble and the value type in the low nibble.
+func (p *CompactReader) ReadMapBegin() (MapHeader, error) {
+	off := p.Offset()
+	v, err := p.r.ReadUvarint()
+	if err != nil {
+		return MapHeader{}, p.varintError(off, err)
+	}
+	if v > math.MaxInt32 {
+		return MapHeader{}, p.errorf(off, "collection size %d", v)
+	}
+	if v == 0 {
+		return MapHeader{}, nil
+	}
+	kv, err := p.r.ReadByte()
+	if err != nil {
+		return MapHeader{}, err
+	}
+	var h MapHeader
+	if h.Key, err = p.compactType(off, kv>>4); err != nil {
+		return MapHeader{}, err
+	}
+	if h.Value, err = p.compactType(off, kv&0x0f); err != nil {
+		return MapHeader{}, err
+	}
+	if err := p.checkSize(off, int64(v), 2); err != nil {
+		return MapHeader{}, err
+	}
+	h.Size = int(v)
+	return h, nil
+}
+
+// ReadBool returns the value of a bool field whose header was just read,
+// or reads a collection element stored as one byte.
+func (p *CompactReader) ReadBool() (bool, error) {
+	if p.pendingBool {
+		p.pendingBool = false
+		return p.boolField, nil
+	}
+	off := p.Offset()
+	b, err := p.r.ReadByte()
+	if err != nil {
+		return false, err
+	}
+	switch b {
+	case compactTrue:
+		return true, nil
+	case compactFalse, 0:
+		return false, nil
+	}
+	return false, p.errorf(off, "bool value %d", b)
+}
+
+// ReadI8 reads a byte.
+func (p *CompactReader) ReadI8() (int8, error) {
+	b, err := p.r.ReadByte()
+	return int8(b), err
+}
+
+// ReadI16 reads a zigzag varint i16.
+func (p *CompactReader) ReadI16() (int16, error) {
+	off := p.Offset()
+	v, err := p.readVarint32()
+	if err != nil {
+		return 0, err
+	}
+	if int64(int16(v)) != v {
+		return 0, p.errorf(off, "i16 value %d", v)
+	}
+	return int16(v), nil
+}
+
+// ReadI32 reads a zigzag varint i32.
+func (p *CompactReader) ReadI32() (int32, error) {
+	v, err := p.readVarint32()
+	return int32(v), err
+}
+
+// ReadI64 reads a zigzag varint i64.
+func (p *CompactReader) ReadI64() (int64, error) {
+	off := p.Offset()
+	u, err := p.r.ReadUvarint()
+	if err != nil {
+		return 0, p.varintError(off, err)
+	}
+	return zigzag(u), nil
+}
+
+// ReadDouble reads a little-endian IEEE 754 double. Unlike the binary
+// protocol, the compact protocol stores doubles little-endian.
+func (p *CompactReader) ReadDouble() (float64, error) {
+	v, err := p.r.ReadUint64LE()
+	return math.Float64frombits(v), err
+}
+
+// ReadBinary reads a varint length followed by that many bytes.
+func (p *CompactReader) ReadBinary() ([]byte, error) {
+	off := p.Offset()
+	n, err := p.r.ReadUvarint()
+	if err != nil {
+		return nil, p.varintError(off, err)
+	}
+	if n > math.MaxInt32 {
+		return nil, p.errorf(off, "length %d", n)
+	}
+	if int(n) > len(p.r.Bytes()) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return p.r.ReadBytes(int(n))
+}
+
+// ReadString reads a string encoded like binary.
+func (p *CompactReader) ReadString() (string, error) {
+	b, err := p.ReadBinary()
+	return string(b), err
+}
+
+// readVarint32 reads a zigzag varint that must fit in 32 bits. It returns
+// an int64 so callers narrowing further can check the range.
+func (p *CompactReader) readVarint32() (int64, error) {
+	off := p.Offset()
+	u, err := p.r.ReadUvarint()
+	if err != nil {
+		return 0, p.varintError(off, err)
+	}
+	if u > math.MaxUint32 {
+		return 0, p.errorf(off, "varint %d overflows 32 bits", u)
+	}
+	return zigzag(u), nil
+}
+
+func (p *CompactReader) compactType(off int, c byte) (Type, error) {
+	if c == compactStop || c >= compactTypeCount {
+		return 0, p.errorf(off, "invalid compact type %d", c)
+	}
+	return compactTypes[c], nil
+}
+
+// varintError keeps io.ErrUnexpectedEOF for truncated varints and reports
+// overlong ones as malformed.
+func (p *CompactReader) varintError(off int, err error) error {
+	if err == io.ErrUnexpectedEOF || err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return p.errorf(off, "varint overflow")
+}
+
+func zigzag(u uint64) int64 {
+	return int64(u>>1) ^ -int64(u&1)
+}
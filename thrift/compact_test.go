@@ -0,0 +1,127 @@
+package thrift
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nemohan/wireread"
+)
+
+// sampleCompact is sampleBinary in the compact encoding, with the last two
+// fields renumbered to exercise the long field header form.
+func sampleCompact() []byte {
+	b := []byte{
+		0x82, 0x21, 0x07, 0x04, 'p', 'i', 'n', 'g', // call, seq 7, "ping"
+		0x15, 0x54, // 1: i32 42
+		0x18, 0x02, 'h', 'i', // 2: "hi"
+		0x19, 0x24, 0x02, 0x03, // 3: list<i16> [1, -2]
+		0x1b, 0x01, 0x81, 0x01, 'a', 0x01, // 4: {"a": true}
+		0x1c, 0x17, 0, 0, 0, 0, 0, 0, 0xf8, 0x3f, 0x00, // 5: {1: 1.5}
+		0x12,       // 6: false
+		0x16, 0x01, // 7: i64 -1
+		0x13, 0xfe, // 8: byte -2
+		0x1d, // 9: uuid
+	}
+	b = append(b, testUUID[:]...)
+	return append(b, 0x00)
+}
+
+func TestCompactReader(t *testing.T) {
+	data := sampleCompact()
+	p := NewCompactReader(wireread.NewSafeReader(data))
+	checkSample(t, p)
+	if p.Offset() != len(data) {
+		t.Errorf("Offset() = %d, want %d", p.Offset(), len(data))
+	}
+}
+
+func TestCompactReader_LongForms(t *testing.T) {
+	// Field 300 as i16 with the explicit id form, then field 301 as a true
+	// bool by delta, then a 20-element byte list with a varint size.
+	data := []byte{0x04, 0xd8, 0x04, 0xf3, 0x03, 0x11, 0x19, 0xf3, 20}
+	for i := 0; i < 20; i++ {
+		data = append(data, byte(i))
+	}
+	p := NewCompactReader(wireread.NewSafeReader(data))
+	p.ReadStructBegin()
+
+	f, err := p.ReadFieldBegin()
+	if err != nil || f != (FieldHeader{Type: I16, ID: 300}) {
+		t.Fatalf("ReadFieldBegin() = %+v, %v", f, err)
+	}
+	if v, err := p.ReadI16(); v != -250 || err != nil {
+		t.Errorf("ReadI16() = %d, %v, want -250", v, err)
+	}
+	f, _ = p.ReadFieldBegin()
+	if v, err := p.ReadBool(); f != (FieldHeader{Type: Bool, ID: 301}) || !v || err != nil {
+		t.Errorf("bool field = %+v, %v, %v", f, v, err)
+	}
+	f, _ = p.ReadFieldBegin()
+	l, err := p.ReadListBegin()
+	if f.ID != 302 || l != (ListHeader{Elem: Byte, Size: 20}) || err != nil {
+		t.Fatalf("list = %+v %+v, %v", f, l, err)
+	}
+	for i := 0; i < l.Size; i++ {
+		if v, _ := p.ReadI8(); v != int8(i) {
+			t.Errorf("element %d = %d", i, v)
+		}
+	}
+}
+
+func TestCompactReader_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		read func(*CompactReader) error
+		want error
+	}{
+		{"bad protocol id", []byte{0x80, 0x21, 0, 0}, func(p *CompactReader) error {
+			_, err := p.ReadMessageBegin()
+			return err
+		}, ErrBadVersion},
+		{"bad version", []byte{0x82, 0x22, 0, 0}, func(p *CompactReader) error {
+			_, err := p.ReadMessageBegin()
+			return err
+		}, ErrBadVersion},
+		{"invalid field type", []byte{0x1e}, func(p *CompactReader) error {
+			_, err := p.ReadFieldBegin()
+			return err
+		}, ErrMalformed},
+		{"field id overflow", []byte{0x05, 0x80, 0x80, 0x04}, func(p *CompactReader) error {
+			_, err := p.ReadFieldBegin()
+			return err
+		}, ErrMalformed},
+		{"list larger than data", []byte{0xf5, 0x10, 0x00}, func(p *CompactReader) error {
+			_, err := p.ReadListBegin()
+			return err
+		}, ErrMalformed},
+		{"map larger than data", []byte{0x02, 0x88, 0x00, 0x00}, func(p *CompactReader) error {
+			_, err := p.ReadMapBegin()
+			return err
+		}, ErrMalformed},
+		{"bool element", []byte{0x03}, func(p *CompactReader) error {
+			_, err := p.ReadBool()
+			return err
+		}, ErrMalformed},
+		{"i32 overflow", []byte{0x80, 0x80, 0x80, 0x80, 0x20}, func(p *CompactReader) error {
+			_, err := p.ReadI32()
+			return err
+		}, ErrMalformed},
+		{"varint overflow", []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}, func(p *CompactReader) error {
+			_, err := p.ReadI64()
+			return err
+		}, ErrMalformed},
+		{"unbalanced struct end", nil, func(p *CompactReader) error {
+			return p.ReadStructEnd()
+		}, ErrMalformed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.read(NewCompactReader(wireread.NewSafeReader(tt.data)))
+			if !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package thrift
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// ErrFrameTooLarge is returned by FrameReader for frames longer than the
+// configured limit.
+var ErrFrameTooLarge = errors.New("thrift: frame too large")
+
+// DefaultMaxFrameSize is the default frame size limit of the reference
+// libraries.
+const DefaultMaxFrameSize = 16384000
+
+// frameChunk bounds how far Next allocates ahead of the bytes that have
+// arrived, so that a length prefix announcing a large frame on a stream that
+// then stalls or ends does not cost the whole announced size.
+const frameChunk = 64 << 10
+
+// FrameReader splits a TFramedTransport stream, in which each message is
+// preceded by its length as a big-endian i32.
+type FrameReader struct {
+	r       io.Reader
+	maxSize int
+	off     int64
+	buf     []byte
+}
+
+// NewFrameReader creates a FrameReader reading from r, with
+// DefaultMaxFrameSize as the size limit.
+func NewFrameReader(r io.Reader) *FrameReader {
+	return &FrameReader{r: r, maxSize: DefaultMaxFrameSize}
+}
+
+// SetMaxFrameSize limits the size of a frame. Larger frames are rejected
+// with ErrFrameTooLarge before they are read.
+func (fr *FrameReader) SetMaxFrameSize(n int) {
+	fr.maxSize = n
+}
+
+// Offset returns the number of bytes consumed from the stream.
+func (fr *FrameReader) Offset() int64 {
+	return fr.off
+}
+
+// Next reads the next frame and returns its payload without the length
+// prefix. It returns io.EOF when the stream ends cleanly between frames and
+// io.ErrUnexpectedEOF when it ends inside one. The returned slice is reused
+// by the following call.
+func (fr *FrameReader) Next() ([]byte, error) {
+	var hdr [4]byte
+	n, err := io.ReadFull(fr.r, hdr[:])
+	fr.off += int64(n)
+	if err != nil {
+		return nil, err
+	}
+	size := int32(binary.BigEndian.Uint32(hdr[:]))
+	if size < 0 {
+		return nil, fmt.Errorf("%w: negative frame size at offset %d", ErrMalformed, fr.off-4)
+	}
+	if int64(size) > int64(fr.maxSize) {
+		return nil, fmt.Errorf("%w: %d bytes at offset %d", ErrFrameTooLarge, size, fr.off-4)
+	}
+	frame := fr.buf[:0]
+	for len(frame) < int(size) {
+		m := min(int(size)-len(frame), max(frameChunk, len(frame)))
+		end := len(frame)
+		frame = slices.Grow(frame, m)[:end+m]
+		n, err = io.ReadFull(fr.r, frame[end:])
+		fr.off += int64(n)
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	fr.buf = frame
+	return frame, nil
+}
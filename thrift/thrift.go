@@ -0,0 +1,269 @@
+// Package thrift reads Apache Thrift data without IDL-generated code.
+//
+// BinaryReader and CompactReader implement the TBinaryProtocol and
+// TCompactProtocol encodings behind the same token-level Protocol
+// interface: a caller asks for a message header, struct fields, collection
+// headers and scalar values in the order the IDL would, and uses Skip for
+// anything it does not care about. FrameReader splits a TFramedTransport
+// stream into the frames the protocol readers consume.
+//
+// Example usage, listing the fields of a struct:
+//
+//	p := thrift.NewCompactReader(wireread.NewSafeReader(footer))
+//	if err := p.ReadStructBegin(); err != nil {
+//	    log.Fatal(err)
+//	}
+//	for {
+//	    f, err := p.ReadFieldBegin()
+//	    if err != nil {
+//	        log.Fatal(err)
+//	    }
+//	    if f.Type == thrift.Stop {
+//	        break
+//	    }
+//	    fmt.Println(f.ID, f.Type)
+//	    if err := thrift.Skip(p, f.Type); err != nil {
+//	        log.Fatal(err)
+//	    }
+//	}
+//	p.ReadStructEnd()
+package thrift
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrMalformed is returned for unknown types, negative or oversized
+	// lengths and other encoding violations.
+	ErrMalformed = errors.New("thrift: malformed data")
+
+	// ErrBadVersion is returned by ReadMessageBegin for an unsupported
+	// protocol identifier or version.
+	ErrBadVersion = errors.New("thrift: bad protocol version")
+
+	// ErrDepthExceeded is returned by Skip when structs and collections
+	// nest deeper than MaxDepth.
+	ErrDepthExceeded = errors.New("thrift: nesting too deep")
+)
+
+// MaxDepth limits the nesting of structs and collections walked by Skip,
+// the same as the default recursion limit of the reference libraries.
+const MaxDepth = 64
+
+// Type is a Thrift field or element type, with the values used by
+// TBinaryProtocol.
+type Type byte
+
+// Types. String covers both string and binary fields.
+const (
+	Stop   Type = 0
+	Void   Type = 1
+	Bool   Type = 2
+	Byte   Type = 3
+	Double Type = 4
+	I16    Type = 6
+	I32    Type = 8
+	I64    Type = 10
+	String Type = 11
+	Struct Type = 12
+	Map    Type = 13
+	Set    Type = 14
+	List   Type = 15
+	UUID   Type = 16
+)
+
+var typeNames = [...]string{
+	Stop:   "stop",
+	Void:   "void",
+	Bool:   "bool",
+	Byte:   "byte",
+	Double: "double",
+	I16:    "i16",
+	I32:    "i32",
+	I64:    "i64",
+	String: "string",
+	Struct: "struct",
+	Map:    "map",
+	Set:    "set",
+	List:   "list",
+	UUID:   "uuid",
+}
+
+func (t Type) String() string {
+	if int(t) < len(typeNames) && typeNames[t] != "" {
+		return typeNames[t]
+	}
+	return fmt.Sprintf("Type(%d)", byte(t))
+}
+
+// isValue reports whether t can be the type of a field or element.
+func (t Type) isValue() bool {
+	return t != Stop && t != Void && int(t) < len(typeNames) && typeNames[t] != ""
+}
+
+// MessageType is the kind of an RPC message.
+type MessageType byte
+
+// Message types.
+const (
+	Call      MessageType = 1
+	Reply     MessageType = 2
+	Exception MessageType = 3
+	Oneway    MessageType = 4
+)
+
+func (t MessageType) String() string {
+	switch t {
+	case Call:
+		return "call"
+	case Reply:
+		return "reply"
+	case Exception:
+		return "exception"
+	case Oneway:
+		return "oneway"
+	}
+	return fmt.Sprintf("MessageType(%d)", byte(t))
+}
+
+// MessageHeader is the envelope of an RPC message. The body that follows
+// is a struct: the call arguments, or the result with the return value in
+// field 0 and declared exceptions in the other fields.
+type MessageHeader struct {
+	Name  string
+	Type  MessageType
+	SeqID int32
+}
+
+// FieldHeader introduces a struct field. A Type of Stop marks the end of
+// the struct, and ID is then zero.
+type FieldHeader struct {
+	Type Type
+	ID   int16
+}
+
+// ListHeader introduces a list or set of Size elements of type Elem.
+type ListHeader struct {
+	Elem Type
+	Size int
+}
+
+// MapHeader introduces a map of Size key-value pairs.
+type MapHeader struct {
+	Key   Type
+	Value Type
+	Size  int
+}
+
+// Protocol is a token-level Thrift reader. Calls must follow the structure
+// of the data: ReadStructBegin and ReadStructEnd around each struct's
+// fields, and the element values after a collection header. The end calls
+// for fields, collections and messages are no-ops in both encodings and
+// are left out.
+//
+// Collection sizes are checked against the remaining data, so a header
+// cannot make a caller loop far beyond the input.
+type Protocol interface {
+	ReadMessageBegin() (MessageHeader, error)
+	ReadStructBegin() error
+	ReadStructEnd() error
+	ReadFieldBegin() (FieldHeader, error)
+	ReadListBegin() (ListHeader, error)
+	ReadSetBegin() (ListHeader, error)
+	ReadMapBegin() (MapHeader, error)
+
+	ReadBool() (bool, error)
+	ReadI8() (int8, error)
+	ReadI16() (int16, error)
+	ReadI32() (int32, error)
+	ReadI64() (int64, error)
+	ReadDouble() (float64, error)
+	// ReadBinary returns a slice of the input; ReadString copies.
+	ReadBinary() ([]byte, error)
+	ReadString() (string, error)
+	ReadUUID() ([16]byte, error)
+
+	// Offset returns the number of bytes consumed so far.
+	Offset() int
+}
+
+// Skip reads and discards a value of type t, including everything nested
+// in it.
+func Skip(p Protocol, t Type) error {
+	return skip(p, t, MaxDepth)
+}
+
+func skip(p Protocol, t Type, depth int) error {
+	if depth == 0 {
+		return ErrDepthExceeded
+	}
+	var err error
+	switch t {
+	case Bool:
+		_, err = p.ReadBool()
+	case Byte:
+		_, err = p.ReadI8()
+	case I16:
+		_, err = p.ReadI16()
+	case I32:
+		_, err = p.ReadI32()
+	case I64:
+		_, err = p.ReadI64()
+	case Double:
+		_, err = p.ReadDouble()
+	case String:
+		_, err = p.ReadBinary()
+	case UUID:
+		_, err = p.ReadUUID()
+	case Struct:
+		if err = p.ReadStructBegin(); err != nil {
+			return err
+		}
+		for {
+			f, err := p.ReadFieldBegin()
+			if err != nil {
+				return err
+			}
+			if f.Type == Stop {
+				break
+			}
+			if err := skip(p, f.Type, depth-1); err != nil {
+				return err
+			}
+		}
+		err = p.ReadStructEnd()
+	case Map:
+		h, err := p.ReadMapBegin()
+		if err != nil {
+			return err
+		}
+		for i := 0; i < h.Size; i++ {
+			if err := skip(p, h.Key, depth-1); err != nil {
+				return err
+			}
+			if err := skip(p, h.Value, depth-1); err != nil {
+				return err
+			}
+		}
+	case Set, List:
+		var h ListHeader
+		if t == Set {
+			h, err = p.ReadSetBegin()
+		} else {
+			h, err = p.ReadListBegin()
+		}
+		if err != nil {
+			return err
+		}
+		for i := 0; i < h.Size; i++ {
+			if err := skip(p, h.Elem, depth-1); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("%w: cannot skip type %v at offset %d", ErrMalformed, t, p.Offset())
+	}
+	return err
+}
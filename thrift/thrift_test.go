@@ -0,0 +1,108 @@
+package thrift
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"runtime"
+	"testing"
+
+	"github.com/nemohan/wireread"
+)
+
+func TestSkip(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		p    func(wireread.Reader) Protocol
+	}{
+		{"binary", sampleBinary(), func(r wireread.Reader) Protocol { return NewBinaryReader(r) }},
+		{"compact", sampleCompact(), func(r wireread.Reader) Protocol { return NewCompactReader(r) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := tt.p(wireread.NewSafeReader(tt.data))
+			if _, err := p.ReadMessageBegin(); err != nil {
+				t.Fatal(err)
+			}
+			if err := Skip(p, Struct); err != nil {
+				t.Fatalf("Skip() error = %v", err)
+			}
+			if p.Offset() != len(tt.data) {
+				t.Errorf("Offset() = %d, want %d", p.Offset(), len(tt.data))
+			}
+		})
+	}
+}
+
+func TestSkip_Depth(t *testing.T) {
+	// Lists of one list each, nested past MaxDepth.
+	data := bytes.Repeat([]byte{0x19}, MaxDepth+1)
+	p := NewCompactReader(wireread.NewSafeReader(data))
+	if err := Skip(p, List); err != ErrDepthExceeded {
+		t.Errorf("Skip() error = %v, want ErrDepthExceeded", err)
+	}
+}
+
+func TestFrameReader(t *testing.T) {
+	stream := []byte{0, 0, 0, 3, 'a', 'b', 'c', 0, 0, 0, 0, 0, 0, 0, 1, 'x'}
+	fr := NewFrameReader(bytes.NewReader(stream))
+	for _, want := range []string{"abc", "", "x"} {
+		got, err := fr.Next()
+		if err != nil || string(got) != want {
+			t.Fatalf("Next() = %q, %v, want %q", got, err, want)
+		}
+	}
+	if _, err := fr.Next(); err != io.EOF {
+		t.Errorf("Next() at end error = %v, want io.EOF", err)
+	}
+	if fr.Offset() != int64(len(stream)) {
+		t.Errorf("Offset() = %d, want %d", fr.Offset(), len(stream))
+	}
+
+	fr = NewFrameReader(bytes.NewReader(stream[:5]))
+	if _, err := fr.Next(); err != io.ErrUnexpectedEOF {
+		t.Errorf("Next(truncated) error = %v, want io.ErrUnexpectedEOF", err)
+	}
+
+	fr = NewFrameReader(bytes.NewReader(stream))
+	fr.SetMaxFrameSize(2)
+	if _, err := fr.Next(); !errors.Is(err, ErrFrameTooLarge) {
+		t.Errorf("Next(large) error = %v, want ErrFrameTooLarge", err)
+	}
+
+	fr = NewFrameReader(bytes.NewReader([]byte{0x80, 0, 0, 0}))
+	if _, err := fr.Next(); !errors.Is(err, ErrMalformed) {
+		t.Errorf("Next(negative) error = %v, want ErrMalformed", err)
+	}
+}
+
+func TestFrameReader_Large(t *testing.T) {
+	// A prefix announcing the largest allowed frame must not allocate it
+	// before the frame arrives.
+	stream := binary.BigEndian.AppendUint32(nil, DefaultMaxFrameSize)
+	stream = append(stream, make([]byte, 10)...)
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	_, err := NewFrameReader(bytes.NewReader(stream)).Next()
+	runtime.ReadMemStats(&after)
+	if err != io.ErrUnexpectedEOF {
+		t.Errorf("Next(truncated) error = %v, want io.ErrUnexpectedEOF", err)
+	}
+	if n := after.TotalAlloc - before.TotalAlloc; n > 1<<20 {
+		t.Errorf("Next(truncated) allocated %d bytes", n)
+	}
+
+	// A frame spanning several chunks is still read whole.
+	frame := bytes.Repeat([]byte{'f'}, 300<<10)
+	stream = append(binary.BigEndian.AppendUint32(nil, uint32(len(frame))), frame...)
+	fr := NewFrameReader(bytes.NewReader(stream))
+	if got, err := fr.Next(); err != nil || !bytes.Equal(got, frame) {
+		t.Errorf("Next() = %d bytes, %v; want %d bytes", len(got), err, len(frame))
+	}
+	if fr.Offset() != int64(len(stream)) {
+		t.Errorf("Offset() = %d, want %d", fr.Offset(), len(stream))
+	}
+}
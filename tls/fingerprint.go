@@ -0,0 +1,191 @@
+package tls
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// JA3 returns the JA3 fingerprint string of the ClientHello:
+//
+//	SSLVersion,Ciphers,Extensions,EllipticCurves,EllipticCurvePointFormats
+//
+// with list elements in wire order, joined by '-' and GREASE values removed.
+func (c *ClientHello) JA3() string {
+	var b strings.Builder
+	b.WriteString(strconv.Itoa(int(c.Version)))
+	b.WriteByte(',')
+	writeDecimalList(&b, c.CipherSuites)
+	b.WriteByte(',')
+	exts := make([]uint16, len(c.Extensions))
+	for i, e := range c.Extensions {
+		exts[i] = e.Type
+	}
+	writeDecimalList(&b, exts)
+	b.WriteByte(',')
+	writeDecimalList(&b, c.SupportedGroups)
+	b.WriteByte(',')
+	for i, f := range c.ECPointFormats {
+		if i > 0 {
+			b.WriteByte('-')
+		}
+		b.WriteString(strconv.Itoa(int(f)))
+	}
+	return b.String()
+}
+
+// JA3Hash returns the MD5 digest of JA3 in hex, the form usually logged.
+func (c *ClientHello) JA3Hash() string {
+	sum := md5.Sum([]byte(c.JA3()))
+	return hex.EncodeToString(sum[:])
+}
+
+func writeDecimalList(b *strings.Builder, list []uint16) {
+	first := true
+	for _, v := range list {
+		if isGREASE(v) {
+			continue
+		}
+		if !first {
+			b.WriteByte('-')
+		}
+		first = false
+		b.WriteString(strconv.Itoa(int(v)))
+	}
+}
+
+// JA4 returns the JA4 fingerprint of the ClientHello as seen over TCP, for
+// example "t13d1516h2_8daaf6152771_e5627efa2ab1":
+//
+//   - protocol, version, SNI presence ('d' or 'i'), cipher and extension
+//     counts, and the first and last characters of the first ALPN value;
+//   - the truncated SHA-256 of the sorted cipher suites;
+//   - the truncated SHA-256 of the sorted extensions, without SNI and ALPN,
+//     followed by the signature algorithms in wire order.
+//
+// GREASE values are ignored throughout.
+func (c *ClientHello) JA4() string {
+	ciphers := withoutGREASE(c.CipherSuites)
+	var exts []uint16
+	for _, e := range c.Extensions {
+		if !isGREASE(e.Type) {
+			exts = append(exts, e.Type)
+		}
+	}
+
+	var b strings.Builder
+	b.WriteByte('t')
+	b.WriteString(ja4Version(c))
+	if c.ServerName != "" {
+		b.WriteByte('d')
+	} else {
+		b.WriteByte('i')
+	}
+	writeCount(&b, len(ciphers))
+	writeCount(&b, len(exts))
+	b.WriteString(ja4ALPN(c.ALPNProtocols))
+
+	b.WriteByte('_')
+	slices.Sort(ciphers)
+	b.WriteString(ja4Hash(hexList(ciphers)))
+
+	b.WriteByte('_')
+	exts = slices.DeleteFunc(exts, func(t uint16) bool {
+		return t == ExtServerName || t == ExtALPN
+	})
+	slices.Sort(exts)
+	input := hexList(exts)
+	if sigs := withoutGREASE(c.SignatureAlgorithms); len(sigs) > 0 {
+		input += "_" + hexList(sigs)
+	}
+	if len(exts) == 0 {
+		input = ""
+	}
+	b.WriteString(ja4Hash(input))
+	return b.String()
+}
+
+func ja4Version(c *ClientHello) string {
+	v := c.Version
+	for _, sv := range c.SupportedVersions {
+		if !isGREASE(sv) && sv > v {
+			v = sv
+		}
+	}
+	switch v {
+	case VersionTLS13:
+		return "13"
+	case VersionTLS12:
+		return "12"
+	case VersionTLS11:
+		return "11"
+	case VersionTLS10:
+		return "10"
+	case VersionSSL30:
+		return "s3"
+	}
+	return "00"
+}
+
+// ja4ALPN returns the first and last characters of the first ALPN value, or
+// of its hex form when either is not alphanumeric.
+func ja4ALPN(protos []string) string {
+	if len(protos) == 0 || protos[0] == "" {
+		return "00"
+	}
+	p := protos[0]
+	first, last := p[0], p[len(p)-1]
+	if !isAlnum(first) || !isAlnum(last) {
+		h := hex.EncodeToString([]byte(p))
+		return h[:1] + h[len(h)-1:]
+	}
+	return string([]byte{first, last})
+}
+
+func isAlnum(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func writeCount(b *strings.Builder, n int) {
+	if n > 99 {
+		n = 99
+	}
+	if n < 10 {
+		b.WriteByte('0')
+	}
+	b.WriteString(strconv.Itoa(n))
+}
+
+// ja4Hash returns the first 12 hex digits of the SHA-256 of s, or twelve
+// zeros for an empty list.
+func ja4Hash(s string) string {
+	if s == "" {
+		return "000000000000"
+	}
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:6])
+}
+
+func hexList(list []uint16) string {
+	var b strings.Builder
+	for i, v := range list {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(hex.EncodeToString([]byte{byte(v >> 8), byte(v)}))
+	}
+	return b.String()
+}
+
+func withoutGREASE(list []uint16) []uint16 {
+	out := make([]uint16, 0, len(list))
+	for _, v := range list {
+		if !isGREASE(v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,141 @@
+package tls
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/nemohan/wireread"
+)
+
+// HandshakeHeaderLen is the size of the handshake message header.
+const HandshakeHeaderLen = 4
+
+// MaxHandshakeLen limits the size of a reassembled handshake message.
+const MaxHandshakeLen = 1 << 18
+
+// HandshakeType is the type of a handshake message.
+type HandshakeType uint8
+
+// Handshake message types.
+const (
+	HandshakeClientHello         HandshakeType = 1
+	HandshakeServerHello         HandshakeType = 2
+	HandshakeNewSessionTicket    HandshakeType = 4
+	HandshakeEncryptedExtensions HandshakeType = 8
+	HandshakeCertificate         HandshakeType = 11
+	HandshakeServerKeyExchange   HandshakeType = 12
+	HandshakeCertificateRequest  HandshakeType = 13
+	HandshakeServerHelloDone     HandshakeType = 14
+	HandshakeCertificateVerify   HandshakeType = 15
+	HandshakeClientKeyExchange   HandshakeType = 16
+	HandshakeFinished            HandshakeType = 20
+)
+
+var handshakeNames = map[HandshakeType]string{
+	HandshakeClientHello:         "client_hello",
+	HandshakeServerHello:         "server_hello",
+	HandshakeNewSessionTicket:    "new_session_ticket",
+	HandshakeEncryptedExtensions: "encrypted_extensions",
+	HandshakeCertificate:         "certificate",
+	HandshakeServerKeyExchange:   "server_key_exchange",
+	HandshakeCertificateRequest:  "certificate_request",
+	HandshakeServerHelloDone:     "server_hello_done",
+	HandshakeCertificateVerify:   "certificate_verify",
+	HandshakeClientKeyExchange:   "client_key_exchange",
+	HandshakeFinished:            "finished",
+}
+
+func (t HandshakeType) String() string {
+	if s, ok := handshakeNames[t]; ok {
+		return s
+	}
+	return fmt.Sprintf("handshake_type(%d)", uint8(t))
+}
+
+// Handshake is a complete handshake message.
+type Handshake struct {
+	Type HandshakeType
+	Body []byte
+}
+
+// HandshakeReader reassembles handshake messages from the fragments of
+// consecutive handshake records. Messages may span records and a record may
+// hold several messages.
+type HandshakeReader struct {
+	buf []byte
+	off int
+}
+
+// AddRecord appends the fragment of a handshake record. The fragment is
+// copied.
+func (h *HandshakeReader) AddRecord(rec Record) error {
+	if rec.Type != ContentHandshake {
+		return fmt.Errorf("%w: %s record", ErrUnexpectedMessage, rec.Type)
+	}
+	if h.off == len(h.buf) {
+		h.buf, h.off = h.buf[:0], 0
+	}
+	h.buf = append(h.buf, rec.Fragment...)
+	return nil
+}
+
+// Next returns the next complete handshake message, or ErrNeedMore when the
+// buffered fragments end inside one. Body is only valid until the next call
+// to AddRecord.
+func (h *HandshakeReader) Next() (Handshake, error) {
+	data := h.buf[h.off:]
+	if len(data) < HandshakeHeaderLen {
+		return Handshake{}, ErrNeedMore
+	}
+	n := int(data[1])<<16 | int(data[2])<<8 | int(data[3])
+	if n > MaxHandshakeLen {
+		return Handshake{}, fmt.Errorf("%w: handshake message of %d bytes", ErrMalformed, n)
+	}
+	if len(data) < HandshakeHeaderLen+n {
+		return Handshake{}, ErrNeedMore
+	}
+	h.off += HandshakeHeaderLen + n
+	return Handshake{Type: HandshakeType(data[0]), Body: data[HandshakeHeaderLen : HandshakeHeaderLen+n]}, nil
+}
+
+// Buffered returns the number of buffered bytes not yet returned by Next.
+func (h *HandshakeReader) Buffered() int {
+	return len(h.buf) - h.off
+}
+
+// PeekClientHello parses the ClientHello at the start of a client's byte
+// stream, reassembling it from as many handshake records as needed. It
+// returns ErrNeedMore while data does not yet hold the complete message and
+// ErrUnexpectedMessage when the stream does not start with a ClientHello.
+func PeekClientHello(data []byte) (*ClientHello, error) {
+	r := wireread.NewSafeReader(data)
+	var hr HandshakeReader
+	for {
+		// Reject other protocols as soon as the first byte is seen rather
+		// than waiting for what would be a record's worth of data.
+		if b := r.Bytes(); len(b) > 0 && ContentType(b[0]) != ContentHandshake {
+			return nil, fmt.Errorf("%w: %s record", ErrUnexpectedMessage, ContentType(b[0]))
+		}
+		rec, err := ReadRecord(r)
+		if err == io.ErrUnexpectedEOF {
+			return nil, ErrNeedMore
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := hr.AddRecord(rec); err != nil {
+			return nil, err
+		}
+		if hr.Buffered() > 0 && HandshakeType(hr.buf[0]) != HandshakeClientHello {
+			return nil, fmt.Errorf("%w: %s", ErrUnexpectedMessage, HandshakeType(hr.buf[0]))
+		}
+		msg, err := hr.Next()
+		if err == ErrNeedMore {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return ParseClientHello(msg.Body)
+	}
+}
@@ -0,0 +1,383 @@
+package tls
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/nemohan/wireread"
+)
+
+// Extension types decoded by this package.
+const (
+	ExtServerName          uint16 = 0
+	ExtSupportedGroups     uint16 = 10
+	ExtECPointFormats      uint16 = 11
+	ExtSignatureAlgorithms uint16 = 13
+	ExtALPN                uint16 = 16
+	ExtSupportedVersions   uint16 = 43
+	ExtKeyShare            uint16 = 51
+)
+
+// helloRetryRequestRandom is the Random value that marks a ServerHello as a
+// HelloRetryRequest (RFC 8446 Section 4.1.3).
+var helloRetryRequestRandom = [32]byte{
+	0xCF, 0x21, 0xAD, 0x74, 0xE5, 0x9A, 0x61, 0x11,
+	0xBE, 0x1D, 0x8C, 0x02, 0x1E, 0x65, 0xB8, 0x91,
+	0xC2, 0xA2, 0x11, 0x16, 0x7A, 0xBB, 0x8C, 0x5E,
+	0x07, 0x9E, 0x09, 0xE2, 0xC8, 0xA8, 0x33, 0x9C,
+}
+
+// Extension is a raw hello extension. Data aliases the input.
+type Extension struct {
+	Type uint16
+	Data []byte
+}
+
+// KeyShare is a key_share entry. KeyExchange is empty in the group-only
+// form of a HelloRetryRequest.
+type KeyShare struct {
+	Group       uint16
+	KeyExchange []byte
+}
+
+// ClientHello is a parsed ClientHello. Extensions keeps every extension in
+// wire order; the remaining fields hold the decoded contents of the
+// extensions this package understands.
+type ClientHello struct {
+	Version            uint16 // legacy_version
+	Random             [32]byte
+	SessionID          []byte
+	CipherSuites       []uint16
+	CompressionMethods []byte
+	Extensions         []Extension
+
+	ServerName          string
+	ALPNProtocols       []string
+	SupportedVersions   []uint16
+	SupportedGroups     []uint16
+	ECPointFormats      []byte
+	SignatureAlgorithms []uint16
+	KeyShares           []KeyShare
+}
+
+// ServerHello is a parsed ServerHello or HelloRetryRequest.
+type ServerHello struct {
+	Version           uint16 // legacy_version
+	Random            [32]byte
+	SessionID         []byte
+	CipherSuite       uint16
+	CompressionMethod uint8
+	Extensions        []Extension
+
+	// SupportedVersion is the version selected through supported_versions,
+	// or zero before TLS 1.3.
+	SupportedVersion uint16
+	ALPNProtocol     string
+	KeyShare         *KeyShare
+}
+
+// IsHelloRetryRequest reports whether the message is a HelloRetryRequest.
+func (s *ServerHello) IsHelloRetryRequest() bool {
+	return s.Random == helloRetryRequestRandom
+}
+
+// NegotiatedVersion returns the protocol version selected by the server.
+func (s *ServerHello) NegotiatedVersion() uint16 {
+	if s.SupportedVersion != 0 {
+		return s.SupportedVersion
+	}
+	return s.Version
+}
+
+// Extension returns the data of the extension typ, if present.
+func (c *ClientHello) Extension(typ uint16) ([]byte, bool) {
+	return findExtension(c.Extensions, typ)
+}
+
+// Extension returns the data of the extension typ, if present.
+func (s *ServerHello) Extension(typ uint16) ([]byte, bool) {
+	return findExtension(s.Extensions, typ)
+}
+
+func findExtension(exts []Extension, typ uint16) ([]byte, bool) {
+	for _, e := range exts {
+		if e.Type == typ {
+			return e.Data, true
+		}
+	}
+	return nil, false
+}
+
+// ParseClientHello parses the body of a ClientHello handshake message.
+// Byte slices in the result alias body.
+func ParseClientHello(body []byte) (*ClientHello, error) {
+	r := wireread.NewSafeReader(body)
+	c := new(ClientHello)
+	var err error
+	if c.Version, c.SessionID, err = helloPrefix(r, &c.Random); err != nil {
+		return nil, fmt.Errorf("client_hello: %w", err)
+	}
+
+	suites, err := ReadVector16(r)
+	if err != nil || len(suites) == 0 {
+		return nil, fmt.Errorf("client_hello: cipher_suites: %w", ErrMalformed)
+	}
+	if c.CipherSuites, err = uint16List(suites); err != nil {
+		return nil, fmt.Errorf("client_hello: cipher_suites: %w", err)
+	}
+	if c.CompressionMethods, err = ReadVector8(r); err != nil || len(c.CompressionMethods) == 0 {
+		return nil, fmt.Errorf("client_hello: compression_methods: %w", ErrMalformed)
+	}
+
+	if c.Extensions, err = readExtensions(r); err != nil {
+		return nil, fmt.Errorf("client_hello: %w", err)
+	}
+	for _, e := range c.Extensions {
+		if err := c.decodeExtension(e); err != nil {
+			return nil, fmt.Errorf("client_hello: extension %d: %w", e.Type, err)
+		}
+	}
+	return c, nil
+}
+
+// ParseServerHello parses the body of a ServerHello handshake message.
+// Byte slices in the result alias body.
+func ParseServerHello(body []byte) (*ServerHello, error) {
+	r := wireread.NewSafeReader(body)
+	s := new(ServerHello)
+	var err error
+	if s.Version, s.SessionID, err = helloPrefix(r, &s.Random); err != nil {
+		return nil, fmt.Errorf("server_hello: %w", err)
+	}
+	if s.CipherSuite, err = r.ReadUint16BE(); err != nil {
+		return nil, fmt.Errorf("server_hello: cipher_suite: %w", ErrMalformed)
+	}
+	if s.CompressionMethod, err = r.ReadByte(); err != nil {
+		return nil, fmt.Errorf("server_hello: compression_method: %w", ErrMalformed)
+	}
+
+	if s.Extensions, err = readExtensions(r); err != nil {
+		return nil, fmt.Errorf("server_hello: %w", err)
+	}
+	for _, e := range s.Extensions {
+		if err := s.decodeExtension(e); err != nil {
+			return nil, fmt.Errorf("server_hello: extension %d: %w", e.Type, err)
+		}
+	}
+	return s, nil
+}
+
+// helloPrefix reads the fields shared by both hellos: legacy_version,
+// random and legacy_session_id.
+func helloPrefix(r *wireread.SafeReader, random *[32]byte) (uint16, []byte, error) {
+	version, err := r.ReadUint16BE()
+	if err != nil {
+		return 0, nil, ErrMalformed
+	}
+	b, err := r.ReadBytes(32)
+	if err != nil {
+		return 0, nil, ErrMalformed
+	}
+	copy(random[:], b)
+	sid, err := ReadVector8(r)
+	if err != nil || len(sid) > 32 {
+		return 0, nil, fmt.Errorf("session_id: %w", ErrMalformed)
+	}
+	return version, sid, nil
+}
+
+// readExtensions reads the optional extensions block, which must end the
+// message. Duplicate extension types are rejected.
+func readExtensions(r *wireread.SafeReader) ([]Extension, error) {
+	if len(r.Bytes()) == 0 {
+		return nil, nil
+	}
+	block, err := ReadVector16(r)
+	if err != nil {
+		return nil, fmt.Errorf("extensions: %w", err)
+	}
+	if len(r.Bytes()) != 0 {
+		return nil, fmt.Errorf("trailing data: %w", ErrMalformed)
+	}
+
+	er := wireread.NewSafeReader(block)
+	exts := make([]Extension, 0, len(block)/4)
+	for len(er.Bytes()) > 0 {
+		var e Extension
+		if e.Type, err = er.ReadUint16BE(); err != nil {
+			return nil, fmt.Errorf("extensions: %w", ErrMalformed)
+		}
+		if e.Data, err = ReadVector16(er); err != nil {
+			return nil, fmt.Errorf("extension %d: %w", e.Type, err)
+		}
+		if _, dup := findExtension(exts, e.Type); dup {
+			return nil, fmt.Errorf("duplicate extension %d: %w", e.Type, ErrMalformed)
+		}
+		exts = append(exts, e)
+	}
+	return exts, nil
+}
+
+func (c *ClientHello) decodeExtension(e Extension) error {
+	r := wireread.NewSafeReader(e.Data)
+	var err error
+	switch e.Type {
+	case ExtServerName:
+		list, err := ReadVector16(r)
+		if err != nil {
+			return err
+		}
+		lr := wireread.NewSafeReader(list)
+		for len(lr.Bytes()) > 0 {
+			typ, err := lr.ReadByte()
+			if err != nil {
+				return ErrMalformed
+			}
+			name, err := ReadVector16(lr)
+			if err != nil {
+				return err
+			}
+			if typ != 0 {
+				continue
+			}
+			if c.ServerName != "" || len(name) == 0 || bytes.IndexByte(name, 0) >= 0 {
+				return ErrMalformed
+			}
+			c.ServerName = string(name)
+		}
+
+	case ExtALPN:
+		if c.ALPNProtocols, err = readALPN(r); err != nil {
+			return err
+		}
+
+	case ExtSupportedVersions:
+		list, err := ReadVector8(r)
+		if err != nil {
+			return err
+		}
+		if c.SupportedVersions, err = uint16List(list); err != nil {
+			return err
+		}
+
+	case ExtSupportedGroups:
+		list, err := ReadVector16(r)
+		if err != nil {
+			return err
+		}
+		if c.SupportedGroups, err = uint16List(list); err != nil {
+			return err
+		}
+
+	case ExtECPointFormats:
+		if c.ECPointFormats, err = ReadVector8(r); err != nil {
+			return err
+		}
+
+	case ExtSignatureAlgorithms:
+		list, err := ReadVector16(r)
+		if err != nil {
+			return err
+		}
+		if c.SignatureAlgorithms, err = uint16List(list); err != nil {
+			return err
+		}
+
+	case ExtKeyShare:
+		list, err := ReadVector16(r)
+		if err != nil {
+			return err
+		}
+		lr := wireread.NewSafeReader(list)
+		for len(lr.Bytes()) > 0 {
+			ks, err := readKeyShare(lr)
+			if err != nil {
+				return err
+			}
+			c.KeyShares = append(c.KeyShares, ks)
+		}
+
+	default:
+		return nil
+	}
+	if len(r.Bytes()) != 0 {
+		return ErrMalformed
+	}
+	return nil
+}
+
+func (s *ServerHello) decodeExtension(e Extension) error {
+	r := wireread.NewSafeReader(e.Data)
+	var err error
+	switch e.Type {
+	case ExtSupportedVersions:
+		if s.SupportedVersion, err = r.ReadUint16BE(); err != nil {
+			return ErrMalformed
+		}
+
+	case ExtALPN:
+		protos, err := readALPN(r)
+		if err != nil {
+			return err
+		}
+		if len(protos) != 1 {
+			return ErrMalformed
+		}
+		s.ALPNProtocol = protos[0]
+
+	case ExtKeyShare:
+		if s.IsHelloRetryRequest() {
+			group, err := r.ReadUint16BE()
+			if err != nil {
+				return ErrMalformed
+			}
+			s.KeyShare = &KeyShare{Group: group}
+			break
+		}
+		ks, err := readKeyShare(r)
+		if err != nil {
+			return err
+		}
+		s.KeyShare = &ks
+
+	default:
+		return nil
+	}
+	if len(r.Bytes()) != 0 {
+		return ErrMalformed
+	}
+	return nil
+}
+
+// readALPN reads a ProtocolNameList.
+func readALPN(r *wireread.SafeReader) ([]string, error) {
+	list, err := ReadVector16(r)
+	if err != nil {
+		return nil, err
+	}
+	lr := wireread.NewSafeReader(list)
+	var protos []string
+	for len(lr.Bytes()) > 0 {
+		p, err := ReadVector8(lr)
+		if err != nil || len(p) == 0 {
+			return nil, ErrMalformed
+		}
+		protos = append(protos, string(p))
+	}
+	if len(protos) == 0 {
+		return nil, ErrMalformed
+	}
+	return protos, nil
+}
+
+func readKeyShare(r *wireread.SafeReader) (KeyShare, error) {
+	group, err := r.ReadUint16BE()
+	if err != nil {
+		return KeyShare{}, ErrMalformed
+	}
+	key, err := ReadVector16(r)
+	if err != nil {
+		return KeyShare{}, err
+	}
+	return KeyShare{Group: group, KeyExchange: key}, nil
+}
@@ -0,0 +1,179 @@
+// Package tls parses the plaintext part of TLS connections: the record
+// layer, handshake messages reassembled across records, and ClientHello and
+// ServerHello messages with their common extensions.
+//
+// It is meant for peeking at a connection without terminating it, for
+// example to route by SNI and ALPN or to fingerprint clients with JA3 and
+// JA4. Nothing is decrypted and no handshake state is verified.
+//
+// Example usage:
+//
+//	hello, err := tls.PeekClientHello(buf)
+//	if err == tls.ErrNeedMore {
+//	    // read more from the connection and try again
+//	}
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	fmt.Println(hello.ServerName, hello.ALPNProtocols, hello.JA4())
+package tls
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/nemohan/wireread"
+)
+
+var (
+	// ErrMalformed is returned for records and handshake messages whose
+	// lengths or contents are inconsistent.
+	ErrMalformed = errors.New("tls: malformed message")
+
+	// ErrRecordOverflow is returned for records longer than MaxRecordLen.
+	ErrRecordOverflow = errors.New("tls: record overflow")
+
+	// ErrUnexpectedMessage is returned when a record or handshake message of
+	// an unexpected type is found, such as data that is not a ClientHello
+	// at the start of a connection.
+	ErrUnexpectedMessage = errors.New("tls: unexpected message")
+
+	// ErrNeedMore is returned when the data seen so far ends inside a record
+	// or handshake message.
+	ErrNeedMore = errors.New("tls: need more data")
+)
+
+// RecordHeaderLen is the size of the record header.
+const RecordHeaderLen = 5
+
+// MaxRecordLen is the largest record payload accepted, the TLS 1.2 limit for
+// protected records (2^14 + 2048).
+const MaxRecordLen = 1<<14 + 2048
+
+// Protocol versions.
+const (
+	VersionSSL30 uint16 = 0x0300
+	VersionTLS10 uint16 = 0x0301
+	VersionTLS11 uint16 = 0x0302
+	VersionTLS12 uint16 = 0x0303
+	VersionTLS13 uint16 = 0x0304
+)
+
+// ContentType is the type of a record.
+type ContentType uint8
+
+// Record content types.
+const (
+	ContentChangeCipherSpec ContentType = 20
+	ContentAlert            ContentType = 21
+	ContentHandshake        ContentType = 22
+	ContentApplicationData  ContentType = 23
+	ContentHeartbeat        ContentType = 24
+)
+
+func (t ContentType) String() string {
+	switch t {
+	case ContentChangeCipherSpec:
+		return "change_cipher_spec"
+	case ContentAlert:
+		return "alert"
+	case ContentHandshake:
+		return "handshake"
+	case ContentApplicationData:
+		return "application_data"
+	case ContentHeartbeat:
+		return "heartbeat"
+	}
+	return fmt.Sprintf("content_type(%d)", uint8(t))
+}
+
+// Record is a TLS record. Fragment aliases the input.
+type Record struct {
+	Type     ContentType
+	Version  uint16
+	Fragment []byte
+}
+
+// ReadRecord reads a record from r. It returns io.ErrUnexpectedEOF if r ends
+// inside the record.
+func ReadRecord(r wireread.Reader) (Record, error) {
+	hdr, err := r.ReadBytes(RecordHeaderLen)
+	if err != nil {
+		return Record{}, err
+	}
+	rec := Record{
+		Type:    ContentType(hdr[0]),
+		Version: uint16(hdr[1])<<8 | uint16(hdr[2]),
+	}
+	n := int(hdr[3])<<8 | int(hdr[4])
+	if n > MaxRecordLen {
+		return Record{}, fmt.Errorf("%w: length %d", ErrRecordOverflow, n)
+	}
+	if n > len(r.Bytes()) {
+		return Record{}, io.ErrUnexpectedEOF
+	}
+	rec.Fragment = r.Bytes()[:n:n]
+	if err := r.Skip(n); err != nil {
+		return Record{}, err
+	}
+	return rec, nil
+}
+
+// isGREASE reports whether v is one of the reserved GREASE values of
+// RFC 8701 (0x0a0a, 0x1a1a, ..., 0xfafa).
+func isGREASE(v uint16) bool {
+	return v&0x0f0f == 0x0a0a && v>>8 == v&0xff
+}
+
+// ReadVector8 reads a vector with a one-byte length prefix. It returns
+// ErrMalformed if r ends inside the vector.
+func ReadVector8(r wireread.Reader) ([]byte, error) {
+	n, err := r.ReadByte()
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	return readN(r, int(n))
+}
+
+// ReadVector16 reads a vector with a two-byte length prefix.
+func ReadVector16(r wireread.Reader) ([]byte, error) {
+	n, err := r.ReadUint16BE()
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	return readN(r, int(n))
+}
+
+// ReadVector24 reads a vector with a three-byte length prefix, as used for
+// handshake messages and certificate lists.
+func ReadVector24(r wireread.Reader) ([]byte, error) {
+	b, err := r.ReadBytes(3)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	return readN(r, int(b[0])<<16|int(b[1])<<8|int(b[2]))
+}
+
+func readN(r wireread.Reader, n int) ([]byte, error) {
+	if n > len(r.Bytes()) {
+		return nil, ErrMalformed
+	}
+	b := r.Bytes()[:n:n]
+	if err := r.Skip(n); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// uint16List decodes a vector body holding 16-bit values.
+func uint16List(b []byte) ([]uint16, error) {
+	if len(b)%2 != 0 {
+		return nil, ErrMalformed
+	}
+	list := make([]uint16, len(b)/2)
+	for i := range list {
+		list[i] = uint16(b[2*i])<<8 | uint16(b[2*i+1])
+	}
+	return list, nil
+}
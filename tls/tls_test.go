@@ -0,0 +1,327 @@
+package tls
+
+import (
+	stdtls "crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/nemohan/wireread"
+)
+
+// captureClientHello returns the first flight of a crypto/tls client.
+func captureClientHello(t *testing.T, config *stdtls.Config) []byte {
+	t.Helper()
+	client, server := net.Pipe()
+	go func() {
+		stdtls.Client(client, config).Handshake()
+	}()
+	defer server.Close()
+	defer client.Close()
+
+	buf := make([]byte, 0, 4096)
+	for {
+		n, err := server.Read(buf[len(buf):cap(buf)])
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf = buf[:len(buf)+n]
+		if _, err := PeekClientHello(buf); err != ErrNeedMore {
+			return buf
+		}
+	}
+}
+
+func TestPeekClientHello_CryptoTLS(t *testing.T) {
+	data := captureClientHello(t, &stdtls.Config{
+		ServerName: "example.com",
+		NextProtos: []string{"h2", "http/1.1"},
+	})
+	c, err := PeekClientHello(data)
+	if err != nil {
+		t.Fatalf("PeekClientHello() error = %v", err)
+	}
+	if c.ServerName != "example.com" {
+		t.Errorf("ServerName = %q", c.ServerName)
+	}
+	if len(c.ALPNProtocols) != 2 || c.ALPNProtocols[0] != "h2" || c.ALPNProtocols[1] != "http/1.1" {
+		t.Errorf("ALPNProtocols = %q", c.ALPNProtocols)
+	}
+	if len(c.SupportedVersions) == 0 || c.SupportedVersions[0] != VersionTLS13 {
+		t.Errorf("SupportedVersions = %x", c.SupportedVersions)
+	}
+	if len(c.KeyShares) == 0 || len(c.SignatureAlgorithms) == 0 || len(c.SupportedGroups) == 0 {
+		t.Errorf("KeyShares = %d, SignatureAlgorithms = %d, SupportedGroups = %d",
+			len(c.KeyShares), len(c.SignatureAlgorithms), len(c.SupportedGroups))
+	}
+	if got := c.JA4(); got[:10] != "t13d"+got[4:8]+"h2" {
+		t.Errorf("JA4() = %q", got)
+	}
+
+	for n := 0; n < len(data); n++ {
+		if _, err := PeekClientHello(data[:n]); err != ErrNeedMore {
+			t.Fatalf("PeekClientHello(data[:%d]) error = %v, want ErrNeedMore", n, err)
+		}
+	}
+}
+
+// sampleHello is a ClientHello body with GREASE values in every list.
+func sampleHello() []byte {
+	var b []byte
+	u16 := func(v uint16) { b = append(b, byte(v>>8), byte(v)) }
+	vec16 := func(f func()) {
+		at := len(b)
+		b = append(b, 0, 0)
+		f()
+		n := len(b) - at - 2
+		b[at], b[at+1] = byte(n>>8), byte(n)
+	}
+	ext := func(typ uint16, f func()) {
+		u16(typ)
+		vec16(f)
+	}
+
+	u16(VersionTLS12)
+	b = append(b, make([]byte, 32)...)
+	b = append(b, 0) // empty session id
+	vec16(func() {
+		for _, s := range []uint16{0x1a1a, 0x1301, 0xc02f, 0x1302} {
+			u16(s)
+		}
+	})
+	b = append(b, 1, 0)
+	vec16(func() {
+		ext(0x2a2a, func() {})
+		ext(ExtServerName, func() {
+			vec16(func() {
+				b = append(b, 0)
+				vec16(func() { b = append(b, "example.net"...) })
+			})
+		})
+		ext(ExtSupportedGroups, func() { vec16(func() { u16(0x3a3a); u16(29); u16(23) }) })
+		ext(ExtECPointFormats, func() { b = append(b, 1, 0) })
+		ext(ExtSignatureAlgorithms, func() { vec16(func() { u16(0x0403); u16(0x0804) }) })
+		ext(ExtALPN, func() {
+			vec16(func() { b = append(b, 2, 'h', '2') })
+		})
+		ext(ExtSupportedVersions, func() { b = append(b, 4, 0x4a, 0x4a, 0x03, 0x04) })
+		ext(ExtKeyShare, func() {
+			vec16(func() {
+				u16(29)
+				vec16(func() { b = append(b, make([]byte, 32)...) })
+			})
+		})
+	})
+	return b
+}
+
+// records wraps a handshake message into records of at most size bytes.
+func records(typ HandshakeType, body []byte, size int) []byte {
+	n := len(body)
+	msg := append([]byte{byte(typ), byte(n >> 16), byte(n >> 8), byte(n)}, body...)
+	var out []byte
+	for len(msg) > 0 {
+		k := min(size, len(msg))
+		out = append(out, byte(ContentHandshake), 0x03, 0x01, byte(k>>8), byte(k))
+		out = append(out, msg[:k]...)
+		msg = msg[k:]
+	}
+	return out
+}
+
+func TestParseClientHello(t *testing.T) {
+	c, err := ParseClientHello(sampleHello())
+	if err != nil {
+		t.Fatalf("ParseClientHello() error = %v", err)
+	}
+	if c.ServerName != "example.net" || len(c.ALPNProtocols) != 1 || c.ALPNProtocols[0] != "h2" {
+		t.Errorf("ServerName = %q, ALPNProtocols = %q", c.ServerName, c.ALPNProtocols)
+	}
+	if len(c.KeyShares) != 1 || c.KeyShares[0].Group != 29 || len(c.KeyShares[0].KeyExchange) != 32 {
+		t.Errorf("KeyShares = %+v", c.KeyShares)
+	}
+	if len(c.Extensions) != 8 {
+		t.Errorf("got %d extensions, want 8", len(c.Extensions))
+	}
+	if data, ok := c.Extension(ExtECPointFormats); !ok || len(data) != 2 {
+		t.Errorf("Extension(ec_point_formats) = %x, %v", data, ok)
+	}
+
+	const ja3 = "771,4865-49199-4866,0-10-11-13-16-43-51,29-23,0"
+	if got := c.JA3(); got != ja3 {
+		t.Errorf("JA3() = %q, want %q", got, ja3)
+	}
+	if got := c.JA3Hash(); got != "10f011af954477025c5cd83fb634df45" {
+		t.Errorf("JA3Hash() = %q", got)
+	}
+	if got := c.JA4(); got != "t13d0307h2_40b44b994229_078775ef5e04" {
+		t.Errorf("JA4() = %q", got)
+	}
+}
+
+func TestPeekClientHello_Fragmented(t *testing.T) {
+	for _, size := range []int{1, 3, 50, 1 << 14} {
+		data := records(HandshakeClientHello, sampleHello(), size)
+		c, err := PeekClientHello(data)
+		if err != nil {
+			t.Fatalf("PeekClientHello(records of %d) error = %v", size, err)
+		}
+		if c.ServerName != "example.net" {
+			t.Errorf("ServerName = %q", c.ServerName)
+		}
+	}
+}
+
+func TestPeekClientHello_Errors(t *testing.T) {
+	hello := records(HandshakeClientHello, sampleHello(), 1<<14)
+	notHandshake := append([]byte(nil), hello...)
+	notHandshake[0] = byte(ContentApplicationData)
+	tooLong := append([]byte(nil), hello...)
+	tooLong[3], tooLong[4] = 0xff, 0xff
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"http request", []byte("GET / HTTP/1.1\r\nHost: x\r\n\r\n"), ErrUnexpectedMessage},
+		{"application data", notHandshake, ErrUnexpectedMessage},
+		{"server hello", records(HandshakeServerHello, sampleHello(), 1<<14), ErrUnexpectedMessage},
+		{"record overflow", tooLong, ErrRecordOverflow},
+		{"truncated body", records(HandshakeClientHello, sampleHello()[:40], 1<<14), ErrMalformed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := PeekClientHello(tt.data); !errors.Is(err, tt.want) {
+				t.Errorf("PeekClientHello() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseClientHello_Malformed(t *testing.T) {
+	hello := sampleHello()
+	dup := append([]byte(nil), hello...)
+	// Turn the empty GREASE extension into a second supported_versions.
+	dup[2+32+1+2+8+2+2+2] = 0x00
+	dup[2+32+1+2+8+2+2+3] = 0x2b
+
+	tests := []struct {
+		name string
+		body []byte
+	}{
+		{"truncated", hello[:len(hello)-1]},
+		{"trailing data", append(append([]byte(nil), hello...), 0)},
+		{"duplicate extension", dup},
+		{"no cipher suites", []byte{0x03, 0x03, 32: 0, 33: 0, 34: 0, 35: 0, 36: 1, 37: 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseClientHello(tt.body); !errors.Is(err, ErrMalformed) {
+				t.Errorf("ParseClientHello() error = %v, want ErrMalformed", err)
+			}
+		})
+	}
+}
+
+func TestParseServerHello(t *testing.T) {
+	body := []byte{0x03, 0x03}
+	body = append(body, helloRetryRequestRandom[:]...)
+	body = append(body, 0, 0x13, 0x01, 0)
+	body = append(body, 0, 12,
+		0x00, 0x2b, 0x00, 0x02, 0x03, 0x04, // supported_versions
+		0x00, 0x33, 0x00, 0x02, 0x00, 0x17, // key_share: secp256r1
+	)
+
+	s, err := ParseServerHello(body)
+	if err != nil {
+		t.Fatalf("ParseServerHello() error = %v", err)
+	}
+	if !s.IsHelloRetryRequest() || s.NegotiatedVersion() != VersionTLS13 || s.CipherSuite != 0x1301 {
+		t.Errorf("ServerHello = %+v", s)
+	}
+	if s.KeyShare == nil || s.KeyShare.Group != 23 || len(s.KeyShare.KeyExchange) != 0 {
+		t.Errorf("KeyShare = %+v", s.KeyShare)
+	}
+}
+
+func TestHandshakeReader(t *testing.T) {
+	var hr HandshakeReader
+	data := append(records(HandshakeServerHelloDone, nil, 1), records(HandshakeFinished, []byte{1, 2, 3}, 2)...)
+	r := wireread.NewSafeReader(data)
+	var got []Handshake
+	for {
+		rec, err := ReadRecord(r)
+		if err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := hr.AddRecord(rec); err != nil {
+			t.Fatal(err)
+		}
+		for {
+			msg, err := hr.Next()
+			if err == ErrNeedMore {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, Handshake{Type: msg.Type, Body: append([]byte(nil), msg.Body...)})
+		}
+	}
+	if len(got) != 2 || got[0].Type != HandshakeServerHelloDone || got[1].Type != HandshakeFinished || string(got[1].Body) != "\x01\x02\x03" {
+		t.Errorf("messages = %+v", got)
+	}
+}
+
+func TestReadRecord(t *testing.T) {
+	data := []byte{22, 3, 3, 0, 2, 'a', 'b', 23, 3, 3, 0, 1, 'c'}
+	r := wireread.NewSafeReader(data)
+	rec, err := ReadRecord(r)
+	if err != nil || rec.Type != ContentHandshake || rec.Version != 0x0303 || string(rec.Fragment) != "ab" {
+		t.Fatalf("ReadRecord() = %+v, %v", rec, err)
+	}
+	_ = append(rec.Fragment, 0xEE, 0xEE, 0xEE)
+	if rec, err = ReadRecord(r); err != nil || rec.Type != 23 || string(rec.Fragment) != "c" {
+		t.Errorf("second ReadRecord() = %+v, %v", rec, err)
+	}
+	if _, err := ReadRecord(wireread.NewSafeReader(data[:6])); err != io.ErrUnexpectedEOF {
+		t.Errorf("ReadRecord(truncated) error = %v, want io.ErrUnexpectedEOF", err)
+	}
+}
+
+func TestReadVector(t *testing.T) {
+	data := []byte{1, 'a', 0, 2, 'b', 'c', 0, 0, 1, 'd', 0, 5}
+	r := wireread.NewSafeReader(data)
+	// Appending to a vector must not overwrite the one after it.
+	v8, err8 := ReadVector8(r)
+	_ = append(v8, 0xEE, 0xEE, 0xEE, 0xEE)
+	v16, err16 := ReadVector16(r)
+	_ = append(v16, 0xEE, 0xEE, 0xEE, 0xEE)
+	v24, err24 := ReadVector24(r)
+	if string(v8) != "a" || string(v16) != "bc" || string(v24) != "d" || err8 != nil || err16 != nil || err24 != nil {
+		t.Errorf("vectors = %q, %q, %q", v8, v16, v24)
+	}
+	if _, err := ReadVector16(r); err != ErrMalformed {
+		t.Errorf("ReadVector16(overrun) error = %v, want ErrMalformed", err)
+	}
+}
+
+func TestIsGREASE(t *testing.T) {
+	for _, v := range []uint16{0x0a0a, 0x1a1a, 0xfafa} {
+		if !isGREASE(v) {
+			t.Errorf("isGREASE(%#x) = false", v)
+		}
+	}
+	for _, v := range []uint16{0x0a1a, 0x1301, 0x0000} {
+		if isGREASE(v) {
+			t.Errorf("isGREASE(%#x) = true", v)
+		}
+	}
+}
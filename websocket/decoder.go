@@ -0,0 +1,219 @@
+package websocket
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"unicode/utf8"
+)
+
+// DefaultMaxMessageSize is the default limit on the payload of a frame or a
+// reassembled message.
+const DefaultMaxMessageSize = 32 << 20
+
+// Frame is a single frame with its payload unmasked.
+type Frame struct {
+	FrameHeader
+	Payload []byte
+}
+
+// Message is a complete data message or a control frame.
+type Message struct {
+	// Opcode is OpText or OpBinary for data messages and the frame's opcode
+	// for control messages.
+	Opcode Opcode
+	Data   []byte
+	// Compressed reports whether RSV1 was set on the first frame, which
+	// means permessage-deflate when that extension was negotiated. Text
+	// messages are only checked for valid UTF-8 when not compressed.
+	Compressed bool
+	// Frames is the number of frames the message was assembled from.
+	Frames int
+}
+
+// Decoder reads frames from a stream.
+//
+// NextFrame and NextMessage should not be mixed on one Decoder, since only
+// NextMessage tracks fragmentation.
+type Decoder struct {
+	r        io.Reader
+	maxSize  uint64
+	mask     int8 // 1: frames must be masked, -1: must not be, 0: either
+	rsv      byte // reserved bits allowed by negotiated extensions
+	off      int64
+	hdr      [MaxFrameHeaderLen]byte
+	buf      []byte
+	fragment *Message // data message awaiting continuation frames
+}
+
+// NewDecoder creates a Decoder reading from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: r, maxSize: DefaultMaxMessageSize}
+}
+
+// SetMaxMessageSize limits the size of a frame payload and of a reassembled
+// message. Oversized frames are rejected before their payload is read.
+func (d *Decoder) SetMaxMessageSize(n uint64) {
+	d.maxSize = n
+}
+
+// RequireMask sets whether incoming frames must be masked. Servers should
+// pass true and clients false; by default both forms are accepted.
+func (d *Decoder) RequireMask(required bool) {
+	if required {
+		d.mask = 1
+	} else {
+		d.mask = -1
+	}
+}
+
+// AllowRSV1 permits the RSV1 bit, which permessage-deflate uses to flag
+// compressed messages. RSV2 and RSV3 are always rejected.
+func (d *Decoder) AllowRSV1(allow bool) {
+	if allow {
+		d.rsv = 0x40
+	} else {
+		d.rsv = 0
+	}
+}
+
+// Offset returns the number of bytes consumed from the stream.
+func (d *Decoder) Offset() int64 {
+	return d.off
+}
+
+// readHeader reads and validates the next frame header. It returns io.EOF
+// if the stream ends cleanly before the header.
+func (d *Decoder) readHeader() (FrameHeader, error) {
+	if _, err := io.ReadFull(d.r, d.hdr[:2]); err != nil {
+		return FrameHeader{}, err
+	}
+	n := headerLen(d.hdr[1])
+	if _, err := io.ReadFull(d.r, d.hdr[2:n]); err != nil {
+		return FrameHeader{}, unexpectedEOF(err)
+	}
+	h, _, err := ParseFrameHeader(d.hdr[:n])
+	if err != nil {
+		return FrameHeader{}, fmt.Errorf("frame at offset %d: %w", d.off, err)
+	}
+	d.off += int64(n)
+
+	if rsv := h.rsv() &^ d.rsv; rsv != 0 {
+		return FrameHeader{}, fmt.Errorf("%w: reserved bits %#x set", ErrProtocol, rsv)
+	}
+	if h.RSV1 && (h.Opcode.IsControl() || h.Opcode == OpContinuation) {
+		return FrameHeader{}, fmt.Errorf("%w: RSV1 on %s frame", ErrProtocol, h.Opcode)
+	}
+	switch {
+	case d.mask > 0 && !h.Masked:
+		return FrameHeader{}, fmt.Errorf("%w: unmasked frame", ErrProtocol)
+	case d.mask < 0 && h.Masked:
+		return FrameHeader{}, fmt.Errorf("%w: masked frame", ErrProtocol)
+	}
+	if h.Length > d.maxSize {
+		return FrameHeader{}, fmt.Errorf("%w: frame payload of %d bytes", ErrMessageTooLarge, h.Length)
+	}
+	return h, nil
+}
+
+// payloadChunk bounds how far readPayload allocates ahead of the bytes that
+// have arrived, so that a header announcing a large frame on a stream that
+// then stalls or ends does not cost the whole announced length.
+const payloadChunk = 64 << 10
+
+// readPayload appends the unmasked payload of h to dst. The buffer grows as
+// the payload is read, at most doubling the part read so far each time.
+func (d *Decoder) readPayload(h *FrameHeader, dst []byte) ([]byte, error) {
+	start := len(dst)
+	for remain := int(h.Length); remain > 0; {
+		n := min(remain, max(payloadChunk, len(dst)-start))
+		end := len(dst)
+		dst = slices.Grow(dst, n)[:end+n]
+		if _, err := io.ReadFull(d.r, dst[end:]); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		remain -= n
+	}
+	d.off += int64(h.Length)
+	if h.Masked {
+		Unmask(dst[start:], h.MaskKey, 0)
+	}
+	return dst, nil
+}
+
+// NextFrame reads the next frame. The payload is only valid until the next
+// call. It returns io.EOF when the stream ends cleanly between frames.
+func (d *Decoder) NextFrame() (Frame, error) {
+	h, err := d.readHeader()
+	if err != nil {
+		return Frame{}, err
+	}
+	if d.buf, err = d.readPayload(&h, d.buf[:0]); err != nil {
+		return Frame{}, err
+	}
+	return Frame{FrameHeader: h, Payload: d.buf}, nil
+}
+
+// NextMessage reads frames until a complete message is available. Control
+// frames, which may arrive between the fragments of a data message, are
+// returned as messages of their own; close frames are validated with
+// ParseClose. The returned data is owned by the caller. NextMessage returns
+// io.EOF when the stream ends cleanly between messages.
+func (d *Decoder) NextMessage() (Message, error) {
+	for {
+		h, err := d.readHeader()
+		if err == io.EOF && d.fragment != nil {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return Message{}, err
+		}
+
+		if h.Opcode.IsControl() {
+			data, err := d.readPayload(&h, nil)
+			if err != nil {
+				return Message{}, err
+			}
+			if h.Opcode == OpClose {
+				if _, _, err := ParseClose(data); err != nil {
+					return Message{}, err
+				}
+			}
+			return Message{Opcode: h.Opcode, Data: data, Frames: 1}, nil
+		}
+
+		msg := d.fragment
+		switch {
+		case msg == nil && h.Opcode == OpContinuation:
+			return Message{}, fmt.Errorf("%w: continuation frame without a message", ErrProtocol)
+		case msg != nil && h.Opcode != OpContinuation:
+			return Message{}, fmt.Errorf("%w: %s frame inside a fragmented message", ErrProtocol, h.Opcode)
+		case msg == nil:
+			msg = &Message{Opcode: h.Opcode, Compressed: h.RSV1, Data: make([]byte, 0, min(h.Length, payloadChunk))}
+		}
+		if uint64(len(msg.Data))+h.Length > d.maxSize {
+			return Message{}, fmt.Errorf("%w: message exceeds %d bytes", ErrMessageTooLarge, d.maxSize)
+		}
+		if msg.Data, err = d.readPayload(&h, msg.Data); err != nil {
+			return Message{}, err
+		}
+		msg.Frames++
+
+		if !h.Fin {
+			d.fragment = msg
+			continue
+		}
+		d.fragment = nil
+		if msg.Opcode == OpText && !msg.Compressed && !utf8.Valid(msg.Data) {
+			return Message{}, ErrInvalidUTF8
+		}
+		return *msg, nil
+	}
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
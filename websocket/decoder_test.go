@@ -0,0 +1,179 @@
+package websocket
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"runtime"
+	"testing"
+	"testing/iotest"
+)
+
+// frame encodes a frame, masking the payload when key is non-nil.
+func frame(b0 byte, key []byte, payload []byte) []byte {
+	var out []byte
+	out = append(out, b0)
+	var maskBit byte
+	if key != nil {
+		maskBit = 0x80
+	}
+	switch n := len(payload); {
+	case n < 126:
+		out = append(out, maskBit|byte(n))
+	case n <= 0xFFFF:
+		out = append(out, maskBit|126, byte(n>>8), byte(n))
+	default:
+		out = append(out, maskBit|127, 0, 0, 0, 0, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+	if key == nil {
+		return append(out, payload...)
+	}
+	out = append(out, key...)
+	masked := make([]byte, len(payload))
+	UnmaskTo(masked, payload, [4]byte(key), 0)
+	return append(out, masked...)
+}
+
+var testKey = []byte{0xA1, 0xB2, 0xC3, 0xD4}
+
+func TestDecoder_NextMessage(t *testing.T) {
+	big := bytes.Repeat([]byte("0123456789"), 7000)
+	var stream []byte
+	stream = append(stream, frame(0x81, testKey, []byte("hello"))...)
+	// A fragmented text message with a ping in the middle.
+	stream = append(stream, frame(0x01, testKey, []byte("fra"))...)
+	stream = append(stream, frame(0x89, testKey, []byte("p"))...)
+	stream = append(stream, frame(0x00, testKey, []byte("gmen"))...)
+	stream = append(stream, frame(0x80, testKey, []byte("ted"))...)
+	stream = append(stream, frame(0x82, testKey, big)...)
+	stream = append(stream, frame(0x88, testKey, []byte{0x03, 0xE8})...)
+
+	d := NewDecoder(iotest.OneByteReader(bytes.NewReader(stream)))
+	d.RequireMask(true)
+	want := []Message{
+		{Opcode: OpText, Data: []byte("hello"), Frames: 1},
+		{Opcode: OpPing, Data: []byte("p"), Frames: 1},
+		{Opcode: OpText, Data: []byte("fragmented"), Frames: 3},
+		{Opcode: OpBinary, Data: big, Frames: 1},
+		{Opcode: OpClose, Data: []byte{0x03, 0xE8}, Frames: 1},
+	}
+	for i, w := range want {
+		msg, err := d.NextMessage()
+		if err != nil {
+			t.Fatalf("NextMessage() #%d error = %v", i, err)
+		}
+		if msg.Opcode != w.Opcode || msg.Frames != w.Frames || !bytes.Equal(msg.Data, w.Data) {
+			t.Errorf("NextMessage() #%d = %v/%d (%d bytes), want %v/%d (%d bytes)",
+				i, msg.Opcode, msg.Frames, len(msg.Data), w.Opcode, w.Frames, len(w.Data))
+		}
+	}
+	if _, err := d.NextMessage(); err != io.EOF {
+		t.Errorf("NextMessage() at end error = %v, want io.EOF", err)
+	}
+	if d.Offset() != int64(len(stream)) {
+		t.Errorf("Offset() = %d, want %d", d.Offset(), len(stream))
+	}
+}
+
+func TestDecoder_NextFrame(t *testing.T) {
+	stream := append(frame(0x01, nil, []byte("ab")), frame(0x80, nil, []byte("c"))...)
+	d := NewDecoder(bytes.NewReader(stream))
+	d.RequireMask(false)
+
+	f, err := d.NextFrame()
+	if err != nil || f.Fin || f.Opcode != OpText || string(f.Payload) != "ab" {
+		t.Errorf("NextFrame() = %+v, %v", f, err)
+	}
+	f, err = d.NextFrame()
+	if err != nil || !f.Fin || f.Opcode != OpContinuation || string(f.Payload) != "c" {
+		t.Errorf("NextFrame() = %+v, %v", f, err)
+	}
+	if _, err := d.NextFrame(); err != io.EOF {
+		t.Errorf("NextFrame() at end error = %v, want io.EOF", err)
+	}
+}
+
+func TestDecoder_Compressed(t *testing.T) {
+	stream := frame(0xC1, nil, []byte{0xFF, 0xFE}) // RSV1 text, not UTF-8
+	d := NewDecoder(bytes.NewReader(stream))
+	if _, err := d.NextMessage(); !errors.Is(err, ErrProtocol) {
+		t.Errorf("NextMessage() without extension error = %v, want ErrProtocol", err)
+	}
+
+	d = NewDecoder(bytes.NewReader(stream))
+	d.AllowRSV1(true)
+	msg, err := d.NextMessage()
+	if err != nil || !msg.Compressed {
+		t.Errorf("NextMessage() = %+v, %v, want compressed message", msg, err)
+	}
+}
+
+func TestDecoder_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream []byte
+		setup  func(d *Decoder)
+		want   error
+	}{
+		{"unmasked from client", frame(0x81, nil, []byte("x")), func(d *Decoder) { d.RequireMask(true) }, ErrProtocol},
+		{"masked from server", frame(0x81, testKey, []byte("x")), func(d *Decoder) { d.RequireMask(false) }, ErrProtocol},
+		{"orphan continuation", frame(0x80, nil, []byte("x")), nil, ErrProtocol},
+		{"interleaved data", append(frame(0x01, nil, []byte("a")), frame(0x81, nil, []byte("b"))...), nil, ErrProtocol},
+		{"rsv2", frame(0xA1, nil, nil), nil, ErrProtocol},
+		{"rsv1 on continuation", append(frame(0x01, nil, []byte("a")), frame(0xC0, nil, []byte("b"))...), func(d *Decoder) { d.AllowRSV1(true) }, ErrProtocol},
+		{"invalid utf8", frame(0x81, nil, []byte{0xC3, 0x28}), nil, ErrInvalidUTF8},
+		{"utf8 split across fragments", append(frame(0x01, nil, []byte{0xC3}), frame(0x80, nil, []byte{0xA9, 0xFF})...), nil, ErrInvalidUTF8},
+		{"bad close code", frame(0x88, nil, []byte{0x03, 0xEE}), nil, ErrProtocol},
+		{"frame too large", frame(0x82, nil, make([]byte, 11)), func(d *Decoder) { d.SetMaxMessageSize(10) }, ErrMessageTooLarge},
+		{"message too large", append(frame(0x02, nil, make([]byte, 6)), frame(0x80, nil, make([]byte, 6))...), func(d *Decoder) { d.SetMaxMessageSize(10) }, ErrMessageTooLarge},
+		{"truncated payload", frame(0x82, nil, make([]byte, 10))[:5], nil, io.ErrUnexpectedEOF},
+		{"eof inside message", frame(0x01, nil, []byte("a")), nil, io.ErrUnexpectedEOF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDecoder(bytes.NewReader(tt.stream))
+			if tt.setup != nil {
+				tt.setup(d)
+			}
+			var err error
+			for err == nil {
+				_, err = d.NextMessage()
+			}
+			if !errors.Is(err, tt.want) {
+				t.Errorf("NextMessage() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecoder_UTF8AcrossFragments(t *testing.T) {
+	stream := append(frame(0x01, nil, []byte{'a', 0xC3}), frame(0x80, nil, []byte{0xA9})...)
+	msg, err := NewDecoder(bytes.NewReader(stream)).NextMessage()
+	if err != nil || string(msg.Data) != "aé" {
+		t.Errorf("NextMessage() = %q, %v, want \"aé\"", msg.Data, err)
+	}
+}
+
+func TestDecoder_TruncatedLargeFrame(t *testing.T) {
+	// A header announcing 16 MiB followed by only 10 bytes must not
+	// allocate the announced length before the payload arrives.
+	stream := []byte{0x82, 127, 0, 0, 0, 0, 0x01, 0, 0, 0}
+	stream = append(stream, make([]byte, 10)...)
+
+	for _, next := range []func(d *Decoder) error{
+		func(d *Decoder) error { _, err := d.NextFrame(); return err },
+		func(d *Decoder) error { _, err := d.NextMessage(); return err },
+	} {
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		err := next(NewDecoder(bytes.NewReader(stream)))
+		runtime.ReadMemStats(&after)
+		if err != io.ErrUnexpectedEOF {
+			t.Errorf("error = %v, want io.ErrUnexpectedEOF", err)
+		}
+		if n := after.TotalAlloc - before.TotalAlloc; n > 1<<20 {
+			t.Errorf("allocated %d bytes for a truncated frame", n)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package websocket
+
+import "encoding/binary"
+
+// Unmask XORs b in place with the masking key, starting at byte pos of the
+// key stream, and returns the key position for the byte following b. A
+// payload received in pieces can be unmasked by passing the returned
+// position to the next call. Masking and unmasking are the same operation.
+func Unmask(b []byte, key [4]byte, pos int) int {
+	return UnmaskTo(b, b, key, pos)
+}
+
+// UnmaskTo writes the unmasked src into dst, which must be at least as long
+// as src, and returns the key position for the byte following src. dst and
+// src may be the same slice.
+func UnmaskTo(dst, src []byte, key [4]byte, pos int) int {
+	dst = dst[:len(src)]
+	i := 0
+	if len(src) >= 8 {
+		// Rotate the key to start at pos and XOR eight bytes at a time;
+		// eight is a multiple of the key length, so the rotation holds.
+		k := uint64(key[pos&3]) | uint64(key[(pos+1)&3])<<8 |
+			uint64(key[(pos+2)&3])<<16 | uint64(key[(pos+3)&3])<<24
+		k |= k << 32
+		for ; i+8 <= len(src); i += 8 {
+			binary.LittleEndian.PutUint64(dst[i:], binary.LittleEndian.Uint64(src[i:])^k)
+		}
+	}
+	for ; i < len(src); i++ {
+		dst[i] = src[i] ^ key[(pos+i)&3]
+	}
+	return (pos + len(src)) & 3
+}
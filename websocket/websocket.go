@@ -0,0 +1,250 @@
+// Package websocket decodes WebSocket frames (RFC 6455).
+//
+// ParseFrameHeader decodes a frame header from a buffer. Decoder reads
+// frames from a stream, unmasks their payloads and reassembles fragmented
+// messages, enforcing the protocol's rules for control frames, fragment
+// sequencing and UTF-8 text.
+//
+// Example usage:
+//
+//	d := websocket.NewDecoder(conn)
+//	d.RequireMask(true) // server side: client frames must be masked
+//	for {
+//	    msg, err := d.NextMessage()
+//	    if err != nil {
+//	        log.Fatal(err)
+//	    }
+//	    if msg.Opcode == websocket.OpText {
+//	        fmt.Println(string(msg.Data))
+//	    }
+//	}
+package websocket
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf8"
+
+	"github.com/nemohan/wireread"
+)
+
+var (
+	// ErrProtocol is returned for frames that violate RFC 6455. The
+	// connection should be closed with CloseProtocolError.
+	ErrProtocol = errors.New("websocket: protocol error")
+
+	// ErrMessageTooLarge is returned when a frame or reassembled message
+	// exceeds the decoder's maximum message size.
+	ErrMessageTooLarge = errors.New("websocket: message too large")
+
+	// ErrInvalidUTF8 is returned for text messages and close reasons that
+	// are not valid UTF-8. The connection should be closed with
+	// CloseInvalidPayload.
+	ErrInvalidUTF8 = errors.New("websocket: invalid UTF-8")
+)
+
+// MaxFrameHeaderLen is the size of the largest frame header: two fixed
+// bytes, an 8-byte extended length and a 4-byte masking key.
+const MaxFrameHeaderLen = 14
+
+// MaxControlPayloadLen is the largest payload of a control frame.
+const MaxControlPayloadLen = 125
+
+// Opcode is the frame opcode.
+type Opcode uint8
+
+// Opcodes defined by RFC 6455.
+const (
+	OpContinuation Opcode = 0x0
+	OpText         Opcode = 0x1
+	OpBinary       Opcode = 0x2
+	OpClose        Opcode = 0x8
+	OpPing         Opcode = 0x9
+	OpPong         Opcode = 0xA
+)
+
+func (op Opcode) String() string {
+	switch op {
+	case OpContinuation:
+		return "continuation"
+	case OpText:
+		return "text"
+	case OpBinary:
+		return "binary"
+	case OpClose:
+		return "close"
+	case OpPing:
+		return "ping"
+	case OpPong:
+		return "pong"
+	}
+	return fmt.Sprintf("opcode(%#x)", uint8(op))
+}
+
+// IsControl reports whether op is a control opcode.
+func (op Opcode) IsControl() bool {
+	return op&0x8 != 0
+}
+
+// valid reports whether op is defined; the others are reserved.
+func (op Opcode) valid() bool {
+	return op <= OpBinary || op >= OpClose && op <= OpPong
+}
+
+// FrameHeader is a decoded frame header.
+type FrameHeader struct {
+	Fin    bool
+	RSV1   bool
+	RSV2   bool
+	RSV3   bool
+	Opcode Opcode
+	Masked bool
+	// MaskKey is the masking key; it is only meaningful when Masked is set.
+	MaskKey [4]byte
+	// Length is the payload length.
+	Length uint64
+}
+
+// rsv returns the reserved bits in their wire positions (0x40, 0x20, 0x10).
+func (h *FrameHeader) rsv() byte {
+	var b byte
+	if h.RSV1 {
+		b |= 0x40
+	}
+	if h.RSV2 {
+		b |= 0x20
+	}
+	if h.RSV3 {
+		b |= 0x10
+	}
+	return b
+}
+
+// headerLen returns the total header length implied by the second byte of
+// a frame.
+func headerLen(b1 byte) int {
+	n := 2
+	switch b1 & 0x7F {
+	case 126:
+		n += 2
+	case 127:
+		n += 8
+	}
+	if b1&0x80 != 0 {
+		n += 4
+	}
+	return n
+}
+
+// ParseFrameHeader parses the frame header at the start of data and returns
+// it with its encoded length. It returns io.ErrUnexpectedEOF if data holds an
+// incomplete header, and ErrProtocol for reserved opcodes, invalid control
+// frames and non-minimal length encodings. Reserved bits are returned as
+// found; whether they are allowed depends on the negotiated extensions.
+func ParseFrameHeader(data []byte) (FrameHeader, int, error) {
+	r := wireread.NewSafeReader(data)
+	b0, err := r.ReadByte()
+	if err != nil {
+		return FrameHeader{}, 0, io.ErrUnexpectedEOF
+	}
+	b1, err := r.ReadByte()
+	if err != nil {
+		return FrameHeader{}, 0, io.ErrUnexpectedEOF
+	}
+	h := FrameHeader{
+		Fin:    b0&0x80 != 0,
+		RSV1:   b0&0x40 != 0,
+		RSV2:   b0&0x20 != 0,
+		RSV3:   b0&0x10 != 0,
+		Opcode: Opcode(b0 & 0x0F),
+		Masked: b1&0x80 != 0,
+		Length: uint64(b1 & 0x7F),
+	}
+
+	switch h.Length {
+	case 126:
+		n, err := r.ReadUint16BE()
+		if err != nil {
+			return FrameHeader{}, 0, err
+		}
+		if n < 126 {
+			return FrameHeader{}, 0, fmt.Errorf("%w: non-minimal 16-bit length %d", ErrProtocol, n)
+		}
+		h.Length = uint64(n)
+	case 127:
+		n, err := r.ReadUint64BE()
+		if err != nil {
+			return FrameHeader{}, 0, err
+		}
+		if n>>63 != 0 {
+			return FrameHeader{}, 0, fmt.Errorf("%w: 64-bit length has the most significant bit set", ErrProtocol)
+		}
+		if n <= 0xFFFF {
+			return FrameHeader{}, 0, fmt.Errorf("%w: non-minimal 64-bit length %d", ErrProtocol, n)
+		}
+		h.Length = n
+	}
+	if h.Masked {
+		key, err := r.ReadBytes(4)
+		if err != nil {
+			return FrameHeader{}, 0, err
+		}
+		copy(h.MaskKey[:], key)
+	}
+
+	if !h.Opcode.valid() {
+		return FrameHeader{}, 0, fmt.Errorf("%w: reserved opcode %#x", ErrProtocol, uint8(h.Opcode))
+	}
+	if h.Opcode.IsControl() {
+		if !h.Fin {
+			return FrameHeader{}, 0, fmt.Errorf("%w: fragmented %s frame", ErrProtocol, h.Opcode)
+		}
+		if h.Length > MaxControlPayloadLen {
+			return FrameHeader{}, 0, fmt.Errorf("%w: %s payload of %d bytes", ErrProtocol, h.Opcode, h.Length)
+		}
+	}
+	return h, len(data) - len(r.Bytes()), nil
+}
+
+// Close status codes.
+const (
+	CloseNormal             uint16 = 1000
+	CloseGoingAway          uint16 = 1001
+	CloseProtocolError      uint16 = 1002
+	CloseUnsupportedData    uint16 = 1003
+	CloseNoStatus           uint16 = 1005
+	CloseAbnormal           uint16 = 1006
+	CloseInvalidPayload     uint16 = 1007
+	ClosePolicyViolation    uint16 = 1008
+	CloseMessageTooBig      uint16 = 1009
+	CloseMandatoryExtension uint16 = 1010
+	CloseInternalError      uint16 = 1011
+	CloseServiceRestart     uint16 = 1012
+	CloseTryAgainLater      uint16 = 1013
+	CloseBadGateway         uint16 = 1014
+)
+
+// ParseClose decodes the payload of a close frame. An empty payload yields
+// CloseNoStatus. Codes that must not be sent on the wire, such as 1005 and
+// 1006, are rejected.
+func ParseClose(payload []byte) (code uint16, reason string, err error) {
+	switch len(payload) {
+	case 0:
+		return CloseNoStatus, "", nil
+	case 1:
+		return 0, "", fmt.Errorf("%w: close payload of 1 byte", ErrProtocol)
+	}
+	code = uint16(payload[0])<<8 | uint16(payload[1])
+	switch {
+	case code >= CloseNormal && code <= CloseUnsupportedData,
+		code >= CloseInvalidPayload && code <= CloseBadGateway,
+		code >= 3000 && code <= 4999:
+	default:
+		return 0, "", fmt.Errorf("%w: invalid close code %d", ErrProtocol, code)
+	}
+	if !utf8.Valid(payload[2:]) {
+		return 0, "", fmt.Errorf("%w: close reason", ErrInvalidUTF8)
+	}
+	return code, string(payload[2:]), nil
+}
@@ -0,0 +1,123 @@
+package websocket
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestParseFrameHeader(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want FrameHeader
+		n    int
+	}{
+		{"small text", []byte{0x81, 0x05}, FrameHeader{Fin: true, Opcode: OpText, Length: 5}, 2},
+		{"masked", []byte{0x82, 0x83, 1, 2, 3, 4}, FrameHeader{Fin: true, Opcode: OpBinary, Masked: true, MaskKey: [4]byte{1, 2, 3, 4}, Length: 3}, 6},
+		{"16-bit length", []byte{0x01, 0x7E, 0x01, 0x00}, FrameHeader{Opcode: OpText, Length: 256}, 4},
+		{"64-bit length", []byte{0xC2, 0x7F, 0, 0, 0, 0, 0, 1, 0, 0}, FrameHeader{Fin: true, RSV1: true, Opcode: OpBinary, Length: 65536}, 10},
+		{"ping", []byte{0x89, 0x00}, FrameHeader{Fin: true, Opcode: OpPing}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, n, err := ParseFrameHeader(tt.data)
+			if err != nil {
+				t.Fatalf("ParseFrameHeader() error = %v", err)
+			}
+			if h != tt.want || n != tt.n {
+				t.Errorf("ParseFrameHeader() = %+v, %d, want %+v, %d", h, n, tt.want, tt.n)
+			}
+			if got := headerLen(tt.data[1]); got != tt.n {
+				t.Errorf("headerLen() = %d, want %d", got, tt.n)
+			}
+		})
+	}
+}
+
+func TestParseFrameHeader_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"empty", nil, io.ErrUnexpectedEOF},
+		{"truncated length", []byte{0x81, 0x7E, 0x01}, io.ErrUnexpectedEOF},
+		{"truncated mask", []byte{0x81, 0x81, 1, 2}, io.ErrUnexpectedEOF},
+		{"reserved opcode", []byte{0x83, 0x00}, ErrProtocol},
+		{"reserved control opcode", []byte{0x8B, 0x00}, ErrProtocol},
+		{"fragmented ping", []byte{0x09, 0x00}, ErrProtocol},
+		{"long close", []byte{0x88, 0x7E, 0x00, 0x7E}, ErrProtocol},
+		{"non-minimal 16-bit", []byte{0x82, 0x7E, 0x00, 0x10}, ErrProtocol},
+		{"non-minimal 64-bit", []byte{0x82, 0x7F, 0, 0, 0, 0, 0, 0, 0xFF, 0xFF}, ErrProtocol},
+		{"64-bit msb", []byte{0x82, 0x7F, 0x80, 0, 0, 0, 0, 0, 0, 0}, ErrProtocol},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := ParseFrameHeader(tt.data); !errors.Is(err, tt.want) {
+				t.Errorf("ParseFrameHeader() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnmask(t *testing.T) {
+	key := [4]byte{0x37, 0xfa, 0x21, 0x3d}
+	// "Hello" masked, from RFC 6455 Section 5.7.
+	masked := []byte{0x7f, 0x9f, 0x4d, 0x51, 0x58}
+
+	dst := make([]byte, len(masked))
+	if pos := UnmaskTo(dst, masked, key, 0); string(dst) != "Hello" || pos != 1 {
+		t.Errorf("UnmaskTo() = %q, %d, want \"Hello\", 1", dst, pos)
+	}
+
+	// Long payloads take the word-at-a-time path; unmasking in uneven
+	// chunks must give the same result as a byte-by-byte reference.
+	src := make([]byte, 1000)
+	for i := range src {
+		src[i] = byte(i * 7)
+	}
+	want := make([]byte, len(src))
+	for i := range src {
+		want[i] = src[i] ^ key[i%4]
+	}
+	b := append([]byte(nil), src...)
+	pos := 0
+	for off, n := 0, 0; off < len(b); off += n {
+		n = min(13+off%9, len(b)-off)
+		pos = Unmask(b[off:off+n], key, pos)
+	}
+	if !bytes.Equal(b, want) {
+		t.Errorf("Unmask() in chunks differs from reference")
+	}
+}
+
+func TestParseClose(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload []byte
+		code    uint16
+		reason  string
+		err     error
+	}{
+		{"empty", nil, CloseNoStatus, "", nil},
+		{"normal", []byte{0x03, 0xE8, 'b', 'y', 'e'}, CloseNormal, "bye", nil},
+		{"private", []byte{0x0F, 0xA0}, 4000, "", nil},
+		{"one byte", []byte{0x03}, 0, "", ErrProtocol},
+		{"no status on wire", []byte{0x03, 0xED}, 0, "", ErrProtocol},
+		{"unassigned", []byte{0x07, 0xD0}, 0, "", ErrProtocol},
+		{"bad reason", []byte{0x03, 0xE8, 0xC3, 0x28}, 0, "", ErrInvalidUTF8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, reason, err := ParseClose(tt.payload)
+			if !errors.Is(err, tt.err) || code != tt.code || reason != tt.reason {
+				t.Errorf("ParseClose() = %d, %q, %v, want %d, %q, %v", code, reason, err, tt.code, tt.reason, tt.err)
+			}
+		})
+	}
+}